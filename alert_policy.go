@@ -0,0 +1,77 @@
+package diodes
+
+import (
+	"log"
+	"sort"
+	"sync/atomic"
+)
+
+// AlertRule sends alerts of at least MinMissed dropped values to every one
+// of its destinations.
+type AlertRule struct {
+	MinMissed    int
+	Destinations []Alerter
+}
+
+// AlertPolicy is an Alerter that fans each alert out to the destinations
+// of every rule it matches, e.g. a counter for any drop, a log line for a
+// few hundred and a page for thousands. Destinations are plain Alerters, so
+// existing alerters and AlertFuncs can be used as callbacks. It is safe to
+// share between diodes if its destinations are.
+type AlertPolicy struct {
+	rules []AlertRule
+}
+
+// NewAlertPolicy returns a new AlertPolicy with the given rules. Alerts that
+// match no rule are ignored.
+func NewAlertPolicy(rules ...AlertRule) *AlertPolicy {
+	rules = append([]AlertRule(nil), rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].MinMissed < rules[j].MinMissed
+	})
+
+	return &AlertPolicy{
+		rules: rules,
+	}
+}
+
+// Alert forwards missed to the destinations of every matching rule, in
+// order of increasing MinMissed.
+func (p *AlertPolicy) Alert(missed int) {
+	for _, r := range p.rules {
+		if missed < r.MinMissed {
+			return
+		}
+
+		for _, d := range r.Destinations {
+			d.Alert(missed)
+		}
+	}
+}
+
+// LogAlerter returns an Alerter that logs every alert to l.
+func LogAlerter(l *log.Logger) Alerter {
+	return AlertFunc(func(missed int) {
+		l.Printf("Dropped %d messages", missed)
+	})
+}
+
+// CounterAlerter returns an Alerter that adds every alert to c, e.g. to
+// back a metric.
+func CounterAlerter(c *atomic.Uint64) Alerter {
+	return AlertFunc(func(missed int) {
+		c.Add(uint64(missed))
+	})
+}
+
+// ChannelAlerter returns an Alerter that sends every alert to ch without
+// blocking the reader, e.g. for a dead-letter handler running on its own
+// go-routine. Alerts are discarded when ch is full.
+func ChannelAlerter(ch chan<- int) Alerter {
+	return AlertFunc(func(missed int) {
+		select {
+		case ch <- missed:
+		default:
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package diodes_test
+
+import (
+	"bytes"
+	"log"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AlertPolicy", func() {
+	var (
+		any, many *spyAlerter
+		p         *diodes.AlertPolicy
+	)
+
+	BeforeEach(func() {
+		any = newSpyAlerter()
+		many = newSpyAlerter()
+
+		p = diodes.NewAlertPolicy(
+			diodes.AlertRule{MinMissed: 100, Destinations: []diodes.Alerter{many}},
+			diodes.AlertRule{MinMissed: 1, Destinations: []diodes.Alerter{any}},
+		)
+	})
+
+	It("forwards alerts to the rules they match", func() {
+		p.Alert(5)
+
+		Expect(any.AlertInput.Missed).To(Receive(Equal(5)))
+		Expect(many.AlertCalled).ToNot(Receive())
+	})
+
+	It("forwards alerts to every matching rule", func() {
+		p.Alert(500)
+
+		Expect(any.AlertInput.Missed).To(Receive(Equal(500)))
+		Expect(many.AlertInput.Missed).To(Receive(Equal(500)))
+	})
+
+	It("ignores alerts that match no rule", func() {
+		p.Alert(0)
+
+		Expect(any.AlertCalled).ToNot(Receive())
+		Expect(many.AlertCalled).ToNot(Receive())
+	})
+})
+
+var _ = Describe("alert destinations", func() {
+	It("logs alerts", func() {
+		var buf bytes.Buffer
+		diodes.LogAlerter(log.New(&buf, "", 0)).Alert(3)
+		Expect(buf.String()).To(Equal("Dropped 3 messages\n"))
+	})
+
+	It("counts alerts", func() {
+		var c atomic.Uint64
+		a := diodes.CounterAlerter(&c)
+		a.Alert(3)
+		a.Alert(4)
+		Expect(c.Load()).To(Equal(uint64(7)))
+	})
+
+	It("sends alerts to a channel without blocking", func() {
+		ch := make(chan int, 1)
+		a := diodes.ChannelAlerter(ch)
+		a.Alert(3)
+		a.Alert(4)
+
+		Expect(ch).To(Receive(Equal(3)))
+		Expect(ch).ToNot(Receive())
+	})
+})
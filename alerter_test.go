@@ -0,0 +1,79 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alerter", func() {
+	// marker sets the negative number of dropped values on the diode it
+	// alerts for.
+	type marker struct {
+		d diodes.Diode
+	}
+
+	alert := func(m *marker) diodes.AlertFunc {
+		return func(missed int) {
+			v := -missed
+			m.d.Set(diodes.GenericDataType(&v))
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	DescribeTable("can set a marker on the diode it alerts for",
+		func(newDiode func(diodes.Alerter) diodes.Diode) {
+			m := &marker{}
+			m.d = newDiode(alert(m))
+			for i := 0; i < 6; i++ {
+				j := i
+				m.d.Set(diodes.GenericDataType(&j))
+			}
+
+			Expect(read(m.d)).To(Equal([]int{4, 5, -4}))
+		},
+		Entry("OneToOne", func(a diodes.Alerter) diodes.Diode {
+			return diodes.NewOneToOne(4, a)
+		}),
+		Entry("ManyToOne", func(a diodes.Alerter) diodes.Diode {
+			return diodes.NewManyToOne(4, a)
+		}),
+		Entry("ManyToMany", func(a diodes.Alerter) diodes.Diode {
+			return diodes.NewManyToMany(4, a)
+		}),
+		Entry("Waiter", func(a diodes.Alerter) diodes.Diode {
+			return diodes.NewWaiter(diodes.NewManyToOne(4, a))
+		}),
+	)
+
+	It("sets the marker after the reader moved on", func() {
+		var took time.Duration
+		m := &marker{}
+		m.d = diodes.NewManyToOne(4, diodes.AlertFunc(func(missed int) {
+			start := time.Now()
+			alert(m)(missed)
+			took = time.Since(start)
+		}), diodes.WithBackpressure(100*time.Millisecond))
+		for i := 0; i < 6; i++ {
+			j := i
+			m.d.Set(diodes.GenericDataType(&j))
+		}
+
+		// The marker does not wait for the reader that is setting it.
+		Expect(read(m.d)).To(Equal([]int{4, 5, -4}))
+		Expect(took).To(BeNumerically("<", 100*time.Millisecond))
+	})
+})
@@ -0,0 +1,44 @@
+//go:build 386 || arm || mips || mipsle
+
+package diodes_test
+
+import (
+	"unsafe"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// On 32-bit platforms the compiler only aligns a uint64 to 4 bytes, so a
+// diode embedded in another struct could end up with indexes the atomic
+// operations panic on.
+var _ = Describe("Alignment on 32-bit platforms", func() {
+	It("keeps the diodes 64-bit aligned when embedded in other structs", func() {
+		var s struct {
+			_ byte
+			a diodes.OneToOne
+			_ byte
+			b diodes.ManyToOne
+			_ byte
+			c diodes.ManyToMany
+			_ byte
+			d diodes.OneToMany
+			_ byte
+			e diodes.OneToManyReader
+			_ byte
+			f diodes.Histogram
+			_ byte
+			g diodes.TapObserver
+		}
+
+		Expect(unsafe.Offsetof(s.a) % 8).To(BeZero())
+		Expect(unsafe.Offsetof(s.b) % 8).To(BeZero())
+		Expect(unsafe.Offsetof(s.c) % 8).To(BeZero())
+		Expect(unsafe.Offsetof(s.d) % 8).To(BeZero())
+		Expect(unsafe.Offsetof(s.e) % 8).To(BeZero())
+		Expect(unsafe.Offsetof(s.f) % 8).To(BeZero())
+		Expect(unsafe.Offsetof(s.g) % 8).To(BeZero())
+	})
+})
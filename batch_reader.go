@@ -0,0 +1,150 @@
+package diodes
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Batch is a set of data read from a diode by a BatchReader. Batches are
+// pooled by the BatchReader and must be given back via Release once the
+// data is no longer needed.
+type Batch struct {
+	// Data holds the values read from the diode.
+	Data []GenericDataType
+
+	pool     *sync.Pool
+	released bool
+}
+
+// Release returns the batch to the BatchReader it came from. Neither the
+// batch nor its Data may be used after it has been released.
+func (b *Batch) Release() {
+	if b.released {
+		return
+	}
+
+	for i := range b.Data {
+		b.Data[i] = nil
+	}
+	b.Data = b.Data[:0]
+	b.released = true
+	b.pool.Put(b)
+}
+
+// BatchReader reads data from a diode in batches. The batches are handed
+// out from an internal pool so that steady state reads do not allocate. It
+// is not thread safe for multiple readers.
+type BatchReader struct {
+	d      Diode
+	size   int
+	pool   sync.Pool
+	onLeak func()
+	yield  int
+	closed atomic.Bool
+}
+
+// BatchReaderConfigOption can be used to setup the batch reader.
+type BatchReaderConfigOption func(*BatchReader)
+
+// WithBatchLeakDetection enables a debug mode where onLeak is invoked when a
+// batch is garbage collected without having been released. It relies on
+// finalizers and should not be used in production.
+func WithBatchLeakDetection(onLeak func()) BatchReaderConfigOption {
+	return BatchReaderConfigOption(func(r *BatchReader) {
+		r.onLeak = onLeak
+	})
+}
+
+// WithBatchYield makes TryNext yield the processor to other go-routines
+// after every n values it reads, so that reading very large batches does not
+// starve them. The default is to never yield.
+func WithBatchYield(n int) BatchReaderConfigOption {
+	return BatchReaderConfigOption(func(r *BatchReader) {
+		r.yield = n
+	})
+}
+
+// NewBatchReader returns a new BatchReader that reads batches of at most
+// size values from the given diode.
+func NewBatchReader(d Diode, size int, opts ...BatchReaderConfigOption) *BatchReader {
+	r := &BatchReader{
+		d:    d,
+		size: size,
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	r.pool.New = func() any {
+		b := &Batch{
+			Data: make([]GenericDataType, 0, r.size),
+			pool: &r.pool,
+		}
+
+		if r.onLeak != nil {
+			onLeak := r.onLeak
+			runtime.SetFinalizer(b, func(b *Batch) {
+				if !b.released {
+					onLeak()
+				}
+			})
+		}
+
+		return b
+	}
+
+	return r
+}
+
+// TryNext will attempt to read a batch of data from the wrapped diode. If
+// there is no data available or the end of the stream was reached (see
+// Poller.Close and Waiter.Close), it will return (nil, false). The returned
+// batch must be released once the caller is done with it.
+func (r *BatchReader) TryNext() (*Batch, bool) {
+	data, ok := r.tryNext()
+	if !ok {
+		return nil, false
+	}
+
+	b := r.pool.Get().(*Batch)
+	b.released = false
+	b.Data = append(b.Data, data)
+
+	budget := readBudget{limit: r.yield}
+	budget.spend()
+	for len(b.Data) < r.size {
+		data, ok := r.tryNext()
+		if !ok {
+			break
+		}
+		budget.spend()
+		b.Data = append(b.Data, data)
+	}
+
+	return b, true
+}
+
+// Closed reports whether the reader has reached the end of the stream,
+// either itself or via the wrapped Poller or Waiter.
+func (r *BatchReader) Closed() bool {
+	if c, ok := r.d.(interface{ Closed() bool }); ok && c.Closed() {
+		return true
+	}
+	return r.closed.Load()
+}
+
+func (r *BatchReader) tryNext() (GenericDataType, bool) {
+	if r.closed.Load() {
+		return nil, false
+	}
+
+	data, ok := r.d.TryNext()
+	if ok && data == endOfStream {
+		r.closed.Store(true)
+		return nil, false
+	}
+
+	return data, ok
+}
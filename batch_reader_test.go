@@ -0,0 +1,165 @@
+package diodes_test
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BatchReader", func() {
+	var (
+		d *diodes.OneToOne
+		r *diodes.BatchReader
+	)
+
+	BeforeEach(func() {
+		d = diodes.NewOneToOne(100, nil)
+		r = diodes.NewBatchReader(d, 2)
+	})
+
+	Describe("TryNext()", func() {
+		It("returns false when there is no data", func() {
+			_, ok := r.TryNext()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns batches of at most the configured size", func() {
+			for i := 0; i < 3; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+
+			b, ok := r.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(b.Data).To(HaveLen(2))
+			Expect(*(*int)(b.Data[0])).To(Equal(0))
+			Expect(*(*int)(b.Data[1])).To(Equal(1))
+			b.Release()
+
+			b, ok = r.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(b.Data).To(HaveLen(1))
+			Expect(*(*int)(b.Data[0])).To(Equal(2))
+			b.Release()
+		})
+
+		It("reuses released batches", func() {
+			if raceEnabled {
+				Skip("sync.Pool randomly drops items with the race detector enabled")
+			}
+
+			data := []byte("some-data")
+			for i := 0; i < 100; i++ {
+				d.Set(diodes.GenericDataType(&data))
+			}
+
+			Expect(testing.AllocsPerRun(10, func() {
+				b, _ := r.TryNext()
+				b.Release()
+			})).To(BeZero())
+		})
+	})
+
+	Context("when the stream is closed", func() {
+		It("returns the data before the end of the stream", func() {
+			p := diodes.NewPoller(d)
+			r = diodes.NewBatchReader(d, 10)
+
+			data := []byte("some-data")
+			p.Set(diodes.GenericDataType(&data))
+			p.Close()
+
+			b, ok := r.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(b.Data).To(HaveLen(1))
+			Expect(r.Closed()).To(BeTrue())
+			b.Release()
+
+			_, ok = r.TryNext()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("reports the end of the stream of a wrapped poller", func() {
+			p := diodes.NewPoller(d)
+			r = diodes.NewBatchReader(p, 10)
+			p.Close()
+
+			_, ok := r.TryNext()
+			Expect(ok).To(BeFalse())
+			Expect(r.Closed()).To(BeTrue())
+		})
+	})
+
+	Context("with a yield", func() {
+		It("yields to other go-routines while reading a batch", func() {
+			defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+			r = diodes.NewBatchReader(d, 100, diodes.WithBatchYield(10))
+			for i := 0; i < 100; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+
+			var ran atomic.Bool
+			go ran.Store(true)
+
+			b, ok := r.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(b.Data).To(HaveLen(100))
+			Expect(ran.Load()).To(BeTrue())
+			b.Release()
+		})
+	})
+
+	Describe("Release()", func() {
+		It("clears the data", func() {
+			data := []byte("some-data")
+			d.Set(diodes.GenericDataType(&data))
+
+			b, _ := r.TryNext()
+			b.Release()
+			Expect(b.Data).To(BeEmpty())
+		})
+	})
+
+	Context("with leak detection", func() {
+		It("reports batches that were never released", func() {
+			var leaked int64
+			r = diodes.NewBatchReader(d, 2, diodes.WithBatchLeakDetection(func() {
+				atomic.AddInt64(&leaked, 1)
+			}))
+
+			data := []byte("some-data")
+			d.Set(diodes.GenericDataType(&data))
+			_, ok := r.TryNext()
+			Expect(ok).To(BeTrue())
+
+			Eventually(func() int64 {
+				runtime.GC()
+				return atomic.LoadInt64(&leaked)
+			}).Should(Equal(int64(1)))
+		})
+
+		It("does not report released batches", func() {
+			var leaked int64
+			r = diodes.NewBatchReader(d, 2, diodes.WithBatchLeakDetection(func() {
+				atomic.AddInt64(&leaked, 1)
+			}))
+
+			data := []byte("some-data")
+			d.Set(diodes.GenericDataType(&data))
+			b, _ := r.TryNext()
+			b.Release()
+
+			Consistently(func() int64 {
+				runtime.GC()
+				return atomic.LoadInt64(&leaked)
+			}, "200ms").Should(BeZero())
+		})
+	})
+})
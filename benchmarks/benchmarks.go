@@ -0,0 +1,181 @@
+// Package benchmarks compares the throughput, drops and allocations of the
+// diodes with buffered channels and a channel of sync.Pool backed payloads
+// under a configurable workload. Unlike the benchmarks of the diodes
+// package, it can be run against any build, e.g. to check an upgrade for
+// performance regressions (see cmd/diodebench).
+package benchmarks
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Queues that can be compared.
+const (
+	OneToOne         = "one-to-one"
+	SPSC             = "spsc"
+	ManyToOne        = "many-to-one"
+	ManyToMany       = "many-to-many"
+	ShardedManyToOne = "sharded-many-to-one"
+	Channel          = "channel"
+	PooledChannel    = "pooled-channel"
+)
+
+// Queues lists every queue in the order they are usually reported.
+var Queues = []string{OneToOne, SPSC, ManyToOne, ManyToMany, ShardedManyToOne, Channel, PooledChannel}
+
+// Config describes a workload.
+type Config struct {
+	// Queue is the kind of queue to drive, one of Queues.
+	Queue string
+	// Size is the size of the diode or the capacity of the channel.
+	Size int
+	// Writers is the number of concurrent writers.
+	Writers int
+	// PayloadSize is the number of bytes written per value.
+	PayloadSize int
+	// ReaderDelay is how long the reader spends on each value, to simulate a
+	// slow consumer.
+	ReaderDelay time.Duration
+	// Duration is how long the writers run.
+	Duration time.Duration
+}
+
+// Result is the outcome of a workload.
+type Result struct {
+	Writes  uint64
+	Reads   uint64
+	Dropped uint64
+	Elapsed time.Duration
+
+	// Allocs and Bytes are the number of heap allocations and allocated
+	// bytes of the whole process during the run.
+	Allocs uint64
+	Bytes  uint64
+}
+
+// WriteRate returns the number of writes per second.
+func (r Result) WriteRate() float64 {
+	return perSecond(r.Writes, r.Elapsed)
+}
+
+// ReadRate returns the number of reads per second.
+func (r Result) ReadRate() float64 {
+	return perSecond(r.Reads, r.Elapsed)
+}
+
+// DropRatio returns the fraction of writes that were dropped.
+func (r Result) DropRatio() float64 {
+	if r.Writes == 0 {
+		return 0
+	}
+	return float64(r.Dropped) / float64(r.Writes)
+}
+
+// AllocsPerWrite returns the number of heap allocations per write.
+func (r Result) AllocsPerWrite() float64 {
+	if r.Writes == 0 {
+		return 0
+	}
+	return float64(r.Allocs) / float64(r.Writes)
+}
+
+// BytesPerWrite returns the number of allocated bytes per write.
+func (r Result) BytesPerWrite() float64 {
+	if r.Writes == 0 {
+		return 0
+	}
+	return float64(r.Bytes) / float64(r.Writes)
+}
+
+func perSecond(n uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// Run drives the workload described by c until its duration has elapsed or
+// the context is done, and then drains what is left in the queue. The
+// writers write as fast as they can.
+func Run(ctx context.Context, c Config) (Result, error) {
+	if c.Size < 1 {
+		return Result{}, errors.New("benchmarks: size must be positive")
+	}
+	if c.Writers < 1 {
+		return Result{}, errors.New("benchmarks: writers must be positive")
+	}
+
+	q, err := newQueue(c)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, c.Duration)
+	defer cancel()
+
+	var (
+		writes atomic.Uint64
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < c.Writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				q.set(q.payload(c.PayloadSize))
+				writes.Add(1)
+			}
+		}()
+	}
+
+	var done atomic.Bool
+	go func() {
+		wg.Wait()
+		done.Store(true)
+	}()
+
+	var reads uint64
+	for {
+		p, ok := q.tryNext()
+		if !ok && !done.Load() {
+			runtime.Gosched()
+			continue
+		}
+		if !ok {
+			// A writer can have set a value between the read and the
+			// writers being done, so only an empty queue after that is the
+			// end.
+			if p, ok = q.tryNext(); !ok {
+				break
+			}
+		}
+
+		reads++
+		q.release(p)
+		if c.ReaderDelay > 0 {
+			time.Sleep(c.ReaderDelay)
+		}
+	}
+
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return Result{
+		Writes:  writes.Load(),
+		Reads:   reads,
+		Dropped: q.dropped(),
+		Elapsed: elapsed,
+		Allocs:  after.Mallocs - before.Mallocs,
+		Bytes:   after.TotalAlloc - before.TotalAlloc,
+	}, nil
+}
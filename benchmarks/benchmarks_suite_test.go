@@ -0,0 +1,13 @@
+package benchmarks_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBenchmarks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Benchmarks Suite")
+}
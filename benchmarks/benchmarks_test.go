@@ -0,0 +1,99 @@
+package benchmarks_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes/benchmarks"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	BeforeEach(func() {
+		log.SetOutput(io.Discard)
+	})
+
+	DescribeTable("accounts for every write",
+		func(queue string, writers int) {
+			r, err := benchmarks.Run(context.Background(), benchmarks.Config{
+				Queue:       queue,
+				Size:        64,
+				Writers:     writers,
+				PayloadSize: 16,
+				Duration:    20 * time.Millisecond,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(r.Writes).ToNot(BeZero())
+			Expect(r.Reads).ToNot(BeZero())
+			Expect(r.Reads + r.Dropped).To(Equal(r.Writes))
+			Expect(r.WriteRate()).To(BeNumerically(">=", r.ReadRate()))
+			Expect(r.Elapsed).To(BeNumerically(">=", 20*time.Millisecond))
+		},
+		Entry("one-to-one", benchmarks.OneToOne, 1),
+		Entry("spsc", benchmarks.SPSC, 1),
+		Entry("many-to-one", benchmarks.ManyToOne, 2),
+		Entry("many-to-many", benchmarks.ManyToMany, 2),
+		Entry("sharded-many-to-one", benchmarks.ShardedManyToOne, 2),
+		Entry("channel", benchmarks.Channel, 2),
+		Entry("pooled-channel", benchmarks.PooledChannel, 2),
+	)
+
+	It("reports drops when the reader falls behind", func() {
+		r, err := benchmarks.Run(context.Background(), benchmarks.Config{
+			Queue:       benchmarks.Channel,
+			Size:        8,
+			Writers:     1,
+			ReaderDelay: time.Millisecond,
+			Duration:    20 * time.Millisecond,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.Dropped).ToNot(BeZero())
+		Expect(r.DropRatio()).To(BeNumerically(">", 0))
+	})
+
+	It("allocates less per write with pooled payloads", func() {
+		c := benchmarks.Config{
+			Size:        1024,
+			Writers:     1,
+			PayloadSize: 4096,
+			Duration:    20 * time.Millisecond,
+		}
+
+		c.Queue = benchmarks.Channel
+		plain, err := benchmarks.Run(context.Background(), c)
+		Expect(err).ToNot(HaveOccurred())
+
+		c.Queue = benchmarks.PooledChannel
+		pooled, err := benchmarks.Run(context.Background(), c)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(plain.BytesPerWrite()).To(BeNumerically(">=", 4096))
+		Expect(pooled.BytesPerWrite()).To(BeNumerically("<", plain.BytesPerWrite()))
+	})
+
+	It("rejects a one-to-one diode with several writers", func() {
+		_, err := benchmarks.Run(context.Background(), benchmarks.Config{
+			Queue:    benchmarks.OneToOne,
+			Size:     8,
+			Writers:  2,
+			Duration: time.Millisecond,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects an unknown queue", func() {
+		_, err := benchmarks.Run(context.Background(), benchmarks.Config{
+			Queue:    "unknown",
+			Size:     8,
+			Writers:  1,
+			Duration: time.Millisecond,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
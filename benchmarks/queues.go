@@ -0,0 +1,152 @@
+package benchmarks
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// queue is the common interface of the compared queues. It carries
+// payloads, which are allocated per write unless the queue pools them.
+type queue interface {
+	payload(size int) *[]byte
+	set(p *[]byte)
+	tryNext() (*[]byte, bool)
+	release(p *[]byte)
+	dropped() uint64
+}
+
+func newQueue(c Config) (queue, error) {
+	switch c.Queue {
+	case OneToOne:
+		if c.Writers != 1 {
+			return nil, errors.New("benchmarks: one-to-one diodes support a single writer")
+		}
+		q := &diodeQueue{}
+		q.d = diodes.NewOneToOne(c.Size, q)
+		return q, nil
+	case SPSC:
+		if c.Writers != 1 {
+			return nil, errors.New("benchmarks: spsc diodes support a single writer")
+		}
+		q := &diodeQueue{}
+		q.d = diodes.NewSPSC(c.Size, q)
+		return q, nil
+	case ManyToOne, "":
+		q := &diodeQueue{}
+		q.d = diodes.NewManyToOne(c.Size, q)
+		return q, nil
+	case ManyToMany:
+		q := &diodeQueue{}
+		q.d = diodes.NewManyToMany(c.Size, q)
+		return q, nil
+	case ShardedManyToOne:
+		q := &diodeQueue{}
+		q.d = diodes.NewShardedManyToOne(0, c.Size, q)
+		return q, nil
+	case Channel:
+		return &chanQueue{ch: make(chan *[]byte, c.Size)}, nil
+	case PooledChannel:
+		return &pooledChanQueue{chanQueue: chanQueue{ch: make(chan *[]byte, c.Size)}}, nil
+	default:
+		return nil, errors.New("benchmarks: unknown queue " + c.Queue)
+	}
+}
+
+// diodeQueue drives a diode and counts the drops it alerts.
+type diodeQueue struct {
+	d     diodes.Diode
+	drops atomic.Uint64
+}
+
+func (q *diodeQueue) Alert(missed int) {
+	q.drops.Add(uint64(missed))
+}
+
+func (q *diodeQueue) payload(size int) *[]byte {
+	p := make([]byte, size)
+	return &p
+}
+
+func (q *diodeQueue) set(p *[]byte) {
+	q.d.Set(diodes.GenericDataType(p))
+}
+
+func (q *diodeQueue) tryNext() (*[]byte, bool) {
+	data, ok := q.d.TryNext()
+	return (*[]byte)(unsafe.Pointer(data)), ok
+}
+
+func (q *diodeQueue) release(*[]byte) {}
+
+func (q *diodeQueue) dropped() uint64 {
+	return q.drops.Load()
+}
+
+// chanQueue drives a buffered channel. Writes to a full channel are dropped
+// instead of blocking the writer, which is the closest a channel gets to a
+// diode.
+type chanQueue struct {
+	ch    chan *[]byte
+	drops atomic.Uint64
+}
+
+func (q *chanQueue) payload(size int) *[]byte {
+	p := make([]byte, size)
+	return &p
+}
+
+func (q *chanQueue) set(p *[]byte) {
+	select {
+	case q.ch <- p:
+	default:
+		q.drops.Add(1)
+	}
+}
+
+func (q *chanQueue) tryNext() (*[]byte, bool) {
+	select {
+	case p := <-q.ch:
+		return p, true
+	default:
+		return nil, false
+	}
+}
+
+func (q *chanQueue) release(*[]byte) {}
+
+func (q *chanQueue) dropped() uint64 {
+	return q.drops.Load()
+}
+
+// pooledChanQueue is a chanQueue that takes its payloads from a sync.Pool
+// and returns them once they were read or dropped.
+type pooledChanQueue struct {
+	chanQueue
+	pool sync.Pool
+}
+
+func (q *pooledChanQueue) payload(size int) *[]byte {
+	if p, ok := q.pool.Get().(*[]byte); ok && cap(*p) >= size {
+		*p = (*p)[:size]
+		return p
+	}
+	p := make([]byte, size)
+	return &p
+}
+
+func (q *pooledChanQueue) set(p *[]byte) {
+	select {
+	case q.ch <- p:
+	default:
+		q.drops.Add(1)
+		q.pool.Put(p)
+	}
+}
+
+func (q *pooledChanQueue) release(p *[]byte) {
+	q.pool.Put(p)
+}
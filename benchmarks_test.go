@@ -68,6 +68,96 @@ func BenchmarkOneToOneWaiter(b *testing.B) {
 	}
 }
 
+func BenchmarkSPSCPoller(b *testing.B) {
+	d := diodes.NewPoller(diodes.NewSPSC(b.N, diodes.AlertFunc(func(missed int) {
+		panic("Oops...")
+	})))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	defer wg.Wait()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < b.N; i++ {
+			data := randData(i)
+			d.Set(diodes.GenericDataType(data))
+		}
+	}()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		d.Next()
+	}
+}
+
+func BenchmarkOneToOneSetTryNext(b *testing.B) {
+	d := diodes.NewOneToOne(1024, nil)
+	data := randData(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		d.Set(diodes.GenericDataType(data))
+		d.TryNext()
+	}
+}
+
+// BenchmarkOneToOneSetTryNextModulo and BenchmarkOneToOneSetTryNextMasked
+// compare locating slots with a modulo to a mask, see WithPowerOfTwoSize.
+func BenchmarkOneToOneSetTryNextModulo(b *testing.B) {
+	benchmarkSetTryNext(b, diodes.NewOneToOne(1000, nil))
+}
+
+func BenchmarkOneToOneSetTryNextMasked(b *testing.B) {
+	benchmarkSetTryNext(b, diodes.NewOneToOne(1000, nil, diodes.WithPowerOfTwoSize()))
+}
+
+func BenchmarkManyToOneSetTryNextModulo(b *testing.B) {
+	benchmarkSetTryNext(b, diodes.NewManyToOne(1000, nil))
+}
+
+func BenchmarkManyToOneSetTryNextMasked(b *testing.B) {
+	benchmarkSetTryNext(b, diodes.NewManyToOne(1000, nil, diodes.WithPowerOfTwoSize()))
+}
+
+func benchmarkSetTryNext(b *testing.B, d diodes.Diode) {
+	data := randData(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		d.Set(diodes.GenericDataType(data))
+		d.TryNext()
+	}
+}
+
+func BenchmarkSPSCSetTryNext(b *testing.B) {
+	d := diodes.NewSPSC(1024, nil)
+	data := randData(0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		d.Set(diodes.GenericDataType(data))
+		d.TryNext()
+	}
+}
+
+// BenchmarkLatestSetTryNext and BenchmarkOneToOneSizeOneSetTryNext
+// compare the Latest register with the OneToOne diode it replaces.
+func BenchmarkLatestSetTryNext(b *testing.B) {
+	benchmarkSetTryNext(b, diodes.NewLatest())
+}
+
+func BenchmarkOneToOneSizeOneSetTryNext(b *testing.B) {
+	benchmarkSetTryNext(b, diodes.NewOneToOne(1, nil))
+}
+
 func BenchmarkManyToOnePoller(b *testing.B) {
 	d := diodes.NewPoller(diodes.NewManyToOne(b.N, diodes.AlertFunc(func(missed int) {
 		panic("Oops...")
@@ -237,6 +327,66 @@ func BenchmarkManyWritersDiode(b *testing.B) {
 	})
 }
 
+func BenchmarkManyWritersShardedDiode(b *testing.B) {
+	d := diodes.NewWaiter(diodes.NewShardedManyToOne(0, 10000, diodes.AlertFunc(func(int) {
+		// NOP
+	})))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		wg.Done()
+		for {
+			d.Next()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	wg.Wait()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			data := randData(i)
+			i++
+			d.Set(diodes.GenericDataType(data))
+		}
+	})
+}
+
+func BenchmarkManyWritersProducer(b *testing.B) {
+	d := diodes.NewManyToOne(10000, diodes.AlertFunc(func(int) {
+		// NOP
+	}))
+	w := diodes.NewWaiter(d)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		wg.Done()
+		for {
+			w.Next()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	wg.Wait()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		p := d.NewProducer()
+		defer p.Close()
+
+		var i int
+		for pb.Next() {
+			data := randData(i)
+			i++
+			p.Set(diodes.GenericDataType(data))
+		}
+	})
+}
+
 func BenchmarkManyWritersChannel(b *testing.B) {
 	c := make(chan []byte, 10000)
 
@@ -0,0 +1,171 @@
+// Package bridge connects diodes across process boundaries. A Sender drains
+// a diode into a connection (typically a unix domain socket) using length
+// prefixed frames and a Receiver writes the frames it reads into a local
+// diode.
+package bridge
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// headerSize is the size of the big endian uint32 length prefix of each
+// frame.
+const headerSize = 4
+
+// DefaultMaxFrameSize is the largest frame a Receiver accepts unless
+// configured otherwise.
+const DefaultMaxFrameSize = 1 << 20
+
+// ErrFrameTooLarge is returned when a frame exceeds the maximum frame size.
+// It matches diodes.ErrTooLarge.
+var ErrFrameTooLarge error = frameTooLargeError{}
+
+type frameTooLargeError struct{}
+
+func (frameTooLargeError) Error() string {
+	return "bridge: frame too large"
+}
+
+func (frameTooLargeError) Is(target error) bool {
+	return target == diodes.ErrTooLarge
+}
+
+// Reader is the read side of a diode that blocks until data is available.
+// It returns an error once no more data will be read, so that nil data is
+// sent like any other value. It is satisfied by diodes.Poller and
+// diodes.Waiter.
+type Reader interface {
+	NextCtx(ctx context.Context) (diodes.GenericDataType, error)
+}
+
+// Writer is the write side of a diode.
+type Writer = diodes.Writer
+
+// EncodeFunc serializes data read from a diode into a frame payload.
+type EncodeFunc func(diodes.GenericDataType) []byte
+
+// DecodeFunc deserializes a frame payload into data for a diode. The given
+// slice is reused for the next frame and must be copied if it is retained.
+type DecodeFunc func([]byte) diodes.GenericDataType
+
+// Sender drains a diode into a connection.
+type Sender struct {
+	r      Reader
+	w      io.Writer
+	encode EncodeFunc
+	buf    []byte
+}
+
+// NewSender returns a new Sender that reads from r and writes length
+// prefixed frames to w.
+func NewSender(r Reader, w io.Writer, encode EncodeFunc) *Sender {
+	return &Sender{
+		r:      r,
+		w:      w,
+		encode: encode,
+	}
+}
+
+// Run writes a frame for every value read from the diode, including nil
+// values. It returns nil once NextCtx returns an error (e.g. the context of
+// the reader is done or the end of the stream was reached) or the first
+// write error.
+func (s *Sender) Run() error {
+	for {
+		data, err := s.r.NextCtx(context.Background())
+		if err != nil {
+			return nil
+		}
+
+		if err := s.send(s.encode(data)); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Sender) send(payload []byte) error {
+	if uint64(len(payload)) > uint64(^uint32(0)) {
+		return ErrFrameTooLarge
+	}
+
+	s.buf = binary.BigEndian.AppendUint32(s.buf[:0], uint32(len(payload)))
+	s.buf = append(s.buf, payload...)
+
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+// Receiver reads frames from a connection and writes them into a diode.
+type Receiver struct {
+	r            io.Reader
+	w            Writer
+	decode       DecodeFunc
+	maxFrameSize int
+}
+
+// ReceiverConfigOption can be used to setup the receiver.
+type ReceiverConfigOption func(*Receiver)
+
+// WithMaxFrameSize sets the largest frame the receiver accepts. The default
+// is DefaultMaxFrameSize.
+func WithMaxFrameSize(size int) ReceiverConfigOption {
+	return ReceiverConfigOption(func(r *Receiver) {
+		r.maxFrameSize = size
+	})
+}
+
+// NewReceiver returns a new Receiver that reads length prefixed frames from
+// r and writes the decoded values to w.
+func NewReceiver(r io.Reader, w Writer, decode DecodeFunc, opts ...ReceiverConfigOption) *Receiver {
+	rc := &Receiver{
+		r:            r,
+		w:            w,
+		decode:       decode,
+		maxFrameSize: DefaultMaxFrameSize,
+	}
+
+	for _, o := range opts {
+		o(rc)
+	}
+
+	return rc
+}
+
+// Run reads frames until the connection is closed, in which case it returns
+// nil, or until a read fails.
+func (r *Receiver) Run() error {
+	var (
+		header [headerSize]byte
+		buf    []byte
+	)
+
+	for {
+		if _, err := io.ReadFull(r.r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		size := binary.BigEndian.Uint32(header[:])
+		if uint64(size) > uint64(r.maxFrameSize) {
+			return ErrFrameTooLarge
+		}
+
+		if cap(buf) < int(size) {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+
+		if _, err := io.ReadFull(r.r, buf); err != nil {
+			return err
+		}
+
+		r.w.Set(r.decode(buf))
+	}
+}
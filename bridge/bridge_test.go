@@ -0,0 +1,162 @@
+package bridge_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/bridge"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bridge", func() {
+	var (
+		encode bridge.EncodeFunc
+		decode bridge.DecodeFunc
+	)
+
+	BeforeEach(func() {
+		encode = func(data diodes.GenericDataType) []byte {
+			if data == nil {
+				return nil
+			}
+			return *(*[]byte)(data)
+		}
+		decode = func(b []byte) diodes.GenericDataType {
+			if len(b) == 0 {
+				return nil
+			}
+			data := append([]byte(nil), b...)
+			return diodes.GenericDataType(&data)
+		}
+	})
+
+	It("moves data from one diode to another over a unix socket", func() {
+		addr := filepath.Join(GinkgoT().TempDir(), "bridge.sock")
+		l, err := net.Listen("unix", addr)
+		Expect(err).ToNot(HaveOccurred())
+		defer l.Close()
+
+		dst := diodes.NewPoller(diodes.NewOneToOne(10, nil), diodes.WithPollingInterval(time.Millisecond))
+		received := make(chan error, 1)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				received <- err
+				return
+			}
+			defer conn.Close()
+			received <- bridge.NewReceiver(conn, dst, decode).Run()
+		}()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		src := diodes.NewWaiter(diodes.NewManyToOne(10, nil), diodes.WithWaiterContext(ctx))
+		conn, err := net.Dial("unix", addr)
+		Expect(err).ToNot(HaveOccurred())
+
+		sent := make(chan error, 1)
+		go func() {
+			sent <- bridge.NewSender(src, conn, encode).Run()
+		}()
+
+		for _, s := range []string{"a", "bb", "ccc"} {
+			data := []byte(s)
+			src.Set(diodes.GenericDataType(&data))
+		}
+		src.Set(nil)
+
+		Expect(*(*[]byte)(dst.Next())).To(Equal([]byte("a")))
+		Expect(*(*[]byte)(dst.Next())).To(Equal([]byte("bb")))
+		Expect(*(*[]byte)(dst.Next())).To(Equal([]byte("ccc")))
+		data, err := dst.NextCtx(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data == nil).To(BeTrue())
+
+		cancel()
+		Eventually(sent).Should(Receive(BeNil()))
+		conn.Close()
+		Eventually(received).Should(Receive(BeNil()))
+	})
+
+	Describe("Sender", func() {
+		It("writes length prefixed frames until the diode's context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			src := diodes.NewWaiter(diodes.NewOneToOne(10, nil), diodes.WithWaiterContext(ctx))
+			data := []byte("some-data")
+			src.Set(diodes.GenericDataType(&data))
+			cancel()
+
+			var buf bytes.Buffer
+			Expect(bridge.NewSender(src, &buf, encode).Run()).To(Succeed())
+			Expect(buf.Bytes()).To(Equal(append([]byte{0, 0, 0, 9}, data...)))
+		})
+
+		It("sends nil values and stops at the end of the stream", func() {
+			src := diodes.NewPoller(diodes.NewOneToOne(10, nil))
+			data := []byte("some-data")
+			src.Set(nil)
+			src.Set(diodes.GenericDataType(&data))
+			src.Close()
+
+			var buf bytes.Buffer
+			Expect(bridge.NewSender(src, &buf, encode).Run()).To(Succeed())
+			Expect(buf.Bytes()).To(Equal(append([]byte{0, 0, 0, 0, 0, 0, 0, 9}, data...)))
+		})
+
+		It("returns write errors", func() {
+			src := diodes.NewWaiter(diodes.NewOneToOne(10, nil))
+			data := []byte("some-data")
+			src.Set(diodes.GenericDataType(&data))
+
+			r, w := net.Pipe()
+			r.Close()
+			Expect(bridge.NewSender(src, w, encode).Run()).ToNot(Succeed())
+		})
+	})
+
+	Describe("Receiver", func() {
+		It("decodes each frame", func() {
+			var buf bytes.Buffer
+			for _, s := range []string{"a", "bb"} {
+				buf.Write(binary.BigEndian.AppendUint32(nil, uint32(len(s))))
+				buf.WriteString(s)
+			}
+
+			dst := diodes.NewOneToOne(10, nil)
+			Expect(bridge.NewReceiver(&buf, dst, decode).Run()).To(Succeed())
+
+			data, ok := dst.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*[]byte)(data)).To(Equal([]byte("a")))
+			data, ok = dst.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*[]byte)(data)).To(Equal([]byte("bb")))
+		})
+
+		It("rejects frames larger than the max frame size", func() {
+			var buf bytes.Buffer
+			buf.Write(binary.BigEndian.AppendUint32(nil, 11))
+			buf.WriteString("01234567890")
+
+			dst := diodes.NewOneToOne(10, nil)
+			err := bridge.NewReceiver(&buf, dst, decode, bridge.WithMaxFrameSize(10)).Run()
+			Expect(err).To(MatchError(bridge.ErrFrameTooLarge))
+			Expect(err).To(MatchError(diodes.ErrTooLarge))
+		})
+
+		It("returns an error on a truncated frame", func() {
+			var buf bytes.Buffer
+			buf.Write(binary.BigEndian.AppendUint32(nil, 10))
+			buf.WriteString("01234")
+
+			dst := diodes.NewOneToOne(10, nil)
+			Expect(bridge.NewReceiver(&buf, dst, decode).Run()).ToNot(Succeed())
+		})
+	})
+})
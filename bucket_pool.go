@@ -0,0 +1,72 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// bucketPool is a fixed size free list of buckets. The reader of a OneToOne
+// diode puts back the buckets it is done with and the writer gets them to
+// set its next values, so that steady state writes do not allocate. It is
+// only safe for a single go-routine putting and a single go-routine getting
+// buckets, and only for diodes where the getter is the only one storing
+// buckets in the slots, so that a recycled bucket cannot turn up in a slot
+// another go-routine compares and swaps (see loadUnread).
+//
+// Diodes with several writers do not pool their buckets. Their writers
+// compare and swap a slot against the address of the bucket they loaded, so
+// a bucket that was read, recycled and stored in the slot again by another
+// writer would pass for the one they loaded. Telling them apart would take
+// a version per slot that changes along with the pointer, which needs a
+// compare and swap wider than sync/atomic provides, or a lock per slot that
+// makes writers wait for each other.
+type bucketPool struct {
+	// head is the next bucket to get and is only written by the getter.
+	head atomic.Uint64
+	_    cacheLinePad
+	// tail is the next free entry to put into and is only written by the
+	// putter.
+	tail    atomic.Uint64
+	_       cacheLinePad
+	buckets []*bucket
+}
+
+// maxPooledBuckets bounds the size of a bucket pool, so that large diodes do
+// not allocate a free list as large as their ring up front. The pool only
+// needs to absorb how much the number of unread values varies.
+const maxPooledBuckets = 1024
+
+func newBucketPool(size int) *bucketPool {
+	return &bucketPool{
+		buckets: make([]*bucket, min(size, maxPooledBuckets)),
+	}
+}
+
+// get returns a bucket that was put back or nil if there is none.
+func (p *bucketPool) get() *bucket {
+	head := p.head.Load()
+	if head == p.tail.Load() {
+		return nil
+	}
+
+	i := head % uint64(len(p.buckets))
+	b := p.buckets[i]
+	p.buckets[i] = nil
+	p.head.Store(head + 1)
+	return b
+}
+
+// put gives the bucket back to the pool. The bucket is left to the garbage
+// collector when the pool is full.
+func (p *bucketPool) put(b *bucket) {
+	tail := p.tail.Load()
+	if tail-p.head.Load() >= uint64(len(p.buckets)) {
+		return
+	}
+
+	// The writer may still be reading the data to merge into it, see
+	// loadUnread.
+	atomic.StorePointer((*unsafe.Pointer)(&b.data), nil)
+	p.buckets[tail%uint64(len(p.buckets))] = b
+	p.tail.Store(tail + 1)
+}
@@ -0,0 +1,100 @@
+package diodes
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// WithByteLimit bounds the payload bytes the diode holds, as measured by
+// size, in addition to its number of slots, for payloads whose sizes vary
+// too much for a slot count to map to a memory budget. A value that would
+// take the unread values over limit bytes is discarded instead of being
+// set, like a value that is set on a full diode with WithDropNewest: the
+// reader counts it as dropped and alerts it with the reason DropPolicy, and
+// Stats.ByteLimited counts it. A value that is larger than the limit on its
+// own is always discarded.
+//
+// It implies WithSizer, so Stats reports the retained bytes and the range
+// of the payload sizes, and size must return the same result for a value as
+// long as it is in the diode. The limit is approximate with many writers,
+// since each of them may add one value on top of what the others just
+// added. SetLazy builds every value, since its size decides whether it is
+// kept. The OneToMany diode does not support it.
+func WithByteLimit(limit int, size SizeFunc) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		WithSizer(size)(c)
+		c.byteLimit = &byteLimit{limit: int64(limit)}
+		if c.discarded == nil {
+			c.discarded = new(atomic.Uint64)
+		}
+	})
+}
+
+// byteLimit is the byte budget of WithByteLimit.
+type byteLimit struct {
+	limit   int64
+	limited atomic.Uint64
+}
+
+// payloadSizes is the range of the sizes of the values that were set.
+type payloadSizes struct {
+	min atomic.Int64
+	max atomic.Int64
+}
+
+func newPayloadSizes() *payloadSizes {
+	s := &payloadSizes{}
+	s.min.Store(math.MaxInt64)
+	return s
+}
+
+// observe widens the range to include n.
+func (s *payloadSizes) observe(n int64) {
+	for {
+		m := s.min.Load()
+		if n >= m || s.min.CompareAndSwap(m, n) {
+			break
+		}
+	}
+	for {
+		m := s.max.Load()
+		if n <= m || s.max.CompareAndSwap(m, n) {
+			break
+		}
+	}
+}
+
+// fill sets the range in the stats.
+func (s *payloadSizes) fill(st *Stats) {
+	if m := s.min.Load(); m != math.MaxInt64 {
+		st.MinPayloadBytes = uint64(m)
+	}
+	st.MaxPayloadBytes = uint64(s.max.Load())
+}
+
+// sizeOf measures data with the sizer. The sentinels the diodes store have
+// no size.
+func (c *diodeConfig) sizeOf(data GenericDataType) int64 {
+	if data == endOfStream || data == failedWrite {
+		return 0
+	}
+	return int64(c.sizer(data))
+}
+
+// overBytes reports whether the data would take the diode over its byte
+// limit, in which case it was discarded. The data must be built.
+func (c *diodeConfig) overBytes(data GenericDataType) bool {
+	// The end of the stream must not be lost, so it always fits.
+	if c.byteLimit == nil || data == endOfStream {
+		return false
+	}
+
+	n := c.sizeOf(data)
+	if n <= c.byteLimit.limit && c.retained.Load()+n <= c.byteLimit.limit {
+		return false
+	}
+
+	c.byteLimit.limited.Add(1)
+	c.discard(data)
+	return true
+}
@@ -0,0 +1,139 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithByteLimit", func() {
+	byteSize := func(data diodes.GenericDataType) int {
+		return len(*(*[]byte)(data))
+	}
+
+	bytesOf := func(s string) diodes.GenericDataType {
+		b := []byte(s)
+		return diodes.GenericDataType(&b)
+	}
+
+	type byteDiode interface {
+		diodes.Diode
+		TrySet(diodes.GenericDataType) bool
+		Stats() diodes.Stats
+	}
+
+	read := func(d byteDiode) []string {
+		var got []string
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, string(*(*[]byte)(data)))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) byteDiode { return diodes.NewOneToOne(8, nil, opts...) }),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) byteDiode { return diodes.NewManyToOne(8, nil, opts...) }),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) byteDiode { return diodes.NewManyToMany(8, nil, opts...) }),
+	}
+
+	DescribeTable("discards values that would go over the limit",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) byteDiode) {
+			d := newDiode(diodes.WithByteLimit(6, byteSize))
+			for _, s := range []string{"aa", "bbb", "cc", "d"} {
+				d.Set(bytesOf(s))
+			}
+
+			st := d.Stats()
+			Expect(st.RetainedBytes).To(Equal(uint64(6)))
+			Expect(st.ByteLimited).To(Equal(uint64(1)))
+
+			Expect(read(d)).To(Equal([]string{"aa", "bbb", "d"}))
+			st = d.Stats()
+			Expect(st.Dropped).To(Equal(uint64(1)))
+			Expect(st.RetainedBytes).To(BeZero())
+		},
+		entries,
+	)
+
+	DescribeTable("fits new values once the reader freed bytes",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) byteDiode) {
+			d := newDiode(diodes.WithByteLimit(4, byteSize))
+			Expect(d.TrySet(bytesOf("aaa"))).To(BeTrue())
+			Expect(d.TrySet(bytesOf("bb"))).To(BeFalse())
+
+			Expect(read(d)).To(Equal([]string{"aaa"}))
+			Expect(d.TrySet(bytesOf("bb"))).To(BeTrue())
+			Expect(read(d)).To(Equal([]string{"bb"}))
+		},
+		entries,
+	)
+
+	DescribeTable("always discards values larger than the limit",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) byteDiode) {
+			d := newDiode(diodes.WithByteLimit(2, byteSize))
+			d.Set(bytesOf("aaa"))
+
+			Expect(read(d)).To(BeEmpty())
+			Expect(d.Stats().ByteLimited).To(Equal(uint64(1)))
+		},
+		entries,
+	)
+
+	It("alerts the discarded values with the policy reason", func() {
+		var alerts []diodes.DropAlert
+		d := diodes.NewOneToOne(8, diodes.DropAlertFunc(func(a diodes.DropAlert) {
+			alerts = append(alerts, a)
+		}), diodes.WithByteLimit(2, byteSize))
+		d.Set(bytesOf("aa"))
+		d.Set(bytesOf("b"))
+		d.Set(bytesOf("c"))
+
+		d.TryNext()
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0].Dropped).To(Equal(2))
+		Expect(alerts[0].Reason).To(Equal(diodes.DropPolicy))
+	})
+
+	It("builds lazy values to measure them", func() {
+		d := diodes.NewManyToOne(8, nil, diodes.WithByteLimit(2, byteSize))
+		d.SetLazy(func() diodes.GenericDataType { return bytesOf("aaa") })
+		d.SetLazy(func() diodes.GenericDataType { return bytesOf("bb") })
+
+		Expect(read(d)).To(Equal([]string{"bb"}))
+		Expect(d.Stats().ByteLimited).To(Equal(uint64(1)))
+	})
+
+	It("still sets the end of the stream", func() {
+		p := diodes.NewPoller(diodes.NewOneToOne(8, nil, diodes.WithByteLimit(2, byteSize)))
+		p.Set(bytesOf("aa"))
+		p.Close()
+
+		Expect(string(*(*[]byte)(p.Next()))).To(Equal("aa"))
+		Expect(p.Next() == nil).To(BeTrue())
+		Expect(p.Closed()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Payload sizes", func() {
+	byteSize := func(data diodes.GenericDataType) int {
+		return len(*(*[]byte)(data))
+	}
+
+	It("reports the range of the sizes of the values that were set", func() {
+		d := diodes.NewManyToOne(2, nil, diodes.WithSizer(byteSize))
+		Expect(d.Stats().MinPayloadBytes).To(BeZero())
+		Expect(d.Stats().MaxPayloadBytes).To(BeZero())
+
+		for _, s := range []string{"bbb", "a", "cccccc", "dd"} {
+			b := []byte(s)
+			d.Set(diodes.GenericDataType(&b))
+		}
+		st := d.Stats()
+		Expect(st.MinPayloadBytes).To(Equal(uint64(1)))
+		Expect(st.MaxPayloadBytes).To(Equal(uint64(6)))
+	})
+})
@@ -0,0 +1,262 @@
+package diodes
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Bytes is a byte slice read from a BytesDiode. It is pooled by the
+// BytesDiode and must be given back via Release once the data is no longer
+// needed.
+type Bytes struct {
+	// Data holds the bytes that were set.
+	Data []byte
+
+	pool     *sync.Pool
+	copied   bool
+	released bool
+
+	// inArena is set for the bytes of a slot of the arena of WithSlotSize,
+	// which are reused once state is back to slotFree instead of pooled.
+	inArena bool
+	state   atomic.Uint32
+}
+
+// The states of a slot of the arena of WithSlotSize.
+const (
+	slotFree uint32 = iota
+	slotWriting
+	slotSet
+	slotReading
+)
+
+// Release returns the bytes to the BytesDiode they came from. Neither the
+// Bytes nor, if they were copied, its Data may be used after they have been
+// released.
+func (b *Bytes) Release() {
+	if b.released {
+		return
+	}
+
+	if b.inArena {
+		b.released = true
+		b.state.Store(slotFree)
+		return
+	}
+
+	if b.copied {
+		b.Data = b.Data[:0]
+	} else {
+		b.Data = nil
+	}
+	b.released = true
+	b.pool.Put(b)
+}
+
+// BytesDiode is a diode of byte slices. The slices are handed through the
+// wrapped diode in pooled holders, so that setting a slice does not need to
+// box it. The wrapped diode must not be used for anything else. Bytes that
+// are dropped by the wrapped diode are not returned to the pool and are left
+// to the garbage collector.
+type BytesDiode struct {
+	d    Diode
+	pool sync.Pool
+	copy bool
+
+	slotSize int
+	slots    []Bytes
+	next     atomic.Uint64
+
+	// pending holds the bytes CopyNext read but could not copy, and is only
+	// used by the reader.
+	pending *Bytes
+}
+
+var _ io.Writer = (*BytesDiode)(nil)
+
+// BytesDiodeConfigOption can be used to setup the bytes diode.
+type BytesDiodeConfigOption func(*BytesDiode)
+
+// WithBytesCopy makes Set copy the slice into a pooled buffer, so that the
+// caller can reuse it right away. Buffers are reused once the reader
+// released them. The default is to hand through the slice itself, which
+// must then not be modified after Set.
+func WithBytesCopy() BytesDiodeConfigOption {
+	return BytesDiodeConfigOption(func(d *BytesDiode) {
+		d.copy = true
+	})
+}
+
+// defaultArenaSlots is the number of slots of the arena of WithSlotSize if
+// the wrapped diode does not report its capacity.
+const defaultArenaSlots = 1024
+
+// WithSlotSize makes Set copy slices of up to size bytes into the slots of
+// an arena that is allocated in one piece up front, so that fixed size
+// records such as syslog lines do not allocate a buffer each. A slot is
+// reused once the reader released the bytes it read from it, or once the
+// wrapped diode dropped them. The arena has two slots per slot of the
+// wrapped diode, or 1024 if it has no Cap method. Slices that are larger, or
+// set while the next slot is still held by the reader, are copied like with
+// WithBytesCopy.
+func WithSlotSize(size int) BytesDiodeConfigOption {
+	return BytesDiodeConfigOption(func(d *BytesDiode) {
+		d.slotSize = size
+	})
+}
+
+// NewBytesDiode returns a new BytesDiode that wraps the given diode.
+func NewBytesDiode(d Diode, opts ...BytesDiodeConfigOption) *BytesDiode {
+	b := &BytesDiode{
+		d: d,
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	b.pool.New = func() any {
+		return &Bytes{
+			pool: &b.pool,
+		}
+	}
+
+	if b.slotSize > 0 {
+		b.initArena()
+	}
+
+	return b
+}
+
+// initArena allocates the arena of WithSlotSize and points every slot at its
+// part of it.
+func (d *BytesDiode) initArena() {
+	n := defaultArenaSlots
+	if c, ok := d.d.(interface{ Cap() int }); ok {
+		n = 2 * c.Cap()
+	}
+
+	arena := make([]byte, n*d.slotSize)
+	d.slots = make([]Bytes, n)
+	for i := range d.slots {
+		s := &d.slots[i]
+		s.Data = arena[i*d.slotSize : i*d.slotSize : (i+1)*d.slotSize]
+		s.inArena = true
+	}
+}
+
+// Set sets the bytes on the wrapped diode.
+func (d *BytesDiode) Set(data []byte) {
+	if d.slots != nil && d.setSlot(data) {
+		return
+	}
+
+	if d.copy || d.slots != nil {
+		d.setCopy(data)
+		return
+	}
+
+	b := d.get()
+	b.Data = data
+	b.copied = false
+	d.d.Set(GenericDataType(b))
+}
+
+// Write implements io.Writer. It always copies p into a pooled buffer and
+// sets it on the wrapped diode as a single frame, so the diode can be used
+// behind a log.Logger or any other producer that writes to an io.Writer.
+// It never fails: frames the reader does not keep up with are dropped by
+// the wrapped diode.
+func (d *BytesDiode) Write(p []byte) (int, error) {
+	if d.slots != nil && d.setSlot(p) {
+		return len(p), nil
+	}
+	d.setCopy(p)
+	return len(p), nil
+}
+
+func (d *BytesDiode) setCopy(data []byte) {
+	// Released bytes only keep their buffer if they were copied.
+	b := d.get()
+	b.Data = append(b.Data, data...)
+	b.copied = true
+	d.d.Set(GenericDataType(b))
+}
+
+// setSlot copies the data into the next slot of the arena and sets it on the
+// wrapped diode. It returns false if the data does not fit or the reader
+// still holds the slot.
+func (d *BytesDiode) setSlot(data []byte) bool {
+	if len(data) > d.slotSize {
+		return false
+	}
+
+	// The value that was set in the slot a full round of the arena ago
+	// was either read or dropped by the wrapped diode, as the arena is
+	// larger than the diode. If it was not read it can be reused; should
+	// the reader still get to it, it finds the slot taken (see TryNext).
+	b := &d.slots[d.next.Add(1)%uint64(len(d.slots))]
+	if !b.state.CompareAndSwap(slotFree, slotWriting) && !b.state.CompareAndSwap(slotSet, slotWriting) {
+		return false
+	}
+
+	b.Data = append(b.Data[:0], data...)
+	b.released = false
+	b.state.Store(slotSet)
+	d.d.Set(GenericDataType(b))
+	return true
+}
+
+func (d *BytesDiode) get() *Bytes {
+	b := d.pool.Get().(*Bytes)
+	b.released = false
+	return b
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available, it will return (nil, false). The returned bytes must be
+// released once the caller is done with them.
+func (d *BytesDiode) TryNext() (*Bytes, bool) {
+	if b := d.pending; b != nil {
+		d.pending = nil
+		return b, true
+	}
+
+	for {
+		data, ok := d.d.TryNext()
+		if !ok {
+			return nil, false
+		}
+
+		// A slot of the arena that was reused by a writer since it was
+		// set is skipped, as its bytes were overwritten.
+		b := (*Bytes)(data)
+		if b.inArena && !b.state.CompareAndSwap(slotSet, slotReading) {
+			continue
+		}
+		return b, true
+	}
+}
+
+// CopyNext copies the next bytes into dst and releases them, so that the
+// caller owns its buffer and does not need to call Release, and returns
+// their number. If there is no data available, it returns (0, false). If
+// dst is shorter than the bytes, they are kept for the next read and
+// CopyNext returns their length and false, so that the caller can retry
+// with a larger buffer. Combined with WithBytesCopy or WithSlotSize, reading
+// with CopyNext does not allocate.
+func (d *BytesDiode) CopyNext(dst []byte) (n int, ok bool) {
+	b, ok := d.TryNext()
+	if !ok {
+		return 0, false
+	}
+	if len(b.Data) > len(dst) {
+		d.pending = b
+		return len(b.Data), false
+	}
+
+	n = copy(dst, b.Data)
+	b.Release()
+	return n, true
+}
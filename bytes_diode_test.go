@@ -0,0 +1,230 @@
+package diodes_test
+
+import (
+	"fmt"
+	"log"
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BytesDiode", func() {
+	It("hands through the slices that were set", func() {
+		d := diodes.NewBytesDiode(diodes.NewManyToOne(5, nil))
+		data := []byte("some-data")
+		d.Set(data)
+
+		b, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(b.Data).To(Equal(data))
+		Expect(&b.Data[0]).To(BeIdenticalTo(&data[0]))
+		b.Release()
+
+		_, ok = d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("copies the slices when copying is enabled", func() {
+		d := diodes.NewBytesDiode(diodes.NewManyToOne(5, nil), diodes.WithBytesCopy())
+		data := []byte("some-data")
+		d.Set(data)
+		copy(data, "other")
+
+		b, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(string(b.Data)).To(Equal("some-data"))
+		b.Release()
+	})
+
+	It("stops at the end of the stream of a wrapped Poller", func() {
+		p := diodes.NewPoller(diodes.NewManyToOne(5, nil))
+		d := diodes.NewBytesDiode(p)
+		d.Set([]byte("some-data"))
+		p.Close()
+
+		_, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		_, ok = d.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(p.Closed()).To(BeTrue())
+	})
+
+	It("reuses released buffers", func() {
+		if raceEnabled {
+			Skip("sync.Pool randomly drops items with the race detector enabled")
+		}
+
+		d := diodes.NewBytesDiode(diodes.NewManyToOne(5, nil), diodes.WithBytesCopy())
+		data := []byte("some-data")
+
+		// Only the bucket of the wrapped diode is allocated.
+		Expect(testing.AllocsPerRun(100, func() {
+			d.Set(data)
+			b, _ := d.TryNext()
+			b.Release()
+		})).To(Equal(1.0))
+	})
+})
+
+var _ = Describe("BytesDiode WithSlotSize()", func() {
+	It("copies the slices into the arena", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil), diodes.WithSlotSize(16))
+		data := []byte("some-data")
+		d.Set(data)
+		copy(data, "other")
+
+		b, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(string(b.Data)).To(Equal("some-data"))
+		Expect(cap(b.Data)).To(Equal(16))
+		b.Release()
+	})
+
+	It("does not allocate", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil), diodes.WithSlotSize(16))
+		data := []byte("some-data")
+
+		Expect(testing.AllocsPerRun(100, func() {
+			d.Set(data)
+			b, _ := d.TryNext()
+			b.Release()
+		})).To(BeZero())
+	})
+
+	It("copies slices that do not fit outside of the arena", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil), diodes.WithSlotSize(4))
+		d.Set([]byte("some-data"))
+
+		b, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(string(b.Data)).To(Equal("some-data"))
+		b.Release()
+	})
+
+	It("does not reuse slots the reader still holds", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(1, nil), diodes.WithSlotSize(16))
+
+		d.Set([]byte("first"))
+		held, _ := d.TryNext()
+		for _, s := range []string{"second", "third", "fourth"} {
+			d.Set([]byte(s))
+			b, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(string(b.Data)).To(Equal(s))
+			b.Release()
+		}
+		Expect(string(held.Data)).To(Equal("first"))
+		held.Release()
+	})
+
+	It("reuses the slots of values the wrapped diode dropped", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(2, nil), diodes.WithSlotSize(16))
+		for i := 0; i < 10; i++ {
+			d.Set(fmt.Appendf(nil, "value-%d", i))
+		}
+
+		var got []string
+		for {
+			b, ok := d.TryNext()
+			if !ok {
+				break
+			}
+			got = append(got, string(b.Data))
+			b.Release()
+		}
+		Expect(got).To(Equal([]string{"value-8", "value-9"}))
+	})
+})
+
+var _ = Describe("BytesDiode Write()", func() {
+	It("sets a copy of every write as a frame", func() {
+		d := diodes.NewBytesDiode(diodes.NewManyToOne(5, nil))
+		logger := log.New(d, "", 0)
+		logger.Print("first")
+		logger.Print("second")
+
+		for _, line := range []string{"first\n", "second\n"} {
+			b, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(string(b.Data)).To(Equal(line))
+			b.Release()
+		}
+	})
+
+	It("does not reuse the buffer of a slice that was set without copying", func() {
+		d := diodes.NewBytesDiode(diodes.NewManyToOne(5, nil))
+		data := []byte("some-data")
+		d.Set(data)
+		b, _ := d.TryNext()
+		b.Release()
+
+		d.Write([]byte("other"))
+		b, _ = d.TryNext()
+		Expect(string(b.Data)).To(Equal("other"))
+		Expect(string(data)).To(Equal("some-data"))
+	})
+})
+
+var _ = Describe("BytesDiode CopyNext()", func() {
+	It("copies the next slice into the given buffer", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil))
+		d.Set([]byte("some-data"))
+
+		buf := make([]byte, 16)
+		n, ok := d.CopyNext(buf)
+		Expect(ok).To(BeTrue())
+		Expect(string(buf[:n])).To(Equal("some-data"))
+
+		n, ok = d.CopyNext(buf)
+		Expect(ok).To(BeFalse())
+		Expect(n).To(BeZero())
+	})
+
+	It("keeps the next slice when the given buffer is too short", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil), diodes.WithSlotSize(16))
+		d.Set([]byte("some-data"))
+		d.Set([]byte("other"))
+
+		buf := make([]byte, 4)
+		n, ok := d.CopyNext(buf)
+		Expect(ok).To(BeFalse())
+		Expect(n).To(Equal(9))
+
+		buf = make([]byte, n)
+		n, ok = d.CopyNext(buf)
+		Expect(ok).To(BeTrue())
+		Expect(string(buf[:n])).To(Equal("some-data"))
+
+		b, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(string(b.Data)).To(Equal("other"))
+		b.Release()
+	})
+
+	It("hands a kept slice to TryNext", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil))
+		d.Set([]byte("some-data"))
+
+		_, ok := d.CopyNext(nil)
+		Expect(ok).To(BeFalse())
+
+		b, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(string(b.Data)).To(Equal("some-data"))
+		b.Release()
+	})
+
+	It("does not allocate with an arena", func() {
+		d := diodes.NewBytesDiode(diodes.NewOneToOne(4, nil), diodes.WithSlotSize(16))
+		data := []byte("some-data")
+		buf := make([]byte, 16)
+
+		Expect(testing.AllocsPerRun(100, func() {
+			d.Set(data)
+			d.CopyNext(buf)
+		})).To(BeZero())
+	})
+})
@@ -0,0 +1,45 @@
+package diodes
+
+import "context"
+
+// BlockingReaderT is a typed reader that can wait for data, such as PollerT
+// and WaiterT.
+type BlockingReaderT[T any] interface {
+	NextCtx(ctx context.Context) (T, error)
+}
+
+// FanIn sets every value received from ch on d until ch is closed. It blocks
+// until then, so it is typically run on its own go-routine. A slow reader of
+// d makes d drop values instead of blocking the senders of ch.
+func FanIn[T any](ch <-chan T, d DiodeT[T]) {
+	for v := range ch {
+		d.Set(v)
+	}
+}
+
+// Out returns a channel that receives every value read from r. A go-routine
+// reads from r and sends to the channel until the context is done or r
+// reached the end of its stream, and then closes the channel. While the
+// receiver of the channel is slow, the diode behind r drops values.
+func Out[T any](ctx context.Context, r BlockingReaderT[T]) <-chan T {
+	ch := make(chan T)
+
+	go func() {
+		defer close(ch)
+
+		for {
+			v, err := r.NextCtx(ctx)
+			if err != nil {
+				return
+			}
+
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
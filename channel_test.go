@@ -0,0 +1,60 @@
+package diodes_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FanIn()", func() {
+	It("sets every value of the channel on the diode", func() {
+		ch := make(chan int, 3)
+		ch <- 1
+		ch <- 2
+		ch <- 3
+		close(ch)
+
+		d := diodes.NewManyToOneT[int](5, nil)
+		diodes.FanIn[int](ch, d)
+
+		for i := 1; i <= 3; i++ {
+			v, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal(i))
+		}
+	})
+})
+
+var _ = Describe("Out()", func() {
+	var w *diodes.WaiterT[int]
+
+	BeforeEach(func() {
+		w = diodes.NewWaiterT[int](diodes.NewManyToOneT[int](5, nil))
+	})
+
+	It("sends every value read until the end of the stream", func() {
+		w.Set(1)
+		w.Set(2)
+		w.Close()
+
+		var got []int
+		for v := range diodes.Out[int](context.Background(), w) {
+			got = append(got, v)
+		}
+		Expect(got).To(Equal([]int{1, 2}))
+	})
+
+	It("closes the channel once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := diodes.Out[int](ctx, w)
+
+		w.Set(1)
+		Eventually(ch).Should(Receive(Equal(1)))
+
+		cancel()
+		Eventually(ch).Should(BeClosed())
+	})
+})
@@ -0,0 +1,74 @@
+package diodes
+
+import "time"
+
+// Clock is the source of time Pollers and Waiters sleep and time out with.
+// The default is the system clock. Tests can pass a fake clock, see
+// diodestest.FakeClock, so that time only passes when the test says so.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sleep blocks until the duration passed.
+	Sleep(d time.Duration)
+
+	// NewTimer returns a timer that fires once the duration passed.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock. It behaves like a time.Timer whose channel
+// is returned by C.
+type Timer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// SystemClock returns the clock that is backed by the time package, which
+// Pollers and Waiters use by default.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{t: time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTimer) Reset(d time.Duration) bool {
+	return t.t.Reset(d)
+}
+
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// stopTimer stops the timer and drains a tick that fired but was not
+// received, so it does not end the next wait right away.
+func stopTimer(t Timer) {
+	if t.Stop() {
+		return
+	}
+
+	select {
+	case <-t.C():
+	default:
+	}
+}
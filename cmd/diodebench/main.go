@@ -0,0 +1,82 @@
+// Command diodebench compares the diodes with buffered channels under a
+// configurable workload and reports throughput, drops and allocations for
+// every combination of queue, writer count and payload size, e.g.:
+//
+//	diodebench -queues many-to-one,channel -writers 1,4,16 -payload 64,1024 -duration 2s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes/benchmarks"
+)
+
+func main() {
+	var (
+		c        benchmarks.Config
+		queues   string
+		writers  string
+		payloads string
+	)
+
+	flag.StringVar(&queues, "queues", strings.Join(benchmarks.Queues, ","), "comma separated list of queues to compare")
+	flag.IntVar(&c.Size, "size", 1024, "size of the diodes and capacity of the channels")
+	flag.StringVar(&writers, "writers", "1,4", "comma separated list of writer counts to run")
+	flag.StringVar(&payloads, "payload", "256", "comma separated list of payload sizes in bytes")
+	flag.DurationVar(&c.ReaderDelay, "reader-delay", 0, "time the reader spends on each value")
+	flag.DurationVar(&c.Duration, "duration", 2*time.Second, "how long to run each combination")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "QUEUE\tWRITERS\tPAYLOAD\tWRITES/S\tREADS/S\tDROP %\tALLOCS/OP\tB/OP")
+	for _, q := range strings.Split(queues, ",") {
+		c.Queue = strings.TrimSpace(q)
+		for _, w := range ints(writers) {
+			// One-to-one diodes are not safe for several writers.
+			if (c.Queue == benchmarks.OneToOne || c.Queue == benchmarks.SPSC) && w > 1 {
+				continue
+			}
+			c.Writers = w
+
+			for _, p := range ints(payloads) {
+				c.PayloadSize = p
+
+				r, err := benchmarks.Run(ctx, c)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f\t%.0f\t%.2f\t%.2f\t%.0f\n",
+					c.Queue, w, p, r.WriteRate(), r.ReadRate(), 100*r.DropRatio(), r.AllocsPerWrite(), r.BytesPerWrite())
+			}
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func ints(list string) []int {
+	var ns []int
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatalf("invalid number %q", s)
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
@@ -0,0 +1,21 @@
+// Command diodectl inspects the file of a shared memory diode, e.g. the one
+// a crashed process left behind:
+//
+//	diodectl header /dev/shm/go-diodes-envelopes
+//	diodectl dump -decoder text -unread /dev/shm/go-diodes-envelopes
+//	diodectl verify /dev/shm/go-diodes-envelopes
+package main
+
+import (
+	"log"
+	"os"
+
+	"code.cloudfoundry.org/go-diodes/diodectl"
+)
+
+func main() {
+	log.SetFlags(0)
+	if err := diodectl.Run(os.Args[1:], os.Stdout, diodectl.Decoders); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,20 @@
+// Command diodegen generates a concrete shell for a diode of a named type,
+// along with Poller and Waiter wrappers for it. It is meant to be run by go
+// generate:
+//
+//	//go:generate go run code.cloudfoundry.org/go-diodes/cmd/diodegen -type []byte -name Bytes -tests
+package main
+
+import (
+	"log"
+	"os"
+
+	"code.cloudfoundry.org/go-diodes/diodegen"
+)
+
+func main() {
+	log.SetFlags(0)
+	if err := diodegen.Run(os.Args[1:], "."); err != nil {
+		log.Fatal(err)
+	}
+}
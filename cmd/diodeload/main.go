@@ -0,0 +1,63 @@
+// Command diodeload drives a configurable workload against a diode and
+// reports drop and latency figures for each writer count, e.g.:
+//
+//	diodeload -diode many-to-one -size 1024 -writers 1,4,16 -duration 5s
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes/loadgen"
+)
+
+func main() {
+	var (
+		c       loadgen.Config
+		writers string
+	)
+
+	flag.StringVar(&c.Diode, "diode", loadgen.ManyToOne, "diode to drive (one-to-one or many-to-one)")
+	flag.IntVar(&c.Size, "size", 1024, "size of the diode")
+	flag.StringVar(&writers, "writers", "1", "comma separated list of writer counts to run")
+	flag.IntVar(&c.PayloadSize, "payload", 256, "payload size in bytes")
+	flag.IntVar(&c.Rate, "rate", 0, "writes per second per writer (0 is unlimited)")
+	flag.IntVar(&c.BurstSize, "burst", 0, "writes per burst (0 disables bursts)")
+	flag.DurationVar(&c.BurstInterval, "burst-interval", 0, "pause between bursts")
+	flag.DurationVar(&c.ReaderDelay, "reader-delay", 0, "time the reader spends on each value")
+	flag.DurationVar(&c.Duration, "duration", 5*time.Second, "how long to run each writer count")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "WRITERS\tWRITES\tREADS\tDROPPED\tDROP %\tP50\tP99\tMAX")
+	for _, w := range strings.Split(writers, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(w))
+		if err != nil {
+			log.Fatalf("invalid writer count %q", w)
+		}
+		c.Writers = n
+
+		r, err := loadgen.Run(ctx, c)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%.2f\t%s\t%s\t%s\n",
+			n, r.Writes, r.Reads, r.Dropped, 100*r.DropRatio(), r.LatencyP50, r.LatencyP99, r.LatencyMax)
+	}
+
+	if err := tw.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
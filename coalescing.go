@@ -0,0 +1,139 @@
+package diodes
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Coalescing diode keeps at most one unread value per key, for status
+// update style streams such as per-app health, where only the latest value
+// of a key matters. A value whose key already has an unread value in the
+// ring buffer replaces that value in place, or is merged into it, instead of
+// taking up a slot of its own, so the capacity of the diode is spent on
+// distinct keys. The replaced value keeps its position, so a key that is set
+// over and over is not starved by the keys behind it.
+//
+// It is safe for many writers and a single reader. Writers synchronize on a
+// mutex, which the reader takes for every value it reads.
+type Coalescing struct {
+	mu     sync.Mutex
+	unread map[string]*coalescingEntry
+	ring   *OneToOne
+	merge  MergeFunc
+
+	coalesced atomic.Uint64
+}
+
+var _ Reader = (*Coalescing)(nil)
+
+// coalescingEntry is the unread value of a key. Its data is guarded by the
+// mutex of the diode.
+type coalescingEntry struct {
+	key  string
+	data GenericDataType
+}
+
+// NewCoalescing creates a new Coalescing diode with room for the unread
+// values of size keys. The alerter is invoked on the reader's go-routine
+// when it notices that the writers lapped it and wrote over data. A nil can
+// be used to ignore alerts. If merge is not nil, a value is merged into the
+// unread value of its key with merge(old, new) instead of replacing it. The
+// reader may not read the old value while merge runs, so merge may modify
+// it.
+func NewCoalescing(size int, alerter Alerter, merge MergeFunc) *Coalescing {
+	d := &Coalescing{
+		unread: make(map[string]*coalescingEntry, size),
+		merge:  merge,
+	}
+
+	// The writers only ever set the ring under the mutex of the diode, so it
+	// has a single writer at a time and the drop handler runs with the mutex
+	// held.
+	d.ring = NewOneToOne(size, alerter, WithDropHandler(DropFunc(d.forget)))
+	return d
+}
+
+// NewCoalescingErr is like NewCoalescing but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots.
+func NewCoalescingErr(size int, alerter Alerter, merge MergeFunc) (*Coalescing, error) {
+	if err := checkSize(size, 0); err != nil {
+		return nil, err
+	}
+	return NewCoalescing(size, alerter, merge), nil
+}
+
+// Set sets the data of the key. If the key has an unread value, the data
+// replaces it or is merged into it.
+func (d *Coalescing) Set(key string, data GenericDataType) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.unread[key]; ok {
+		if d.merge != nil {
+			data = d.merge(e.data, data)
+		}
+		e.data = data
+		d.coalesced.Add(1)
+		return
+	}
+
+	e := &coalescingEntry{key: key, data: data}
+	d.unread[key] = e
+	d.ring.Set(GenericDataType(e))
+}
+
+// TryNext will attempt to read the oldest unread value. If there is no data
+// available, it will return (nil, false).
+func (d *Coalescing) TryNext() (data GenericDataType, ok bool) {
+	_, data, ok = d.TryNextKey()
+	return data, ok
+}
+
+// TryNextKey is like TryNext but also returns the key of the value.
+func (d *Coalescing) TryNextKey() (key string, data GenericDataType, ok bool) {
+	p, ok := d.ring.TryNext()
+	if !ok {
+		return "", nil, false
+	}
+	e := (*coalescingEntry)(p)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.forget(GenericDataType(e))
+	return e.key, e.data, true
+}
+
+// forget removes the entry data points to from the unread values, once it
+// was read or overwritten. The mutex must be held.
+func (d *Coalescing) forget(data GenericDataType) {
+	e := (*coalescingEntry)(data)
+	if d.unread[e.key] == e {
+		delete(d.unread, e.key)
+	}
+}
+
+// Coalesced returns the total number of values that replaced or were merged
+// into the unread value of their key.
+func (d *Coalescing) Coalesced() uint64 {
+	return d.coalesced.Load()
+}
+
+// Len returns the number of keys with an unread value.
+func (d *Coalescing) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.unread)
+}
+
+// Stats returns a snapshot of the counters of the ring buffer. Coalesced
+// values are neither counted as writes nor as drops.
+func (d *Coalescing) Stats() Stats {
+	return d.ring.Stats()
+}
+
+// ReadStatsInto fills st with the counters of the ring buffer like Stats,
+// but leaves the rates zero. It does not take the mutex of the diode. See
+// OneToOne.ReadStatsInto.
+func (d *Coalescing) ReadStatsInto(st *Stats) {
+	d.ring.ReadStatsInto(st)
+}
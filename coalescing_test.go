@@ -0,0 +1,108 @@
+package diodes_test
+
+import (
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Coalescing", func() {
+	status := func(s string) diodes.GenericDataType {
+		return diodes.GenericDataType(&s)
+	}
+
+	read := func(d *diodes.Coalescing) []string {
+		var got []string
+		for {
+			key, data, ok := d.TryNextKey()
+			if !ok {
+				return got
+			}
+			got = append(got, key+"="+*(*string)(data))
+		}
+	}
+
+	It("replaces the unread value of a key in place", func() {
+		d := diodes.NewCoalescing(4, nil, nil)
+		d.Set("app-a", status("starting"))
+		d.Set("app-b", status("starting"))
+		d.Set("app-a", status("running"))
+		d.Set("app-a", status("crashed"))
+
+		Expect(d.Len()).To(Equal(2))
+		Expect(read(d)).To(Equal([]string{"app-a=crashed", "app-b=starting"}))
+		Expect(d.Coalesced()).To(Equal(uint64(2)))
+		Expect(d.Stats().Writes).To(Equal(uint64(2)))
+	})
+
+	It("appends a value of a key that was read", func() {
+		d := diodes.NewCoalescing(4, nil, nil)
+		d.Set("app-a", status("starting"))
+		Expect(read(d)).To(Equal([]string{"app-a=starting"}))
+
+		d.Set("app-a", status("running"))
+		Expect(read(d)).To(Equal([]string{"app-a=running"}))
+		Expect(d.Coalesced()).To(BeZero())
+	})
+
+	It("merges values into the unread value of their key", func() {
+		sum := func(old, new diodes.GenericDataType) diodes.GenericDataType {
+			v := *(*int)(old) + *(*int)(new)
+			return diodes.GenericDataType(&v)
+		}
+		d := diodes.NewCoalescing(4, nil, sum)
+		for i := 1; i <= 3; i++ {
+			j := i
+			d.Set("requests", diodes.GenericDataType(&j))
+		}
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(6))
+	})
+
+	It("forgets the values of keys that were overwritten", func() {
+		var dropped int
+		d := diodes.NewCoalescing(2, diodes.AlertFunc(func(missed int) {
+			dropped += missed
+		}), nil)
+		d.Set("app-a", status("starting"))
+		d.Set("app-b", status("starting"))
+		d.Set("app-c", status("starting"))
+		d.Set("app-a", status("running"))
+
+		Expect(d.Coalesced()).To(BeZero())
+		Expect(read(d)).To(Equal([]string{"app-c=starting", "app-a=running"}))
+		Expect(dropped).To(Equal(2))
+		Expect(d.Len()).To(BeZero())
+	})
+
+	It("keeps only the latest value of every key with many writers", func() {
+		d := diodes.NewCoalescing(16, nil, nil)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					d.Set(fmt.Sprint("app-", w), status(fmt.Sprint(i)))
+					if w == 0 {
+						d.TryNext()
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		got := read(d)
+		Expect(len(got)).To(BeNumerically("<=", 8))
+		for _, s := range got {
+			Expect(s).To(HaveSuffix("=999"))
+		}
+	})
+})
@@ -0,0 +1,115 @@
+package diodes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"runtime/debug"
+)
+
+// ConsumeConfigOption can be used to setup Consume and ConsumeBatch.
+type ConsumeConfigOption func(*consumeConfig)
+
+type consumeConfig struct {
+	onPanic func(recovered any, stack []byte)
+}
+
+// WithPanicHandler sets the function that is invoked on the reader's
+// go-routine with the recovered value and the stack trace when the consumer
+// function panics. The default is to log both with the standard logger.
+// Either way, consumption restarts with the next value, so the values the
+// function panicked on are lost.
+func WithPanicHandler(handle func(recovered any, stack []byte)) ConsumeConfigOption {
+	return ConsumeConfigOption(func(c *consumeConfig) {
+		c.onPanic = handle
+	})
+}
+
+func newConsumeConfig(opts []ConsumeConfigOption) consumeConfig {
+	c := consumeConfig{
+		onPanic: func(recovered any, stack []byte) {
+			log.Printf("Diode consumer panicked: %v\n%s", recovered, stack)
+		},
+	}
+
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return c
+}
+
+// Consume runs the read loop of the poller and invokes fn on the reader's
+// go-routine for every value, including nil values, until the end of the
+// stream, which returns nil, or until the given or the poller's context is
+// done or the poller is stopped, which returns the error NextCtx would. A
+// panic in fn is recovered, handed to the panic handler (see
+// WithPanicHandler) and consumption restarts with the next value. It must
+// be called by the reader.
+func (p *Poller) Consume(ctx context.Context, fn func(GenericDataType), opts ...ConsumeConfigOption) error {
+	return consume(ctx, p, 1, func(batch []GenericDataType) { fn(batch[0]) }, opts)
+}
+
+// ConsumeBatch is like Consume but invokes fn with up to size values at a
+// time: whatever is available once the poller found data, without waiting
+// for more. The slice is reused, so fn must not keep it.
+func (p *Poller) ConsumeBatch(ctx context.Context, size int, fn func([]GenericDataType), opts ...ConsumeConfigOption) error {
+	return consume(ctx, p, size, fn, opts)
+}
+
+// Consume runs the read loop of the waiter and invokes fn for every value,
+// see Poller.Consume.
+func (w *Waiter) Consume(ctx context.Context, fn func(GenericDataType), opts ...ConsumeConfigOption) error {
+	return consume(ctx, w, 1, func(batch []GenericDataType) { fn(batch[0]) }, opts)
+}
+
+// ConsumeBatch runs the read loop of the waiter and invokes fn with up to
+// size values at a time, see Poller.ConsumeBatch.
+func (w *Waiter) ConsumeBatch(ctx context.Context, size int, fn func([]GenericDataType), opts ...ConsumeConfigOption) error {
+	return consume(ctx, w, size, fn, opts)
+}
+
+// consumable is a reader that can wait for data.
+type consumable interface {
+	NextCtx(ctx context.Context) (GenericDataType, error)
+	TryNext() (GenericDataType, bool)
+}
+
+// consume waits for a value, reads up to size values without waiting and
+// invokes fn with them, until the end of the stream or an error.
+func consume(ctx context.Context, r consumable, size int, fn func([]GenericDataType), opts []ConsumeConfigOption) error {
+	c := newConsumeConfig(opts)
+	batch := make([]GenericDataType, 0, max(size, 1))
+
+	for {
+		data, err := r.NextCtx(ctx)
+		if errors.Is(err, ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch[:0], data)
+		for len(batch) < cap(batch) {
+			data, ok := r.TryNext()
+			if !ok {
+				break
+			}
+			batch = append(batch, data)
+		}
+
+		c.invoke(fn, batch)
+	}
+}
+
+// invoke invokes fn with the batch and recovers from a panic in it.
+func (c *consumeConfig) invoke(fn func([]GenericDataType), batch []GenericDataType) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.onPanic(r, debug.Stack())
+		}
+	}()
+
+	fn(batch)
+}
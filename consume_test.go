@@ -0,0 +1,126 @@
+package diodes_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Consume", func() {
+	type consumer interface {
+		diodes.Diode
+		Close()
+		Stop()
+		Consume(ctx context.Context, fn func(diodes.GenericDataType), opts ...diodes.ConsumeConfigOption) error
+		ConsumeBatch(ctx context.Context, size int, fn func([]diodes.GenericDataType), opts ...diodes.ConsumeConfigOption) error
+	}
+
+	set := func(c consumer, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			c.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("Poller", func() consumer {
+			return diodes.NewPoller(diodes.NewOneToOne(16, nil), diodes.WithPollingInterval(time.Millisecond))
+		}),
+		Entry("Waiter", func() consumer {
+			return diodes.NewWaiter(diodes.NewOneToOne(16, nil))
+		}),
+	}
+
+	DescribeTable("invokes the function for every value until the end of the stream",
+		func(newConsumer func() consumer) {
+			c := newConsumer()
+			set(c, 0, 3)
+			c.Close()
+
+			var got []int
+			err := c.Consume(context.Background(), func(data diodes.GenericDataType) {
+				got = append(got, *(*int)(data))
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal([]int{0, 1, 2}))
+		},
+		entries,
+	)
+
+	DescribeTable("invokes the function with the available values at once",
+		func(newConsumer func() consumer) {
+			c := newConsumer()
+			set(c, 0, 5)
+			c.Close()
+
+			var got [][]int
+			err := c.ConsumeBatch(context.Background(), 3, func(batch []diodes.GenericDataType) {
+				var ints []int
+				for _, data := range batch {
+					ints = append(ints, *(*int)(data))
+				}
+				got = append(got, ints)
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal([][]int{{0, 1, 2}, {3, 4}}))
+		},
+		entries,
+	)
+
+	DescribeTable("restarts after the function panicked",
+		func(newConsumer func() consumer) {
+			c := newConsumer()
+			set(c, 0, 3)
+			c.Close()
+
+			var (
+				got       []int
+				recovered []any
+			)
+			err := c.Consume(context.Background(), func(data diodes.GenericDataType) {
+				if *(*int)(data) == 1 {
+					panic("boom")
+				}
+				got = append(got, *(*int)(data))
+			}, diodes.WithPanicHandler(func(r any, stack []byte) {
+				recovered = append(recovered, r)
+				Expect(stack).ToNot(BeEmpty())
+			}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got).To(Equal([]int{0, 2}))
+			Expect(recovered).To(Equal([]any{"boom"}))
+		},
+		entries,
+	)
+
+	DescribeTable("returns the error of the context",
+		func(newConsumer func() consumer) {
+			c := newConsumer()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := c.Consume(ctx, func(diodes.GenericDataType) {})
+			Expect(errors.Is(err, diodes.ErrTimeout)).To(BeTrue())
+		},
+		entries,
+	)
+
+	DescribeTable("returns once it was stopped",
+		func(newConsumer func() consumer) {
+			c := newConsumer()
+			done := make(chan error)
+			go func() {
+				done <- c.Consume(context.Background(), func(diodes.GenericDataType) {})
+			}()
+
+			c.Stop()
+			Eventually(done).Should(Receive(MatchError(diodes.ErrStopped)))
+		},
+		entries,
+	)
+})
@@ -0,0 +1,39 @@
+package diodes
+
+// CopyUnread moves up to max unread values, or all of them if max is less
+// than 1, into dst, in the order they would have been read, and returns how
+// many it moved, e.g. to migrate the backlog to a diode of another size or
+// with other options. The values are read, so they are no longer in the
+// diode, and dst treats them as new values. If the diode was closed, the
+// end of the stream is moved as well. It must be called by the reader.
+func (d *OneToOne) CopyUnread(dst Diode, max int) int {
+	return copyUnread(d, dst, max)
+}
+
+// CopyUnread moves up to max unread values into dst. See
+// OneToOne.CopyUnread.
+func (d *ManyToOne) CopyUnread(dst Diode, max int) int {
+	return copyUnread(d, dst, max)
+}
+
+// CopyUnread moves up to max unread values into dst. See
+// OneToOne.CopyUnread. Values that other readers read meanwhile are not
+// moved.
+func (d *ManyToMany) CopyUnread(dst Diode, max int) int {
+	return copyUnread(d, dst, max)
+}
+
+func copyUnread(src, dst Diode, max int) int {
+	var n int
+	for max < 1 || n < max {
+		data, ok := src.TryNext()
+		if !ok {
+			break
+		}
+		dst.Set(data)
+		if data != endOfStream {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type unreadCopier interface {
+	diodes.Diode
+	CopyUnread(dst diodes.Diode, max int) int
+}
+
+var _ = Describe("CopyUnread()", func() {
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	DescribeTable("moves the unread values into another diode",
+		func(src unreadCopier) {
+			for i := 0; i < 5; i++ {
+				j := i
+				src.Set(diodes.GenericDataType(&j))
+			}
+
+			dst := diodes.NewManyToOne(16, nil)
+			Expect(src.CopyUnread(dst, 2)).To(Equal(2))
+			Expect(src.CopyUnread(dst, 0)).To(Equal(3))
+			Expect(src.CopyUnread(dst, 0)).To(BeZero())
+
+			Expect(read(src)).To(BeEmpty())
+			Expect(read(dst)).To(Equal([]int{0, 1, 2, 3, 4}))
+		},
+		Entry("OneToOne", diodes.NewOneToOne(8, nil)),
+		Entry("ManyToOne", diodes.NewManyToOne(8, nil)),
+		Entry("ManyToMany", diodes.NewManyToMany(8, nil)),
+	)
+
+	It("carries the end of the stream over", func() {
+		src := diodes.NewPoller(diodes.NewManyToOne(8, nil))
+		v := 1
+		src.Set(diodes.GenericDataType(&v))
+		src.Close()
+
+		dst := diodes.NewPoller(diodes.NewManyToOne(16, nil))
+		Expect(src.Diode.(*diodes.ManyToOne).CopyUnread(dst, 0)).To(Equal(1))
+
+		Expect(*(*int)(dst.Next())).To(Equal(1))
+		Expect(dst.Next() == nil).To(BeTrue())
+		Expect(dst.Closed()).To(BeTrue())
+	})
+})
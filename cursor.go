@@ -0,0 +1,180 @@
+package diodes
+
+import "sync/atomic"
+
+// Cursor is the position of the reader of a diode, i.e. the index of the
+// value it reads next. It can be saved, e.g. to checkpoint the progress of
+// a consumer, and restored later via Restore.
+type Cursor uint64
+
+// Cursor returns the position of the reader. It must be called by the
+// reader.
+func (d *OneToOne) Cursor() Cursor {
+	return Cursor(d.readIndex.Load())
+}
+
+// Restore moves the reader to the given position and returns how many
+// values were lost since, because the writer lapped the position while the
+// reader was paused. Unlike a lapped read, which skips ahead to the newest
+// value in the slot, the reader resumes at the oldest value that is still
+// in the diode, so exactly the lost values are dropped and reported to the
+// alerter. Values that were read already can not be read again, so a
+// position behind the reader leaves it where it is. It must be called by
+// the reader.
+func (d *OneToOne) Restore(c Cursor) (lost uint64) {
+	lost = restore(&d.readIndex, uint64(c), d.writeIndex.Load(), d.buffer.size)
+	if lost > 0 {
+		d.addDropped(lost)
+		d.observeDrops(lost)
+		index := d.readIndex.Load()
+		d.alerter.AlertDrop(newDropAlert(DropLapped, index-lost, index))
+	}
+	return lost
+}
+
+// Cursor returns the position of the reader. It must be called by the
+// reader.
+func (d *ManyToOne) Cursor() Cursor {
+	return Cursor(d.readIndex.Load())
+}
+
+// Restore moves the reader to the given position and returns how many
+// values were lost since, because writers lapped the position while the
+// reader was paused. Unlike a lapped read, which skips ahead to the newest
+// value in the slot, the reader resumes at the oldest value that is still
+// in the diode, so exactly the lost values are dropped and reported to the
+// alerter. Values that were read already can not be read again, so a
+// position behind the reader leaves it where it is. It must be called by
+// the reader.
+func (d *ManyToOne) Restore(c Cursor) (lost uint64) {
+	lost = restore(&d.readIndex, uint64(c), d.writeIndex.Load()+1, d.buffer.size)
+	if lost > 0 {
+		d.addDropped(lost)
+		d.observeDrops(lost)
+		index := d.readIndex.Load()
+		d.alerter.AlertDrop(newDropAlert(DropLapped, index-lost, index))
+	}
+	return lost
+}
+
+// Checkpoint returns the position of the reader, which it can rewind to
+// later via Rewind. It must be called by the reader.
+func (r *OneToManyReader) Checkpoint() Cursor {
+	return Cursor(r.readIndex.Load())
+}
+
+// Rewind moves the reader back to a position returned by Checkpoint, so that
+// it reads the values since once more, e.g. after a downstream failure.
+// Values stay in the ring buffer of a OneToMany diode until they are
+// overwritten, so this replays them without keeping a copy. It returns
+// ErrLapped and leaves the reader where it is if the writer has overwritten
+// the value at the position since. A value that the writer overwrites after
+// Rewind is reported as dropped by the next read, as usual. It must be
+// called by the reader.
+func (r *OneToManyReader) Rewind(c Cursor) error {
+	index, nextWrite := uint64(c), r.d.writeIndex.Load()
+	if nextWrite-min(index, nextWrite) > r.d.buffer.size {
+		return ErrLapped
+	}
+
+	r.readIndex.Store(min(index, nextWrite))
+	return nil
+}
+
+// restore moves the read index to the given index, bounded by the read
+// index and the index that will be written next, and past every value that
+// was overwritten since. It returns the number of values that were skipped
+// because they were overwritten.
+func restore(readIndex *atomic.Uint64, index, nextWrite, size uint64) uint64 {
+	index = min(max(index, readIndex.Load()), nextWrite)
+
+	var lost uint64
+	if nextWrite-index > size {
+		lost = nextWrite - size - index
+		index += lost
+	}
+
+	readIndex.Store(index)
+	return lost
+}
+
+// NewCursor returns a new cursor that follows the values of the diode at its
+// own speed, starting at the value the reader reads next, e.g. for a sampling
+// debugger next to a real-time forwarder. It does not take the values from
+// the reader: once the diode has a cursor, reads leave the values in the
+// ring buffer until they are overwritten, and the buckets are no longer
+// reused. The writer does not wait for cursors, so a slow cursor drops data
+// without affecting the reader. The alerter is invoked on the cursor's
+// go-routine when it notices that the writer has passed it and wrote over
+// data. A nil can be used to ignore alerts. It must be called by the reader.
+func (d *OneToOne) NewCursor(alerter Alerter) *OneToOneCursor {
+	d.cursors.Store(true)
+	c := &OneToOneCursor{
+		d:       d,
+		alerter: dropAlerter(alerter),
+	}
+	c.readIndex.Store(d.readIndex.Load())
+	return c
+}
+
+// OneToOneCursor is an additional reader of a OneToOne diode, see
+// OneToOne.NewCursor. It is not thread safe for multiple go-routines.
+type OneToOneCursor struct {
+	d         *OneToOne
+	readIndex atomic.Uint64
+	alerter   DropAlerter
+	dropped   atomic.Uint64
+}
+
+var _ Reader = (*OneToOneCursor)(nil)
+
+// TryNext will attempt to read the next value for this cursor. If there is
+// no data available, it will return (nil, false).
+func (c *OneToOneCursor) TryNext() (data GenericDataType, ok bool) {
+	data, _, ok = c.TryNextSeq()
+	return data, ok
+}
+
+// TryNextSeq is like TryNext but also returns the sequence number of the
+// value, see OneToOne.TryNextSeq.
+func (c *OneToOneCursor) TryNextSeq() (data GenericDataType, seq uint64, ok bool) {
+	readIndex := c.readIndex.Load()
+	slot := c.d.buffer.peek(readIndex)
+	if slot == nil {
+		return nil, 0, false
+	}
+
+	result := (*bucket)(atomic.LoadPointer(slot))
+	if result == nil || result.seq < readIndex {
+		return nil, 0, false
+	}
+
+	// The writer lapped this cursor, which catches up the same way the
+	// reader does.
+	var dropped uint64
+	if result.seq > readIndex {
+		dropped = result.seq - readIndex
+		readIndex = result.seq
+		c.dropped.Add(dropped)
+	}
+
+	c.readIndex.Store(readIndex + 1)
+	if dropped > 0 {
+		c.alerter.AlertDrop(newDropAlert(DropLapped, readIndex-dropped, readIndex))
+	}
+	raceReadPayload(result.data)
+	return result.data, result.seq, true
+}
+
+// Len returns the approximate number of values this cursor has not read
+// yet, bounded by Cap. It is safe to call from any go-routine.
+func (c *OneToOneCursor) Len() int {
+	return int(unread(c.d.writeIndex.Load(), c.readIndex.Load(), c.d.buffer.size))
+}
+
+// Dropped returns the total number of values this cursor noticed were
+// overwritten before it read them. It does not include the values the
+// reader dropped. It is safe to call from any go-routine.
+func (c *OneToOneCursor) Dropped() uint64 {
+	return c.dropped.Load()
+}
@@ -0,0 +1,216 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cursor", func() {
+	type cursorDiode interface {
+		diodes.Diode
+		Cursor() diodes.Cursor
+		Restore(diodes.Cursor) uint64
+		Dropped() uint64
+	}
+
+	set := func(d cursorDiode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d cursorDiode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	DescribeTable("resumes where the reader stopped",
+		func(newDiode func(size int, alerter diodes.Alerter) cursorDiode) {
+			d := newDiode(4, nil)
+			set(d, 0, 2)
+			Expect(read(d)).To(Equal([]int{0, 1}))
+
+			c := d.Cursor()
+			Expect(c).To(Equal(diodes.Cursor(2)))
+
+			set(d, 2, 4)
+			Expect(d.Restore(c)).To(BeZero())
+			Expect(read(d)).To(Equal([]int{2, 3}))
+		},
+		Entry("OneToOne", func(size int, a diodes.Alerter) cursorDiode { return diodes.NewOneToOne(size, a) }),
+		Entry("ManyToOne", func(size int, a diodes.Alerter) cursorDiode { return diodes.NewManyToOne(size, a) }),
+	)
+
+	DescribeTable("reports exactly the values lost while paused",
+		func(newDiode func(size int, alerter diodes.Alerter) cursorDiode) {
+			spy := newSpyAlerter()
+			d := newDiode(4, spy)
+			c := d.Cursor()
+
+			set(d, 0, 10)
+			Expect(d.Restore(c)).To(Equal(uint64(6)))
+			Expect(spy.AlertInput.Missed).To(Receive(Equal(6)))
+			Expect(d.Dropped()).To(Equal(uint64(6)))
+
+			// A lapped read would have skipped ahead to 8.
+			Expect(read(d)).To(Equal([]int{6, 7, 8, 9}))
+		},
+		Entry("OneToOne", func(size int, a diodes.Alerter) cursorDiode { return diodes.NewOneToOne(size, a) }),
+		Entry("ManyToOne", func(size int, a diodes.Alerter) cursorDiode { return diodes.NewManyToOne(size, a) }),
+	)
+
+	DescribeTable("keeps the reader between what was read and what was written",
+		func(newDiode func(size int, alerter diodes.Alerter) cursorDiode) {
+			d := newDiode(4, nil)
+			set(d, 0, 3)
+			Expect(read(d)).To(Equal([]int{0, 1, 2}))
+
+			Expect(d.Restore(0)).To(BeZero())
+			Expect(d.Cursor()).To(Equal(diodes.Cursor(3)))
+
+			Expect(d.Restore(100)).To(BeZero())
+			Expect(d.Cursor()).To(Equal(diodes.Cursor(3)))
+
+			set(d, 3, 4)
+			Expect(read(d)).To(Equal([]int{3}))
+		},
+		Entry("OneToOne", func(size int, a diodes.Alerter) cursorDiode { return diodes.NewOneToOne(size, a) }),
+		Entry("ManyToOne", func(size int, a diodes.Alerter) cursorDiode { return diodes.NewManyToOne(size, a) }),
+	)
+
+	Describe("OneToOne NewCursor", func() {
+		var d *diodes.OneToOne
+
+		BeforeEach(func() {
+			d = diodes.NewOneToOne(4, nil)
+		})
+
+		readCursor := func(c *diodes.OneToOneCursor) []int {
+			var got []int
+			for {
+				data, ok := c.TryNext()
+				if !ok {
+					return got
+				}
+				got = append(got, *(*int)(data))
+			}
+		}
+
+		It("reads the same values as the reader", func() {
+			c := d.NewCursor(nil)
+			set(d, 0, 3)
+
+			Expect(read(d)).To(Equal([]int{0, 1, 2}))
+			Expect(readCursor(c)).To(Equal([]int{0, 1, 2}))
+		})
+
+		It("starts at the value the reader reads next", func() {
+			set(d, 0, 3)
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(0))
+
+			c := d.NewCursor(nil)
+			Expect(c.Len()).To(Equal(2))
+			Expect(readCursor(c)).To(Equal([]int{1, 2}))
+			Expect(read(d)).To(Equal([]int{1, 2}))
+		})
+
+		It("keeps a position of its own for every cursor", func() {
+			a := d.NewCursor(nil)
+			b := d.NewCursor(nil)
+			set(d, 0, 2)
+			Expect(readCursor(a)).To(Equal([]int{0, 1}))
+
+			set(d, 2, 3)
+			Expect(readCursor(a)).To(Equal([]int{2}))
+			Expect(readCursor(b)).To(Equal([]int{0, 1, 2}))
+			Expect(a.Len()).To(BeZero())
+			Expect(d.Len()).To(Equal(3))
+		})
+
+		It("drops data for a slow cursor without affecting the reader", func() {
+			cursorSpy := newSpyAlerter()
+			readerSpy := newSpyAlerter()
+			d = diodes.NewOneToOne(4, readerSpy)
+			c := d.NewCursor(cursorSpy)
+
+			set(d, 0, 4)
+			Expect(read(d)).To(Equal([]int{0, 1, 2, 3}))
+			set(d, 4, 8)
+			Expect(read(d)).To(Equal([]int{4, 5, 6, 7}))
+			Expect(d.Dropped()).To(BeZero())
+			Expect(readerSpy.AlertCalled).ToNot(Receive())
+
+			Expect(readCursor(c)).To(Equal([]int{4, 5, 6, 7}))
+			Expect(cursorSpy.AlertInput.Missed).To(Receive(Equal(4)))
+			Expect(c.Dropped()).To(Equal(uint64(4)))
+		})
+
+		It("drops data for a slow reader without affecting the cursor", func() {
+			c := d.NewCursor(nil)
+
+			set(d, 0, 4)
+			Expect(readCursor(c)).To(Equal([]int{0, 1, 2, 3}))
+			set(d, 4, 8)
+			Expect(readCursor(c)).To(Equal([]int{4, 5, 6, 7}))
+			Expect(c.Dropped()).To(BeZero())
+
+			Expect(read(d)).To(Equal([]int{4, 5, 6, 7}))
+			Expect(d.Dropped()).To(Equal(uint64(4)))
+		})
+
+		It("does not hand values the reader read to the drop handler", func() {
+			var handed []int
+			d = diodes.NewOneToOne(2, nil, diodes.WithDropHandler(diodes.DropFunc(func(data diodes.GenericDataType) {
+				handed = append(handed, *(*int)(data))
+			})))
+			d.NewCursor(nil)
+
+			set(d, 0, 2)
+			Expect(read(d)).To(Equal([]int{0, 1}))
+			set(d, 2, 5)
+
+			Expect(handed).To(Equal([]int{2}))
+		})
+
+		It("reads while the writer and the reader go on", func() {
+			c := d.NewCursor(nil)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				set(d, 0, 10000)
+			}()
+
+			last := -1
+			for {
+				select {
+				case <-done:
+					for _, v := range readCursor(c) {
+						Expect(v).To(BeNumerically(">", last))
+						last = v
+					}
+					Expect(last).To(Equal(9999))
+					Expect(d.CheckInvariants()).To(Succeed())
+					return
+				default:
+				}
+
+				read(d)
+				for _, v := range readCursor(c) {
+					Expect(v).To(BeNumerically(">", last))
+					last = v
+				}
+			}
+		})
+	})
+})
@@ -0,0 +1,28 @@
+package diodes
+
+// WithDeadLetter offers every value the diode drops to dl before it is lost:
+// values that are overwritten before they were read, and values that
+// WithDropNewest or the sampler of WithOverloadSampling discard. dl is
+// typically larger or slower, e.g. a SpillDiode, which makes for a tiered
+// loss hierarchy without changing the producers. Values are still counted
+// as dropped by the diode. dl is set on the go-routine of the writer that
+// dropped the value, so with many writers it must be safe for many writers,
+// and it must not be the diode itself. It can be combined with
+// WithDropHandler, which is invoked first. It is not supported by the
+// OneToMany diode.
+func WithDeadLetter(dl Diode) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.deadLetter = dl
+	})
+}
+
+// handOff hands data that was dropped to the drop handler and the dead
+// letter diode.
+func (c *diodeConfig) handOff(data GenericDataType) {
+	if c.onDrop != nil {
+		c.onDrop.Dropped(data)
+	}
+	if c.deadLetter != nil {
+		c.deadLetter.Set(data)
+	}
+}
@@ -0,0 +1,83 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithDeadLetter", func() {
+	set := func(d diodes.Diode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	DescribeTable("offers overwritten values to the dead letter diode",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) diodes.Diode) {
+			dl := diodes.NewManyToOne(16, nil)
+			d := newDiode(diodes.WithDeadLetter(dl))
+			set(d, 0, 6)
+
+			Expect(read(d)).To(Equal([]int{4, 5}))
+			Expect(read(dl)).To(Equal([]int{0, 1}))
+		},
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewOneToOne(4, nil, opts...)
+		}),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToOne(4, nil, opts...)
+		}),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToMany(4, nil, opts...)
+		}),
+	)
+
+	It("offers discarded values to the dead letter diode", func() {
+		dl := diodes.NewManyToOne(16, nil)
+		d := diodes.NewManyToOne(4, nil, diodes.WithDropNewest(), diodes.WithDeadLetter(dl))
+		set(d, 0, 6)
+
+		Expect(read(d)).To(Equal([]int{0, 1, 2, 3}))
+		Expect(read(dl)).To(Equal([]int{4, 5}))
+		Expect(d.Dropped()).To(Equal(uint64(2)))
+	})
+
+	It("is combined with the drop handler", func() {
+		var handled []int
+		dl := diodes.NewManyToOne(16, nil)
+		d := diodes.NewManyToOne(4, nil,
+			diodes.WithDeadLetter(dl),
+			diodes.WithDropHandler(diodes.DropFunc(func(data diodes.GenericDataType) {
+				handled = append(handled, *(*int)(data))
+			})),
+		)
+		set(d, 0, 5)
+
+		Expect(handled).To(Equal([]int{0}))
+		Expect(read(dl)).To(Equal([]int{0}))
+	})
+
+	It("does not offer the end of the stream", func() {
+		dl := diodes.NewManyToOne(16, nil)
+		p := diodes.NewPoller(diodes.NewManyToOne(4, nil, diodes.WithDeadLetter(dl)))
+		p.Close()
+		set(p, 0, 4)
+
+		_, ok := dl.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,90 @@
+package diodes
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// HashFunc returns a hash of the content of data.
+type HashFunc func(GenericDataType) uint64
+
+// Dedup wraps a diode and drops values whose hash matches one of the values
+// most recently passed on, before they take up space in the wrapped diode.
+type Dedup struct {
+	Diode
+	hash HashFunc
+
+	mu     sync.Mutex
+	recent []uint64
+	next   int
+	full   bool
+	seen   map[uint64]int
+
+	duplicates atomic.Uint64
+}
+
+var _ Diode = (*Dedup)(nil)
+
+// NewDedup returns a new Dedup that wraps the given diode and remembers the
+// hashes of the last window values that were passed on to it.
+func NewDedup(d Diode, window int, hash HashFunc) *Dedup {
+	return &Dedup{
+		Diode:  d,
+		hash:   hash,
+		recent: make([]uint64, window),
+		seen:   make(map[uint64]int, window),
+	}
+}
+
+// Set invokes the wrapped diode's Set with the given data unless a value
+// with the same hash is within the window. The end of the stream that Close
+// sets is passed on without hashing it.
+func (d *Dedup) Set(data GenericDataType) {
+	if data == endOfStream {
+		d.Diode.Set(data)
+		return
+	}
+
+	if !d.remember(d.hash(data)) {
+		d.duplicates.Add(1)
+		return
+	}
+
+	d.Diode.Set(data)
+}
+
+// Duplicates returns the total number of values that were dropped as
+// duplicates.
+func (d *Dedup) Duplicates() uint64 {
+	return d.duplicates.Load()
+}
+
+// remember records h and reports whether it was not within the window.
+func (d *Dedup) remember(h uint64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.recent) == 0 {
+		return true
+	}
+
+	if d.seen[h] > 0 {
+		return false
+	}
+
+	if d.full {
+		evicted := d.recent[d.next]
+		if d.seen[evicted]--; d.seen[evicted] <= 0 {
+			delete(d.seen, evicted)
+		}
+	}
+
+	d.recent[d.next] = h
+	d.seen[h]++
+	d.next = (d.next + 1) % len(d.recent)
+	if d.next == 0 {
+		d.full = true
+	}
+
+	return true
+}
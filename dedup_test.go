@@ -0,0 +1,70 @@
+package diodes_test
+
+import (
+	"hash/fnv"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dedup", func() {
+	var (
+		d     *diodes.OneToOne
+		dedup *diodes.Dedup
+	)
+
+	set := func(s string) {
+		data := []byte(s)
+		dedup.Set(diodes.GenericDataType(&data))
+	}
+
+	drain := func() []string {
+		var result []string
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return result
+			}
+			result = append(result, string(*(*[]byte)(data)))
+		}
+	}
+
+	BeforeEach(func() {
+		d = diodes.NewOneToOne(10, nil)
+		dedup = diodes.NewDedup(d, 2, func(data diodes.GenericDataType) uint64 {
+			h := fnv.New64a()
+			h.Write(*(*[]byte)(data)) //nolint:errcheck
+			return h.Sum64()
+		})
+	})
+
+	It("drops duplicates within the window", func() {
+		set("a")
+		set("a")
+		set("b")
+		set("a")
+
+		Expect(drain()).To(Equal([]string{"a", "b"}))
+		Expect(dedup.Duplicates()).To(Equal(uint64(2)))
+	})
+
+	It("forgets values that left the window", func() {
+		set("a")
+		set("b")
+		set("c")
+		set("a")
+
+		Expect(drain()).To(Equal([]string{"a", "b", "c", "a"}))
+		Expect(dedup.Duplicates()).To(BeZero())
+	})
+
+	It("reads from the wrapped diode", func() {
+		set("a")
+
+		data, ok := dedup.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*[]byte)(data)).To(Equal([]byte("a")))
+	})
+})
@@ -0,0 +1,19 @@
+package diodes
+
+// Writer is the write side of a diode.
+type Writer interface {
+	Set(GenericDataType)
+}
+
+// Reader is the read side of a diode.
+type Reader interface {
+	TryNext() (GenericDataType, bool)
+}
+
+// Diode is any implementation of a diode. It is satisfied by OneToOne,
+// ManyToOne and Unbounded as well as the types that wrap a diode, so code
+// that only depends on Diode, Writer or Reader can switch between them.
+type Diode interface {
+	Writer
+	Reader
+}
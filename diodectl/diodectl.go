@@ -0,0 +1,165 @@
+// Package diodectl implements the diodectl command, which inspects the file
+// of a shared memory diode offline, e.g. the one a crashed process left
+// behind: it prints the header, dumps the records and verifies them. Values
+// are printed by a Decoder. Programs that know how their values are encoded
+// build their own command with a decoder of their own:
+//
+//	decoders := maps.Clone(diodectl.Decoders)
+//	decoders["envelope"] = decodeEnvelope
+//	if err := diodectl.Run(os.Args[1:], os.Stdout, decoders); err != nil {
+//		log.Fatal(err)
+//	}
+package diodectl
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/go-diodes/diodesshm"
+)
+
+// Decoder formats a value of a diode for the dump.
+type Decoder func(value []byte) (string, error)
+
+// Decoders are the decoders of the diodectl command.
+var Decoders = map[string]Decoder{
+	"hex":  func(v []byte) (string, error) { return hex.EncodeToString(v), nil },
+	"text": func(v []byte) (string, error) { return strconv.Quote(string(v)), nil },
+	"json": func(v []byte) (string, error) {
+		var b bytes.Buffer
+		err := json.Compact(&b, v)
+		return b.String(), err
+	},
+}
+
+const usage = `usage: diodectl COMMAND [FLAGS] FILE
+
+Commands:
+  header  print the header of the diode
+  dump    print the header and every record that is still in the ring
+  verify  check every record that is still in the ring
+`
+
+// Run runs the diodectl command with the given arguments, without the name
+// of the command, and writes its output to out.
+func Run(args []string, out io.Writer, decoders map[string]Decoder) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	fs := flag.NewFlagSet("diodectl "+args[0], flag.ContinueOnError)
+	fs.SetOutput(out)
+	decoder := fs.String("decoder", "hex", "decoder of the values ("+strings.Join(names(decoders), ", ")+")")
+	unread := fs.Bool("unread", false, "only dump the records the reader did not read yet")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(usage)
+	}
+	path := fs.Arg(0)
+
+	switch args[0] {
+	case "header":
+		h, err := diodesshm.Inspect(path, nil)
+		printHeader(out, h)
+		return err
+	case "dump":
+		decode, ok := decoders[*decoder]
+		if !ok {
+			return fmt.Errorf("unknown decoder %q", *decoder)
+		}
+		return dump(out, path, decode, *unread)
+	case "verify":
+		return verify(out, path)
+	default:
+		return errors.New(usage)
+	}
+}
+
+func names(decoders map[string]Decoder) []string {
+	var names []string
+	for name := range decoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printHeader(out io.Writer, h diodesshm.Header) {
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "magic\t%#x\n", h.Magic)
+	fmt.Fprintf(tw, "version\t%d\n", h.Version)
+	fmt.Fprintf(tw, "slots\t%d\n", h.Slots)
+	fmt.Fprintf(tw, "slot size\t%d\n", h.SlotSize)
+	fmt.Fprintf(tw, "checksums\t%t\n", h.Checksums())
+	fmt.Fprintf(tw, "write index\t%d\n", h.WriteIndex)
+	fmt.Fprintf(tw, "read index\t%d\n", h.ReadIndex)
+	tw.Flush()
+}
+
+func dump(out io.Writer, path string, decode Decoder, unread bool) error {
+	h, err := diodesshm.Inspect(path, nil)
+	printHeader(out, h)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "INDEX\tSTATE\tLEN\tVALUE")
+	_, err = diodesshm.Inspect(path, func(r diodesshm.Record) error {
+		if unread && !r.Unread {
+			return nil
+		}
+
+		state, value := "read", ""
+		if r.Unread {
+			state = "unread"
+		}
+		if r.Err != nil {
+			state, value = "corrupt", r.Err.Error()
+		} else if r.EndOfStream {
+			value = "end of stream"
+		} else if v, err := decode(r.Value); err != nil {
+			value = "decoding: " + err.Error()
+		} else {
+			value = v
+		}
+
+		_, err := fmt.Fprintf(tw, "%d\t%s\t%d\t%s\n", r.Index, state, len(r.Value), value)
+		return err
+	})
+	return errors.Join(err, tw.Flush())
+}
+
+func verify(out io.Writer, path string) error {
+	var records, corrupt int
+	_, err := diodesshm.Inspect(path, func(r diodesshm.Record) error {
+		records++
+		if r.Err != nil {
+			corrupt++
+			_, err := fmt.Fprintln(out, r.Err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%d records, %d corrupt\n", records, corrupt)
+	if corrupt > 0 {
+		return fmt.Errorf("%w: %d of %d records", diodesshm.ErrCorrupt, corrupt, records)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package diodectl_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodectl(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diodectl Suite")
+}
@@ -0,0 +1,139 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package diodectl_test
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodectl"
+	"code.cloudfoundry.org/go-diodes/diodesshm"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var (
+		path string
+		out  *bytes.Buffer
+	)
+
+	encode := func(data diodes.GenericDataType) []byte {
+		return []byte(`{"v":` + strconv.Itoa(*(*int)(data)) + `}`)
+	}
+
+	run := func(args ...string) error {
+		return diodectl.Run(args, out, diodectl.Decoders)
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "diode")
+		out = &bytes.Buffer{}
+
+		d, err := diodesshm.Open(path, 4, 16, nil, encode, nil, diodesshm.WithChecksums())
+		Expect(err).NotTo(HaveOccurred())
+		defer d.Close()
+		for i := 0; i < 6; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		_, ok := d.CopyNext(make([]byte, 16))
+		Expect(ok).To(BeTrue())
+	})
+
+	// damage overwrites the value of the record for the given index.
+	damage := func(index int) {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+
+		// The slots of 16 bytes follow the 192 byte header, and their
+		// values follow their 16 byte headers.
+		_, err = f.WriteAt([]byte("X"), int64(192+(index%4)*32+16))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("prints the header", func() {
+		Expect(run("header", path)).To(Succeed())
+		Expect(out.String()).To(MatchRegexp(`slots\s+4\n`))
+		Expect(out.String()).To(MatchRegexp(`slot size\s+16\n`))
+		Expect(out.String()).To(MatchRegexp(`checksums\s+true\n`))
+		Expect(out.String()).To(MatchRegexp(`write index\s+6\n`))
+		Expect(out.String()).To(MatchRegexp(`read index\s+3\n`))
+	})
+
+	It("dumps the records that are still in the ring", func() {
+		Expect(run("dump", "-decoder", "json", path)).To(Succeed())
+		Expect(out.String()).To(MatchRegexp(`2\s+read\s+7\s+\{"v":2\}\n`))
+		Expect(out.String()).To(MatchRegexp(`5\s+unread\s+7\s+\{"v":5\}\n`))
+		Expect(out.String()).NotTo(ContainSubstring(`{"v":1}`))
+	})
+
+	It("only dumps the unread records", func() {
+		Expect(run("dump", "-unread", path)).To(Succeed())
+		Expect(out.String()).NotTo(MatchRegexp(`(?m)^\d+\s+read\s`))
+		Expect(out.String()).To(MatchRegexp(`3\s+unread`))
+	})
+
+	It("dumps the records with a decoder of its own", func() {
+		decoders := maps.Clone(diodectl.Decoders)
+		decoders["upper"] = func(v []byte) (string, error) {
+			if v[2] != 'v' {
+				return "", errors.New("no v")
+			}
+			return string(bytes.ToUpper(v)), nil
+		}
+
+		Expect(diodectl.Run([]string{"dump", "-decoder", "upper", path}, out, decoders)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring(`{"V":4}`))
+	})
+
+	It("marks corrupt records in the dump", func() {
+		damage(5)
+
+		Expect(run("dump", path)).To(Succeed())
+		Expect(out.String()).To(MatchRegexp(`5\s+corrupt\s+7\s+.*checksum of record 5 does not match`))
+	})
+
+	It("marks the end of the stream in the dump", func() {
+		d, err := diodesshm.Open(path, 4, 16, nil, encode, nil)
+		Expect(err).NotTo(HaveOccurred())
+		diodes.NewPoller(d).Close()
+		Expect(d.Close()).To(Succeed())
+
+		Expect(run("dump", path)).To(Succeed())
+		Expect(out.String()).To(MatchRegexp(`6\s+unread\s+0\s+end of stream\n`))
+	})
+
+	It("verifies the records", func() {
+		Expect(run("verify", path)).To(Succeed())
+		Expect(out.String()).To(Equal("4 records, 0 corrupt\n"))
+
+		damage(4)
+		out.Reset()
+		Expect(run("verify", path)).To(MatchError(diodesshm.ErrCorrupt))
+		Expect(out.String()).To(ContainSubstring("checksum of record 4 does not match"))
+		Expect(out.String()).To(ContainSubstring("4 records, 1 corrupt"))
+	})
+
+	It("rejects files that are not a diode", func() {
+		other := filepath.Join(GinkgoT().TempDir(), "other")
+		Expect(os.WriteFile(other, make([]byte, 4096), 0o600)).To(Succeed())
+
+		Expect(run("header", other)).To(MatchError(diodesshm.ErrCorrupt))
+		Expect(run("dump", other)).To(MatchError(diodesshm.ErrCorrupt))
+	})
+
+	It("rejects unknown commands and decoders", func() {
+		Expect(run()).To(HaveOccurred())
+		Expect(run("header")).To(HaveOccurred())
+		Expect(run("undo", path)).To(HaveOccurred())
+		Expect(run("dump", "-decoder", "unknown", path)).To(MatchError(ContainSubstring("unknown decoder")))
+	})
+})
@@ -0,0 +1,408 @@
+// Package diodegen implements the diodegen command, which generates a
+// concrete shell for a diode of a named type, like the one the README shows
+// for []byte, so that repositories can stop copying shells by hand. It is
+// meant to be run by go generate:
+//
+//	//go:generate go run code.cloudfoundry.org/go-diodes/cmd/diodegen -type *loggregator_v2.Envelope -name Envelope -import code.cloudfoundry.org/go-loggregator/v9/rpc/loggregator_v2 -tests
+//
+// The shell consists of a diode type, which is backed by a OneToOne or a
+// ManyToOne diode, and of a Poller and a Waiter wrapper for it. With -tests,
+// a test file for the shell is generated as well.
+package diodegen
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Config describes the shell to generate.
+type Config struct {
+	// Package is the name of the package the shell is generated into.
+	Package string
+
+	// Type is the Go type of the values, e.g. []byte or *pb.Envelope.
+	Type string
+
+	// Name prefixes the names of the generated types and constructors,
+	// e.g. Envelope for EnvelopeDiode, EnvelopePoller and EnvelopeWaiter.
+	Name string
+
+	// Imports are the import paths the type needs.
+	Imports []string
+}
+
+// check validates the config.
+func (c Config) check() error {
+	if !token.IsIdentifier(c.Package) {
+		return fmt.Errorf("invalid package name %q", c.Package)
+	}
+	if c.Type == "" {
+		return errors.New("no type")
+	}
+	if !token.IsIdentifier(c.Name) || !token.IsExported(c.Name) {
+		return fmt.Errorf("invalid name %q, it must be an exported identifier", c.Name)
+	}
+	return nil
+}
+
+// lower is the name with its leading initialism or first letter in lower
+// case, e.g. url for URL and httpEnvelope for HTTPEnvelope, for the
+// unexported helpers of the shell.
+func (c Config) lower() string {
+	r := []rune(c.Name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+
+	// The last upper case letter of an initialism that is followed by a
+	// lower case one starts the next word.
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n--
+	}
+	for i := 0; i < max(n, 1); i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// Generate returns the source of the shell.
+func Generate(c Config) ([]byte, error) {
+	return execute(shellTemplate, c)
+}
+
+// GenerateTest returns the source of the tests of the shell.
+func GenerateTest(c Config) ([]byte, error) {
+	return execute(testTemplate, c)
+}
+
+func execute(t *template.Template, c Config) ([]byte, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	err := t.Execute(&b, struct {
+		Config
+		Lower string
+		Args  string
+	}{c, c.lower(), args(c)})
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid type %q: %w", c.Type, err)
+	}
+	return src, nil
+}
+
+// args returns the arguments of diodegen that generate the shell for the
+// config, for the header of the generated files.
+func args(c Config) string {
+	a := []string{"-type", c.Type, "-name", c.Name}
+	if len(c.Imports) > 0 {
+		a = append(a, "-import", strings.Join(c.Imports, ","))
+	}
+	return strings.Join(a, " ")
+}
+
+const usage = `usage: diodegen -type TYPE -name NAME [FLAGS]`
+
+// Run runs the diodegen command with the given arguments, without the name
+// of the command. It writes the generated files into dir, unless the output
+// is an absolute path. The package defaults to $GOPACKAGE, which go
+// generate sets.
+func Run(args []string, dir string) error {
+	fs := flag.NewFlagSet("diodegen", flag.ContinueOnError)
+	var c Config
+	fs.StringVar(&c.Type, "type", "", "type of the values, e.g. []byte or *pb.Envelope")
+	fs.StringVar(&c.Name, "name", "", "prefix of the generated names, e.g. Envelope")
+	fs.StringVar(&c.Package, "package", os.Getenv("GOPACKAGE"), "name of the package (default $GOPACKAGE)")
+	imports := fs.String("import", "", "comma separated import paths the type needs")
+	output := fs.String("output", "", "name of the generated file (default <name>_diode.go)")
+	tests := fs.Bool("tests", false, "generate a test file for the shell as well")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || c.Type == "" || c.Name == "" {
+		return errors.New(usage)
+	}
+	if *imports != "" {
+		c.Imports = strings.Split(*imports, ",")
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(c.Name) + "_diode.go"
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+
+	src, err := Generate(c)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name, src, 0o644); err != nil {
+		return err
+	}
+
+	if !*tests {
+		return nil
+	}
+	src, err = GenerateTest(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(strings.TrimSuffix(name, ".go")+"_test.go", src, 0o644)
+}
+
+var shellTemplate = template.Must(template.New("shell").Parse(`// Code generated by diodegen {{.Args}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+{{- if .Imports}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+
+// {{.Name}}Diode is a diode of {{.Type}} values.
+type {{.Name}}Diode struct {
+	d diodes.Diode
+}
+
+// New{{.Name}}OneToOne returns a {{.Name}}Diode that is backed by a OneToOne
+// diode, see diodes.NewOneToOne.
+func New{{.Name}}OneToOne(size int, alerter diodes.Alerter, opts ...diodes.DiodeConfigOption) *{{.Name}}Diode {
+	return &{{.Name}}Diode{d: diodes.NewOneToOne(size, alerter, opts...)}
+}
+
+// New{{.Name}}ManyToOne returns a {{.Name}}Diode that is backed by a ManyToOne
+// diode, see diodes.NewManyToOne.
+func New{{.Name}}ManyToOne(size int, alerter diodes.Alerter, opts ...diodes.DiodeConfigOption) *{{.Name}}Diode {
+	return &{{.Name}}Diode{d: diodes.NewManyToOne(size, alerter, opts...)}
+}
+
+// Set sets the value on the diode.
+func (d *{{.Name}}Diode) Set(v {{.Type}}) {
+	d.d.Set(diodes.GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the diode. If there is no data
+// available, it will return the zero value and false.
+func (d *{{.Name}}Diode) TryNext() ({{.Type}}, bool) {
+	return {{.Lower}}FromGeneric(d.d.TryNext())
+}
+
+// Untyped returns the untyped diode that holds *{{.Type}} values.
+func (d *{{.Name}}Diode) Untyped() diodes.Diode {
+	return d.d
+}
+
+// {{.Name}}Poller will poll a {{.Name}}Diode until a value is available.
+type {{.Name}}Poller struct {
+	p *diodes.Poller
+}
+
+// New{{.Name}}Poller returns a new {{.Name}}Poller that wraps the given diode,
+// see diodes.NewPoller.
+func New{{.Name}}Poller(d *{{.Name}}Diode, opts ...diodes.PollerConfigOption) *{{.Name}}Poller {
+	return &{{.Name}}Poller{p: diodes.NewPoller(d.Untyped(), opts...)}
+}
+
+// Set sets the value on the wrapped diode.
+func (p *{{.Name}}Poller) Set(v {{.Type}}) {
+	p.p.Set(diodes.GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return the zero
+// value and false.
+func (p *{{.Name}}Poller) TryNext() ({{.Type}}, bool) {
+	return {{.Lower}}FromGeneric(p.p.TryNext())
+}
+
+// Next polls the diode until data is available or until the context of the
+// poller is done. If the context is done or the end of the stream was
+// reached, it returns the zero value and false.
+func (p *{{.Name}}Poller) Next() ({{.Type}}, bool) {
+	data := p.p.Next()
+	return {{.Lower}}FromGeneric(data, data != nil)
+}
+
+// NextCtx is like Next but also returns once the given context is done, with
+// an error that tells why no value was returned, see diodes.Poller.NextCtx.
+func (p *{{.Name}}Poller) NextCtx(ctx context.Context) ({{.Type}}, error) {
+	data, err := p.p.NextCtx(ctx)
+	v, _ := {{.Lower}}FromGeneric(data, err == nil)
+	return v, err
+}
+
+// Close marks the end of the stream.
+func (p *{{.Name}}Poller) Close() {
+	p.p.Close()
+}
+
+// Closed reports whether the reader has reached the end of the stream.
+func (p *{{.Name}}Poller) Closed() bool {
+	return p.p.Closed()
+}
+
+// {{.Name}}Waiter will use a channel signal to alert the reader to when data
+// is available on a {{.Name}}Diode.
+type {{.Name}}Waiter struct {
+	w *diodes.Waiter
+}
+
+// New{{.Name}}Waiter returns a new {{.Name}}Waiter that wraps the given diode,
+// see diodes.NewWaiter.
+func New{{.Name}}Waiter(d *{{.Name}}Diode, opts ...diodes.WaiterConfigOption) *{{.Name}}Waiter {
+	return &{{.Name}}Waiter{w: diodes.NewWaiter(d.Untyped(), opts...)}
+}
+
+// Set sets the value on the wrapped diode and wakes up the reader.
+func (w *{{.Name}}Waiter) Set(v {{.Type}}) {
+	w.w.Set(diodes.GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return the zero
+// value and false.
+func (w *{{.Name}}Waiter) TryNext() ({{.Type}}, bool) {
+	return {{.Lower}}FromGeneric(w.w.TryNext())
+}
+
+// Next returns the next value on the wrapped diode. If there is none, it
+// waits for Set to be called or the context of the waiter to be done. If
+// the context is done or the end of the stream was reached, it returns the
+// zero value and false.
+func (w *{{.Name}}Waiter) Next() ({{.Type}}, bool) {
+	data := w.w.Next()
+	return {{.Lower}}FromGeneric(data, data != nil)
+}
+
+// NextCtx is like Next but also returns once the given context is done, with
+// an error that tells why no value was returned, see diodes.Waiter.NextCtx.
+func (w *{{.Name}}Waiter) NextCtx(ctx context.Context) ({{.Type}}, error) {
+	data, err := w.w.NextCtx(ctx)
+	v, _ := {{.Lower}}FromGeneric(data, err == nil)
+	return v, err
+}
+
+// NextWithTimeout is like Next but also returns the zero value and false
+// once the timeout passed.
+func (w *{{.Name}}Waiter) NextWithTimeout(timeout time.Duration) ({{.Type}}, bool) {
+	return {{.Lower}}FromGeneric(w.w.NextWithTimeout(timeout))
+}
+
+// Close marks the end of the stream and wakes up the reader.
+func (w *{{.Name}}Waiter) Close() {
+	w.w.Close()
+}
+
+// Closed reports whether the reader has reached the end of the stream.
+func (w *{{.Name}}Waiter) Closed() bool {
+	return w.w.Closed()
+}
+
+// {{.Lower}}FromGeneric converts the result of a read to a value.
+func {{.Lower}}FromGeneric(data diodes.GenericDataType, ok bool) ({{.Type}}, bool) {
+	if !ok {
+		var zero {{.Type}}
+		return zero, false
+	}
+	return *(*{{.Type}})(data), true
+}
+`))
+
+var testTemplate = template.Must(template.New("test").Parse(`// Code generated by diodegen {{.Args}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+{{- if .Imports}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+
+func Test{{.Name}}Diode(t *testing.T) {
+	for name, d := range map[string]*{{.Name}}Diode{
+		"OneToOne":  New{{.Name}}OneToOne(2, nil),
+		"ManyToOne": New{{.Name}}ManyToOne(2, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := d.TryNext(); ok {
+				t.Fatal("TryNext read a value from an empty diode")
+			}
+
+			var want {{.Type}}
+			d.Set(want)
+			got, ok := d.TryNext()
+			if !ok || !reflect.DeepEqual(got, want) {
+				t.Fatalf("TryNext() = %v, %v, want %v, true", got, ok, want)
+			}
+		})
+	}
+}
+
+func Test{{.Name}}Poller(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := New{{.Name}}Poller(New{{.Name}}OneToOne(2, nil))
+
+	var want {{.Type}}
+	p.Set(want)
+	got, err := p.NextCtx(ctx)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("NextCtx() = %v, %v, want %v, nil", got, err, want)
+	}
+
+	p.Close()
+	if _, ok := p.Next(); ok || !p.Closed() {
+		t.Fatal("Next read a value after the end of the stream")
+	}
+}
+
+func Test{{.Name}}Waiter(t *testing.T) {
+	w := New{{.Name}}Waiter(New{{.Name}}ManyToOne(2, nil))
+	if _, ok := w.NextWithTimeout(time.Millisecond); ok {
+		t.Fatal("NextWithTimeout read a value from an empty diode")
+	}
+
+	var want {{.Type}}
+	go w.Set(want)
+	got, ok := w.NextWithTimeout(time.Minute)
+	if !ok || !reflect.DeepEqual(got, want) {
+		t.Fatalf("NextWithTimeout() = %v, %v, want %v, true", got, ok, want)
+	}
+
+	w.Close()
+	if _, ok := w.Next(); ok || !w.Closed() {
+		t.Fatal("Next read a value after the end of the stream")
+	}
+}
+`))
@@ -0,0 +1,13 @@
+package diodegen_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodegen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diodegen Suite")
+}
@@ -0,0 +1,84 @@
+package diodegen_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/go-diodes/diodegen"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate", func() {
+	DescribeTable("keeps the shells of the example package up to date",
+		func(c diodegen.Config, file string) {
+			c.Package = "example"
+
+			src, err := diodegen.Generate(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(filepath.Join("example", file+".go"))).To(Equal(src), "run go generate ./diodegen/example")
+
+			src, err = diodegen.GenerateTest(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.ReadFile(filepath.Join("example", file+"_test.go"))).To(Equal(src), "run go generate ./diodegen/example")
+		},
+		Entry("[]byte", diodegen.Config{Type: "[]byte", Name: "Bytes"}, "bytes_diode"),
+		Entry("*url.URL", diodegen.Config{Type: "*url.URL", Name: "URL", Imports: []string{"net/url"}}, "url_diode"),
+	)
+
+	DescribeTable("lower cases the leading initialism of the name for the helpers",
+		func(name, helper string) {
+			src, err := diodegen.Generate(diodegen.Config{Package: "p", Type: "int", Name: name})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(src)).To(ContainSubstring("func " + helper + "FromGeneric("))
+		},
+		Entry("a word", "Envelope", "envelope"),
+		Entry("a single letter", "X", "x"),
+		Entry("an initialism", "URL", "url"),
+		Entry("an initialism followed by a word", "HTTPEnvelope", "httpEnvelope"),
+	)
+
+	DescribeTable("rejects an invalid config",
+		func(c diodegen.Config, message string) {
+			_, err := diodegen.Generate(c)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("no package", diodegen.Config{Type: "int", Name: "Int"}, `invalid package name ""`),
+		Entry("no type", diodegen.Config{Package: "p", Name: "Int"}, "no type"),
+		Entry("unexported name", diodegen.Config{Package: "p", Type: "int", Name: "int"}, `invalid name "int"`),
+		Entry("invalid type", diodegen.Config{Package: "p", Type: "int(", Name: "Int"}, `invalid type "int("`),
+	)
+})
+
+var _ = Describe("Run", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		GinkgoT().Setenv("GOPACKAGE", "envelopes")
+	})
+
+	It("writes the shell into the package that go generate runs for", func() {
+		Expect(diodegen.Run([]string{"-type", "[]byte", "-name", "Envelope"}, dir)).To(Succeed())
+
+		want, err := diodegen.Generate(diodegen.Config{Package: "envelopes", Type: "[]byte", Name: "Envelope"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.ReadFile(filepath.Join(dir, "envelope_diode.go"))).To(Equal(want))
+		Expect(filepath.Join(dir, "envelope_diode_test.go")).NotTo(BeAnExistingFile())
+	})
+
+	It("writes the tests along with the shell", func() {
+		Expect(diodegen.Run([]string{"-type", "[]byte", "-name", "Envelope", "-package", "other", "-output", "shell.go", "-tests"}, dir)).To(Succeed())
+
+		want, err := diodegen.GenerateTest(diodegen.Config{Package: "other", Type: "[]byte", Name: "Envelope"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(dir, "shell.go")).To(BeAnExistingFile())
+		Expect(os.ReadFile(filepath.Join(dir, "shell_test.go"))).To(Equal(want))
+	})
+
+	It("requires a type and a name", func() {
+		Expect(diodegen.Run([]string{"-type", "[]byte"}, dir)).To(MatchError(ContainSubstring("usage")))
+		Expect(diodegen.Run([]string{"-name", "Envelope"}, dir)).To(MatchError(ContainSubstring("usage")))
+	})
+})
@@ -0,0 +1,7 @@
+// Package example holds shells that were generated by diodegen. They are
+// compiled and tested with the module, and the diodegen tests check that
+// they are up to date.
+package example
+
+//go:generate go run code.cloudfoundry.org/go-diodes/cmd/diodegen -type []byte -name Bytes -tests
+//go:generate go run code.cloudfoundry.org/go-diodes/cmd/diodegen -type *url.URL -name URL -import net/url -tests
@@ -0,0 +1,13 @@
+package diodesfakes_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodesfakes(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diodesfakes Suite")
+}
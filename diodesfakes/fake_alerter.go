@@ -0,0 +1,54 @@
+package diodesfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// FakeAlerter is a fake diodes.Alerter that records its calls.
+type FakeAlerter struct {
+	AlertStub        func(int)
+	alertMutex       sync.RWMutex
+	alertArgsForCall []struct {
+		arg1 int
+	}
+}
+
+// Alert records the call and invokes AlertStub if it is set.
+func (fake *FakeAlerter) Alert(arg1 int) {
+	fake.alertMutex.Lock()
+	fake.alertArgsForCall = append(fake.alertArgsForCall, struct {
+		arg1 int
+	}{arg1})
+	stub := fake.AlertStub
+	fake.alertMutex.Unlock()
+
+	if stub != nil {
+		stub(arg1)
+	}
+}
+
+// AlertCallCount returns how many times Alert was called.
+func (fake *FakeAlerter) AlertCallCount() int {
+	fake.alertMutex.RLock()
+	defer fake.alertMutex.RUnlock()
+	return len(fake.alertArgsForCall)
+}
+
+// AlertCalls sets the stub that is invoked by Alert.
+func (fake *FakeAlerter) AlertCalls(stub func(int)) {
+	fake.alertMutex.Lock()
+	defer fake.alertMutex.Unlock()
+	fake.AlertStub = stub
+}
+
+// AlertArgsForCall returns the number of missed values passed to the i-th
+// call of Alert.
+func (fake *FakeAlerter) AlertArgsForCall(i int) int {
+	fake.alertMutex.RLock()
+	defer fake.alertMutex.RUnlock()
+	return fake.alertArgsForCall[i].arg1
+}
+
+var _ diodes.Alerter = new(FakeAlerter)
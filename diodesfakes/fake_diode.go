@@ -0,0 +1,130 @@
+// Package diodesfakes provides fakes of the diodes interfaces for testing
+// code that uses diodes without real concurrency.
+package diodesfakes
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// FakeDiode is a fake diodes.Diode that records its calls. It also
+// satisfies diodes.Writer and diodes.Reader.
+type FakeDiode struct {
+	SetStub        func(diodes.GenericDataType)
+	setMutex       sync.RWMutex
+	setArgsForCall []struct {
+		arg1 diodes.GenericDataType
+	}
+
+	TryNextStub        func() (diodes.GenericDataType, bool)
+	tryNextMutex       sync.RWMutex
+	tryNextArgsForCall []struct{}
+	tryNextReturns     struct {
+		result1 diodes.GenericDataType
+		result2 bool
+	}
+	tryNextReturnsOnCall map[int]struct {
+		result1 diodes.GenericDataType
+		result2 bool
+	}
+}
+
+// Set records the call and invokes SetStub if it is set.
+func (fake *FakeDiode) Set(arg1 diodes.GenericDataType) {
+	fake.setMutex.Lock()
+	fake.setArgsForCall = append(fake.setArgsForCall, struct {
+		arg1 diodes.GenericDataType
+	}{arg1})
+	stub := fake.SetStub
+	fake.setMutex.Unlock()
+
+	if stub != nil {
+		stub(arg1)
+	}
+}
+
+// SetCallCount returns how many times Set was called.
+func (fake *FakeDiode) SetCallCount() int {
+	fake.setMutex.RLock()
+	defer fake.setMutex.RUnlock()
+	return len(fake.setArgsForCall)
+}
+
+// SetCalls sets the stub that is invoked by Set.
+func (fake *FakeDiode) SetCalls(stub func(diodes.GenericDataType)) {
+	fake.setMutex.Lock()
+	defer fake.setMutex.Unlock()
+	fake.SetStub = stub
+}
+
+// SetArgsForCall returns the data passed to the i-th call of Set.
+func (fake *FakeDiode) SetArgsForCall(i int) diodes.GenericDataType {
+	fake.setMutex.RLock()
+	defer fake.setMutex.RUnlock()
+	return fake.setArgsForCall[i].arg1
+}
+
+// TryNext records the call and returns the result of TryNextStub if it is
+// set, otherwise the configured return values.
+func (fake *FakeDiode) TryNext() (diodes.GenericDataType, bool) {
+	fake.tryNextMutex.Lock()
+	ret, specificReturn := fake.tryNextReturnsOnCall[len(fake.tryNextArgsForCall)]
+	fake.tryNextArgsForCall = append(fake.tryNextArgsForCall, struct{}{})
+	stub := fake.TryNextStub
+	fakeReturns := fake.tryNextReturns
+	fake.tryNextMutex.Unlock()
+
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+// TryNextCallCount returns how many times TryNext was called.
+func (fake *FakeDiode) TryNextCallCount() int {
+	fake.tryNextMutex.RLock()
+	defer fake.tryNextMutex.RUnlock()
+	return len(fake.tryNextArgsForCall)
+}
+
+// TryNextCalls sets the stub that is invoked by TryNext.
+func (fake *FakeDiode) TryNextCalls(stub func() (diodes.GenericDataType, bool)) {
+	fake.tryNextMutex.Lock()
+	defer fake.tryNextMutex.Unlock()
+	fake.TryNextStub = stub
+}
+
+// TryNextReturns sets the values returned by every call of TryNext.
+func (fake *FakeDiode) TryNextReturns(result1 diodes.GenericDataType, result2 bool) {
+	fake.tryNextMutex.Lock()
+	defer fake.tryNextMutex.Unlock()
+	fake.TryNextStub = nil
+	fake.tryNextReturns = struct {
+		result1 diodes.GenericDataType
+		result2 bool
+	}{result1, result2}
+}
+
+// TryNextReturnsOnCall sets the values returned by the i-th call of
+// TryNext.
+func (fake *FakeDiode) TryNextReturnsOnCall(i int, result1 diodes.GenericDataType, result2 bool) {
+	fake.tryNextMutex.Lock()
+	defer fake.tryNextMutex.Unlock()
+	fake.TryNextStub = nil
+	if fake.tryNextReturnsOnCall == nil {
+		fake.tryNextReturnsOnCall = make(map[int]struct {
+			result1 diodes.GenericDataType
+			result2 bool
+		})
+	}
+	fake.tryNextReturnsOnCall[i] = struct {
+		result1 diodes.GenericDataType
+		result2 bool
+	}{result1, result2}
+}
+
+var _ diodes.Diode = new(FakeDiode)
@@ -0,0 +1,33 @@
+package diodesfakes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodesfakes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FakeDiode", func() {
+	It("records the data that was set", func() {
+		fake := &diodesfakes.FakeDiode{}
+		data := []byte("some-data")
+		fake.Set(diodes.GenericDataType(&data))
+
+		Expect(fake.SetCallCount()).To(Equal(1))
+		Expect(fake.SetArgsForCall(0)).To(Equal(diodes.GenericDataType(&data)))
+	})
+
+	It("returns the configured values from TryNext", func() {
+		fake := &diodesfakes.FakeDiode{}
+		data := []byte("some-data")
+		fake.TryNextReturnsOnCall(0, diodes.GenericDataType(&data), true)
+
+		p := diodes.NewPoller(fake)
+		Expect(p.Next()).To(Equal(diodes.GenericDataType(&data)))
+
+		_, ok := fake.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(fake.TryNextCallCount()).To(Equal(2))
+	})
+})
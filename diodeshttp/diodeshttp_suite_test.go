@@ -0,0 +1,13 @@
+package diodeshttp_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodesHTTP(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DiodesHTTP Suite")
+}
@@ -0,0 +1,79 @@
+package diodeshttp
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// MetadataFunc returns the metadata of observed data that client filters are
+// evaluated against.
+type MetadataFunc func(diodes.GenericDataType) map[string]string
+
+// filter is a set of conditions that must all match. An empty filter matches
+// everything.
+type filter []condition
+
+type condition struct {
+	key   string
+	value string
+	op    string
+}
+
+// parseFilter parses filter expressions. Each expression is either
+// "key=value", "key!=value" or "key" (the key is present).
+func parseFilter(exprs []string) (filter, error) {
+	var f filter
+	for _, expr := range exprs {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+
+		c := condition{op: "?"}
+		switch {
+		case strings.Contains(expr, "!="):
+			c.op = "!="
+		case strings.Contains(expr, "="):
+			c.op = "="
+		}
+
+		c.key = expr
+		if c.op != "?" {
+			c.key, c.value, _ = strings.Cut(expr, c.op)
+		}
+
+		c.key = strings.TrimSpace(c.key)
+		c.value = strings.TrimSpace(c.value)
+		if c.key == "" {
+			return nil, fmt.Errorf("invalid filter expression %q", expr)
+		}
+
+		f = append(f, c)
+	}
+
+	return f, nil
+}
+
+func (f filter) match(metadata map[string]string) bool {
+	for _, c := range f {
+		v, ok := metadata[c.key]
+		switch c.op {
+		case "=":
+			if !ok || v != c.value {
+				return false
+			}
+		case "!=":
+			if ok && v == c.value {
+				return false
+			}
+		default:
+			if !ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
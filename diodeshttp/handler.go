@@ -0,0 +1,76 @@
+// Package diodeshttp provides HTTP handlers for inspecting diodes.
+package diodeshttp
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// FormatFunc renders observed data for a client.
+type FormatFunc func(diodes.GenericDataType) string
+
+// HandlerConfigOption can be used to setup the handlers.
+type HandlerConfigOption func(*handlerConfig)
+
+type handlerConfig struct {
+	every   int
+	size    int
+	origins []string
+}
+
+// WithSampling sets how many values are set on the tap for every value that
+// is streamed. Clients can override it with the "every" query parameter. The
+// default is 1 (every value).
+func WithSampling(every int) HandlerConfigOption {
+	return HandlerConfigOption(func(c *handlerConfig) {
+		c.every = every
+	})
+}
+
+// WithBufferSize sets the size of the diode buffering data for each client.
+// The default is 1024.
+func WithBufferSize(size int) HandlerConfigOption {
+	return HandlerConfigOption(func(c *handlerConfig) {
+		c.size = size
+	})
+}
+
+// WithAllowedOrigins allows browsers on the given origins, e.g.
+// "https://dashboard.example.com", to open a WebSocket of a
+// WebSocketHandler. By default only pages served from the host of the
+// handler itself are allowed. It does not affect the SSEHandler.
+func WithAllowedOrigins(origins ...string) HandlerConfigOption {
+	return HandlerConfigOption(func(c *handlerConfig) {
+		c.origins = append(c.origins, origins...)
+	})
+}
+
+func newHandlerConfig(opts []HandlerConfigOption) handlerConfig {
+	c := handlerConfig{
+		every: 1,
+		size:  1024,
+	}
+
+	for _, o := range opts {
+		o(&c)
+	}
+
+	return c
+}
+
+// sampling returns the sampling rate requested by the client.
+func (c handlerConfig) sampling(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("every")
+	if v == "" {
+		return c.every, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, errors.New("every must be a positive integer")
+	}
+	return n, nil
+}
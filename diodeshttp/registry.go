@@ -0,0 +1,55 @@
+package diodeshttp
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// RegistryHandler serves the state of every diode in the registry of the
+// process (see diodes.Register) as a JSON object keyed by name, for
+// debugging endpoints. Diodes that report their stats or a snapshot of
+// their ring buffer include them. The snapshots are only included with the
+// "snapshot" query parameter, since they hold every slot.
+type RegistryHandler struct{}
+
+// NewRegistryHandler returns a new RegistryHandler.
+func NewRegistryHandler() *RegistryHandler {
+	return &RegistryHandler{}
+}
+
+// registeredDiode is the state of a registered diode.
+type registeredDiode struct {
+	Stats    *diodes.Stats    `json:"stats,omitempty"`
+	Snapshot *diodes.Snapshot `json:"snapshot,omitempty"`
+}
+
+// ServeHTTP writes the state of the registered diodes.
+func (h *RegistryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	snapshots := r.URL.Query().Has("snapshot")
+
+	state := make(map[string]registeredDiode)
+	diodes.Range(func(name string, d diodes.Diode) bool {
+		var rd registeredDiode
+		if s, ok := d.(interface{ Stats() diodes.Stats }); ok {
+			st := s.Stats()
+			rd.Stats = &st
+		}
+		if s, ok := d.(interface{ Snapshot() diodes.Snapshot }); ok && snapshots {
+			snap := s.Snapshot()
+			rd.Snapshot = &snap
+		}
+		state[name] = rd
+		return true
+	})
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,57 @@
+package diodeshttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodeshttp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegistryHandler", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		d := diodes.NewManyToOne(4, nil)
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(diodes.Register("ingress", d)).To(Succeed())
+
+		server = httptest.NewServer(diodeshttp.NewRegistryHandler())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		diodes.Unregister("ingress")
+	})
+
+	get := func(path string) map[string]map[string]map[string]any {
+		resp, err := http.Get(server.URL + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/json"))
+
+		var state map[string]map[string]map[string]any
+		Expect(json.NewDecoder(resp.Body).Decode(&state)).To(Succeed())
+		return state
+	}
+
+	It("serves the stats of the registered diodes", func() {
+		state := get("/")
+		Expect(state).To(HaveKey("ingress"))
+		Expect(state["ingress"]["stats"]).To(HaveKeyWithValue("Writes", BeNumerically("==", 3)))
+		Expect(state["ingress"]).ToNot(HaveKey("snapshot"))
+	})
+
+	It("serves the snapshots when asked to", func() {
+		state := get("/?snapshot")
+		Expect(state["ingress"]["snapshot"]).To(HaveKeyWithValue("WriteIndex", BeNumerically("==", 3)))
+		Expect(state["ingress"]["snapshot"]["Slots"]).To(HaveLen(4))
+	})
+})
@@ -0,0 +1,68 @@
+package diodeshttp
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// SSEHandler streams a sample of the data set on a diodes.Tap as Server-Sent
+// Events. Each client gets its own observer, so a slow client drops data
+// without affecting the tapped diode or other clients.
+type SSEHandler struct {
+	handlerConfig
+	tap    *diodes.Tap
+	format FormatFunc
+}
+
+// NewSSEHandler returns a new SSEHandler that streams data observed on the
+// given tap, rendered by format.
+func NewSSEHandler(t *diodes.Tap, format FormatFunc, opts ...HandlerConfigOption) *SSEHandler {
+	return &SSEHandler{
+		handlerConfig: newHandlerConfig(opts),
+		tap:           t,
+		format:        format,
+	}
+}
+
+// ServeHTTP streams events until the client goes away.
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	every, err := h.sampling(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	o := h.tap.Attach(r.Context(), every, h.size)
+	defer o.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		data, err := o.NextCtx(r.Context())
+		if err != nil {
+			return
+		}
+
+		for _, line := range strings.Split(h.format(data), "\n") {
+			if _, err := fmt.Fprintf(w, "data: %s\n", line); err != nil {
+				return
+			}
+		}
+		if _, err := fmt.Fprint(w, "\n"); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
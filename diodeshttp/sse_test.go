@@ -0,0 +1,110 @@
+package diodeshttp_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodeshttp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SSEHandler", func() {
+	var (
+		tap    *diodes.Tap
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		tap = diodes.NewTap(diodes.NewOneToOne(10, nil))
+		server = httptest.NewServer(diodeshttp.NewSSEHandler(tap, func(data diodes.GenericDataType) string {
+			return string(*(*[]byte)(data))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	setUntilStreamed := func(lines chan string, data []byte) string {
+		var line string
+		Eventually(func() bool {
+			tap.Set(diodes.GenericDataType(&data))
+			select {
+			case line = <-lines:
+				return true
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}).Should(BeTrue())
+		return line
+	}
+
+	stream := func(url string) (*http.Response, chan string) {
+		resp, err := http.Get(url)
+		Expect(err).ToNot(HaveOccurred())
+
+		lines := make(chan string, 100)
+		go func() {
+			defer GinkgoRecover()
+			s := bufio.NewScanner(resp.Body)
+			for s.Scan() {
+				if s.Text() != "" {
+					lines <- s.Text()
+				}
+			}
+		}()
+		return resp, lines
+	}
+
+	It("streams set data as events", func() {
+		resp, lines := stream(server.URL)
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+		Expect(setUntilStreamed(lines, []byte("some-data"))).To(Equal("data: some-data"))
+	})
+
+	It("splits multi-line data into several data fields", func() {
+		resp, lines := stream(server.URL)
+		defer resp.Body.Close()
+
+		Expect(setUntilStreamed(lines, []byte("a\nb"))).To(Equal("data: a"))
+		Eventually(lines).Should(Receive(Equal("data: b")))
+	})
+
+	It("streams nil data", func() {
+		nilServer := httptest.NewServer(diodeshttp.NewSSEHandler(tap, func(data diodes.GenericDataType) string {
+			if data == nil {
+				return "nil"
+			}
+			return string(*(*[]byte)(data))
+		}))
+		defer nilServer.Close()
+
+		resp, lines := stream(nilServer.URL)
+		defer resp.Body.Close()
+
+		Eventually(func() []string {
+			tap.Set(nil)
+			var got []string
+			for len(lines) > 0 {
+				got = append(got, <-lines)
+			}
+			return got
+		}).Should(ContainElement("data: nil"))
+	})
+
+	It("rejects an invalid sampling rate", func() {
+		resp, err := http.Get(server.URL + "?every=0")
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,129 @@
+package diodeshttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/websocket"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// WebSocketHandler streams a sample of the data set on a diodes.Tap over a
+// WebSocket. Clients can restrict what they receive with filter expressions
+// that are evaluated against the metadata of each value, either via "filter"
+// query parameters or by sending a message with one expression per line,
+// which replaces the current filter. Expressions are "key=value",
+// "key!=value" or "key" and all of them must match. Browsers on other
+// origins than the host of the handler are rejected with 403 Forbidden
+// unless they are allowed by WithAllowedOrigins.
+type WebSocketHandler struct {
+	handlerConfig
+	tap      *diodes.Tap
+	format   FormatFunc
+	metadata MetadataFunc
+}
+
+// NewWebSocketHandler returns a new WebSocketHandler that streams data
+// observed on the given tap, rendered by format. Filters are evaluated
+// against the result of metadata.
+func NewWebSocketHandler(t *diodes.Tap, format FormatFunc, metadata MetadataFunc, opts ...HandlerConfigOption) *WebSocketHandler {
+	return &WebSocketHandler{
+		handlerConfig: newHandlerConfig(opts),
+		tap:           t,
+		format:        format,
+		metadata:      metadata,
+	}
+}
+
+// ServeHTTP upgrades the connection and streams messages until the client
+// goes away.
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	every, err := h.sampling(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, err := parseFilter(r.URL.Query()["filter"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	websocket.Server{
+		Handshake: h.handshake,
+		Handler: func(ws *websocket.Conn) {
+			h.stream(ws, every, f)
+		},
+	}.ServeHTTP(w, r)
+}
+
+// handshake rejects browsers on origins other than the host of the request
+// or the allowed ones, so that other sites can not read the stream. Clients
+// that are not browsers usually send no origin and are accepted.
+func (h *WebSocketHandler) handshake(config *websocket.Config, r *http.Request) error {
+	origin, err := websocket.Origin(config, r)
+	if err != nil {
+		return err
+	}
+	config.Origin = origin
+	if origin == nil || strings.EqualFold(origin.Host, r.Host) {
+		return nil
+	}
+
+	for _, allowed := range h.origins {
+		if strings.EqualFold(allowed, origin.Scheme+"://"+origin.Host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+func (h *WebSocketHandler) stream(ws *websocket.Conn, every int, f filter) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+
+	var current atomic.Pointer[filter]
+	current.Store(&f)
+
+	go func() {
+		defer cancel()
+		for {
+			var msg string
+			if err := websocket.Message.Receive(ws, &msg); err != nil {
+				return
+			}
+
+			f, err := parseFilter(strings.Split(msg, "\n"))
+			if err != nil {
+				if err := websocket.Message.Send(ws, "error: "+err.Error()); err != nil {
+					return
+				}
+				continue
+			}
+			current.Store(&f)
+		}
+	}()
+
+	o := h.tap.Attach(ctx, every, h.size)
+	defer o.Close()
+
+	for {
+		data, err := o.NextCtx(ctx)
+		if err != nil {
+			return
+		}
+
+		if !current.Load().match(h.metadata(data)) {
+			continue
+		}
+
+		if err := websocket.Message.Send(ws, h.format(data)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package diodeshttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodeshttp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WebSocketHandler", func() {
+	type entry struct {
+		level string
+		msg   string
+	}
+
+	var (
+		tap    *diodes.Tap
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		tap = diodes.NewTap(diodes.NewOneToOne(10, nil))
+		server = httptest.NewServer(diodeshttp.NewWebSocketHandler(
+			tap,
+			func(data diodes.GenericDataType) string {
+				return (*entry)(data).msg
+			},
+			func(data diodes.GenericDataType) map[string]string {
+				return map[string]string{"level": (*entry)(data).level}
+			},
+		))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	dial := func(query string) *websocket.Conn {
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + query
+		ws, err := websocket.Dial(url, "", server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		return ws
+	}
+
+	messages := func(ws *websocket.Conn) chan string {
+		c := make(chan string, 100)
+		go func() {
+			for {
+				var msg string
+				if err := websocket.Message.Receive(ws, &msg); err != nil {
+					return
+				}
+				c <- msg
+			}
+		}()
+		return c
+	}
+
+	setUntil := func(c chan string, e *entry) string {
+		var msg string
+		Eventually(func() bool {
+			tap.Set(diodes.GenericDataType(e))
+			select {
+			case msg = <-c:
+				return true
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}).Should(BeTrue())
+		return msg
+	}
+
+	It("streams set data as messages", func() {
+		ws := dial("")
+		defer ws.Close()
+
+		Expect(setUntil(messages(ws), &entry{level: "info", msg: "some-data"})).To(Equal("some-data"))
+	})
+
+	It("only streams data matching the filter from the query", func() {
+		ws := dial("?filter=level!=debug")
+		defer ws.Close()
+		c := messages(ws)
+
+		for i := 0; i < 5; i++ {
+			tap.Set(diodes.GenericDataType(&entry{level: "debug", msg: "debug-data"}))
+		}
+		Expect(setUntil(c, &entry{level: "error", msg: "error-data"})).To(Equal("error-data"))
+	})
+
+	It("replaces the filter when the client sends one", func() {
+		ws := dial("?filter=level=debug")
+		defer ws.Close()
+		c := messages(ws)
+
+		Expect(websocket.Message.Send(ws, "level=error")).To(Succeed())
+		Expect(setUntil(c, &entry{level: "error", msg: "error-data"})).To(Equal("error-data"))
+	})
+
+	It("reports invalid filters sent by the client", func() {
+		ws := dial("")
+		defer ws.Close()
+		c := messages(ws)
+
+		Expect(websocket.Message.Send(ws, "=value")).To(Succeed())
+		Eventually(c).Should(Receive(HavePrefix("error: ")))
+	})
+
+	It("streams nil data", func() {
+		nilServer := httptest.NewServer(diodeshttp.NewWebSocketHandler(
+			tap,
+			func(data diodes.GenericDataType) string {
+				if data == nil {
+					return "nil"
+				}
+				return (*entry)(data).msg
+			},
+			func(diodes.GenericDataType) map[string]string { return nil },
+		))
+		defer nilServer.Close()
+
+		url := "ws" + strings.TrimPrefix(nilServer.URL, "http")
+		ws, err := websocket.Dial(url, "", nilServer.URL)
+		Expect(err).ToNot(HaveOccurred())
+		defer ws.Close()
+		c := messages(ws)
+
+		Eventually(func() bool {
+			tap.Set(nil)
+			select {
+			case msg := <-c:
+				return msg == "nil"
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}).Should(BeTrue())
+	})
+
+	upgrade := func(url, origin string) int {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Origin", origin)
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	It("rejects browsers on other origins", func() {
+		Expect(upgrade(server.URL, "https://evil.example.com")).To(Equal(http.StatusForbidden))
+		Expect(upgrade(server.URL, server.URL)).To(Equal(http.StatusSwitchingProtocols))
+	})
+
+	It("accepts browsers on the allowed origins", func() {
+		allowed := httptest.NewServer(diodeshttp.NewWebSocketHandler(
+			tap,
+			func(diodes.GenericDataType) string { return "" },
+			func(diodes.GenericDataType) map[string]string { return nil },
+			diodeshttp.WithAllowedOrigins("https://dashboard.example.com"),
+		))
+		defer allowed.Close()
+
+		Expect(upgrade(allowed.URL, "https://dashboard.example.com")).To(Equal(http.StatusSwitchingProtocols))
+		Expect(upgrade(allowed.URL, "http://dashboard.example.com")).To(Equal(http.StatusForbidden))
+	})
+
+	It("rejects an invalid filter in the query", func() {
+		resp, err := http.Get(server.URL + "?filter=!=value")
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
@@ -0,0 +1,13 @@
+package diodesmetrics_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodesMetrics(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DiodesMetrics Suite")
+}
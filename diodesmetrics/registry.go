@@ -0,0 +1,205 @@
+// Package diodesmetrics exposes the stats of named diodes as expvar
+// variables and in the Prometheus text exposition format, so that a process
+// with many diodes does not need its own glue for every one of them.
+package diodesmetrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// ErrDuplicate is returned when a name is registered twice.
+var ErrDuplicate = errors.New("diodesmetrics: name already registered")
+
+// Source is a diode that reports its stats. It is satisfied by every diode
+// with a Stats method, such as the OneToOne, ManyToOne, ManyToMany and
+// Unbounded diodes.
+type Source interface {
+	ReadStatsInto(st *diodes.Stats)
+}
+
+// Registry holds diodes keyed by name. It is an expvar.Var, so it can be
+// published with expvar.Publish, and an http.Handler that serves the stats
+// in the Prometheus text exposition format. It is safe for concurrent use.
+//
+// The stats are read with ReadStatsInto into a value that is kept for every
+// diode, so a scrape neither allocates stats nor takes a lock of the diodes.
+// The rates are the averages per second since the previous scrape.
+type Registry struct {
+	mu      sync.Mutex
+	sources []*source
+
+	// scraping serializes scrapes, which reuse the stats of the sources.
+	scraping sync.Mutex
+}
+
+// source is a registered diode along with its last stats.
+type source struct {
+	name  string
+	s     Source
+	stats diodes.Stats
+
+	// prev holds the counters of the previous scrape, as of at, to compute
+	// the rates with.
+	prev diodes.Stats
+	at   time.Time
+}
+
+// NewRegistry returns a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds the diode under the given name. It returns ErrDuplicate if
+// the name is already registered.
+func (r *Registry) Register(name string, s Source) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := sort.Search(len(r.sources), func(i int) bool {
+		return r.sources[i].name >= name
+	})
+	if i < len(r.sources) && r.sources[i].name == name {
+		return fmt.Errorf("%w: %q", ErrDuplicate, name)
+	}
+
+	src := &source{name: name, s: s, at: time.Now()}
+	s.ReadStatsInto(&src.prev)
+
+	// Scrapes may still use the old slice, so it is copied.
+	sources := make([]*source, 0, len(r.sources)+1)
+	sources = append(sources, r.sources[:i]...)
+	sources = append(sources, src)
+	r.sources = append(sources, r.sources[i:]...)
+	return nil
+}
+
+// Unregister removes the diode with the given name, if there is one.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, src := range r.sources {
+		if src.name == name {
+			r.sources = slices.Delete(slices.Clone(r.sources), i, i+1)
+			return
+		}
+	}
+}
+
+// String returns the stats of every diode as a JSON object keyed by name,
+// which makes the registry an expvar.Var.
+func (r *Registry) String() string {
+	stats := make(map[string]diodes.Stats)
+	r.scrape(func(sources []*source) {
+		for _, src := range sources {
+			stats[src.name] = src.stats
+		}
+	})
+
+	b, err := json.Marshal(stats)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+// ServeHTTP writes the stats of every diode in the Prometheus text
+// exposition format, with the name of the diode as the "diode" label.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// WriteTo writes the stats of every diode in the Prometheus text exposition
+// format to w.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	r.scrape(func(sources []*source) {
+		for _, m := range metrics {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+			for _, src := range sources {
+				fmt.Fprintf(&b, "%s{diode=\"%s\"} %v\n", m.name, escapeLabel(src.name), m.value(src.stats))
+			}
+		}
+	})
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// scrape reads the stats of every diode and invokes fn with the diodes,
+// sorted by name, while no other scrape can change their stats.
+func (r *Registry) scrape(fn func([]*source)) {
+	r.scraping.Lock()
+	defer r.scraping.Unlock()
+
+	r.mu.Lock()
+	sources := r.sources
+	r.mu.Unlock()
+
+	now := time.Now()
+	for _, src := range sources {
+		src.s.ReadStatsInto(&src.stats)
+		src.rates(now)
+	}
+
+	fn(sources)
+}
+
+// rates sets the rates of the stats to the averages since the previous
+// scrape.
+func (src *source) rates(now time.Time) {
+	dt := now.Sub(src.at).Seconds()
+	if dt > 0 {
+		src.stats.WriteRate = perSecond(src.stats.Writes, src.prev.Writes, dt)
+		src.stats.ReadRate = perSecond(src.stats.Reads, src.prev.Reads, dt)
+		src.stats.DropRate = perSecond(src.stats.Dropped, src.prev.Dropped, dt)
+	}
+
+	src.prev = src.stats
+	src.at = now
+}
+
+func perSecond(count, prev uint64, dt float64) float64 {
+	if count < prev {
+		return 0
+	}
+	return float64(count-prev) / dt
+}
+
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func(diodes.Stats) any
+}
+
+var metrics = []metric{
+	{"diodes_writes_total", "Total number of values that were set.", "counter", func(s diodes.Stats) any { return s.Writes }},
+	{"diodes_reads_total", "Total number of values that were read.", "counter", func(s diodes.Stats) any { return s.Reads }},
+	{"diodes_dropped_total", "Total number of values that were overwritten before they were read.", "counter", func(s diodes.Stats) any { return s.Dropped }},
+	{"diodes_rate_limited_total", "Total number of values that were dropped by the write rate limit.", "counter", func(s diodes.Stats) any { return s.RateLimited }},
+	{"diodes_collisions_total", "Total number of writer collisions.", "counter", func(s diodes.Stats) any { return s.Collisions }},
+	{"diodes_lag", "Number of values that were set but not read yet.", "gauge", func(s diodes.Stats) any { return s.Lag }},
+	{"diodes_capacity", "Number of slots of the diode.", "gauge", func(s diodes.Stats) any { return s.Capacity }},
+	{"diodes_write_rate", "Writes per second since the previous scrape.", "gauge", func(s diodes.Stats) any { return s.WriteRate }},
+	{"diodes_read_rate", "Reads per second since the previous scrape.", "gauge", func(s diodes.Stats) any { return s.ReadRate }},
+	{"diodes_drop_rate", "Drops per second since the previous scrape.", "gauge", func(s diodes.Stats) any { return s.DropRate }},
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
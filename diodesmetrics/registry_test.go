@@ -0,0 +1,128 @@
+package diodesmetrics_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodesmetrics"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		r          *diodesmetrics.Registry
+		envelopes  *diodes.ManyToOne
+		logs       *diodes.OneToOne
+		setAndRead func(d diodes.Diode, set, read int)
+	)
+
+	BeforeEach(func() {
+		r = diodesmetrics.NewRegistry()
+		envelopes = diodes.NewManyToOne(4, nil)
+		logs = diodes.NewOneToOne(8, nil)
+
+		Expect(r.Register("envelopes", envelopes)).To(Succeed())
+		Expect(r.Register(`logs "app"`, logs)).To(Succeed())
+
+		setAndRead = func(d diodes.Diode, set, read int) {
+			for i := 0; i < set; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+			for i := 0; i < read; i++ {
+				d.TryNext()
+			}
+		}
+	})
+
+	It("rejects a name that is already registered", func() {
+		err := r.Register("envelopes", diodes.NewOneToOne(1, nil))
+		Expect(err).To(MatchError(diodesmetrics.ErrDuplicate))
+	})
+
+	It("serves the stats in the Prometheus text format", func() {
+		setAndRead(envelopes, 10, 1)
+		setAndRead(logs, 3, 3)
+
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		lines := strings.Split(string(body), "\n")
+		Expect(lines).To(ContainElements(
+			"# TYPE diodes_dropped_total counter",
+			`diodes_writes_total{diode="envelopes"} 10`,
+			`diodes_dropped_total{diode="envelopes"} 8`,
+			`diodes_lag{diode="envelopes"} 1`,
+			`diodes_capacity{diode="envelopes"} 4`,
+			`diodes_reads_total{diode="logs \"app\""} 3`,
+			`diodes_lag{diode="logs \"app\""} 0`,
+		))
+	})
+
+	It("stops reporting a diode once it is unregistered", func() {
+		r.Unregister("envelopes")
+
+		var b strings.Builder
+		_, err := r.WriteTo(&b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.String()).ToNot(ContainSubstring("envelopes"))
+	})
+
+	It("computes the rates since the previous scrape", func() {
+		scrape := func() map[string]diodes.Stats {
+			var stats map[string]diodes.Stats
+			Expect(json.Unmarshal([]byte(r.String()), &stats)).To(Succeed())
+			return stats
+		}
+
+		setAndRead(envelopes, 10, 10)
+		time.Sleep(10 * time.Millisecond)
+		st := scrape()["envelopes"]
+		Expect(st.WriteRate).To(BeNumerically(">", 0))
+		Expect(st.ReadRate).To(BeNumerically(">", 0))
+
+		st = scrape()["envelopes"]
+		Expect(st.WriteRate).To(BeZero())
+		Expect(st.ReadRate).To(BeZero())
+	})
+
+	It("scrapes any diode that can read its stats into a value", func() {
+		u := diodes.NewUnbounded(4, 1024, func(diodes.GenericDataType) int { return 8 }, nil)
+		Expect(r.Register("unbounded", u)).To(Succeed())
+		setAndRead(u, 3, 1)
+
+		var b strings.Builder
+		_, err := r.WriteTo(&b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Split(b.String(), "\n")).To(ContainElements(
+			`diodes_writes_total{diode="unbounded"} 3`,
+			`diodes_lag{diode="unbounded"} 2`,
+		))
+	})
+
+	It("can be published as an expvar", func() {
+		setAndRead(envelopes, 2, 0)
+		expvar.Publish("diodesmetrics-test", r)
+
+		var stats map[string]diodes.Stats
+		Expect(json.Unmarshal([]byte(expvar.Get("diodesmetrics-test").String()), &stats)).To(Succeed())
+		Expect(stats).To(HaveLen(2))
+		Expect(stats["envelopes"].Writes).To(Equal(uint64(2)))
+	})
+})
@@ -0,0 +1,638 @@
+package diodesshm
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// The file starts with a header that holds the version of the format, the
+// geometry of the ring, its flags and the write and read indexes, each on
+// their own cache line, followed by the slots. Every slot holds a sequence
+// number, the length of its value, along with its CRC-32C in the upper half
+// if the diode has checksums, and the value itself. The sequence number is
+// odd while the writer fills the slot for an index and even once it is
+// done, so the reader can tell whether the value it copied is complete and
+// still belongs to the index it reads. The end of the stream is stored as a
+// value of endOfStreamSize bytes, which no slot can hold.
+const (
+	magic = 0x6d6873646f6964 // "diodshm" in little endian
+
+	// version is the version of the format. Files of another version are
+	// rejected, since the processes would not agree on the layout.
+	version = 1
+
+	magicOffset      = 0
+	slotsOffset      = 8
+	slotSizeOffset   = 16
+	versionOffset    = 24
+	flagsOffset      = 32
+	writeIndexOffset = 64
+	readIndexOffset  = 128
+	headerSize       = 192
+
+	slotHeaderSize = 16
+
+	// endOfStreamSize is the length that marks the end of the stream that
+	// the Close of a diodes.Poller or diodes.Waiter sets.
+	endOfStreamSize = math.MaxUint32
+
+	// flagChecksums marks a diode that stores the checksum of every value.
+	flagChecksums = 1
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+const (
+	// pollInterval is how long NextCtx waits between reads without a
+	// notifier.
+	pollInterval = 10 * time.Millisecond
+
+	// maxWait is the longest NextCtx waits on its notifier before it checks
+	// whether its context is done.
+	maxWait = 100 * time.Millisecond
+)
+
+// ErrCorrupt is returned when a file is not a valid shared memory diode, or
+// one of another version of the format.
+var ErrCorrupt = errors.New("diodesshm: corrupt file")
+
+// EncodeFunc serializes a value that is set on the diode.
+type EncodeFunc func(diodes.GenericDataType) []byte
+
+// DecodeFunc deserializes a value that is read from the diode. The given
+// slice is reused for the next value and must be copied if it is retained.
+type DecodeFunc func([]byte) diodes.GenericDataType
+
+// Diode is a diode in a memory mapped file that is shared by a single writer
+// and a single reader, which are usually different processes. Like the
+// other diodes, the writer never waits for the reader and overwrites the
+// oldest values once the ring is full. The indexes are kept in the file, so
+// either side can be restarted.
+//
+// Each process opens the file with its own Diode and only calls either Set
+// or TryNext on it.
+type Diode struct {
+	mem      []byte
+	unmap    func() error
+	slots    uint64
+	slotSize uint64
+	stride   uint64
+
+	encode  EncodeFunc
+	decode  DecodeFunc
+	alerter diodes.Alerter
+
+	// buf holds the value the reader copied out of its slot.
+	buf      []byte
+	dropped  atomic.Uint64
+	tooLarge atomic.Uint64
+	corrupt  atomic.Uint64
+
+	config
+}
+
+// ConfigOption can be used to setup the diode.
+type ConfigOption func(*config)
+
+type config struct {
+	notifier  Notifier
+	checksums bool
+}
+
+func newConfig(opts []ConfigOption) config {
+	var c config
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
+
+// flags returns the flags of the header of a diode that is created with the
+// config.
+func (c config) flags() uint64 {
+	if c.checksums {
+		return flagChecksums
+	}
+	return 0
+}
+
+// WithChecksums creates the diode with room for the CRC-32C of every value,
+// which the writer stores and the reader checks, so that values that are
+// damaged in a file that is not backed by memory, e.g. by a power loss, are
+// skipped and counted by Corrupt instead of being handed to the decode
+// function. Like the geometry, it only takes effect when the diode is
+// created: an existing diode keeps checksums if it has them.
+func WithChecksums() ConfigOption {
+	return ConfigOption(func(c *config) {
+		c.checksums = true
+	})
+}
+
+// WithNotifier sets the notifier the writer notifies once it set a value on
+// the empty diode, and that the reader waits on in NextCtx, so that the
+// reader does not need to poll. The writer and the reader each need their
+// own notifier for the same diode, e.g. from OpenNotifier. The notifier is
+// not closed with the diode.
+func WithNotifier(n Notifier) ConfigOption {
+	return ConfigOption(func(c *config) {
+		c.notifier = n
+	})
+}
+
+// Open maps the diode in the file at path, creating it with the given number
+// of slots of slotSize bytes each if it does not exist. An existing file
+// keeps the geometry it was created with. The alerter is invoked on the
+// reader's go-routine when it notices that the writer has passed it and
+// wrote over data. A nil can be used to ignore alerts.
+func Open(path string, slots, slotSize int, alerter diodes.Alerter, encode EncodeFunc, decode DecodeFunc, opts ...ConfigOption) (*Diode, error) {
+	if err := validGeometry(slots, slotSize); err != nil {
+		return nil, err
+	}
+
+	c := newConfig(opts)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := create(path, uint64(slots), uint64(slotSize), c.flags()); err != nil {
+			return nil, err
+		}
+	}
+
+	mem, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return attach(mem, unmap, alerter, encode, decode, c)
+}
+
+// OpenNamed maps the diode with the given name, creating it like Open if no
+// process did so yet, so that processes built from different binaries, e.g.
+// a collector agent and the emitter library of an app, only need to agree on
+// the name to find the same diode. Names must not be empty or contain path
+// separators.
+//
+// On Linux, named diodes are files in /dev/shm, which is backed by memory,
+// and in the temporary directory on other unix systems. They outlive the
+// processes that use them until they are removed with Remove. On Windows,
+// they are named file mappings in the session of the process, backed by the
+// paging file, which exist as long as any process has them open. The first
+// process to open one sets its geometry, and every other process waits for
+// it to do so before it attaches.
+func OpenNamed(name string, slots, slotSize int, alerter diodes.Alerter, encode EncodeFunc, decode DecodeFunc, opts ...ConfigOption) (*Diode, error) {
+	if err := validGeometry(slots, slotSize); err != nil {
+		return nil, err
+	}
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+
+	c := newConfig(opts)
+	mem, unmap, err := mapNamed(name, uint64(slots), uint64(slotSize), c.flags())
+	if err != nil {
+		return nil, err
+	}
+	return attach(mem, unmap, alerter, encode, decode, c)
+}
+
+// OpenReader maps the existing diode in the file at path for its reader,
+// with the geometry it was created with. Unlike Open, it never creates the
+// diode and it returns a Reader, which can not set values, so that a process
+// that only consumes a diode can attach to it without knowing its geometry
+// or how to encode its values. The error matches os.ErrNotExist if there is
+// no diode.
+func OpenReader(path string, alerter diodes.Alerter, decode DecodeFunc, opts ...ConfigOption) (*Reader, error) {
+	mem, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return attachReader(mem, unmap, alerter, decode, opts)
+}
+
+// OpenNamedReader maps the existing diode with the given name for its
+// reader, like OpenReader.
+func OpenNamedReader(name string, alerter diodes.Alerter, decode DecodeFunc, opts ...ConfigOption) (*Reader, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+
+	mem, unmap, err := mapExistingNamed(name)
+	if err != nil {
+		return nil, err
+	}
+	return attachReader(mem, unmap, alerter, decode, opts)
+}
+
+func attachReader(mem []byte, unmap func() error, alerter diodes.Alerter, decode DecodeFunc, opts []ConfigOption) (*Reader, error) {
+	d, err := attach(mem, unmap, alerter, nil, decode, newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{d: d}, nil
+}
+
+// Remove removes the named diode and the notifier of the same name, so that
+// the next process to open them creates new ones. Processes that have them
+// open keep using the old ones. It does nothing on Windows, where named
+// diodes and notifiers are removed once they are closed by every process.
+func Remove(name string) error {
+	if err := validName(name); err != nil {
+		return err
+	}
+	return remove(name)
+}
+
+func validGeometry(slots, slotSize int) error {
+	if slots <= 0 || slotSize <= 0 || uint64(slotSize) >= endOfStreamSize {
+		return fmt.Errorf("diodesshm: invalid geometry of %d slots of %d bytes", slots, slotSize)
+	}
+	return nil
+}
+
+func validName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("diodesshm: invalid name %q", name)
+	}
+	return nil
+}
+
+// create writes a new file with the given geometry and moves it into place
+// at once, so that the other process never maps a file that is only half
+// initialized. If the other process created the file in the meantime, its
+// file is used instead.
+func create(path string, slots, slotSize, flags uint64) error {
+	tmp := path + ".tmp" + strconv.Itoa(os.Getpid())
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	var header [headerSize]byte
+	binary.NativeEndian.PutUint64(header[magicOffset:], magic)
+	binary.NativeEndian.PutUint64(header[slotsOffset:], slots)
+	binary.NativeEndian.PutUint64(header[slotSizeOffset:], slotSize)
+	binary.NativeEndian.PutUint64(header[versionOffset:], version)
+	binary.NativeEndian.PutUint64(header[flagsOffset:], flags)
+	if _, err := f.Write(header[:]); err != nil {
+		return err
+	}
+	if err := f.Truncate(int64(headerSize + slots*stride(slotSize))); err != nil {
+		return err
+	}
+
+	if err := os.Link(tmp, path); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// attach validates the header of the mapped memory and returns the diode in
+// it. The memory is unmapped if it is not a valid diode.
+func attach(mem []byte, unmap func() error, alerter diodes.Alerter, encode EncodeFunc, decode DecodeFunc, c config) (*Diode, error) {
+	if len(mem) < headerSize {
+		unmap()
+		return nil, ErrCorrupt
+	}
+
+	h := Header{
+		Magic:    atomic.LoadUint64(word(mem, magicOffset)),
+		Version:  *word(mem, versionOffset),
+		Slots:    *word(mem, slotsOffset),
+		SlotSize: *word(mem, slotSizeOffset),
+		flags:    *word(mem, flagsOffset),
+	}
+	if err := h.check(uint64(len(mem))); err != nil {
+		unmap()
+		return nil, err
+	}
+
+	d := &Diode{
+		mem:      mem,
+		unmap:    unmap,
+		slots:    h.Slots,
+		slotSize: h.SlotSize,
+		stride:   stride(h.SlotSize),
+		encode:   encode,
+		decode:   decode,
+		alerter:  alerter,
+		config:   c,
+	}
+
+	// Checksums are a property of the diode rather than of the process
+	// that opened it.
+	d.checksums = h.Checksums()
+
+	if d.alerter == nil {
+		d.alerter = diodes.AlertFunc(func(int) {})
+	}
+	return d, nil
+}
+
+// Set writes the data into the next slot. Values that are larger than the
+// slots are dropped and counted by TooLarge. The end of the stream that the
+// Close of a diodes.Poller or diodes.Waiter sets is not encoded but written
+// as a marker that TryNext hands back as is. It must only be called by the
+// writer.
+func (d *Diode) Set(data diodes.GenericDataType) {
+	if data == diodes.EndOfStream() {
+		d.write(nil, endOfStreamSize)
+		return
+	}
+
+	payload := d.encode(data)
+	if uint64(len(payload)) > d.slotSize {
+		d.tooLarge.Add(1)
+		return
+	}
+
+	n := uint64(len(payload))
+	if d.checksums {
+		n |= uint64(crc32.Checksum(payload, castagnoli)) << 32
+	}
+	d.write(payload, n)
+}
+
+// write writes the payload with the length word n into the next slot.
+func (d *Diode) write(payload []byte, n uint64) {
+	writeIndex := word(d.mem, writeIndexOffset)
+	index := atomic.LoadUint64(writeIndex)
+	off := d.slot(index)
+
+	// Swap rather than store the odd seq, so that the copy below can not be
+	// reordered before it on weakly ordered processors.
+	atomic.SwapUint64(word(d.mem, off), 2*index+1)
+	atomic.StoreUint64(word(d.mem, off+8), n)
+	copy(d.mem[off+slotHeaderSize:], payload)
+	atomic.StoreUint64(word(d.mem, off), 2*index+2)
+
+	atomic.StoreUint64(writeIndex, index+1)
+
+	// Only a reader that read everything before this value can be waiting
+	// for it, so the reader is not notified while it is behind.
+	if d.notifier != nil && atomic.LoadUint64(word(d.mem, readIndexOffset)) >= index {
+		d.notifier.Notify()
+	}
+}
+
+// TryNext will attempt to read the next value. If there is no data
+// available, it will return (nil, false). It must only be called by the
+// reader.
+func (d *Diode) TryNext() (data diodes.GenericDataType, ok bool) {
+	buf, n, ok := d.next(d.buf, true)
+	if !ok {
+		return nil, false
+	}
+	if n < 0 {
+		return diodes.EndOfStream(), true
+	}
+	d.buf = buf
+	return d.decode(d.buf), true
+}
+
+// NextCtx returns the next value, waiting for the writer to set one until
+// the context is done, in which case it returns the error of the context,
+// wrapped with diodes.ErrTimeout once its deadline passed. It waits on the
+// notifier of the diode, or polls without one. It must only be called by the
+// reader.
+func (d *Diode) NextCtx(ctx context.Context) (diodes.GenericDataType, error) {
+	for {
+		if data, ok := d.TryNext(); ok {
+			return data, nil
+		}
+		if err := ctx.Err(); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w: %w", diodes.ErrTimeout, err)
+			}
+			return nil, err
+		}
+		d.wait(ctx)
+	}
+}
+
+// wait waits for the writer to set a value, for at most until the deadline
+// of the context.
+func (d *Diode) wait(ctx context.Context) {
+	timeout := pollInterval
+	if d.notifier != nil {
+		timeout = maxWait
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = min(timeout, time.Until(deadline))
+	}
+
+	if d.notifier == nil {
+		time.Sleep(timeout)
+		return
+	}
+	d.notifier.Wait(timeout)
+}
+
+// CopyNext copies the bytes of the next value into dst without decoding
+// them, so that a reader that handles the bytes itself neither allocates
+// nor hands out a slice the diode reuses, and returns their number. If
+// there is no data available, it returns (0, false). If dst is shorter than
+// the value, the value is not read and CopyNext returns its length and
+// false, so that the caller can retry with a larger buffer. It must only
+// be called by the reader, and the decode function may be nil if the
+// reader only calls CopyNext. The end of the stream is skipped.
+func (d *Diode) CopyNext(dst []byte) (n int, ok bool) {
+	for {
+		_, n, ok = d.next(dst, false)
+		if n >= 0 {
+			return n, ok
+		}
+	}
+}
+
+// next copies the next value into dst and moves the read index past it. If
+// grow is set, the value is appended to dst[:0] and the resulting slice is
+// returned. Otherwise it is only copied if it fits into dst. For the end of
+// the stream it returns a length of -1.
+func (d *Diode) next(dst []byte, grow bool) ([]byte, int, bool) {
+	readIndex := word(d.mem, readIndexOffset)
+	for {
+		index := atomic.LoadUint64(readIndex)
+		nextWrite := atomic.LoadUint64(word(d.mem, writeIndexOffset))
+		if index >= nextWrite {
+			return dst, 0, false
+		}
+
+		// The writer lapped the reader, skip to the oldest value that is
+		// still in the ring.
+		if nextWrite-index > d.slots {
+			d.drop(readIndex, nextWrite-d.slots-index)
+			continue
+		}
+
+		off := d.slot(index)
+		seq := atomic.LoadUint64(word(d.mem, off))
+		n := atomic.LoadUint64(word(d.mem, off+8))
+		var sum uint32
+		if d.checksums {
+			sum, n = uint32(n>>32), n&math.MaxUint32
+		}
+		if seq == 2*index+2 && n == endOfStreamSize {
+			if !atomic.CompareAndSwapUint64(word(d.mem, off), seq, seq) {
+				d.drop(readIndex, 1)
+				continue
+			}
+			atomic.StoreUint64(readIndex, index+1)
+			return dst, -1, true
+		}
+		if seq != 2*index+2 || n > d.slotSize {
+			// The writer is already filling the slot for a later lap.
+			d.drop(readIndex, 1)
+			continue
+		}
+
+		// The seq is checked again with a compare-and-swap rather than a
+		// load, so that the copy can not be reordered after it on weakly
+		// ordered processors.
+		value := d.mem[off+slotHeaderSize : off+slotHeaderSize+n]
+		if grow {
+			dst = append(dst[:0], value...)
+		} else if int(n) > len(dst) {
+			return dst, int(n), false
+		} else {
+			copy(dst, value)
+		}
+		if !atomic.CompareAndSwapUint64(word(d.mem, off), seq, seq) {
+			// The writer overwrote the value while it was copied.
+			d.drop(readIndex, 1)
+			continue
+		}
+
+		atomic.StoreUint64(readIndex, index+1)
+		if d.checksums && crc32.Checksum(dst[:n], castagnoli) != sum {
+			d.corrupt.Add(1)
+			continue
+		}
+		return dst, int(n), true
+	}
+}
+
+// drop moves the read index past the given number of values that were
+// overwritten before they were read.
+func (d *Diode) drop(readIndex *uint64, missed uint64) {
+	atomic.AddUint64(readIndex, missed)
+	d.dropped.Add(missed)
+	d.alerter.Alert(int(missed))
+}
+
+// Len returns the approximate number of unread values, bounded by Cap. It is
+// safe to call from either process.
+func (d *Diode) Len() int {
+	nextWrite := atomic.LoadUint64(word(d.mem, writeIndexOffset))
+	nextRead := atomic.LoadUint64(word(d.mem, readIndexOffset))
+	if nextWrite <= nextRead {
+		return 0
+	}
+	return int(min(nextWrite-nextRead, d.slots))
+}
+
+// Cap returns the number of slots of the diode.
+func (d *Diode) Cap() int {
+	return int(d.slots)
+}
+
+// Dropped returns the total number of values this reader noticed were
+// overwritten before they were read.
+func (d *Diode) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// Corrupt returns the total number of values this reader skipped because
+// their checksum did not match, see WithChecksums.
+func (d *Diode) Corrupt() uint64 {
+	return d.corrupt.Load()
+}
+
+// TooLarge returns the total number of values this writer dropped because
+// they did not fit into a slot.
+func (d *Diode) TooLarge() uint64 {
+	return d.tooLarge.Load()
+}
+
+// Close unmaps the diode and closes its file. The diode must not be used
+// afterwards.
+func (d *Diode) Close() error {
+	return d.unmap()
+}
+
+// slot returns the offset of the slot for the given index.
+func (d *Diode) slot(index uint64) uint64 {
+	return headerSize + (index%d.slots)*d.stride
+}
+
+// stride returns the size of a slot, rounded up so that every slot header
+// stays aligned for atomic access.
+func stride(slotSize uint64) uint64 {
+	return slotHeaderSize + (slotSize+7)&^7
+}
+
+// word returns the uint64 at the given offset, which must be aligned to 8
+// bytes.
+func word(mem []byte, off uint64) *uint64 {
+	return (*uint64)(unsafe.Pointer(&mem[off]))
+}
+
+// Reader is the reading side of a diode, see OpenReader. It only writes the
+// read index to the file.
+type Reader struct {
+	d *Diode
+}
+
+// TryNext will attempt to read the next value. See Diode.TryNext.
+func (r *Reader) TryNext() (diodes.GenericDataType, bool) {
+	return r.d.TryNext()
+}
+
+// NextCtx returns the next value, waiting for the writer to set one until
+// the context is done. See Diode.NextCtx.
+func (r *Reader) NextCtx(ctx context.Context) (diodes.GenericDataType, error) {
+	return r.d.NextCtx(ctx)
+}
+
+// CopyNext copies the bytes of the next value into dst. See Diode.CopyNext.
+func (r *Reader) CopyNext(dst []byte) (int, bool) {
+	return r.d.CopyNext(dst)
+}
+
+// Len returns the approximate number of unread values. See Diode.Len.
+func (r *Reader) Len() int {
+	return r.d.Len()
+}
+
+// Cap returns the number of slots of the diode.
+func (r *Reader) Cap() int {
+	return r.d.Cap()
+}
+
+// Dropped returns the total number of values this reader noticed were
+// overwritten before they were read.
+func (r *Reader) Dropped() uint64 {
+	return r.d.Dropped()
+}
+
+// Corrupt returns the total number of values this reader skipped because
+// their checksum did not match, see WithChecksums.
+func (r *Reader) Corrupt() uint64 {
+	return r.d.Corrupt()
+}
+
+// Close unmaps the diode and closes its file. The reader must not be used
+// afterwards.
+func (r *Reader) Close() error {
+	return r.d.Close()
+}
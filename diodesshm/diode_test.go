@@ -0,0 +1,424 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package diodesshm_test
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodesshm"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ diodes.Diode = (*diodesshm.Diode)(nil)
+
+var _ = Describe("Diode", func() {
+	var (
+		path   string
+		writer *diodesshm.Diode
+		reader *diodesshm.Diode
+		spy    *spyAlerter
+	)
+
+	encode := func(data diodes.GenericDataType) []byte {
+		Expect(data == diodes.EndOfStream()).To(BeFalse(), "the end of the stream was encoded")
+		return []byte(strconv.Itoa(*(*int)(data)))
+	}
+
+	decode := func(b []byte) diodes.GenericDataType {
+		i, err := strconv.Atoi(string(b))
+		Expect(err).NotTo(HaveOccurred())
+		return diodes.GenericDataType(&i)
+	}
+
+	open := func(alerter diodes.Alerter) *diodesshm.Diode {
+		d, err := diodesshm.Open(path, 4, 8, alerter, encode, decode)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(d.Close)
+		return d
+	}
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			writer.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	readAll := func() []int {
+		var got []int
+		for {
+			data, ok := reader.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "diode")
+		spy = &spyAlerter{}
+
+		// The writer and the reader each map the file, like two processes.
+		writer = open(nil)
+		reader = open(spy)
+	})
+
+	It("hands values from the writer to the reader", func() {
+		set(0, 3)
+
+		Expect(reader.Len()).To(Equal(3))
+		Expect(readAll()).To(Equal([]int{0, 1, 2}))
+		Expect(reader.Len()).To(BeZero())
+		Expect(spy.missed).To(BeEmpty())
+	})
+
+	It("drops the oldest values once the writer laps the reader", func() {
+		set(0, 10)
+
+		Expect(reader.Len()).To(Equal(4))
+		Expect(readAll()).To(Equal([]int{6, 7, 8, 9}))
+		Expect(reader.Dropped()).To(Equal(uint64(6)))
+		Expect(spy.missed).To(Equal([]int{6}))
+	})
+
+	It("drops values that do not fit into a slot", func() {
+		set(123456789, 123456790)
+		set(1, 2)
+
+		Expect(writer.TooLarge()).To(Equal(uint64(1)))
+		Expect(readAll()).To(Equal([]int{1}))
+	})
+
+	It("copies the bytes of the next value into the given buffer", func() {
+		set(12, 14)
+
+		buf := make([]byte, 8)
+		n, ok := reader.CopyNext(buf)
+		Expect(ok).To(BeTrue())
+		Expect(string(buf[:n])).To(Equal("12"))
+
+		n, ok = reader.CopyNext(buf)
+		Expect(ok).To(BeTrue())
+		Expect(string(buf[:n])).To(Equal("13"))
+
+		n, ok = reader.CopyNext(buf)
+		Expect(ok).To(BeFalse())
+		Expect(n).To(BeZero())
+	})
+
+	It("keeps the next value when the given buffer is too short", func() {
+		set(1234, 1235)
+
+		buf := make([]byte, 2)
+		n, ok := reader.CopyNext(buf)
+		Expect(ok).To(BeFalse())
+		Expect(n).To(Equal(4))
+
+		buf = make([]byte, n)
+		n, ok = reader.CopyNext(buf)
+		Expect(ok).To(BeTrue())
+		Expect(string(buf[:n])).To(Equal("1234"))
+		Expect(reader.Len()).To(BeZero())
+	})
+
+	It("does not allocate when copying into the given buffer", func() {
+		buf := make([]byte, 8)
+		value := 1
+		data := diodes.GenericDataType(&value)
+		raw, err := diodesshm.Open(path, 4, 8, nil, func(diodes.GenericDataType) []byte { return buf[:1] }, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer raw.Close()
+
+		Expect(testing.AllocsPerRun(100, func() {
+			raw.Set(data)
+			raw.CopyNext(buf)
+		})).To(BeZero())
+	})
+
+	Describe("the end of the stream", func() {
+		It("is handed to the reader without encoding it", func() {
+			set(1, 3)
+			diodes.NewPoller(writer).Close()
+
+			p := diodes.NewPoller(reader)
+			for i := 1; i < 3; i++ {
+				data, err := p.NextCtx(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*(*int)(data)).To(Equal(i))
+			}
+			_, err := p.NextCtx(context.Background())
+			Expect(err).To(MatchError(diodes.ErrClosed))
+		})
+
+		DescribeTable("ends draining",
+			func(drain func(diodes.Diode) ([]diodes.GenericDataType, error)) {
+				set(1, 3)
+
+				data, err := drain(writer)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(data).To(HaveLen(2))
+				Expect(*(*int)(data[1])).To(Equal(2))
+			},
+			Entry("a Poller", func(d diodes.Diode) ([]diodes.GenericDataType, error) {
+				return diodes.NewPoller(d).Drain(context.Background())
+			}),
+			Entry("a Waiter", func(d diodes.Diode) ([]diodes.GenericDataType, error) {
+				return diodes.NewWaiter(d).Drain(context.Background())
+			}),
+		)
+
+		It("is skipped when copying", func() {
+			set(1, 2)
+			writer.Set(diodes.EndOfStream())
+			set(2, 3)
+
+			buf := make([]byte, 8)
+			n, ok := reader.CopyNext(buf)
+			Expect(ok).To(BeTrue())
+			Expect(string(buf[:n])).To(Equal("1"))
+			n, ok = reader.CopyNext(buf)
+			Expect(ok).To(BeTrue())
+			Expect(string(buf[:n])).To(Equal("2"))
+		})
+	})
+
+	It("keeps unread values and the geometry when it is opened again", func() {
+		set(0, 3)
+		Expect(readAll()[:1]).To(Equal([]int{0}))
+
+		d, err := diodesshm.Open(path, 16, 64, nil, encode, decode)
+		Expect(err).NotTo(HaveOccurred())
+		defer d.Close()
+
+		Expect(d.Cap()).To(Equal(4))
+		Expect(d.Len()).To(BeZero())
+
+		set(3, 5)
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(3))
+	})
+
+	It("is safe for a concurrent writer and reader", func() {
+		path = filepath.Join(GinkgoT().TempDir(), "concurrent")
+		w, err := diodesshm.Open(path, 64, 8, nil, encode, decode)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+		r, err := diodesshm.Open(path, 64, 8, nil, encode, decode)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 10000; i++ {
+				j := i
+				w.Set(diodes.GenericDataType(&j))
+			}
+		}()
+		defer func() { <-done }()
+
+		last := -1
+		for last < 9999 {
+			data, ok := r.TryNext()
+			if !ok {
+				runtime.Gosched()
+				continue
+			}
+			Expect(*(*int)(data)).To(BeNumerically(">", last))
+			last = *(*int)(data)
+		}
+	})
+
+	It("is found by its name", func() {
+		name := "test-" + strconv.Itoa(os.Getpid())
+		DeferCleanup(diodesshm.Remove, name)
+
+		w, err := diodesshm.OpenNamed(name, 4, 8, nil, encode, decode)
+		Expect(err).NotTo(HaveOccurred())
+		defer w.Close()
+		r, err := diodesshm.OpenNamed(name, 16, 64, nil, encode, decode)
+		Expect(err).NotTo(HaveOccurred())
+		defer r.Close()
+
+		j := 1
+		w.Set(diodes.GenericDataType(&j))
+
+		Expect(r.Cap()).To(Equal(4))
+		data, ok := r.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(1))
+	})
+
+	It("rejects invalid names", func() {
+		_, err := diodesshm.OpenNamed("", 4, 8, nil, encode, decode)
+		Expect(err).To(HaveOccurred())
+		_, err = diodesshm.OpenNamed("../diode", 4, 8, nil, encode, decode)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects invalid geometries", func() {
+		_, err := diodesshm.Open(filepath.Join(GinkgoT().TempDir(), "invalid"), 0, 8, nil, encode, decode)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects files that are not a diode", func() {
+		other := filepath.Join(GinkgoT().TempDir(), "other")
+		Expect(os.WriteFile(other, make([]byte, 4096), 0o600)).To(Succeed())
+
+		_, err := diodesshm.Open(other, 4, 8, nil, encode, decode)
+		Expect(err).To(MatchError(diodesshm.ErrCorrupt))
+	})
+
+	DescribeTable("rejects files of another version of the format",
+		func(v uint64) {
+			other := filepath.Join(GinkgoT().TempDir(), "other")
+			d, err := diodesshm.Open(other, 4, 8, nil, encode, decode)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(d.Close()).To(Succeed())
+
+			f, err := os.OpenFile(other, os.O_RDWR, 0)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.WriteAt(binary.NativeEndian.AppendUint64(nil, v), diodesshm.VersionOffset)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			_, err = diodesshm.Open(other, 4, 8, nil, encode, decode)
+			Expect(err).To(MatchError(diodesshm.ErrCorrupt))
+			_, err = diodesshm.OpenReader(other, nil, decode)
+			Expect(err).To(MatchError(diodesshm.ErrCorrupt))
+		},
+		Entry("older", uint64(0)),
+		Entry("newer", uint64(2)),
+	)
+
+	Describe("WithChecksums()", func() {
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "checksums")
+		})
+
+		It("skips and counts values that were damaged in the file", func() {
+			w, err := diodesshm.Open(path, 4, 8, nil, encode, decode, diodesshm.WithChecksums())
+			Expect(err).NotTo(HaveOccurred())
+			defer w.Close()
+			for i := 10; i < 13; i++ {
+				j := i
+				w.Set(diodes.GenericDataType(&j))
+			}
+
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.WriteAt([]byte("9"), diodesshm.ValueOffset(4, 8, 1))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			r, err := diodesshm.OpenReader(path, nil, decode)
+			Expect(err).NotTo(HaveOccurred())
+			defer r.Close()
+
+			var got []int
+			for {
+				data, ok := r.TryNext()
+				if !ok {
+					break
+				}
+				got = append(got, *(*int)(data))
+			}
+			Expect(got).To(Equal([]int{10, 12}))
+			Expect(r.Corrupt()).To(Equal(uint64(1)))
+			Expect(r.Dropped()).To(BeZero())
+		})
+
+		It("keeps checksums for a process that opens the diode without them", func() {
+			w, err := diodesshm.Open(path, 4, 8, nil, encode, decode, diodesshm.WithChecksums())
+			Expect(err).NotTo(HaveOccurred())
+			defer w.Close()
+			r, err := diodesshm.Open(path, 4, 8, nil, encode, decode)
+			Expect(err).NotTo(HaveOccurred())
+			defer r.Close()
+
+			j := 7
+			w.Set(diodes.GenericDataType(&j))
+
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = f.WriteAt([]byte("8"), diodesshm.ValueOffset(4, 8, 0))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			_, ok := r.TryNext()
+			Expect(ok).To(BeFalse())
+			Expect(r.Corrupt()).To(Equal(uint64(1)))
+		})
+	})
+
+	Describe("OpenReader()", func() {
+		It("attaches to an existing diode with its geometry", func() {
+			set(0, 3)
+
+			r, err := diodesshm.OpenReader(path, nil, decode)
+			Expect(err).NotTo(HaveOccurred())
+			defer r.Close()
+
+			Expect(r.Cap()).To(Equal(4))
+			Expect(r.Len()).To(Equal(3))
+			data, ok := r.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(0))
+
+			buf := make([]byte, 8)
+			n, ok := r.CopyNext(buf)
+			Expect(ok).To(BeTrue())
+			Expect(string(buf[:n])).To(Equal("1"))
+			Expect(readAll()).To(Equal([]int{2}))
+		})
+
+		It("does not create a diode", func() {
+			missing := filepath.Join(GinkgoT().TempDir(), "missing")
+			_, err := diodesshm.OpenReader(missing, nil, decode)
+			Expect(err).To(MatchError(os.ErrNotExist))
+			Expect(missing).NotTo(BeAnExistingFile())
+
+			_, err = diodesshm.OpenNamedReader("missing-"+strconv.Itoa(os.Getpid()), nil, decode)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+
+		It("attaches to an existing diode by its name", func() {
+			name := "reader-" + strconv.Itoa(os.Getpid())
+			DeferCleanup(diodesshm.Remove, name)
+
+			w, err := diodesshm.OpenNamed(name, 4, 8, nil, encode, decode)
+			Expect(err).NotTo(HaveOccurred())
+			defer w.Close()
+			j := 1
+			w.Set(diodes.GenericDataType(&j))
+
+			r, err := diodesshm.OpenNamedReader(name, nil, decode)
+			Expect(err).NotTo(HaveOccurred())
+			defer r.Close()
+
+			data, ok := r.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(1))
+			Expect(r.Dropped()).To(BeZero())
+		})
+	})
+})
+
+type spyAlerter struct {
+	missed []int
+}
+
+func (s *spyAlerter) Alert(missed int) {
+	s.missed = append(s.missed, missed)
+}
@@ -0,0 +1,13 @@
+package diodesshm_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodesSHM(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DiodesSHM Suite")
+}
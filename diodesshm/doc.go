@@ -0,0 +1,8 @@
+// Package diodesshm provides a diode in a memory mapped file, so that a
+// writer process and a reader process on the same host can hand off values
+// with the drop semantics of the diodes, without the overhead of a socket.
+// Values are serialized with user-provided functions. Diodes are opened
+// either by the path of their file or by a name that every process can
+// derive on its own. Readers either poll the diode or block on a Notifier
+// that the writer wakes up. It is available on unix systems and on Windows.
+package diodesshm
@@ -0,0 +1,10 @@
+package diodesshm
+
+// VersionOffset is the offset of the version of the format in the header.
+const VersionOffset = versionOffset
+
+// ValueOffset returns the offset of the value in the slot for the given
+// index of a diode with the given geometry.
+func ValueOffset(slots, slotSize, index uint64) int64 {
+	return int64(headerSize + (index%slots)*stride(slotSize) + slotHeaderSize)
+}
@@ -0,0 +1,148 @@
+package diodesshm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+)
+
+// Header is the header of a diode as it is stored in its file.
+type Header struct {
+	Magic      uint64
+	Version    uint64
+	Slots      uint64
+	SlotSize   uint64
+	WriteIndex uint64
+	ReadIndex  uint64
+
+	flags uint64
+}
+
+// Checksums reports whether the diode stores the checksum of every value,
+// see WithChecksums.
+func (h Header) Checksums() bool {
+	return h.flags&flagChecksums != 0
+}
+
+// check validates the header of a file of the given size.
+func (h Header) check(size uint64) error {
+	if h.Magic != magic {
+		return ErrCorrupt
+	}
+	if h.Version != version {
+		return fmt.Errorf("%w: version %d of the format, want %d", ErrCorrupt, h.Version, version)
+	}
+	if h.Slots == 0 || h.SlotSize == 0 || h.SlotSize >= endOfStreamSize || h.flags&^flagChecksums != 0 || size != headerSize+h.Slots*stride(h.SlotSize) {
+		return ErrCorrupt
+	}
+	return nil
+}
+
+// Record is the slot of a diode for an index, as it is stored in its file.
+type Record struct {
+	// Index is the index the value was set for.
+	Index uint64
+
+	// Unread reports whether the reader did not read the value yet.
+	Unread bool
+
+	// Value is the value as it was encoded by the writer. It is reused for
+	// the next record and must be copied if it is retained.
+	Value []byte
+
+	// EndOfStream reports whether the slot holds the end of the stream that
+	// the Close of a diodes.Poller or diodes.Waiter set instead of a value.
+	EndOfStream bool
+
+	// Err is an error matching ErrCorrupt if the slot does not hold a
+	// complete value for the index, or one whose checksum does not match.
+	// Value still holds the value if only its checksum does not match.
+	Err error
+}
+
+// Inspect reads the diode in the file at path without mapping or changing
+// it, e.g. to look into the file a crashed process left behind, and returns
+// its header. It calls fn with the record of every index that is still in
+// the ring, from the oldest to the newest, unless fn is nil, and stops at
+// the first error fn returns. If the header is not valid, Inspect returns an
+// error matching ErrCorrupt along with the header as it was read.
+func Inspect(path string, fn func(Record) error) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+
+	var raw [headerSize]byte
+	if _, err := io.ReadFull(f, raw[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return Header{}, ErrCorrupt
+		}
+		return Header{}, err
+	}
+	h := Header{
+		Magic:      binary.NativeEndian.Uint64(raw[magicOffset:]),
+		Version:    binary.NativeEndian.Uint64(raw[versionOffset:]),
+		Slots:      binary.NativeEndian.Uint64(raw[slotsOffset:]),
+		SlotSize:   binary.NativeEndian.Uint64(raw[slotSizeOffset:]),
+		WriteIndex: binary.NativeEndian.Uint64(raw[writeIndexOffset:]),
+		ReadIndex:  binary.NativeEndian.Uint64(raw[readIndexOffset:]),
+		flags:      binary.NativeEndian.Uint64(raw[flagsOffset:]),
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return h, err
+	}
+	if err := h.check(uint64(info.Size())); err != nil || fn == nil {
+		return h, err
+	}
+
+	slot := make([]byte, stride(h.SlotSize))
+	for index := h.WriteIndex - min(h.WriteIndex, h.Slots); index < h.WriteIndex; index++ {
+		off := headerSize + (index%h.Slots)*uint64(len(slot))
+		if _, err := f.ReadAt(slot, int64(off)); err != nil {
+			return h, err
+		}
+		if err := fn(h.record(index, slot)); err != nil {
+			return h, err
+		}
+	}
+	return h, nil
+}
+
+// record returns the record of the index in the given slot.
+func (h Header) record(index uint64, slot []byte) Record {
+	r := Record{
+		Index:  index,
+		Unread: index >= h.ReadIndex,
+	}
+
+	seq := binary.NativeEndian.Uint64(slot)
+	n := binary.NativeEndian.Uint64(slot[8:])
+	var sum uint32
+	if h.Checksums() {
+		sum, n = uint32(n>>32), n&math.MaxUint32
+	}
+
+	switch {
+	case seq == 2*index+1:
+		r.Err = fmt.Errorf("%w: record %d was not completely written", ErrCorrupt, index)
+	case seq != 2*index+2:
+		r.Err = fmt.Errorf("%w: slot of record %d has the sequence number %d", ErrCorrupt, index, seq)
+	case n == endOfStreamSize:
+		r.EndOfStream = true
+	case n > h.SlotSize:
+		r.Err = fmt.Errorf("%w: record %d has a length of %d bytes", ErrCorrupt, index, n)
+	default:
+		r.Value = slot[slotHeaderSize : slotHeaderSize+n]
+		if h.Checksums() && crc32.Checksum(r.Value, castagnoli) != sum {
+			r.Err = fmt.Errorf("%w: checksum of record %d does not match", ErrCorrupt, index)
+		}
+	}
+	return r
+}
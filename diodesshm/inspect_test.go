@@ -0,0 +1,115 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package diodesshm_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodesshm"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Inspect", func() {
+	var path string
+
+	encode := func(data diodes.GenericDataType) []byte {
+		return []byte(strconv.Itoa(*(*int)(data)))
+	}
+
+	inspect := func() (diodesshm.Header, []diodesshm.Record) {
+		var records []diodesshm.Record
+		h, err := diodesshm.Inspect(path, func(r diodesshm.Record) error {
+			r.Value = append([]byte(nil), r.Value...)
+			records = append(records, r)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return h, records
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "diode")
+
+		d, err := diodesshm.Open(path, 4, 8, nil, encode, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer d.Close()
+		for i := 10; i < 16; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		_, ok := d.CopyNext(make([]byte, 8))
+		Expect(ok).To(BeTrue())
+	})
+
+	It("returns the header and the records that are still in the ring", func() {
+		h, records := inspect()
+		Expect(h.Slots).To(Equal(uint64(4)))
+		Expect(h.SlotSize).To(Equal(uint64(8)))
+		Expect(h.Version).To(Equal(uint64(1)))
+		Expect(h.Checksums()).To(BeFalse())
+		Expect(h.WriteIndex).To(Equal(uint64(6)))
+		Expect(h.ReadIndex).To(Equal(uint64(3)))
+
+		Expect(records).To(HaveLen(4))
+		for i, r := range records {
+			Expect(r.Index).To(Equal(uint64(i + 2)))
+			Expect(r.Unread).To(Equal(i > 0))
+			Expect(string(r.Value)).To(Equal(strconv.Itoa(12 + i)))
+			Expect(r.Err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("reports records that were not completely written", func() {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteAt(binary.NativeEndian.AppendUint64(nil, 2*5+1), diodesshm.ValueOffset(4, 8, 5)-16)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		_, records := inspect()
+		Expect(records[3].Err).To(MatchError(diodesshm.ErrCorrupt))
+		Expect(records[3].Err).To(MatchError(ContainSubstring("record 5 was not completely written")))
+		Expect(records[2].Err).NotTo(HaveOccurred())
+	})
+
+	It("reports the end of the stream", func() {
+		d, err := diodesshm.Open(path, 4, 8, nil, encode, nil)
+		Expect(err).NotTo(HaveOccurred())
+		diodes.NewPoller(d).Close()
+		Expect(d.Close()).To(Succeed())
+
+		_, records := inspect()
+		Expect(records[3].Index).To(Equal(uint64(6)))
+		Expect(records[3].EndOfStream).To(BeTrue())
+		Expect(records[3].Value).To(BeEmpty())
+		Expect(records[3].Err).NotTo(HaveOccurred())
+		Expect(records[2].EndOfStream).To(BeFalse())
+	})
+
+	It("stops at the first error of the function", func() {
+		stop := errors.New("stop")
+		calls := 0
+		_, err := diodesshm.Inspect(path, func(diodesshm.Record) error {
+			calls++
+			return stop
+		})
+		Expect(err).To(MatchError(stop))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("returns the header of files that are not a diode as it was read", func() {
+		other := filepath.Join(GinkgoT().TempDir(), "other")
+		Expect(os.WriteFile(other, make([]byte, 4096), 0o600)).To(Succeed())
+
+		h, err := diodesshm.Inspect(other, nil)
+		Expect(err).To(MatchError(diodesshm.ErrCorrupt))
+		Expect(h.Magic).To(BeZero())
+	})
+})
@@ -0,0 +1,80 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package diodesshm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// mapFile maps the file at path.
+func mapFile(path string) ([]byte, func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.Size() < headerSize {
+		f.Close()
+		return nil, nil, ErrCorrupt
+	}
+
+	mem, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("diodesshm: mapping file: %w", err)
+	}
+
+	return mem, func() error {
+		return errors.Join(syscall.Munmap(mem), f.Close())
+	}, nil
+}
+
+// mapNamed maps the file of the named diode, creating it if needed.
+func mapNamed(name string, slots, slotSize, flags uint64) ([]byte, func() error, error) {
+	path := namedPath(name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := create(path, slots, slotSize, flags); err != nil {
+			return nil, nil, err
+		}
+	}
+	return mapFile(path)
+}
+
+// mapExistingNamed maps the file of the named diode, which must exist.
+func mapExistingNamed(name string) ([]byte, func() error, error) {
+	return mapFile(namedPath(name))
+}
+
+// remove removes the file of the named diode and the file of the notifier
+// of the same name.
+func remove(name string) error {
+	path := namedPath(name)
+	return errors.Join(removeFile(path), removeFile(path+notifySuffix))
+}
+
+func removeFile(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// namedPath returns the path of the file of the named diode, in /dev/shm if
+// the system has one.
+func namedPath(name string) string {
+	dir := "/dev/shm"
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "go-diodes-"+name)
+}
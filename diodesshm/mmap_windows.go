@@ -0,0 +1,168 @@
+//go:build windows
+
+package diodesshm
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// initializing marks the header of a named diode while the process that
+// created it sets its geometry.
+const initializing = 1
+
+// attachTimeout is how long a process waits for the process that created a
+// named diode to set its geometry.
+const attachTimeout = time.Second
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procOpenFileMappingW = kernel32.NewProc("OpenFileMappingW")
+)
+
+// mapFile maps the file at path.
+func mapFile(path string) ([]byte, func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.Size() < headerSize {
+		f.Close()
+		return nil, nil, ErrCorrupt
+	}
+
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READWRITE, 0, 0, nil)
+	if err != nil {
+		f.Close()
+		return nil, nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+
+	mem, addr, err := mapView(h, uint64(info.Size()))
+	if err != nil {
+		syscall.CloseHandle(h)
+		f.Close()
+		return nil, nil, err
+	}
+
+	unmap := unmapper(h, addr)
+	return mem, func() error {
+		return errors.Join(unmap(), f.Close())
+	}, nil
+}
+
+// mapNamed maps the named file mapping of the diode, creating it if needed.
+// A new mapping is all zeros, so the process that claims its header first
+// sets the geometry, while every other process waits for the magic number
+// that marks the geometry as set.
+func mapNamed(name string, slots, slotSize, flags uint64) ([]byte, func() error, error) {
+	mappingName, err := syscall.UTF16PtrFromString(`Local\go-diodes-` + name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	size := headerSize + slots*stride(slotSize)
+	h, err := syscall.CreateFileMapping(syscall.InvalidHandle, nil, syscall.PAGE_READWRITE, uint32(size>>32), uint32(size), mappingName)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+
+	header, addr, err := mapView(h, headerSize)
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, nil, err
+	}
+
+	if atomic.CompareAndSwapUint64(word(header, magicOffset), 0, initializing) {
+		*word(header, slotsOffset) = slots
+		*word(header, slotSizeOffset) = slotSize
+		*word(header, versionOffset) = version
+		*word(header, flagsOffset) = flags
+		atomic.StoreUint64(word(header, magicOffset), magic)
+	}
+	syscall.UnmapViewOfFile(addr)
+
+	return mapHeader(h)
+}
+
+// mapExistingNamed maps the named file mapping of the diode, which must
+// exist.
+func mapExistingNamed(name string) ([]byte, func() error, error) {
+	mappingName, err := syscall.UTF16PtrFromString(`Local\go-diodes-` + name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, _, err := procOpenFileMappingW.Call(syscall.FILE_MAP_WRITE, 0, uintptr(unsafe.Pointer(mappingName)))
+	if h == 0 {
+		if errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+			return nil, nil, &os.PathError{Op: "OpenFileMapping", Path: name, Err: os.ErrNotExist}
+		}
+		return nil, nil, os.NewSyscallError("OpenFileMapping", err)
+	}
+	return mapHeader(syscall.Handle(h))
+}
+
+// mapHeader maps the file mapping with the geometry in its header. The
+// header is mapped on its own first, since a mapping that was created by
+// another process can have another geometry.
+func mapHeader(h syscall.Handle) ([]byte, func() error, error) {
+	header, addr, err := mapView(h, headerSize)
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, nil, err
+	}
+
+	// A header that is still not set after the timeout is rejected by
+	// attach.
+	deadline := time.Now().Add(attachTimeout)
+	for atomic.LoadUint64(word(header, magicOffset)) == initializing && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	size := headerSize + *word(header, slotsOffset)*stride(*word(header, slotSizeOffset))
+	syscall.UnmapViewOfFile(addr)
+
+	mem, addr, err := mapView(h, size)
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, nil, err
+	}
+	return mem, unmapper(h, addr), nil
+}
+
+// mapView maps size bytes of the file mapping.
+func mapView(h syscall.Handle, size uint64) ([]byte, uintptr, error) {
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_WRITE, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, 0, os.NewSyscallError("MapViewOfFile", err)
+	}
+
+	// Convert the address without a uintptr to unsafe.Pointer conversion,
+	// which vet can not tell apart from a misuse.
+	return unsafe.Slice(*(**byte)(unsafe.Pointer(&addr)), size), addr, nil
+}
+
+// unmapper returns a function that unmaps the view and closes the mapping.
+func unmapper(h syscall.Handle, addr uintptr) func() error {
+	return func() error {
+		return errors.Join(
+			os.NewSyscallError("UnmapViewOfFile", syscall.UnmapViewOfFile(addr)),
+			os.NewSyscallError("CloseHandle", syscall.CloseHandle(h)),
+		)
+	}
+}
+
+// remove does nothing, since a named file mapping is removed once every
+// process closed it.
+func remove(string) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package diodesshm
+
+import "time"
+
+// notifySuffix is appended to the name of a diode to name its notifier.
+const notifySuffix = ".notify"
+
+// Notifier wakes up the reader of a diode once the writer in another process
+// set a value, so that the reader can block in NextCtx instead of polling
+// TryNext. Notifications are coalesced, and Wait may return early, so the
+// reader always checks the diode again after Wait returns.
+type Notifier interface {
+	// Notify wakes up the reader if it waits, or makes its next Wait
+	// return right away otherwise. It never blocks.
+	Notify()
+
+	// Wait blocks until Notify is called or the timeout passed, and
+	// reports whether it was notified.
+	Wait(timeout time.Duration) bool
+
+	// Close releases the notifier. It must not be used afterwards.
+	Close() error
+}
+
+// OpenNotifier opens the notifier with the given name, creating it if no
+// process did so yet. The writer and the reader of a diode each open it with
+// the same name, usually the name of the diode, and pass it to WithNotifier.
+//
+// On Linux, the notifier is a futex in a file in /dev/shm next to the named
+// diodes, and on other unix systems it is a named pipe, see NewPipeNotifier.
+// Both are removed by Remove along with the diode of the same name. On
+// Windows, it is a named event that exists as long as any process has it
+// open.
+func OpenNotifier(name string) (Notifier, error) {
+	if err := validName(name); err != nil {
+		return nil, err
+	}
+	return openNotifier(name)
+}
@@ -0,0 +1,8 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package diodesshm
+
+// openNotifier opens the named pipe of the named notifier.
+func openNotifier(name string) (Notifier, error) {
+	return NewPipeNotifier(namedPath(name) + notifySuffix)
+}
@@ -0,0 +1,91 @@
+package diodesshm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	futexWait = 0
+	futexWake = 1
+)
+
+// futexNotifier is a Notifier that sets a flag in shared memory and wakes up
+// the reader with a futex while it waits for the flag to be set. Only
+// notifications that find the flag unset make a system call.
+type futexNotifier struct {
+	mem   []byte
+	unmap func() error
+}
+
+// openNotifier maps the file of the named notifier, creating it if needed.
+func openNotifier(name string) (Notifier, error) {
+	f, err := os.OpenFile(namedPath(name)+notifySuffix, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file is only extended, so that a process that opens it while
+	// another one waits on it does not clear the flag.
+	info, err := f.Stat()
+	if err == nil && info.Size() < 8 {
+		err = f.Truncate(8)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	mem, err := syscall.Mmap(int(f.Fd()), 0, 8, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("diodesshm: mapping file: %w", err)
+	}
+
+	return &futexNotifier{
+		mem: mem,
+		unmap: func() error {
+			return errors.Join(syscall.Munmap(mem), f.Close())
+		},
+	}, nil
+}
+
+// Notify sets the flag and wakes up the reader if the flag was unset.
+func (n *futexNotifier) Notify() {
+	if atomic.SwapUint32(n.flag(), 1) == 0 {
+		n.futex(futexWake, 1, nil)
+	}
+}
+
+// Wait unsets the flag, waiting for it to be set until the timeout passed.
+func (n *futexNotifier) Wait(timeout time.Duration) bool {
+	if atomic.SwapUint32(n.flag(), 0) == 1 {
+		return true
+	}
+
+	// The futex only waits while the flag is still unset, so a Notify
+	// since the swap above is not missed.
+	ts := syscall.NsecToTimespec(int64(timeout))
+	n.futex(futexWait, 0, &ts)
+	return atomic.SwapUint32(n.flag(), 0) == 1
+}
+
+// Close unmaps the notifier and closes its file.
+func (n *futexNotifier) Close() error {
+	return n.unmap()
+}
+
+func (n *futexNotifier) flag() *uint32 {
+	return (*uint32)(unsafe.Pointer(&n.mem[0]))
+}
+
+// futex invokes the futex system call on the flag. The futex is not private
+// to the process, since the flag is shared with the other process.
+func (n *futexNotifier) futex(op, val uintptr, ts *syscall.Timespec) {
+	syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(n.flag())), op, val, uintptr(unsafe.Pointer(ts)), 0, 0)
+}
@@ -0,0 +1,196 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package diodesshm_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodesshm"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// notifierTests are the specs every Notifier passes, given a function that
+// opens the writer's and the reader's notifier, like two processes would.
+func notifierTests(open func() (writer, reader diodesshm.Notifier)) {
+	var writer, reader diodesshm.Notifier
+
+	BeforeEach(func() {
+		writer, reader = open()
+		DeferCleanup(writer.Close)
+		DeferCleanup(reader.Close)
+	})
+
+	It("waits until the timeout passed without a notification", func() {
+		start := time.Now()
+		Expect(reader.Wait(20 * time.Millisecond)).To(BeFalse())
+		Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+	})
+
+	It("returns right away after a notification that coalesces the earlier ones", func() {
+		writer.Notify()
+		writer.Notify()
+
+		Expect(reader.Wait(time.Hour)).To(BeTrue())
+		Expect(reader.Wait(time.Millisecond)).To(BeFalse())
+	})
+
+	It("wakes up a waiting reader", func() {
+		woken := make(chan bool, 1)
+		go func() { woken <- reader.Wait(time.Hour) }()
+		Consistently(woken, 20*time.Millisecond).ShouldNot(Receive())
+
+		writer.Notify()
+		Eventually(woken).Should(Receive(BeTrue()))
+	})
+}
+
+var _ = Describe("OpenNotifier", func() {
+	notifierTests(func() (diodesshm.Notifier, diodesshm.Notifier) {
+		name := "notify-" + strconv.Itoa(os.Getpid())
+		DeferCleanup(diodesshm.Remove, name)
+
+		writer, err := diodesshm.OpenNotifier(name)
+		Expect(err).NotTo(HaveOccurred())
+		reader, err := diodesshm.OpenNotifier(name)
+		Expect(err).NotTo(HaveOccurred())
+		return writer, reader
+	})
+
+	It("rejects invalid names", func() {
+		_, err := diodesshm.OpenNotifier("../notify")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Diode WithNotifier()", func() {
+	var path string
+
+	encode := func(data diodes.GenericDataType) []byte {
+		return []byte(strconv.Itoa(*(*int)(data)))
+	}
+
+	decode := func(b []byte) diodes.GenericDataType {
+		i, err := strconv.Atoi(string(b))
+		Expect(err).NotTo(HaveOccurred())
+		return diodes.GenericDataType(&i)
+	}
+
+	open := func(n diodesshm.Notifier) *diodesshm.Diode {
+		d, err := diodesshm.Open(path, 4, 8, nil, encode, decode, diodesshm.WithNotifier(n))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(d.Close)
+		return d
+	}
+
+	set := func(d *diodesshm.Diode, i int) {
+		d.Set(diodes.GenericDataType(&i))
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "diode")
+	})
+
+	It("wakes up a reader that waits in NextCtx", func() {
+		name := "notify-" + strconv.Itoa(os.Getpid())
+		DeferCleanup(diodesshm.Remove, name)
+		wn, err := diodesshm.OpenNotifier(name)
+		Expect(err).NotTo(HaveOccurred())
+		defer wn.Close()
+		rn, err := diodesshm.OpenNotifier(name)
+		Expect(err).NotTo(HaveOccurred())
+		defer rn.Close()
+
+		writer := open(wn)
+		reader := open(rn)
+
+		datas := make(chan diodes.GenericDataType, 1)
+		go func() {
+			defer GinkgoRecover()
+			data, err := reader.NextCtx(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			datas <- data
+		}()
+		Consistently(datas, 20*time.Millisecond).ShouldNot(Receive())
+
+		start := time.Now()
+		set(writer, 7)
+
+		var data diodes.GenericDataType
+		Eventually(datas).Should(Receive(&data))
+		Expect(*(*int)(data)).To(Equal(7))
+		Expect(time.Since(start)).To(BeNumerically("<", 100*time.Millisecond))
+	})
+
+	It("only notifies the reader when it set a value on the empty diode", func() {
+		n := &countingNotifier{}
+		writer := open(n)
+		reader := open(nil)
+
+		set(writer, 1)
+		set(writer, 2)
+		Expect(n.notified.Load()).To(Equal(int32(1)))
+
+		_, ok := reader.TryNext()
+		Expect(ok).To(BeTrue())
+		set(writer, 3)
+		Expect(n.notified.Load()).To(Equal(int32(1)))
+
+		_, _ = reader.TryNext()
+		_, _ = reader.TryNext()
+		set(writer, 4)
+		Expect(n.notified.Load()).To(Equal(int32(2)))
+	})
+
+	It("returns the error of the context from NextCtx", func() {
+		reader := open(nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := reader.NextCtx(ctx)
+		Expect(err).To(MatchError(diodes.ErrTimeout))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		_, err = reader.NextCtx(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("polls in NextCtx without a notifier", func() {
+		writer := open(nil)
+		reader := open(nil)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			set(writer, 5)
+		}()
+
+		data, err := reader.NextCtx(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*(*int)(data)).To(Equal(5))
+	})
+})
+
+type countingNotifier struct {
+	notified atomic.Int32
+}
+
+func (n *countingNotifier) Notify() {
+	n.notified.Add(1)
+}
+
+func (n *countingNotifier) Wait(time.Duration) bool {
+	return false
+}
+
+func (n *countingNotifier) Close() error {
+	return nil
+}
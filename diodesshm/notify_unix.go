@@ -0,0 +1,81 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package diodesshm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// wake is the byte that is written to a pipe to wake up the reader.
+var wake = []byte{1}
+
+// pipeNotifier is a Notifier that writes a byte to a named pipe for every
+// notification. The reader reads every byte that is buffered at once.
+type pipeNotifier struct {
+	f    *os.File
+	conn syscall.RawConn
+	buf  [64]byte
+}
+
+// NewPipeNotifier opens the named pipe at path as a Notifier, creating it if
+// it does not exist. It is available on every unix system and needs nothing
+// but a path both processes can reach.
+func NewPipeNotifier(path string) (Notifier, error) {
+	if err := syscall.Mkfifo(path, 0o600); err != nil && !errors.Is(err, os.ErrExist) {
+		return nil, &os.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+
+	// The pipe is opened for both reading and writing, so that opening it
+	// does not wait for the other process, and is handed to os.NewFile in
+	// non-blocking mode, so that reads can time out on every system.
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	f := os.NewFile(uintptr(fd), path)
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Mode()&os.ModeNamedPipe == 0 {
+		f.Close()
+		return nil, fmt.Errorf("diodesshm: %s is not a named pipe", path)
+	}
+
+	conn, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &pipeNotifier{f: f, conn: conn}, nil
+}
+
+// Notify writes a byte to the pipe. If the pipe is full, the reader is woken
+// up by the bytes that are already in it, so the write is not retried.
+func (n *pipeNotifier) Notify() {
+	n.conn.Write(func(fd uintptr) bool {
+		syscall.Write(int(fd), wake)
+		return true
+	})
+}
+
+// Wait reads the bytes that were written to the pipe, waiting for the first
+// one until the timeout passed.
+func (n *pipeNotifier) Wait(timeout time.Duration) bool {
+	if err := n.f.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false
+	}
+	read, _ := n.f.Read(n.buf[:])
+	return read > 0
+}
+
+// Close closes the pipe.
+func (n *pipeNotifier) Close() error {
+	return n.f.Close()
+}
@@ -0,0 +1,33 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package diodesshm_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/go-diodes/diodesshm"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewPipeNotifier", func() {
+	notifierTests(func() (diodesshm.Notifier, diodesshm.Notifier) {
+		path := filepath.Join(GinkgoT().TempDir(), "notify")
+
+		writer, err := diodesshm.NewPipeNotifier(path)
+		Expect(err).NotTo(HaveOccurred())
+		reader, err := diodesshm.NewPipeNotifier(path)
+		Expect(err).NotTo(HaveOccurred())
+		return writer, reader
+	})
+
+	It("rejects files that are not a named pipe", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "other")
+		Expect(os.WriteFile(path, nil, 0o600)).To(Succeed())
+
+		_, err := diodesshm.NewPipeNotifier(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,52 @@
+//go:build windows
+
+package diodesshm
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	procCreateEventW = kernel32.NewProc("CreateEventW")
+	procSetEvent     = kernel32.NewProc("SetEvent")
+)
+
+// eventNotifier is a Notifier that signals a named auto-reset event, which
+// wakes up the reader and is reset once the reader's wait returns.
+type eventNotifier struct {
+	h syscall.Handle
+}
+
+// openNotifier opens the named event of the notifier, creating it if
+// needed.
+func openNotifier(name string) (Notifier, error) {
+	eventName, err := syscall.UTF16PtrFromString(`Local\go-diodes-` + name + notifySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	h, _, err := procCreateEventW.Call(0, 0, 0, uintptr(unsafe.Pointer(eventName)))
+	if h == 0 {
+		return nil, os.NewSyscallError("CreateEvent", err)
+	}
+	return &eventNotifier{h: syscall.Handle(h)}, nil
+}
+
+// Notify signals the event.
+func (n *eventNotifier) Notify() {
+	procSetEvent.Call(uintptr(n.h))
+}
+
+// Wait waits for the event to be signaled until the timeout passed.
+func (n *eventNotifier) Wait(timeout time.Duration) bool {
+	event, err := syscall.WaitForSingleObject(n.h, uint32(timeout.Milliseconds()))
+	return err == nil && event == syscall.WAIT_OBJECT_0
+}
+
+// Close closes the event.
+func (n *eventNotifier) Close() error {
+	return os.NewSyscallError("CloseHandle", syscall.CloseHandle(n.h))
+}
@@ -0,0 +1,195 @@
+// Package diodestest provides helpers for testing code that consumes diodes
+// without sleeps or timing assumptions: clocks that only advance when the
+// test says so, an in-memory diode that records its calls and helpers
+// that force a reader to be lapped.
+package diodestest
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// PollClock drives Pollers from the test. Pollers created with its Option
+// poll again only when the clock ticks.
+type PollClock struct {
+	ticker *diodes.PollTicker
+}
+
+// NewPollClock returns a new PollClock. It must be stopped via Stop once it
+// is no longer needed, which lets its pollers fall back to their polling
+// interval.
+func NewPollClock() *PollClock {
+	return &PollClock{
+		ticker: diodes.NewManualPollTicker(),
+	}
+}
+
+// Option returns the option that makes a Poller wait for the ticks of the
+// clock.
+func (c *PollClock) Option() diodes.PollerConfigOption {
+	return diodes.WithPollTicker(c.ticker)
+}
+
+// Tick wakes up the pollers that are waiting.
+func (c *PollClock) Tick() {
+	c.ticker.Tick()
+}
+
+// Waiting returns the number of pollers that are waiting for the next tick.
+func (c *PollClock) Waiting() int {
+	return c.ticker.Waiting()
+}
+
+// TickWhenWaiting waits until at least n pollers are waiting and then wakes
+// them up, so that none of them misses the tick.
+func (c *PollClock) TickWhenWaiting(n int) {
+	for c.ticker.Waiting() < n {
+		runtime.Gosched()
+	}
+	c.ticker.Tick()
+}
+
+// Stop stops the clock.
+func (c *PollClock) Stop() {
+	c.ticker.Stop()
+}
+
+// FakeClock is a diodes.Clock whose time only passes when the test advances
+// it. Pass it to WithPollingClock or WithWaiterClock so that timeouts and
+// polling intervals elapse without sleeping.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a timer of a FakeClock. It is waiting while it is active.
+type fakeTimer struct {
+	clock  *FakeClock
+	c      chan time.Time
+	at     time.Time
+	active bool
+}
+
+// NewFakeClock returns a new FakeClock that starts at the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now: now,
+	}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep blocks until the clock was advanced by at least d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.NewTimer(d).C()
+}
+
+// NewTimer returns a timer that fires once the clock was advanced by at
+// least d.
+func (c *FakeClock) NewTimer(d time.Duration) diodes.Timer {
+	t := &fakeTimer{
+		clock: c,
+		c:     make(chan time.Time, 1),
+	}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the clock forward by d and fires the timers that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	waiting := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			waiting = append(waiting, t)
+			continue
+		}
+		t.fire()
+	}
+	c.timers = waiting
+}
+
+// Waiting returns the number of timers that did not fire yet.
+func (c *FakeClock) Waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// AdvanceWhenWaiting waits until at least n timers are waiting and then
+// advances the clock by d, so that the timer of a reader that is about to
+// wait is not missed.
+func (c *FakeClock) AdvanceWhenWaiting(n int, d time.Duration) {
+	for c.Waiting() < n {
+		runtime.Gosched()
+	}
+	c.Advance(d)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	active := t.stop()
+	t.at = c.now.Add(d)
+	if d <= 0 {
+		t.fire()
+		return active
+	}
+
+	t.active = true
+	c.timers = append(c.timers, t)
+	return active
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return t.stop()
+}
+
+// stop removes the timer from the waiting timers and reports whether it was
+// waiting. The clock must be locked.
+func (t *fakeTimer) stop() bool {
+	if !t.active {
+		return false
+	}
+
+	t.active = false
+	timers := t.clock.timers
+	for i, w := range timers {
+		if w == t {
+			t.clock.timers = append(timers[:i], timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// fire sends the time of the timer unless an earlier tick was not received
+// yet, like a time.Timer. The clock must be locked.
+func (t *fakeTimer) fire() {
+	t.active = false
+	select {
+	case t.c <- t.at:
+	default:
+	}
+}
@@ -0,0 +1,99 @@
+package diodestest
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// Diode is an in-memory diodes.Diode that hands out its values in the order
+// they were set and records every call. Unlike a ring buffer it never drops
+// values on its own, drops are forced with Drop. It is safe for concurrent
+// use.
+type Diode struct {
+	mu       sync.Mutex
+	alerter  diodes.Alerter
+	queue    []diodes.GenericDataType
+	sets     []diodes.GenericDataType
+	tryNexts int
+	missed   int
+}
+
+// NewDiode returns a new Diode. The alerter is invoked by TryNext for drops
+// forced with Drop. A nil can be used to ignore alerts.
+func NewDiode(alerter diodes.Alerter) *Diode {
+	if alerter == nil {
+		alerter = diodes.AlertFunc(func(int) {})
+	}
+
+	return &Diode{
+		alerter: alerter,
+	}
+}
+
+// Set records the data and queues it.
+func (d *Diode) Set(data diodes.GenericDataType) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sets = append(d.sets, data)
+	d.queue = append(d.queue, data)
+}
+
+// TryNext records the call and returns the oldest queued value. If drops
+// were forced since the last read, the alerter is invoked first, the way a
+// lapped reader does. If there is no data available, it will return
+// (nil, false).
+func (d *Diode) TryNext() (diodes.GenericDataType, bool) {
+	d.mu.Lock()
+	d.tryNexts++
+	if len(d.queue) == 0 {
+		d.mu.Unlock()
+		return nil, false
+	}
+
+	data := d.queue[0]
+	d.queue = d.queue[1:]
+	missed := d.missed
+	d.missed = 0
+	d.mu.Unlock()
+
+	if missed > 0 {
+		d.alerter.Alert(missed)
+	}
+	return data, true
+}
+
+// Drop discards the n oldest queued values, as if writers had overwritten
+// them, and returns how many were discarded. The next read that returns
+// data alerts about them.
+func (d *Diode) Drop(n int) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n = min(n, len(d.queue))
+	d.queue = d.queue[n:]
+	d.missed += n
+	return n
+}
+
+// Len returns the number of queued values.
+func (d *Diode) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.queue)
+}
+
+// Sets returns the data of every call to Set, in order.
+func (d *Diode) Sets() []diodes.GenericDataType {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]diodes.GenericDataType(nil), d.sets...)
+}
+
+// TryNextCallCount returns how many times TryNext was called.
+func (d *Diode) TryNextCallCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.tryNexts
+}
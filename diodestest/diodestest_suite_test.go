@@ -0,0 +1,13 @@
+package diodestest_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestDiodestest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diodestest Suite")
+}
@@ -0,0 +1,170 @@
+package diodestest_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodesfakes"
+	"code.cloudfoundry.org/go-diodes/diodestest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ diodes.Diode = (*diodestest.Diode)(nil)
+
+var _ = Describe("PollClock", func() {
+	It("only lets the poller poll again when it ticks", func() {
+		clock := diodestest.NewPollClock()
+		defer clock.Stop()
+
+		d := diodestest.NewDiode(nil)
+		p := diodes.NewPoller(d, clock.Option(), diodes.WithPollingInterval(time.Millisecond))
+
+		done := make(chan int)
+		go func() {
+			done <- diodestest.ToInt(p.Next())
+		}()
+
+		clock.TickWhenWaiting(1)
+		clock.TickWhenWaiting(1)
+		Eventually(clock.Waiting).Should(Equal(1))
+		calls := d.TryNextCallCount()
+		Expect(calls).To(Equal(3))
+
+		p.Set(diodestest.Int(42))
+		Expect(d.TryNextCallCount()).To(Equal(calls))
+
+		clock.TickWhenWaiting(1)
+		Eventually(done).Should(Receive(Equal(42)))
+		Expect(d.TryNextCallCount()).To(Equal(calls + 1))
+	})
+})
+
+var _ diodes.Clock = (*diodestest.FakeClock)(nil)
+
+var _ = Describe("FakeClock", func() {
+	var clock *diodestest.FakeClock
+
+	BeforeEach(func() {
+		clock = diodestest.NewFakeClock(time.Unix(0, 0))
+	})
+
+	It("fires timers once it was advanced past them", func() {
+		t := clock.NewTimer(time.Minute)
+		Expect(clock.Waiting()).To(Equal(1))
+
+		clock.Advance(59 * time.Second)
+		Consistently(t.C()).ShouldNot(Receive())
+
+		clock.Advance(time.Second)
+		Eventually(t.C()).Should(Receive(Equal(time.Unix(60, 0))))
+		Expect(clock.Waiting()).To(BeZero())
+		Expect(clock.Now()).To(Equal(time.Unix(60, 0)))
+	})
+
+	It("does not fire timers that were stopped", func() {
+		t := clock.NewTimer(time.Minute)
+		Expect(t.Stop()).To(BeTrue())
+		Expect(t.Stop()).To(BeFalse())
+
+		clock.Advance(time.Hour)
+		Consistently(t.C()).ShouldNot(Receive())
+
+		Expect(t.Reset(time.Minute)).To(BeFalse())
+		clock.Advance(time.Minute)
+		Eventually(t.C()).Should(Receive())
+	})
+
+	It("lets the poller sleep for its interval", func() {
+		d := diodestest.NewDiode(nil)
+		p := diodes.NewPoller(d, diodes.WithPollingClock(clock), diodes.WithPollingInterval(time.Hour))
+
+		done := make(chan int)
+		go func() {
+			done <- diodestest.ToInt(p.Next())
+		}()
+
+		clock.AdvanceWhenWaiting(1, time.Minute)
+		p.Set(diodestest.Int(42))
+		Consistently(done).ShouldNot(Receive())
+
+		clock.AdvanceWhenWaiting(1, time.Hour)
+		Eventually(done).Should(Receive(Equal(42)))
+	})
+
+	It("times out the waiter", func() {
+		w := diodes.NewWaiter(diodestest.NewDiode(nil), diodes.WithWaiterClock(clock))
+
+		done := make(chan bool)
+		go func() {
+			_, ok := w.NextWithTimeout(time.Hour)
+			done <- ok
+		}()
+
+		clock.AdvanceWhenWaiting(1, time.Minute)
+		Consistently(done).ShouldNot(Receive())
+
+		clock.AdvanceWhenWaiting(1, time.Hour)
+		Eventually(done).Should(Receive(BeFalse()))
+	})
+})
+
+var _ = Describe("Diode", func() {
+	var (
+		alerter *diodesfakes.FakeAlerter
+		d       *diodestest.Diode
+	)
+
+	BeforeEach(func() {
+		alerter = &diodesfakes.FakeAlerter{}
+		d = diodestest.NewDiode(alerter)
+	})
+
+	It("records the calls and hands out the values in order", func() {
+		diodestest.SetInts(d, 0, 3)
+		Expect(d.Sets()).To(HaveLen(3))
+		Expect(d.Len()).To(Equal(3))
+
+		for i := 0; i < 3; i++ {
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(diodestest.ToInt(data)).To(Equal(i))
+		}
+
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(d.TryNextCallCount()).To(Equal(4))
+		Expect(d.Sets()).To(HaveLen(3))
+	})
+
+	It("alerts about forced drops on the next read", func() {
+		diodestest.SetInts(d, 0, 5)
+		Expect(d.Drop(2)).To(Equal(2))
+		Expect(alerter.AlertCallCount()).To(BeZero())
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(diodestest.ToInt(data)).To(Equal(2))
+		Expect(alerter.AlertCallCount()).To(Equal(1))
+		Expect(alerter.AlertArgsForCall(0)).To(Equal(2))
+
+		Expect(d.Drop(10)).To(Equal(2))
+	})
+})
+
+var _ = Describe("Lap", func() {
+	It("laps the reader of a ring buffer", func() {
+		alerter := &diodesfakes.FakeAlerter{}
+		d := diodes.NewManyToOne(4, alerter)
+		diodestest.Lap(d, 4, 2)
+
+		for i := 8; i < 12; i++ {
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(diodestest.ToInt(data)).To(Equal(i))
+		}
+		Expect(alerter.AlertCallCount()).To(Equal(1))
+		Expect(alerter.AlertArgsForCall(0)).To(Equal(8))
+	})
+})
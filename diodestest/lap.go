@@ -0,0 +1,29 @@
+package diodestest
+
+import "code.cloudfoundry.org/go-diodes"
+
+// Int returns i as data for a diode.
+func Int(i int) diodes.GenericDataType {
+	return diodes.GenericDataType(&i)
+}
+
+// ToInt returns the int of data created by Int.
+func ToInt(data diodes.GenericDataType) int {
+	return *(*int)(data)
+}
+
+// SetInts sets the ints from from up to but not including to on w.
+func SetInts(w diodes.Writer, from, to int) {
+	for i := from; i < to; i++ {
+		w.Set(Int(i))
+	}
+}
+
+// Lap sets enough values on a ring buffer diode with size slots, that no
+// value has been read from yet, for the writer to lap its reader the given
+// number of times. The values are the ints from 0 up to (laps+1)*size. The
+// next read drops the first laps*size of them and the reader then reads the
+// remaining size values.
+func Lap(w diodes.Writer, size, laps int) {
+	SetInts(w, 0, (laps+1)*size)
+}
@@ -0,0 +1,137 @@
+package diodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Closer is an access layer that can mark the end of its stream and report
+// once its reader has reached it. It is satisfied by Poller and Waiter.
+type Closer interface {
+	Close()
+	Closed() bool
+}
+
+// DrainGroup shuts down a pipeline of diodes in dependency order. Each stage
+// is closed only once every stage it reads from has been drained, so data
+// that is still flowing from sources is not cut off by closing a sink too
+// early. It is not thread safe.
+type DrainGroup struct {
+	interval time.Duration
+	stages   []*drainStage
+	byName   map[string]*drainStage
+}
+
+type drainStage struct {
+	name  string
+	c     Closer
+	after []string
+}
+
+// DrainGroupConfigOption can be used to setup the drain group.
+type DrainGroupConfigOption func(*DrainGroup)
+
+// WithDrainInterval sets the interval at which a closed stage is checked for
+// having been drained. The default is 10ms.
+func WithDrainInterval(interval time.Duration) DrainGroupConfigOption {
+	return DrainGroupConfigOption(func(g *DrainGroup) {
+		g.interval = interval
+	})
+}
+
+// NewDrainGroup returns a new, empty DrainGroup.
+func NewDrainGroup(opts ...DrainGroupConfigOption) *DrainGroup {
+	g := &DrainGroup{
+		interval: 10 * time.Millisecond,
+		byName:   make(map[string]*drainStage),
+	}
+
+	for _, o := range opts {
+		o(g)
+	}
+
+	return g
+}
+
+// Add adds a stage to the group. The stage is closed after all of the stages
+// named in after (the stages that write into it) have been drained. Stages
+// without dependencies are sources and are closed first.
+func (g *DrainGroup) Add(name string, c Closer, after ...string) {
+	s := &drainStage{
+		name:  name,
+		c:     c,
+		after: after,
+	}
+
+	g.stages = append(g.stages, s)
+	g.byName[name] = s
+}
+
+// Drain closes every stage in dependency order and waits for each one to be
+// drained before closing the stages that depend on it. It returns an error
+// if the dependencies can not be ordered or if the context is done before
+// every stage was drained. The error matches ErrTimeout if the context's
+// deadline passed. Stages that are independent of each other are closed in
+// the order they were added.
+func (g *DrainGroup) Drain(ctx context.Context) error {
+	order, err := g.order()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range order {
+		s.c.Close()
+
+		for !s.c.Closed() {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("draining %q: %w", s.name, contextErr(ctx))
+			case <-time.After(g.interval):
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *DrainGroup) order() ([]*drainStage, error) {
+	for _, s := range g.stages {
+		for _, dep := range s.after {
+			if _, ok := g.byName[dep]; !ok {
+				return nil, fmt.Errorf("stage %q depends on unknown stage %q", s.name, dep)
+			}
+		}
+	}
+
+	order := make([]*drainStage, 0, len(g.stages))
+	done := make(map[string]bool, len(g.stages))
+	for len(order) < len(g.stages) {
+		progress := false
+		for _, s := range g.stages {
+			if done[s.name] || !dependenciesDone(s, done) {
+				continue
+			}
+
+			done[s.name] = true
+			order = append(order, s)
+			progress = true
+		}
+
+		if !progress {
+			return nil, errors.New("stages have a dependency cycle")
+		}
+	}
+
+	return order, nil
+}
+
+func dependenciesDone(s *drainStage, done map[string]bool) bool {
+	for _, dep := range s.after {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package diodes_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DrainGroup", func() {
+	var g *diodes.DrainGroup
+
+	BeforeEach(func() {
+		g = diodes.NewDrainGroup()
+	})
+
+	It("drains sources before sinks", func() {
+		source := diodes.NewWaiter(diodes.NewManyToOne(100, nil))
+		sink := diodes.NewWaiter(diodes.NewManyToOne(100, nil))
+
+		go func() {
+			for {
+				data := source.Next()
+				if data == nil {
+					return
+				}
+				sink.Set(data)
+			}
+		}()
+
+		var (
+			mu       sync.Mutex
+			received []int
+		)
+		go func() {
+			for {
+				data := sink.Next()
+				if data == nil {
+					return
+				}
+				mu.Lock()
+				received = append(received, *(*int)(data))
+				mu.Unlock()
+			}
+		}()
+
+		for i := 0; i < 50; i++ {
+			j := i
+			source.Set(diodes.GenericDataType(&j))
+		}
+
+		g.Add("sink", sink, "source")
+		g.Add("source", source)
+		Expect(g.Drain(context.Background())).To(Succeed())
+
+		Expect(source.Closed()).To(BeTrue())
+		Expect(sink.Closed()).To(BeTrue())
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(received).To(HaveLen(50))
+	})
+
+	It("returns an error for an unknown dependency", func() {
+		g.Add("sink", &spyCloser{}, "source")
+		Expect(g.Drain(context.Background())).To(MatchError(ContainSubstring(`unknown stage "source"`)))
+	})
+
+	It("returns an error for a dependency cycle", func() {
+		g.Add("a", &spyCloser{}, "b")
+		g.Add("b", &spyCloser{}, "a")
+		Expect(g.Drain(context.Background())).To(MatchError(ContainSubstring("cycle")))
+	})
+
+	It("returns the context error if a stage is not drained in time", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		c := &spyCloser{}
+		g.Add("stuck", c)
+
+		err := g.Drain(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(err).To(MatchError(ContainSubstring(`"stuck"`)))
+		Expect(c.closeCalled).To(BeTrue())
+		Expect(errors.Is(err, diodes.ErrTimeout)).To(BeFalse())
+	})
+
+	It("returns ErrTimeout if the deadline passes", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		g.Add("stuck", &spyCloser{})
+
+		err := g.Drain(ctx)
+		Expect(err).To(MatchError(diodes.ErrTimeout))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
+
+type spyCloser struct {
+	closeCalled bool
+}
+
+func (s *spyCloser) Close() {
+	s.closeCalled = true
+}
+
+func (s *spyCloser) Closed() bool {
+	return false
+}
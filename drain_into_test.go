@@ -0,0 +1,104 @@
+package diodes_test
+
+import (
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DrainInto", func() {
+	type drainer interface {
+		Set(diodes.GenericDataType)
+		DrainInto([]diodes.GenericDataType) int
+	}
+
+	// readerDrainer sets values on a OneToMany diode and drains its reader.
+	type readerDrainer struct {
+		*diodes.OneToMany
+		*diodes.OneToManyReader
+	}
+
+	value := func(i int) diodes.GenericDataType {
+		return diodes.GenericDataType(&i)
+	}
+
+	ints := func(data []diodes.GenericDataType) []int {
+		got := make([]int, len(data))
+		for i, d := range data {
+			got[i] = *(*int)(d)
+		}
+		return got
+	}
+
+	DescribeTable("reads the available values until dst is full",
+		func(newDiode func() drainer) {
+			d := newDiode()
+			for i := 0; i < 3; i++ {
+				d.Set(value(i))
+			}
+
+			dst := make([]diodes.GenericDataType, 2)
+			Expect(d.DrainInto(dst)).To(Equal(2))
+			Expect(ints(dst)).To(Equal([]int{0, 1}))
+
+			Expect(d.DrainInto(dst)).To(Equal(1))
+			Expect(ints(dst[:1])).To(Equal([]int{2}))
+			Expect(d.DrainInto(dst)).To(BeZero())
+
+			d.Set(value(3))
+			Expect(testing.AllocsPerRun(10, func() {
+				d.DrainInto(dst)
+			})).To(BeZero())
+		},
+		Entry("ManyToMany", func() drainer { return diodes.NewManyToMany(4, nil) }),
+		Entry("OneToManyReader", func() drainer {
+			d := diodes.NewOneToMany(4)
+			return readerDrainer{d, d.NewReader(nil)}
+		}),
+		Entry("SPSC", func() drainer { return diodes.NewSPSC(4, nil) }),
+	)
+
+	type typedDrainer interface {
+		Set(int)
+		DrainInto([]int) int
+	}
+
+	DescribeTable("reads the available values of a typed diode until dst is full",
+		func(newDiode func() typedDrainer) {
+			d := newDiode()
+			for i := 0; i < 3; i++ {
+				d.Set(i)
+			}
+
+			dst := make([]int, 2)
+			Expect(d.DrainInto(dst)).To(Equal(2))
+			Expect(dst).To(Equal([]int{0, 1}))
+
+			Expect(d.DrainInto(dst)).To(Equal(1))
+			Expect(dst).To(Equal([]int{2, 1}))
+			Expect(d.DrainInto(dst)).To(BeZero())
+
+			Expect(testing.AllocsPerRun(10, func() {
+				d.DrainInto(dst)
+			})).To(BeZero())
+		},
+		Entry("OneToOneT", func() typedDrainer { return diodes.NewOneToOneT[int](4, nil) }),
+		Entry("ManyToOneT", func() typedDrainer { return diodes.NewManyToOneT[int](4, nil) }),
+		Entry("PollerT", func() typedDrainer { return diodes.NewPollerT[int](diodes.NewOneToOneT[int](4, nil)) }),
+		Entry("WaiterT", func() typedDrainer { return diodes.NewWaiterT[int](diodes.NewManyToOneT[int](4, nil)) }),
+	)
+
+	It("stops at the end of the stream of a typed Poller", func() {
+		p := diodes.NewPollerT[int](diodes.NewOneToOneT[int](4, nil))
+		p.Set(1)
+		p.Close()
+
+		dst := make([]int, 4)
+		Expect(p.DrainInto(dst)).To(Equal(1))
+		Expect(p.DrainInto(dst)).To(BeZero())
+		Expect(p.Closed()).To(BeTrue())
+	})
+})
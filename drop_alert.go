@@ -0,0 +1,132 @@
+package diodes
+
+import "time"
+
+// DropReason is the reason why values were dropped.
+type DropReason int
+
+const (
+	// DropLapped means the writer overwrote the values before the reader
+	// read them.
+	DropLapped DropReason = iota
+
+	// DropPolicy means a write policy discarded the values before they were
+	// stored, e.g. WithDropNewest or WithSampling on a full diode.
+	DropPolicy
+
+	// DropExpired means the reader skipped the values because they were
+	// older than the maximum age of WithMaxAge.
+	DropExpired
+
+	// DropLate means the reader skipped the values because their writers
+	// did not store them within the timeout of WithMissingTimeout.
+	DropLate
+)
+
+// String returns the name of the reason, e.g. for a metric label.
+func (r DropReason) String() string {
+	switch r {
+	case DropLapped:
+		return "lapped"
+	case DropPolicy:
+		return "policy"
+	case DropExpired:
+		return "expired"
+	case DropLate:
+		return "late"
+	default:
+		return "unknown"
+	}
+}
+
+// DropAlert describes values that were dropped together.
+type DropAlert struct {
+	// Dropped is the number of values that were dropped.
+	Dropped int
+
+	// FromSeq and ToSeq are the sequence numbers of the dropped values, from
+	// FromSeq up to but not including ToSeq. Values a write policy discarded
+	// never got a sequence number, so both are 0 for DropPolicy.
+	FromSeq, ToSeq uint64
+
+	// Reason is why the values were dropped.
+	Reason DropReason
+
+	// At is the time the reader noticed the drop.
+	At time.Time
+}
+
+// DropAlerter is an Alerter that is told why and where values were dropped.
+// If the alerter given to NewOneToOne, NewManyToOne, NewManyToMany, NewSPSC
+// or OneToMany.NewReader implements DropAlerter, AlertDrop is invoked
+// instead of Alert. Unlike Alert, it is also invoked for every value that
+// expired (see WithMaxAge), with the reason DropExpired.
+type DropAlerter interface {
+	AlertDrop(a DropAlert)
+}
+
+// DropAlertFunc type is an adapter to allow the use of ordinary functions as
+// DropAlerters. It is an Alerter as well, so it can be passed to the
+// constructors of the diodes.
+type DropAlertFunc func(a DropAlert)
+
+// AlertDrop calls f(a)
+func (f DropAlertFunc) AlertDrop(a DropAlert) {
+	f(a)
+}
+
+// Alert calls f with an alert of missed lapped values whose sequence numbers
+// are unknown, so that the function can be used by the wrappers that are
+// only given an Alerter.
+func (f DropAlertFunc) Alert(missed int) {
+	f(DropAlert{
+		Dropped: missed,
+		Reason:  DropLapped,
+		At:      time.Now(),
+	})
+}
+
+// dropAlerter returns the DropAlerter of the given alerter, which adapts a
+// plain Alerter. A plain Alerter is not told about expired values, as
+// before DropAlerter existed.
+func dropAlerter(a Alerter) DropAlerter {
+	if a == nil {
+		return DropAlertFunc(func(DropAlert) {})
+	}
+	if da, ok := a.(DropAlerter); ok {
+		return da
+	}
+	return alerterAdapter{a}
+}
+
+type alerterAdapter struct {
+	Alerter
+}
+
+func (a alerterAdapter) AlertDrop(d DropAlert) {
+	if d.Reason != DropExpired {
+		a.Alert(d.Dropped)
+	}
+}
+
+// newDropAlert returns the alert of the values from up to but not including
+// to that were dropped for the given reason.
+func newDropAlert(reason DropReason, from, to uint64) DropAlert {
+	return DropAlert{
+		Dropped: int(to - from),
+		FromSeq: from,
+		ToSeq:   to,
+		Reason:  reason,
+		At:      time.Now(),
+	}
+}
+
+// discardedAlert returns the alert of n values that a write policy
+// discarded.
+func discardedAlert(n uint64) DropAlert {
+	return DropAlert{
+		Dropped: int(n),
+		Reason:  DropPolicy,
+		At:      time.Now(),
+	}
+}
@@ -0,0 +1,150 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DropAlerter", func() {
+	var (
+		alerts []diodes.DropAlert
+		a      diodes.DropAlertFunc
+	)
+
+	set := func(d diodes.Diode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	drain := func(d diodes.Reader) {
+		for {
+			if _, ok := d.TryNext(); !ok {
+				return
+			}
+		}
+	}
+
+	BeforeEach(func() {
+		alerts = nil
+		a = diodes.DropAlertFunc(func(da diodes.DropAlert) {
+			alerts = append(alerts, da)
+		})
+	})
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewOneToOne(size, a, opts...)
+		}),
+		Entry("ManyToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToOne(size, a, opts...)
+		}),
+		Entry("ManyToMany", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToMany(size, a, opts...)
+		}),
+	}
+
+	DescribeTable("reports the sequence numbers of lapped values",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) diodes.Diode) {
+			d := newDiode(4, a)
+			set(d, 0, 6)
+			drain(d)
+
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].Dropped).To(Equal(4))
+			Expect(alerts[0].FromSeq).To(Equal(uint64(0)))
+			Expect(alerts[0].ToSeq).To(Equal(uint64(4)))
+			Expect(alerts[0].Reason).To(Equal(diodes.DropLapped))
+			Expect(alerts[0].At).To(BeTemporally("~", time.Now(), time.Second))
+		},
+		entries,
+	)
+
+	DescribeTable("reports values a write policy discarded",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) diodes.Diode) {
+			d := newDiode(4, a, diodes.WithDropNewest())
+			set(d, 0, 6)
+			drain(d)
+
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].Dropped).To(Equal(2))
+			Expect(alerts[0].Reason).To(Equal(diodes.DropPolicy))
+		},
+		entries,
+	)
+
+	DescribeTable("reports every value that expired",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) diodes.Diode) {
+			d := newDiode(8, a, diodes.WithMaxAge(20*time.Millisecond))
+			set(d, 0, 2)
+			time.Sleep(30 * time.Millisecond)
+			set(d, 2, 3)
+			drain(d)
+
+			Expect(alerts).To(HaveLen(2))
+			for i, da := range alerts {
+				Expect(da.Dropped).To(Equal(1))
+				Expect(da.FromSeq).To(Equal(uint64(i)))
+				Expect(da.ToSeq).To(Equal(uint64(i + 1)))
+				Expect(da.Reason).To(Equal(diodes.DropExpired))
+			}
+		},
+		entries,
+	)
+
+	DescribeTable("does not tell plain alerters about expired values",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) diodes.Diode) {
+			var missed []int
+			d := newDiode(8, diodes.AlertFunc(func(n int) {
+				missed = append(missed, n)
+			}), diodes.WithMaxAge(20*time.Millisecond))
+			set(d, 0, 2)
+			time.Sleep(30 * time.Millisecond)
+			set(d, 2, 3)
+			drain(d)
+
+			Expect(missed).To(BeEmpty())
+		},
+		entries,
+	)
+
+	It("reports the lapped values of a OneToMany reader", func() {
+		d := diodes.NewOneToMany(4)
+		r := d.NewReader(a)
+		for i := 0; i < 6; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		for {
+			if _, ok := r.TryNext(); !ok {
+				break
+			}
+		}
+
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0].FromSeq).To(Equal(uint64(0)))
+		Expect(alerts[0].ToSeq).To(Equal(uint64(4)))
+	})
+
+	It("reports the values lost while the reader was paused", func() {
+		d := diodes.NewOneToOne(4, a)
+		c := d.Cursor()
+		set(d, 0, 6)
+
+		Expect(d.Restore(c)).To(Equal(uint64(2)))
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0].FromSeq).To(Equal(uint64(0)))
+		Expect(alerts[0].ToSeq).To(Equal(uint64(2)))
+	})
+
+	It("names the reasons", func() {
+		Expect(diodes.DropLapped.String()).To(Equal("lapped"))
+		Expect(diodes.DropPolicy.String()).To(Equal("policy"))
+		Expect(diodes.DropExpired.String()).To(Equal("expired"))
+	})
+})
@@ -0,0 +1,122 @@
+package diodes_test
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithDropNewest", func() {
+	type dropDiode interface {
+		diodes.Diode
+		Dropped() uint64
+	}
+
+	set := func(d diodes.Diode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) dropDiode {
+			return diodes.NewOneToOne(size, a, opts...)
+		}),
+		Entry("ManyToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) dropDiode {
+			return diodes.NewManyToOne(size, a, opts...)
+		}),
+		Entry("ManyToMany", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) dropDiode {
+			return diodes.NewManyToMany(size, a, opts...)
+		}),
+	}
+
+	DescribeTable("keeps the oldest values and alerts the reader",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) dropDiode) {
+			spy := newSpyAlerter()
+			d := newDiode(4, spy, diodes.WithDropNewest())
+
+			set(d, 0, 10)
+			Expect(d.Dropped()).To(BeZero())
+
+			Expect(read(d)).To(Equal([]int{0, 1, 2, 3}))
+			Expect(spy.AlertInput.Missed).To(Receive(Equal(6)))
+			Expect(d.Dropped()).To(Equal(uint64(6)))
+
+			set(d, 10, 12)
+			Expect(read(d)).To(Equal([]int{10, 11}))
+			Expect(spy.AlertInput.Missed).ToNot(Receive())
+		},
+		entries,
+	)
+
+	DescribeTable("hands the discarded values to the drop handler",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) dropDiode) {
+			var dropped []int
+			d := newDiode(2, nil, diodes.WithDropNewest(), diodes.WithDropHandler(diodes.DropFunc(func(data diodes.GenericDataType) {
+				dropped = append(dropped, *(*int)(data))
+			})))
+
+			set(d, 0, 4)
+			Expect(dropped).To(Equal([]int{2, 3}))
+		},
+		entries,
+	)
+
+	DescribeTable("does not discard the end of the stream",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) dropDiode) {
+			p := diodes.NewPoller(newDiode(2, nil, diodes.WithDropNewest()))
+			set(p, 0, 2)
+			p.Close()
+
+			for p.Next() != nil {
+			}
+			Expect(p.Closed()).To(BeTrue())
+		},
+		entries,
+	)
+
+	It("accounts for every write under concurrent writers", func() {
+		d := diodes.NewManyToOne(16, nil, diodes.WithDropNewest())
+
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				set(d, 0, 1000)
+			}()
+		}
+
+		var reads uint64
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		for {
+			select {
+			case <-done:
+				reads += uint64(len(read(d)))
+				Expect(reads + d.Dropped()).To(Equal(uint64(4000)))
+				return
+			default:
+				reads += uint64(len(read(d)))
+			}
+		}
+	})
+})
@@ -0,0 +1,51 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// dropShape tracks the distribution of the time between drop events and of
+// their sizes. A drop event is every time the reader notices that values
+// were dropped, which it does once per burst of dropped values.
+type dropShape struct {
+	intervals buckets
+	bursts    buckets
+	last      atomic.Int64
+}
+
+// WithDropHistograms tracks the shape of overloads, next to the totals: how
+// much time passes between drop events and how many values every one of
+// them dropped, with p50, p99 and max reported by Stats. A drop event is
+// every time the reader notices dropped values, so a burst that overwrote
+// many values counts once with its size.
+func WithDropHistograms() DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.dropShape = new(dropShape)
+	})
+}
+
+// observeDrops records a drop event of n values. Several readers may call
+// it at once.
+func (c *diodeConfig) observeDrops(n uint64) {
+	if c.dropShape == nil {
+		return
+	}
+
+	c.dropShape.bursts.observe(n)
+	now := nanotime()
+	if last := c.dropShape.last.Swap(now); last != 0 {
+		c.dropShape.intervals.observe(uint64(max(now-last, 0)))
+	}
+}
+
+// fill sets the drop interval and burst stats.
+func (s *dropShape) fill(st *Stats) {
+	st.DropIntervalP50 = time.Duration(percentile(0.5, &s.intervals))
+	st.DropIntervalP99 = time.Duration(percentile(0.99, &s.intervals))
+	st.DropIntervalMax = time.Duration(s.intervals.maximum())
+
+	st.DropBurstP50 = percentile(0.5, &s.bursts)
+	st.DropBurstP99 = percentile(0.99, &s.bursts)
+	st.DropBurstMax = s.bursts.maximum()
+}
@@ -0,0 +1,229 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Elastic diode is a ManyToOne diode that resizes itself between a minimum
+// and a maximum size. It doubles its size when the reader notices drops and
+// halves it once the backlog stayed low for a while, so that many diodes do
+// not all have to be sized for their worst case burst. It is safe for many
+// writers and a single reader.
+//
+// A resize replaces the ring buffer. Writers move on to the new one right
+// away, while the reader first reads what is left in the old one, so no
+// values are lost and their order is kept.
+type Elastic struct {
+	cur     atomic.Pointer[elasticRing]
+	old     atomic.Pointer[elasticRing]
+	alerter Alerter
+	dropped atomic.Uint64
+
+	minSize     int
+	maxSize     int
+	cooldown    time.Duration
+	shrinkAfter time.Duration
+
+	// The following fields are only used by the reader.
+	size      int
+	missed    int
+	run       int
+	resizedAt int64
+	lastBusy  int64
+}
+
+var _ Diode = (*Elastic)(nil)
+
+// elasticRing is one of the ring buffers of an Elastic diode along with the
+// number of writers that are currently setting data on it.
+type elasticRing struct {
+	d       *ManyToOne
+	writers atomic.Int64
+}
+
+// ElasticConfigOption can be used to setup the elastic diode.
+type ElasticConfigOption func(*Elastic)
+
+// WithResizeCooldown sets the minimum time between two resizes, so that a
+// single burst does not grow the diode all the way to its maximum size. The
+// default is one second.
+func WithResizeCooldown(cooldown time.Duration) ElasticConfigOption {
+	return ElasticConfigOption(func(e *Elastic) {
+		e.cooldown = cooldown
+	})
+}
+
+// WithShrinkAfter sets how long the backlog has to stay below a quarter of
+// the size before the diode shrinks. The default is one minute.
+func WithShrinkAfter(idle time.Duration) ElasticConfigOption {
+	return ElasticConfigOption(func(e *Elastic) {
+		e.shrinkAfter = idle
+	})
+}
+
+// NewElastic creates a new Elastic diode that starts at minSize and grows
+// up to maxSize. The alerter is invoked on the reader's go-routine when it
+// notices that writers have passed it and wrote over data. A nil can be
+// used to ignore alerts.
+func NewElastic(minSize, maxSize int, alerter Alerter, opts ...ElasticConfigOption) *Elastic {
+	if alerter == nil {
+		alerter = AlertFunc(func(int) {})
+	}
+
+	e := &Elastic{
+		alerter:     alerter,
+		minSize:     minSize,
+		maxSize:     max(minSize, maxSize),
+		cooldown:    time.Second,
+		shrinkAfter: time.Minute,
+	}
+
+	for _, o := range opts {
+		o(e)
+	}
+
+	now := nanotime()
+	e.size = minSize
+	e.resizedAt = now
+	e.lastBusy = now
+	e.cur.Store(e.newRing(minSize))
+	return e
+}
+
+// NewElasticErr is like NewElastic but returns an error matching
+// ErrInvalidSize instead of a diode that starts without any slots.
+func NewElasticErr(minSize, maxSize int, alerter Alerter, opts ...ElasticConfigOption) (*Elastic, error) {
+	if err := checkSize(minSize, 0); err != nil {
+		return nil, err
+	}
+	return NewElastic(minSize, maxSize, alerter, opts...), nil
+}
+
+// Set sets the data in the next slot of the current ring buffer.
+func (e *Elastic) Set(data GenericDataType) {
+	for {
+		r := e.cur.Load()
+
+		// Registering before checking that the ring buffer is still current
+		// lets the reader know when no writer can set data on a ring buffer
+		// that was replaced anymore.
+		r.writers.Add(1)
+		if e.cur.Load() == r {
+			r.d.Set(data)
+			r.writers.Add(-1)
+			return
+		}
+		r.writers.Add(-1)
+	}
+}
+
+// TryNext will attempt to read the next value, first from a ring buffer
+// that was replaced by a resize and then from the current one. If there is
+// no data available, it will return (nil, false).
+func (e *Elastic) TryNext() (data GenericDataType, ok bool) {
+	if old := e.old.Load(); old != nil {
+		// Once there are no writers left, an empty read means the old ring
+		// buffer is drained for good.
+		done := old.writers.Load() == 0
+		if data, ok := old.d.TryNext(); ok {
+			e.run++
+			return data, true
+		}
+		if !done {
+			return nil, false
+		}
+		e.old.Store(nil)
+	}
+
+	data, ok = e.cur.Load().d.TryNext()
+	if !ok {
+		e.idle()
+		return nil, false
+	}
+
+	e.run++
+	if e.missed > 0 {
+		e.grow()
+	}
+	return data, true
+}
+
+// Len returns the approximate number of unread values. It is safe to call
+// concurrently with the reader and writers.
+func (e *Elastic) Len() int {
+	n := e.cur.Load().d.Len()
+	if old := e.old.Load(); old != nil {
+		n += old.d.Len()
+	}
+	return n
+}
+
+// Cap returns the number of slots of the current ring buffer. It is safe to
+// call concurrently with the reader and writers.
+func (e *Elastic) Cap() int {
+	return e.cur.Load().d.Cap()
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read.
+func (e *Elastic) Dropped() uint64 {
+	return e.dropped.Load()
+}
+
+func (e *Elastic) newRing(size int) *elasticRing {
+	return &elasticRing{
+		d: NewManyToOne(size, AlertFunc(e.alert)),
+	}
+}
+
+// alert is invoked by the ring buffers on the reader's go-routine.
+func (e *Elastic) alert(missed int) {
+	e.missed += missed
+	e.dropped.Add(uint64(missed))
+	e.alerter.Alert(missed)
+}
+
+// grow doubles the size after drops, unless the diode is already at its
+// maximum size or was resized recently.
+func (e *Elastic) grow() {
+	if e.size >= e.maxSize || e.old.Load() != nil {
+		return
+	}
+
+	now := nanotime()
+	if now-e.resizedAt < int64(e.cooldown) {
+		return
+	}
+	e.resize(min(2*e.size, e.maxSize), now)
+}
+
+// idle is called when a read found no data. The reads since the previous
+// empty read tell how large the backlog got, and the diode shrinks once it
+// stayed below a quarter of the size for long enough.
+func (e *Elastic) idle() {
+	busy := e.run > e.size/4
+	e.run = 0
+	if e.size <= e.minSize && !busy {
+		return
+	}
+
+	now := nanotime()
+	if busy {
+		e.lastBusy = now
+		return
+	}
+
+	if e.old.Load() != nil || now-e.lastBusy < int64(e.shrinkAfter) || now-e.resizedAt < int64(e.cooldown) {
+		return
+	}
+	e.resize(max(e.size/2, e.minSize), now)
+}
+
+func (e *Elastic) resize(size int, now int64) {
+	e.old.Store(e.cur.Swap(e.newRing(size)))
+	e.size = size
+	e.missed = 0
+	e.resizedAt = now
+	e.lastBusy = now
+}
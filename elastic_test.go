@@ -0,0 +1,142 @@
+package diodes_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Elastic", func() {
+	var (
+		d   *diodes.Elastic
+		spy *spyAlerter
+	)
+
+	BeforeEach(func() {
+		spy = newSpyAlerter()
+		d = diodes.NewElastic(4, 16, spy, diodes.WithResizeCooldown(0), diodes.WithShrinkAfter(10*time.Millisecond))
+	})
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	readAll := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	It("starts at the minimum size", func() {
+		Expect(d.Cap()).To(Equal(4))
+
+		set(0, 3)
+		Expect(d.Len()).To(Equal(3))
+		Expect(readAll()).To(Equal([]int{0, 1, 2}))
+	})
+
+	It("grows when the reader notices drops", func() {
+		set(0, 6)
+		Expect(readAll()).To(Equal([]int{4, 5}))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(4)))
+		Expect(d.Dropped()).To(Equal(uint64(4)))
+		Expect(d.Cap()).To(Equal(8))
+
+		set(6, 14)
+		Expect(readAll()).To(Equal([]int{6, 7, 8, 9, 10, 11, 12, 13}))
+		Expect(d.Dropped()).To(Equal(uint64(4)))
+	})
+
+	It("does not grow beyond the maximum size", func() {
+		for i := 0; i < 5; i++ {
+			set(0, 40)
+			readAll()
+		}
+
+		Expect(d.Cap()).To(Equal(16))
+	})
+
+	It("reads what is left of the old ring buffer first", func() {
+		set(0, 5)
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(4))
+		Expect(d.Cap()).To(Equal(8))
+
+		set(5, 8)
+		Expect(readAll()).To(Equal([]int{5, 6, 7}))
+	})
+
+	It("shrinks once the backlog stayed low", func() {
+		set(0, 6)
+		readAll()
+		Expect(d.Cap()).To(Equal(8))
+
+		time.Sleep(20 * time.Millisecond)
+		d.TryNext()
+		Expect(d.Cap()).To(Equal(4))
+	})
+
+	It("does not shrink while the backlog is high", func() {
+		set(0, 6)
+		readAll()
+		Expect(d.Cap()).To(Equal(8))
+
+		for i := 0; i < 5; i++ {
+			time.Sleep(5 * time.Millisecond)
+			set(0, 4)
+			readAll()
+		}
+		Expect(d.Cap()).To(Equal(8))
+	})
+
+	It("accounts for every write while resizing under concurrent writers", func() {
+		d = diodes.NewElastic(2, 64, nil, diodes.WithResizeCooldown(0), diodes.WithShrinkAfter(time.Millisecond))
+
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 5000; i++ {
+					j := i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}()
+		}
+
+		var reads uint64
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		for {
+			if _, ok := d.TryNext(); ok {
+				reads++
+				continue
+			}
+
+			select {
+			case <-done:
+				reads += uint64(len(readAll()))
+				Expect(reads + d.Dropped()).To(Equal(uint64(20000)))
+				return
+			default:
+			}
+		}
+	})
+})
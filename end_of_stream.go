@@ -0,0 +1,61 @@
+package diodes
+
+import "unsafe"
+
+// eos is only used for its address.
+var eos byte
+
+// endOfStream is the sentinel value that Close sets on a diode. Its address
+// can not be used by any other value, so it never collides with user data.
+var endOfStream = GenericDataType(unsafe.Pointer(&eos))
+
+// EndOfStream returns the sentinel value that the Close of a Poller or a
+// Waiter sets on its diode. Diodes outside of this package that hand values
+// to user code, e.g. to an encoder, must pass it through as is instead, and
+// hand it back to their reader once everything before it was read.
+func EndOfStream() GenericDataType {
+	return endOfStream
+}
+
+// drainMarker is implemented by the diodes whose reader can tell whether it
+// read every value that was set before a point, including values whose
+// writers are still storing them.
+type drainMarker interface {
+	// drainMark returns the index that follows the last value that was set
+	// or claimed so far.
+	drainMark() uint64
+
+	// drained reports whether the reader read past the given mark.
+	drained(mark uint64) bool
+}
+
+// drainEnd ends the stream of a Drain on the reader's side. Setting the end
+// of the stream instead would make the reader another writer, which a diode
+// with a single writer does not allow for. Diodes that are not a
+// drainMarker, e.g. wrappers, end the stream once they are empty. It must
+// only be used by the reader.
+type drainEnd struct {
+	started bool
+	marker  drainMarker
+	mark    uint64
+}
+
+// start marks everything that was set on d so far as the values to drain.
+func (e *drainEnd) start(d Diode) {
+	e.started = true
+	e.marker, _ = d.(drainMarker)
+	if e.marker != nil {
+		e.mark = e.marker.drainMark()
+	}
+}
+
+// reached reports whether every value before the mark was read.
+func (e *drainEnd) reached() bool {
+	return e.started && e.marker != nil && e.marker.drained(e.mark)
+}
+
+// emptied reports whether an empty diode ends the stream, since it can not
+// tell how far to read.
+func (e *drainEnd) emptied() bool {
+	return e.started && e.marker == nil
+}
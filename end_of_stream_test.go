@@ -0,0 +1,113 @@
+package diodes_test
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// stream is implemented by both the Poller and the Waiter.
+type stream interface {
+	diodes.Diode
+	Close()
+	Drain(ctx context.Context) ([]diodes.GenericDataType, error)
+	NextCtx(ctx context.Context) (diodes.GenericDataType, error)
+}
+
+var _ = Describe("end of the stream", func() {
+	// value dereferences data the way the callbacks of users do, and fails
+	// if it is handed the end of the stream.
+	value := func(data diodes.GenericDataType) int {
+		Expect(data == diodes.EndOfStream()).To(BeFalse(), "the end of the stream was handed to a callback")
+		return *(*int)(data)
+	}
+
+	encode := func(data diodes.GenericDataType) []byte {
+		return binary.BigEndian.AppendUint64(nil, uint64(value(data)))
+	}
+
+	decode := func(p []byte) diodes.GenericDataType {
+		i := int(binary.BigEndian.Uint64(p))
+		return diodes.GenericDataType(&i)
+	}
+
+	openSpill := func() *diodes.Spill {
+		s, err := diodes.OpenSpill(filepath.Join(GinkgoT().TempDir(), "diode.spill"), 1024, encode, decode)
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(s.Close)
+		return s
+	}
+
+	wrappers := []TableEntry{
+		Entry("Dedup", func() diodes.Diode {
+			return diodes.NewDedup(diodes.NewManyToOne(4, nil), 4, func(data diodes.GenericDataType) uint64 {
+				return uint64(value(data))
+			})
+		}),
+		Entry("Spill", func() diodes.Diode {
+			return openSpill()
+		}),
+		Entry("SpillDiode", func() diodes.Diode {
+			return diodes.NewSpillDiode(diodes.NewManyToOne(4, nil), openSpill(), 1)
+		}),
+		Entry("Unbounded", func() diodes.Diode {
+			return diodes.NewUnbounded(4, 64, func(data diodes.GenericDataType) int {
+				return value(data) + 1
+			}, nil)
+		}),
+	}
+
+	streams := []struct {
+		name      string
+		newStream func(diodes.Diode) stream
+	}{
+		{"Poller", func(d diodes.Diode) stream { return diodes.NewPoller(d) }},
+		{"Waiter", func(d diodes.Diode) stream { return diodes.NewWaiter(d) }},
+	}
+
+	set := func(s stream) {
+		for i := 1; i <= 2; i++ {
+			j := i
+			s.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	for _, st := range streams {
+		name, newStream := st.name, st.newStream
+		DescribeTable("is passed through by a wrapper to a "+name+" that is closed",
+			func(newDiode func() diodes.Diode) {
+				s := newStream(newDiode())
+				set(s)
+				s.Close()
+
+				for i := 1; i <= 2; i++ {
+					data, err := s.NextCtx(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(*(*int)(data)).To(Equal(i))
+				}
+				_, err := s.NextCtx(context.Background())
+				Expect(err).To(MatchError(diodes.ErrClosed))
+			},
+			wrappers,
+		)
+
+		DescribeTable("is passed through by a wrapper to a "+name+" that is drained",
+			func(newDiode func() diodes.Diode) {
+				s := newStream(newDiode())
+				set(s)
+
+				data, err := s.Drain(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(HaveLen(2))
+				Expect(*(*int)(data[0])).To(Equal(1))
+				Expect(*(*int)(data[1])).To(Equal(2))
+			},
+			wrappers,
+		)
+	}
+})
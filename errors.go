@@ -0,0 +1,46 @@
+package diodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTimeout is returned when an operation did not finish before the
+	// deadline of its context.
+	ErrTimeout = errors.New("diodes: timed out")
+
+	// ErrTooLarge is returned when a value or frame exceeds a configured
+	// size limit.
+	ErrTooLarge = errors.New("diodes: too large")
+
+	// ErrClosed is returned when reading past the end of a stream that was
+	// closed.
+	ErrClosed = errors.New("diodes: closed")
+
+	// ErrStopped is returned when reading from a Poller or Waiter that was
+	// stopped.
+	ErrStopped = errors.New("diodes: stopped")
+
+	// ErrLapped is returned when rewinding a reader to a position whose
+	// values the writer has overwritten since.
+	ErrLapped = errors.New("diodes: lapped")
+
+	// ErrInvalidSize is returned when creating a diode with a size it can
+	// not hold any value with.
+	ErrInvalidSize = errors.New("diodes: invalid size")
+
+	// ErrRegistered is returned when registering a diode under a name that
+	// is already registered.
+	ErrRegistered = errors.New("diodes: name already registered")
+)
+
+// contextErr returns the error of a context that is done. It matches
+// ErrTimeout if the context's deadline passed.
+func contextErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, ctx.Err())
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,86 @@
+package diodes
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// AlertLevel routes alerts to Alerter once drops occur faster than Rate.
+type AlertLevel struct {
+	// Rate is the number of drops per second above which the level applies.
+	// A Rate of 0 applies to any drop.
+	Rate float64
+	// Alerter is invoked for the drops that occur at this level.
+	Alerter Alerter
+}
+
+// EscalatingAlerter is an Alerter that routes every alert to the highest
+// level whose rate is exceeded by the current drop rate, e.g. info at any
+// drop, warn above 10 drops per second and critical above 1000 drops per
+// second. The drop rate is measured over a sliding window. Alerts are
+// forwarded synchronously, so the timing of each drop is preserved. It is
+// safe to share between diodes.
+type EscalatingAlerter struct {
+	mu       sync.Mutex
+	levels   []AlertLevel
+	window   time.Duration
+	start    time.Time
+	current  float64
+	previous float64
+}
+
+// NewEscalatingAlerter returns a new EscalatingAlerter that measures the
+// drop rate over the given window. Drops that are below the rate of every
+// level are not forwarded.
+func NewEscalatingAlerter(window time.Duration, levels ...AlertLevel) *EscalatingAlerter {
+	levels = append([]AlertLevel(nil), levels...)
+	sort.SliceStable(levels, func(i, j int) bool {
+		return levels[i].Rate < levels[j].Rate
+	})
+
+	return &EscalatingAlerter{
+		levels: levels,
+		window: window,
+		start:  time.Now(),
+	}
+}
+
+// Alert records the drops and forwards them to the level they fall in.
+func (a *EscalatingAlerter) Alert(missed int) {
+	if l, ok := a.record(missed); ok {
+		l.Alerter.Alert(missed)
+	}
+}
+
+func (a *EscalatingAlerter) record(missed int) (AlertLevel, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(a.start)
+	switch {
+	case elapsed >= 2*a.window:
+		a.previous, a.current = 0, 0
+		a.start = now
+		elapsed = 0
+	case elapsed >= a.window:
+		a.previous, a.current = a.current, 0
+		a.start = a.start.Add(a.window)
+		elapsed -= a.window
+	}
+	a.current += float64(missed)
+
+	// Weigh the previous window by how much of it still overlaps with the
+	// sliding window ending now.
+	overlap := 1 - elapsed.Seconds()/a.window.Seconds()
+	rate := (a.previous*overlap + a.current) / a.window.Seconds()
+
+	for i := len(a.levels) - 1; i >= 0; i-- {
+		if a.levels[i].Rate == 0 || rate > a.levels[i].Rate {
+			return a.levels[i], true
+		}
+	}
+
+	return AlertLevel{}, false
+}
@@ -0,0 +1,80 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EscalatingAlerter", func() {
+	var (
+		info, warn, critical *spyAlerter
+		a                    *diodes.EscalatingAlerter
+	)
+
+	BeforeEach(func() {
+		info = newSpyAlerter()
+		warn = newSpyAlerter()
+		critical = newSpyAlerter()
+
+		a = diodes.NewEscalatingAlerter(time.Minute,
+			diodes.AlertLevel{Rate: 1, Alerter: warn},
+			diodes.AlertLevel{Rate: 0, Alerter: info},
+			diodes.AlertLevel{Rate: 2, Alerter: critical},
+		)
+	})
+
+	It("routes drops below every threshold to the lowest level", func() {
+		a.Alert(5)
+
+		Expect(info.AlertInput.Missed).To(Receive(Equal(5)))
+		Expect(warn.AlertCalled).ToNot(Receive())
+		Expect(critical.AlertCalled).ToNot(Receive())
+	})
+
+	It("escalates as the drop rate rises", func() {
+		a.Alert(5)
+		Expect(info.AlertInput.Missed).To(Receive(Equal(5)))
+
+		a.Alert(60)
+		Expect(warn.AlertInput.Missed).To(Receive(Equal(60)))
+
+		a.Alert(60)
+		Expect(critical.AlertInput.Missed).To(Receive(Equal(60)))
+	})
+
+	It("drops alerts below every level", func() {
+		a = diodes.NewEscalatingAlerter(time.Minute, diodes.AlertLevel{Rate: 1, Alerter: warn})
+
+		Expect(func() { a.Alert(1) }).ToNot(Panic())
+		Expect(warn.AlertCalled).ToNot(Receive())
+	})
+
+	It("calms down once the window has passed", func() {
+		a = diodes.NewEscalatingAlerter(10*time.Millisecond,
+			diodes.AlertLevel{Rate: 0, Alerter: info},
+			diodes.AlertLevel{Rate: 1000, Alerter: critical},
+		)
+
+		a.Alert(100)
+		Expect(critical.AlertInput.Missed).To(Receive(Equal(100)))
+
+		time.Sleep(20 * time.Millisecond)
+		a.Alert(1)
+		Expect(info.AlertInput.Missed).To(Receive(Equal(1)))
+	})
+
+	It("can be used as the alerter of a diode", func() {
+		d := diodes.NewOneToOne(5, a)
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+
+		Expect(info.AlertInput.Missed).To(Receive(Equal(5)))
+	})
+})
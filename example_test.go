@@ -0,0 +1,168 @@
+package diodes_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+func ExampleOneToOne() {
+	d := diodes.NewOneToOne(8, nil)
+
+	for i := 0; i < 3; i++ {
+		// Take the address of a variable that is new for every value. The
+		// address of i would point every slot at the same variable.
+		j := i
+		d.Set(diodes.GenericDataType(&j))
+	}
+
+	for {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+
+		// Cast back to a pointer of the type that was set.
+		fmt.Println(*(*int)(data))
+	}
+	// Output:
+	// 0
+	// 1
+	// 2
+}
+
+func ExampleManyToOne() {
+	d := diodes.NewManyToOne(64, nil)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 2; i++ {
+				msg := fmt.Sprintf("writer-%d-%d", w, i)
+				d.Set(diodes.GenericDataType(&msg))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// Writers race each other, so only the order of the values of every
+	// single writer is kept.
+	var got []string
+	for {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		got = append(got, *(*string)(data))
+	}
+
+	sort.Strings(got)
+	fmt.Println(got)
+	// Output:
+	// [writer-0-0 writer-0-1 writer-1-0 writer-1-1 writer-2-0 writer-2-1 writer-3-0 writer-3-1]
+}
+
+func ExamplePoller() {
+	p := diodes.NewPoller(
+		diodes.NewOneToOne(8, nil),
+		diodes.WithPollingInterval(time.Millisecond),
+	)
+
+	go func() {
+		for _, s := range []string{"a", "b", "c"} {
+			s := s
+			p.Set(diodes.GenericDataType(&s))
+		}
+		p.Close()
+	}()
+
+	// Next polls until there is data and returns nil at the end of the
+	// stream.
+	for data := p.Next(); data != nil; data = p.Next() {
+		fmt.Println(*(*string)(data))
+	}
+	// Output:
+	// a
+	// b
+	// c
+}
+
+func ExampleWaiter() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	w := diodes.NewWaiter(
+		diodes.NewManyToOne(8, nil),
+		diodes.WithWaiterContext(ctx),
+	)
+
+	go func() {
+		for _, s := range []string{"a", "b", "c"} {
+			s := s
+			w.Set(diodes.GenericDataType(&s))
+		}
+		w.Close()
+	}()
+
+	// Next sleeps until a writer wakes it up, and returns nil at the end of
+	// the stream or once the context is done.
+	for data := w.Next(); data != nil; data = w.Next() {
+		fmt.Println(*(*string)(data))
+	}
+	// Output:
+	// a
+	// b
+	// c
+}
+
+func ExamplePollerT() {
+	// The typed diodes copy the value on Set, so there are no casts and
+	// no variables to copy in loops.
+	p := diodes.NewPollerT[[]byte](diodes.NewOneToOneT[[]byte](8, nil))
+
+	for _, s := range []string{"a", "b", "c"} {
+		p.Set([]byte(s))
+	}
+	p.Close()
+
+	for data, ok := p.Next(); ok; data, ok = p.Next() {
+		fmt.Println(string(data))
+	}
+	// Output:
+	// a
+	// b
+	// c
+}
+
+func ExampleAlertFunc() {
+	d := diodes.NewOneToOne(4, diodes.AlertFunc(func(missed int) {
+		fmt.Printf("dropped %d values\n", missed)
+	}))
+
+	// The writer laps the reader, overwriting the oldest values.
+	for i := 0; i < 6; i++ {
+		j := i
+		d.Set(diodes.GenericDataType(&j))
+	}
+
+	// The alerter is invoked on the reader's go-routine once the reader
+	// notices that it was lapped. It skips ahead to the newest values, so
+	// the values it skipped count as dropped along with the overwritten ones.
+	for {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		fmt.Println(*(*int)(data))
+	}
+	// Output:
+	// dropped 4 values
+	// 4
+	// 5
+}
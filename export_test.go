@@ -0,0 +1,82 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// StoreSeq stores a value with the given seq in the given slot of the
+// diode, bypassing its writer, so that tests can build states that the
+// writer never gets a diode into.
+func StoreSeq(d any, slot, seq uint64) {
+	switch d := d.(type) {
+	case *OneToOne:
+		storeSeq(&d.buffer, slot, seq)
+	case *ManyToOne:
+		storeSeq(&d.buffer, slot, seq)
+	case *ManyToMany:
+		storeSeq(&d.buffer, slot, seq)
+	case *OneToMany:
+		storeSeq(&d.buffer, slot, seq)
+	case *SPSC:
+		d.slots[slot].seq.Store(2*seq + 2)
+	default:
+		panic("diodes: StoreSeq does not support the diode")
+	}
+}
+
+// StorePartialSeq stores the seq that the writer of an SPSC diode stores in
+// the given slot while it sets the value.
+func StorePartialSeq(d *SPSC, slot, seq uint64) {
+	d.slots[slot].seq.Store(2*seq + 1)
+}
+
+func storeSeq(buffer *ring, slot, seq uint64) {
+	atomic.StorePointer(buffer.slot(slot), unsafe.Pointer(&bucket{seq: seq}))
+}
+
+// SetIndexes sets the index the diode writes next and the index its reader
+// reads next. The OneToMany diode keeps no read index, so the read index is
+// ignored for it.
+func SetIndexes(d any, nextWrite, nextRead uint64) {
+	switch d := d.(type) {
+	case *OneToOne:
+		d.writeIndex.Store(nextWrite)
+		d.readIndex.Store(nextRead)
+	case *ManyToOne:
+		d.writeIndex.Store(nextWrite - 1)
+		d.readIndex.Store(nextRead)
+	case *ManyToMany:
+		d.writeIndex.Store(nextWrite - 1)
+		d.readIndex.Store(nextRead)
+	case *OneToMany:
+		d.writeIndex.Store(nextWrite)
+	case *SPSC:
+		d.writeIndex.Store(nextWrite)
+		d.readIndex.Store(nextRead)
+	default:
+		panic("diodes: SetIndexes does not support the diode")
+	}
+}
+
+// SlotBucket returns the address of the bucket in the given slot of the
+// diode and its seq, or nil if the slot is empty.
+func SlotBucket(d any, slot uint64) (unsafe.Pointer, uint64) {
+	var buffer *ring
+	switch d := d.(type) {
+	case *OneToOne:
+		buffer = &d.buffer
+	case *ManyToOne:
+		buffer = &d.buffer
+	case *ManyToMany:
+		buffer = &d.buffer
+	default:
+		panic("diodes: SlotBucket does not support the diode")
+	}
+
+	b := atomic.LoadPointer(buffer.peek(slot))
+	if b == nil {
+		return nil, 0
+	}
+	return b, atomic.LoadUint64(&(*bucket)(b).seq)
+}
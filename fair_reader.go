@@ -0,0 +1,83 @@
+package diodes
+
+// FairReader reads from several diodes on a single go-routine with weighted
+// round-robin. Every diode is read up to its weight of values in a row
+// before the next one takes its turn, and a diode without data gives up the
+// rest of its turn. A busy diode therefore can not starve a quiet one: a
+// diode with data is read after at most the sum of the weights of the other
+// diodes. It is not thread safe for multiple readers.
+type FairReader struct {
+	diodes  []Diode
+	weights []int
+	closed  []bool
+	open    int
+
+	// next is the index of the diode whose turn it is, which may still be
+	// read credit more times before the turn passes on.
+	next   int
+	credit int
+}
+
+// NewFairReader returns a new FairReader that reads from the given diodes,
+// each of which is read up to the weight of the same index in a row. A
+// weight of less than 1 is treated as 1, so that every diode is read. The
+// diodes should only be read through the FairReader from then on.
+func NewFairReader(diodes []Diode, weights []int) *FairReader {
+	r := &FairReader{
+		diodes:  diodes,
+		weights: make([]int, len(diodes)),
+		closed:  make([]bool, len(diodes)),
+		open:    len(diodes),
+		next:    len(diodes) - 1,
+	}
+
+	for i := range r.weights {
+		r.weights[i] = 1
+		if i < len(weights) && weights[i] > 1 {
+			r.weights[i] = weights[i]
+		}
+	}
+
+	return r
+}
+
+// TryNext will attempt to read from the diode whose turn it is, passing the
+// turn on until a diode has data. It returns the data along with the index
+// of the diode it was read from. If there is no data available, it will
+// return (nil, -1, false).
+func (r *FairReader) TryNext() (data GenericDataType, index int, ok bool) {
+	for range r.diodes {
+		if r.credit == 0 {
+			r.next = (r.next + 1) % len(r.diodes)
+			r.credit = r.weights[r.next]
+		}
+
+		index = r.next
+		if r.closed[index] {
+			r.credit = 0
+			continue
+		}
+
+		data, ok = r.diodes[index].TryNext()
+		if !ok {
+			r.credit = 0
+			continue
+		}
+		if data == endOfStream {
+			r.closed[index] = true
+			r.open--
+			r.credit = 0
+			continue
+		}
+
+		r.credit--
+		return data, index, true
+	}
+	return nil, -1, false
+}
+
+// Closed reports whether every diode reached the end of its stream (see
+// Poller.Close).
+func (r *FairReader) Closed() bool {
+	return r.open == 0
+}
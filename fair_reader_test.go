@@ -0,0 +1,97 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FairReader", func() {
+	var (
+		busy, quiet *diodes.ManyToOne
+		r           *diodes.FairReader
+	)
+
+	set := func(d *diodes.ManyToOne, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	indexes := func(n int) []int {
+		var got []int
+		for i := 0; i < n; i++ {
+			_, index, ok := r.TryNext()
+			if !ok {
+				break
+			}
+			got = append(got, index)
+		}
+		return got
+	}
+
+	BeforeEach(func() {
+		busy = diodes.NewManyToOne(100, nil)
+		quiet = diodes.NewManyToOne(100, nil)
+		r = diodes.NewFairReader([]diodes.Diode{busy, quiet}, []int{3, 1})
+	})
+
+	It("reads the diodes by their weights", func() {
+		set(busy, 0, 50)
+		set(quiet, 0, 50)
+
+		Expect(indexes(8)).To(Equal([]int{0, 0, 0, 1, 0, 0, 0, 1}))
+	})
+
+	It("reads a quiet diode within a round of a busy one", func() {
+		set(busy, 0, 50)
+		Expect(indexes(2)).To(Equal([]int{0, 0}))
+
+		set(quiet, 100, 101)
+		data, index, ok := r.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(index).To(Equal(0))
+		Expect(*(*int)(data)).To(Equal(2))
+
+		data, index, ok = r.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(index).To(Equal(1))
+		Expect(*(*int)(data)).To(Equal(100))
+	})
+
+	It("passes the turn on when a diode has no data", func() {
+		set(quiet, 0, 3)
+
+		Expect(indexes(5)).To(Equal([]int{1, 1, 1}))
+		_, index, ok := r.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(index).To(Equal(-1))
+	})
+
+	It("treats weights of less than 1 as 1", func() {
+		r = diodes.NewFairReader([]diodes.Diode{busy, quiet}, []int{0})
+		set(busy, 0, 3)
+		set(quiet, 0, 3)
+
+		Expect(indexes(4)).To(Equal([]int{0, 1, 0, 1}))
+	})
+
+	It("is closed once every diode reached the end of its stream", func() {
+		pb := diodes.NewPoller(busy)
+		pq := diodes.NewPoller(quiet)
+		set(busy, 0, 1)
+		pb.Close()
+		pq.Close()
+
+		_, index, ok := r.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(index).To(Equal(0))
+		Expect(r.Closed()).To(BeFalse())
+
+		_, _, ok = r.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(r.Closed()).To(BeTrue())
+	})
+})
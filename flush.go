@@ -0,0 +1,78 @@
+package diodes
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// maxFlushWait bounds the sleep between two checks of a flush.
+const maxFlushWait = 10 * time.Millisecond
+
+// Flush blocks until every value that was set before the call was either
+// read or overwritten, or until the context is done, in which case it
+// returns the context's error, matching ErrTimeout if its deadline passed.
+// It lets writers implement sync points, e.g. to only acknowledge a request
+// once its audit records were delivered. It must not be called by the
+// reader.
+func (d *OneToOne) Flush(ctx context.Context) error {
+	return flush(ctx, &d.readIndex, d.buffer.size, func() uint64 {
+		return d.writeIndex.Load()
+	})
+}
+
+// Flush blocks until every value that was set before the call was either
+// read or overwritten, or until the context is done, in which case it
+// returns the context's error, matching ErrTimeout if its deadline passed.
+// It lets writers implement sync points, e.g. to only acknowledge a request
+// once its audit records were delivered. It must not be called by the
+// reader.
+func (d *ManyToOne) Flush(ctx context.Context) error {
+	return flush(ctx, &d.readIndex, d.buffer.size, func() uint64 {
+		return d.writeIndex.Load() + 1
+	})
+}
+
+// Flush blocks until every value that was set before the call was either
+// read or overwritten, or until the context is done, in which case it
+// returns the context's error, matching ErrTimeout if its deadline passed.
+// It must not be called by a reader.
+func (d *ManyToMany) Flush(ctx context.Context) error {
+	return flush(ctx, &d.readIndex, d.buffer.size, func() uint64 {
+		return d.writeIndex.Load() + 1
+	})
+}
+
+// flush waits until the reader read past every value that was set before,
+// or until the writers overwrote all of them.
+func flush(ctx context.Context, readIndex *atomic.Uint64, size uint64, nextWrite func() uint64) error {
+	index := nextWrite()
+	return pollUntil(ctx, func() bool {
+		return readIndex.Load() >= index || nextWrite() >= index+size
+	})
+}
+
+// pollUntil waits until done returns true, checking it with an increasing
+// delay of up to maxFlushWait, or until the context is done.
+func pollUntil(ctx context.Context, done func() bool) error {
+	if done() {
+		return nil
+	}
+
+	wait := time.Microsecond
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return contextErr(ctx)
+		}
+
+		if done() {
+			return nil
+		}
+		wait = min(2*wait, maxFlushWait)
+		t.Reset(wait)
+	}
+}
@@ -0,0 +1,112 @@
+package diodes_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Flush", func() {
+	type flushDiode interface {
+		diodes.Diode
+		Flush(ctx context.Context) error
+	}
+
+	set := func(d flushDiode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int) flushDiode { return diodes.NewOneToOne(size, nil) }),
+		Entry("ManyToOne", func(size int) flushDiode { return diodes.NewManyToOne(size, nil) }),
+		Entry("ManyToMany", func(size int) flushDiode { return diodes.NewManyToMany(size, nil) }),
+	}
+
+	DescribeTable("returns right away when everything was read",
+		func(newDiode func(size int) flushDiode) {
+			d := newDiode(4)
+			Expect(d.Flush(context.Background())).To(Succeed())
+
+			set(d, 0, 2)
+			d.TryNext()
+			d.TryNext()
+			Expect(d.Flush(context.Background())).To(Succeed())
+		},
+		entries,
+	)
+
+	DescribeTable("waits until the values set before were read",
+		func(newDiode func(size int) flushDiode) {
+			d := newDiode(8)
+			set(d, 0, 3)
+
+			done := make(chan error)
+			go func() {
+				done <- d.Flush(context.Background())
+			}()
+			Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+			d.TryNext()
+			d.TryNext()
+			Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+			d.TryNext()
+			Eventually(done).Should(Receive(BeNil()))
+		},
+		entries,
+	)
+
+	DescribeTable("does not wait for values set after the call",
+		func(newDiode func(size int) flushDiode) {
+			d := newDiode(8)
+			set(d, 0, 1)
+
+			done := make(chan error)
+			go func() {
+				done <- d.Flush(context.Background())
+			}()
+			Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+			d.TryNext()
+			set(d, 1, 3)
+			Eventually(done).Should(Receive(BeNil()))
+		},
+		entries,
+	)
+
+	DescribeTable("returns once the values set before were overwritten",
+		func(newDiode func(size int) flushDiode) {
+			d := newDiode(4)
+			set(d, 0, 2)
+
+			done := make(chan error)
+			go func() {
+				done <- d.Flush(context.Background())
+			}()
+			Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+			set(d, 2, 6)
+			Eventually(done).Should(Receive(BeNil()))
+		},
+		entries,
+	)
+
+	DescribeTable("returns the error of the context",
+		func(newDiode func(size int) flushDiode) {
+			d := newDiode(4)
+			set(d, 0, 1)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			Expect(d.Flush(ctx)).To(MatchError(diodes.ErrTimeout))
+		},
+		entries,
+	)
+})
@@ -0,0 +1,101 @@
+package diodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Group runs the consumers of a set of diodes and shuts them down together.
+// Like a DrainGroup, it drains the stages in dependency order, and like an
+// errgroup, the first consumer that fails cancels the context of every other
+// consumer and its error is returned by Wait and Shutdown. Stages must be
+// added before Shutdown is called.
+type Group struct {
+	drain  *DrainGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// groupStage is a stage of a Group. It counts as drained once its consumer
+// returned, so that the stages after a failed consumer are still drained.
+type groupStage struct {
+	Closer
+	done chan struct{}
+}
+
+func (s *groupStage) Closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return s.Closer.Closed()
+	}
+}
+
+// NewGroup returns a new, empty Group and the context its consumers are run
+// with. The context is derived from ctx and is canceled once a consumer
+// fails or the group was shut down. The options configure the DrainGroup
+// the stages are drained with.
+func NewGroup(ctx context.Context, opts ...DrainGroupConfigOption) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{
+		drain:  NewDrainGroup(opts...),
+		ctx:    ctx,
+		cancel: cancel,
+	}, ctx
+}
+
+// Go adds a stage to the group and runs consume on a new go-routine. consume
+// reads from c, e.g. via NextCtx, and returns nil once it reaches the end of
+// the stream. The stage is closed after all of the stages named in after
+// have been drained, see DrainGroup.Add. A non-nil error fails the group.
+func (g *Group) Go(name string, c Closer, consume func(ctx context.Context) error, after ...string) {
+	s := &groupStage{
+		Closer: c,
+		done:   make(chan struct{}),
+	}
+	g.drain.Add(name, s, after...)
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer close(s.done)
+
+		if err := consume(g.ctx); err != nil {
+			g.fail(fmt.Errorf("consuming %q: %w", name, err))
+		}
+	}()
+}
+
+func (g *Group) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
+
+// Wait blocks until every consumer returned and returns the error of the
+// first consumer that failed, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// Shutdown closes every stage in dependency order and waits for each one to
+// be drained, like DrainGroup.Drain, and then cancels the context of the
+// consumers and waits for them to return. Once the given context is done,
+// the remaining stages are not drained any more. It returns the error of the
+// first consumer that failed, along with the error of the drain.
+func (g *Group) Shutdown(ctx context.Context) error {
+	err := g.drain.Drain(ctx)
+	g.cancel()
+	g.wg.Wait()
+	return errors.Join(g.err, err)
+}
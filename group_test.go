@@ -0,0 +1,105 @@
+package diodes_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Group", func() {
+	var (
+		g   *diodes.Group
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		g, ctx = diodes.NewGroup(context.Background())
+	})
+
+	forward := func(from *diodes.Waiter, to *diodes.Waiter) func(context.Context) error {
+		return func(ctx context.Context) error {
+			for {
+				data, err := from.NextCtx(ctx)
+				if errors.Is(err, diodes.ErrClosed) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				to.Set(data)
+			}
+		}
+	}
+
+	It("drains the stages in order before it stops the consumers", func() {
+		source := diodes.NewWaiter(diodes.NewManyToOne(100, nil))
+		sink := diodes.NewWaiter(diodes.NewManyToOne(100, nil))
+
+		var (
+			mu       sync.Mutex
+			received []int
+		)
+		g.Go("source", source, forward(source, sink))
+		g.Go("sink", sink, func(ctx context.Context) error {
+			for {
+				data, err := sink.NextCtx(ctx)
+				if err != nil {
+					return nil
+				}
+				mu.Lock()
+				received = append(received, *(*int)(data))
+				mu.Unlock()
+			}
+		}, "source")
+
+		for i := 0; i < 50; i++ {
+			j := i
+			source.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(g.Shutdown(context.Background())).To(Succeed())
+		Expect(ctx.Err()).To(MatchError(context.Canceled))
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(received).To(HaveLen(50))
+	})
+
+	It("cancels the other consumers once one fails", func() {
+		boom := errors.New("boom")
+		source := diodes.NewWaiter(diodes.NewManyToOne(100, nil))
+		sink := diodes.NewWaiter(diodes.NewManyToOne(100, nil))
+
+		g.Go("source", source, forward(source, sink))
+		g.Go("sink", sink, func(context.Context) error {
+			return boom
+		}, "source")
+
+		err := g.Wait()
+		Expect(err).To(MatchError(boom))
+		Expect(err).To(MatchError(ContainSubstring(`"sink"`)))
+		Expect(ctx.Err()).To(MatchError(context.Canceled))
+
+		Expect(g.Shutdown(context.Background())).To(MatchError(boom))
+	})
+
+	It("stops draining once the context is done", func() {
+		stuck := &spyCloser{}
+		g.Go("stuck", stuck, func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+
+		shutdownCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := g.Shutdown(shutdownCtx)
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(stuck.closeCalled).To(BeTrue())
+	})
+})
@@ -0,0 +1,136 @@
+package diodes
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// subBucketBits is the number of bits used to split every power of two into
+// sub-buckets. With 3 bits, values are recorded within 12.5% of their true
+// value.
+const subBucketBits = 3
+
+const (
+	subBuckets       = 1 << subBucketBits
+	histogramBuckets = (64 - subBucketBits + 1) * subBuckets
+)
+
+// Histogram records durations in logarithmic buckets. It is safe for
+// concurrent use and recording a value does not allocate.
+type Histogram struct {
+	b buckets
+}
+
+// NewHistogram returns a new, empty Histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{}
+}
+
+// Observe records the given duration. Negative durations are recorded as
+// zero.
+func (h *Histogram) Observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	h.b.observe(uint64(d))
+}
+
+// Count returns the number of recorded durations.
+func (h *Histogram) Count() uint64 {
+	return h.b.total()
+}
+
+// Max returns the largest recorded duration.
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.b.maximum())
+}
+
+// Percentile returns the duration below which the given fraction (0 to 1)
+// of the recorded durations fall. The result is the upper bound of the
+// bucket the percentile lands in. It returns 0 if nothing was recorded.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	return time.Duration(percentile(p, &h.b))
+}
+
+// buckets counts values in logarithmic buckets.
+type buckets struct {
+	counts [histogramBuckets]atomic.Uint64
+	count  atomic.Uint64
+	max    atomic.Uint64
+}
+
+func (b *buckets) observe(v uint64) {
+	b.counts[histogramIndex(v)].Add(1)
+	b.count.Add(1)
+
+	for {
+		current := b.max.Load()
+		if v <= current || b.max.CompareAndSwap(current, v) {
+			return
+		}
+	}
+}
+
+func (b *buckets) total() uint64 {
+	return b.count.Load()
+}
+
+func (b *buckets) maximum() uint64 {
+	return b.max.Load()
+}
+
+// percentile returns the value below which the given fraction of the values
+// recorded across all of the given buckets fall.
+func percentile(p float64, bs ...*buckets) uint64 {
+	var total uint64
+	for _, b := range bs {
+		total += b.total()
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(p * float64(total))
+	if rank >= total {
+		rank = total - 1
+	}
+
+	var seen uint64
+	for i := 0; i < histogramBuckets; i++ {
+		for _, b := range bs {
+			seen += b.counts[i].Load()
+		}
+		if seen > rank {
+			return histogramUpperBound(i)
+		}
+	}
+
+	return histogramUpperBound(histogramBuckets - 1)
+}
+
+// histogramIndex returns the bucket for v. Values below subBuckets each have
+// their own bucket, larger values share a bucket with the values that have
+// the same most significant subBucketBits+1 bits.
+func histogramIndex(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+
+	exp := bits.Len64(v) - 1
+	sub := (v >> (exp - subBucketBits)) & (subBuckets - 1)
+	return (exp-subBucketBits+1)*subBuckets + int(sub)
+}
+
+// histogramUpperBound returns the largest value recorded in bucket i.
+func histogramUpperBound(i int) uint64 {
+	if i < subBuckets {
+		return uint64(i)
+	}
+
+	exp := i/subBuckets + subBucketBits - 1
+	sub := uint64(i % subBuckets)
+	width := uint64(1) << (exp - subBucketBits)
+	return (subBuckets+sub)*width + width - 1
+}
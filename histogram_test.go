@@ -0,0 +1,63 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Histogram", func() {
+	var h *diodes.Histogram
+
+	BeforeEach(func() {
+		h = diodes.NewHistogram()
+	})
+
+	It("returns zero when nothing was recorded", func() {
+		Expect(h.Count()).To(BeZero())
+		Expect(h.Percentile(0.5)).To(BeZero())
+	})
+
+	It("counts the recorded durations", func() {
+		h.Observe(time.Millisecond)
+		h.Observe(time.Second)
+
+		Expect(h.Count()).To(Equal(uint64(2)))
+	})
+
+	It("records small durations exactly", func() {
+		for i := 0; i < 16; i++ {
+			h.Observe(time.Duration(i))
+		}
+
+		Expect(h.Percentile(0)).To(Equal(time.Duration(0)))
+		Expect(h.Percentile(0.5)).To(Equal(time.Duration(8)))
+		Expect(h.Percentile(1)).To(Equal(time.Duration(15)))
+	})
+
+	It("returns percentiles within the bucket precision", func() {
+		for i := 1; i <= 100; i++ {
+			h.Observe(time.Duration(i) * time.Millisecond)
+		}
+
+		Expect(h.Percentile(0.5)).To(BeNumerically("~", 50*time.Millisecond, 50*time.Millisecond/8))
+		Expect(h.Percentile(0.99)).To(BeNumerically("~", 99*time.Millisecond, 99*time.Millisecond/8))
+		Expect(h.Percentile(0.99)).To(BeNumerically(">=", 99*time.Millisecond))
+	})
+
+	It("returns the largest recorded duration", func() {
+		h.Observe(time.Second)
+		h.Observe(time.Millisecond)
+
+		Expect(h.Max()).To(Equal(time.Second))
+	})
+
+	It("records negative durations as zero", func() {
+		h.Observe(-time.Second)
+
+		Expect(h.Percentile(1)).To(BeZero())
+	})
+})
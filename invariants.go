@@ -0,0 +1,126 @@
+package diodes
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// CheckInvariants validates the internal state of the diode and returns an
+// error describing every violation it finds. It is meant to be called from
+// fuzz and property tests between operations and must not be called while
+// the diode is being written to or read from.
+func (d *OneToOne) CheckInvariants() error {
+	return checkInvariants(&d.buffer, d.writeIndex.Load(), d.readIndex.Load())
+}
+
+// CheckInvariants validates the internal state of the diode and returns an
+// error describing every violation it finds. It is meant to be called from
+// fuzz and property tests between operations and must not be called while
+// the diode is being written to or read from.
+func (d *ManyToOne) CheckInvariants() error {
+	// The write index is the last claimed index, the next one is what is
+	// comparable to the OneToOne diode.
+	return checkInvariants(&d.buffer, d.writeIndex.Load()+1, d.readIndex.Load())
+}
+
+// CheckInvariants validates the internal state of the diode and returns an
+// error describing every violation it finds. It is meant to be called from
+// fuzz and property tests between operations and must not be called while
+// the diode is being written to or read from.
+func (d *ManyToMany) CheckInvariants() error {
+	return checkInvariants(&d.buffer, d.writeIndex.Load()+1, d.readIndex.Load())
+}
+
+// CheckInvariants validates the internal state of the diode and returns an
+// error describing every violation it finds. The positions of the readers
+// are not checked, since the diode does not keep track of its readers. It
+// is meant to be called from fuzz and property tests between operations
+// and must not be called while the diode is being written to.
+func (d *OneToMany) CheckInvariants() error {
+	nextWrite := d.writeIndex.Load()
+	return checkInvariants(&d.buffer, nextWrite, nextWrite)
+}
+
+// CheckInvariants validates the internal state of the diode and returns an
+// error describing every violation it finds. It is meant to be called from
+// fuzz and property tests between operations and must not be called while
+// the diode is being written to or read from.
+func (d *SPSC) CheckInvariants() error {
+	nextWrite, nextRead := d.writeIndex.Load(), d.readIndex.Load()
+	errs, ok := checkIndexes(d.size, nextWrite, nextRead)
+	if !ok {
+		return errs[0]
+	}
+
+	for i := range d.slots {
+		seq := d.slots[i].seq.Load()
+		switch {
+		case seq == 0:
+			// The slot has never been written to.
+		case seq%2 == 1:
+			errs = append(errs, fmt.Errorf("slot %d holds seq %d which is still being written", i, seq/2))
+		default:
+			errs = append(errs, checkSlot(uint64(i), seq/2-1, nextWrite, d.size)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkInvariants validates a buffer given the index that will be written
+// next and the index that will be read next.
+func checkInvariants(buffer *ring, nextWrite, nextRead uint64) error {
+	errs, ok := checkIndexes(buffer.size, nextWrite, nextRead)
+	if !ok {
+		return errs[0]
+	}
+
+	for i := uint64(0); i < buffer.size; i++ {
+		slot := buffer.peek(i)
+		if slot == nil {
+			continue
+		}
+
+		b := (*bucket)(atomic.LoadPointer(slot))
+		if b == nil {
+			continue
+		}
+
+		errs = append(errs, checkSlot(i, b.seq, nextWrite, buffer.size)...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkIndexes validates the size of a diode and its indexes. It reports
+// false if the diode has no capacity, in which case its slots can not be
+// checked.
+func checkIndexes(size, nextWrite, nextRead uint64) ([]error, bool) {
+	if size == 0 {
+		return []error{errors.New("diode has no capacity")}, false
+	}
+
+	var errs []error
+	if nextRead > nextWrite {
+		errs = append(errs, fmt.Errorf("read index %d is ahead of write index %d", nextRead, nextWrite))
+	}
+	return errs, true
+}
+
+// checkSlot validates the seq that slot i holds.
+func checkSlot(i, seq, nextWrite, size uint64) []error {
+	var errs []error
+	if seq%size != i {
+		errs = append(errs, fmt.Errorf("slot %d holds seq %d which belongs in slot %d", i, seq, seq%size))
+	}
+
+	if seq >= nextWrite {
+		errs = append(errs, fmt.Errorf("slot %d holds seq %d which has not been written yet (write index %d)", i, seq, nextWrite))
+	}
+
+	if nextWrite > size && seq < nextWrite-size {
+		errs = append(errs, fmt.Errorf("slot %d holds seq %d which should have been overwritten (write index %d)", i, seq, nextWrite))
+	}
+	return errs
+}
@@ -0,0 +1,147 @@
+package diodes_test
+
+import (
+	"math/rand"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CheckInvariants()", func() {
+	type checkedDiode interface {
+		diodes.Diode
+		CheckInvariants() error
+	}
+
+	DescribeTable("holds across random operations",
+		func(newDiode func(size int) checkedDiode) {
+			r := rand.New(rand.NewSource(GinkgoRandomSeed()))
+			for _, size := range []int{1, 2, 5, 16} {
+				d := newDiode(size)
+				Expect(d.CheckInvariants()).To(Succeed())
+
+				for i := 0; i < 1000; i++ {
+					if r.Intn(3) == 0 {
+						d.TryNext()
+					} else {
+						j := i
+						d.Set(diodes.GenericDataType(&j))
+					}
+
+					Expect(d.CheckInvariants()).To(Succeed(), "size %d after %d operations", size, i+1)
+				}
+			}
+		},
+		Entry("OneToOne", func(size int) checkedDiode { return diodes.NewOneToOne(size, nil) }),
+		Entry("ManyToOne", func(size int) checkedDiode { return diodes.NewManyToOne(size, nil) }),
+		Entry("ManyToMany", func(size int) checkedDiode { return diodes.NewManyToMany(size, nil) }),
+		Entry("SPSC", func(size int) checkedDiode { return diodes.NewSPSC(size, nil) }),
+	)
+
+	It("holds across random operations of a OneToMany diode", func() {
+		r := rand.New(rand.NewSource(GinkgoRandomSeed()))
+		for _, size := range []int{1, 2, 5, 16} {
+			d := diodes.NewOneToMany(size)
+			reader := d.NewReader(nil)
+			Expect(d.CheckInvariants()).To(Succeed())
+
+			for i := 0; i < 1000; i++ {
+				if r.Intn(3) == 0 {
+					reader.TryNext()
+				} else {
+					j := i
+					d.Set(diodes.GenericDataType(&j))
+				}
+
+				Expect(d.CheckInvariants()).To(Succeed(), "size %d after %d operations", size, i+1)
+			}
+		}
+	})
+
+	It("reports a diode without capacity", func() {
+		Expect(diodes.NewOneToOne(0, nil).CheckInvariants()).To(MatchError(ContainSubstring("no capacity")))
+		Expect(diodes.NewManyToOne(0, nil).CheckInvariants()).To(MatchError(ContainSubstring("no capacity")))
+		Expect(diodes.NewManyToMany(0, nil).CheckInvariants()).To(MatchError(ContainSubstring("no capacity")))
+		Expect(diodes.NewOneToMany(0).CheckInvariants()).To(MatchError(ContainSubstring("no capacity")))
+		Expect(diodes.NewSPSC(0, nil).CheckInvariants()).To(MatchError(ContainSubstring("no capacity")))
+	})
+
+	Describe("broken states", func() {
+		type invariantChecker interface {
+			CheckInvariants() error
+		}
+
+		entries := []TableEntry{
+			Entry("OneToOne", func() invariantChecker { return diodes.NewOneToOne(4, nil) }),
+			Entry("ManyToOne", func() invariantChecker { return diodes.NewManyToOne(4, nil) }),
+			Entry("ManyToMany", func() invariantChecker { return diodes.NewManyToMany(4, nil) }),
+			Entry("OneToMany", func() invariantChecker { return diodes.NewOneToMany(4) }),
+			Entry("SPSC", func() invariantChecker { return diodes.NewSPSC(4, nil) }),
+		}
+
+		DescribeTable("reports a seq in the wrong slot",
+			func(newDiode func() invariantChecker) {
+				d := newDiode()
+				diodes.SetIndexes(d, 4, 4)
+				diodes.StoreSeq(d, 1, 2)
+
+				Expect(d.CheckInvariants()).To(MatchError("slot 1 holds seq 2 which belongs in slot 2"))
+			},
+			entries,
+		)
+
+		DescribeTable("reports a seq that has not been written yet",
+			func(newDiode func() invariantChecker) {
+				d := newDiode()
+				diodes.SetIndexes(d, 2, 2)
+				diodes.StoreSeq(d, 3, 3)
+
+				Expect(d.CheckInvariants()).To(MatchError("slot 3 holds seq 3 which has not been written yet (write index 2)"))
+			},
+			entries,
+		)
+
+		DescribeTable("reports a stale seq",
+			func(newDiode func() invariantChecker) {
+				d := newDiode()
+				diodes.SetIndexes(d, 10, 10)
+				diodes.StoreSeq(d, 1, 1)
+
+				Expect(d.CheckInvariants()).To(MatchError("slot 1 holds seq 1 which should have been overwritten (write index 10)"))
+			},
+			entries,
+		)
+
+		DescribeTable("reports a read index ahead of the write index",
+			func(newDiode func() invariantChecker) {
+				d := newDiode()
+				diodes.SetIndexes(d, 3, 5)
+
+				Expect(d.CheckInvariants()).To(MatchError("read index 5 is ahead of write index 3"))
+			},
+			entries[:3],
+			entries[4],
+		)
+
+		It("reports every violation it finds", func() {
+			d := diodes.NewOneToOne(4, nil)
+			diodes.SetIndexes(d, 10, 12)
+			diodes.StoreSeq(d, 1, 2)
+
+			err := d.CheckInvariants()
+			Expect(err).To(MatchError(ContainSubstring("read index 12 is ahead of write index 10")))
+			Expect(err).To(MatchError(ContainSubstring("slot 1 holds seq 2 which belongs in slot 2")))
+			Expect(err).To(MatchError(ContainSubstring("slot 1 holds seq 2 which should have been overwritten (write index 10)")))
+		})
+
+		It("reports a value an SPSC writer is still setting", func() {
+			d := diodes.NewSPSC(4, nil)
+			diodes.SetIndexes(d, 2, 1)
+			diodes.StorePartialSeq(d, 1, 1)
+
+			Expect(d.CheckInvariants()).To(MatchError("slot 1 holds seq 1 which is still being written"))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+package diodes
+
+import "runtime"
+
+// KeyedDiodes holds a ManyToOne diode per partition and routes every value
+// to a partition by the hash of its key, so that the values of one key are
+// always read in the order they were set and a noisy key only drops data in
+// its own partition. The reader takes turns reading from the partitions. It
+// is safe for many writers and a single reader.
+type KeyedDiodes struct {
+	partitions []*ManyToOne
+
+	// next is the partition the reader reads from next and is only used by
+	// the reader.
+	next int
+}
+
+var _ Reader = (*KeyedDiodes)(nil)
+
+// NewKeyedDiodes creates a new keyed diode with the given number of
+// partitions of the given size each. A number of partitions of 0 or less
+// uses GOMAXPROCS partitions. The alerter is invoked on the reader's
+// go-routine with the partition that dropped data. A nil can be used to
+// ignore alerts. The options are applied to every partition.
+func NewKeyedDiodes(partitions, size int, alerter LaneAlerter, opts ...DiodeConfigOption) *KeyedDiodes {
+	if partitions <= 0 {
+		partitions = runtime.GOMAXPROCS(0)
+	}
+	if alerter == nil {
+		alerter = LaneAlertFunc(func(int, int) {})
+	}
+
+	d := &KeyedDiodes{
+		partitions: make([]*ManyToOne, partitions),
+	}
+	for i := range d.partitions {
+		partition := i
+		d.partitions[i] = NewManyToOne(size, AlertFunc(func(missed int) {
+			alerter.AlertLane(partition, missed)
+		}), opts...)
+	}
+
+	return d
+}
+
+// NewKeyedDiodesErr is like NewKeyedDiodes but returns an error matching
+// ErrInvalidSize instead of partitions that have no slots, see WithMinSize.
+func NewKeyedDiodesErr(partitions, size int, alerter LaneAlerter, opts ...DiodeConfigOption) (*KeyedDiodes, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(size); err != nil {
+		return nil, err
+	}
+	return NewKeyedDiodes(partitions, size, alerter, opts...), nil
+}
+
+// Set sets the data in the partition of the given key.
+func (d *KeyedDiodes) Set(key string, data GenericDataType) {
+	d.partitions[d.Partition(key)].Set(data)
+}
+
+// Partition returns the partition the values of the given key are routed
+// to. The routing only depends on the key and the number of partitions, so
+// it is the same in every process. When the number of partitions changes
+// from n to m, only about |n-m|/max(n, m) of the keys move to another
+// partition.
+func (d *KeyedDiodes) Partition(key string) int {
+	return jumpHash(fnv1a(key), len(d.partitions))
+}
+
+// TryNext will attempt to read from the partitions, starting with the one
+// after the partition that was read from last, so that a busy partition
+// does not starve the others. If there is no data available in any
+// partition, it will return (nil, false).
+func (d *KeyedDiodes) TryNext() (data GenericDataType, ok bool) {
+	for range d.partitions {
+		p := d.partitions[d.next]
+		d.next = (d.next + 1) % len(d.partitions)
+
+		if data, ok := p.TryNext(); ok {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the approximate number of unread values across all
+// partitions. It is safe to call concurrently with the reader and writers.
+func (d *KeyedDiodes) Len() int {
+	var n int
+	for _, p := range d.partitions {
+		n += p.Len()
+	}
+	return n
+}
+
+// Cap returns the number of slots across all partitions.
+func (d *KeyedDiodes) Cap() int {
+	var n int
+	for _, p := range d.partitions {
+		n += p.Cap()
+	}
+	return n
+}
+
+// Partitions returns the number of partitions.
+func (d *KeyedDiodes) Partitions() int {
+	return len(d.partitions)
+}
+
+// Stats returns a snapshot of the counters of the given partition.
+func (d *KeyedDiodes) Stats(partition int) Stats {
+	return d.partitions[partition].Stats()
+}
+
+// ReadStatsInto fills st with the counters of the given partition like
+// Stats, but leaves the rates zero. See ManyToOne.ReadStatsInto.
+func (d *KeyedDiodes) ReadStatsInto(partition int, st *Stats) {
+	d.partitions[partition].ReadStatsInto(st)
+}
+
+// Dropped returns the number of values the reader noticed were overwritten
+// in the given partition before they were read.
+func (d *KeyedDiodes) Dropped(partition int) uint64 {
+	return d.partitions[partition].Dropped()
+}
+
+// fnv1a returns the 64-bit FNV-1a hash of the key.
+func fnv1a(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// jumpHash maps the hash to one of n buckets with the jump consistent hash
+// of Lamping and Veach, which moves the fewest keys when n changes.
+func jumpHash(h uint64, n int) int {
+	var b, j int64 = -1, 0
+	for j < int64(n) {
+		b = j
+		h = h*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((h>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,139 @@
+package diodes_test
+
+import (
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("KeyedDiodes", func() {
+	var d *diodes.KeyedDiodes
+
+	BeforeEach(func() {
+		d = diodes.NewKeyedDiodes(4, 32, nil)
+	})
+
+	readAll := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	// keysIn returns n keys that are routed to the given partition.
+	keysIn := func(partition, n int) []string {
+		var keys []string
+		for i := 0; len(keys) < n; i++ {
+			key := fmt.Sprintf("app-%d", i)
+			if d.Partition(key) == partition {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	}
+
+	It("defaults to a partition per processor", func() {
+		d = diodes.NewKeyedDiodes(0, 32, nil)
+		Expect(d.Partitions()).To(BeNumerically(">=", 1))
+		Expect(d.Cap()).To(Equal(32 * d.Partitions()))
+	})
+
+	It("routes a key to the same partition every time", func() {
+		other := diodes.NewKeyedDiodes(4, 8, nil)
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("app-%d", i)
+			Expect(d.Partition(key)).To(Equal(other.Partition(key)))
+			Expect(d.Partition(key)).To(And(BeNumerically(">=", 0), BeNumerically("<", 4)))
+		}
+	})
+
+	It("spreads keys over the partitions", func() {
+		counts := make([]int, 4)
+		for i := 0; i < 4000; i++ {
+			counts[d.Partition(fmt.Sprintf("app-%d", i))]++
+		}
+
+		for _, c := range counts {
+			Expect(c).To(BeNumerically("~", 1000, 150))
+		}
+	})
+
+	It("moves few keys when a partition is added", func() {
+		more := diodes.NewKeyedDiodes(5, 32, nil)
+
+		var moved int
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("app-%d", i)
+			if d.Partition(key) != more.Partition(key) {
+				moved++
+			}
+		}
+		Expect(moved).To(BeNumerically("~", 200, 50))
+	})
+
+	It("keeps the order of a key and takes turns reading the partitions", func() {
+		a := keysIn(0, 1)[0]
+		b := keysIn(1, 1)[0]
+
+		for i := 0; i < 3; i++ {
+			j, k := i, 10+i
+			d.Set(a, diodes.GenericDataType(&j))
+			d.Set(b, diodes.GenericDataType(&k))
+		}
+
+		Expect(d.Len()).To(Equal(6))
+		Expect(readAll()).To(Equal([]int{0, 10, 1, 11, 2, 12}))
+		Expect(d.Len()).To(BeZero())
+	})
+
+	It("drops and alerts per partition", func() {
+		var alerts [][2]int
+		d = diodes.NewKeyedDiodes(4, 32, diodes.LaneAlertFunc(func(partition, missed int) {
+			alerts = append(alerts, [2]int{partition, missed})
+		}))
+		noisy := keysIn(2, 1)[0]
+		quiet := keysIn(3, 1)[0]
+
+		for i := 0; i < 34; i++ {
+			j := i
+			d.Set(noisy, diodes.GenericDataType(&j))
+		}
+		q := 100
+		d.Set(quiet, diodes.GenericDataType(&q))
+
+		Expect(readAll()).To(ConsistOf(32, 33, 100))
+		Expect(alerts).To(Equal([][2]int{{2, 32}}))
+		Expect(d.Dropped(2)).To(Equal(uint64(32)))
+		Expect(d.Dropped(3)).To(BeZero())
+		Expect(d.Stats(2).Dropped).To(Equal(uint64(32)))
+		Expect(d.Stats(3).Reads).To(Equal(uint64(1)))
+	})
+
+	It("is safe for many writers", func() {
+		d = diodes.NewKeyedDiodes(4, 1024, nil)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				key := fmt.Sprintf("app-%d", w)
+				for i := 0; i < 100; i++ {
+					j := w*100 + i
+					d.Set(key, diodes.GenericDataType(&j))
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		Expect(readAll()).To(HaveLen(800))
+	})
+})
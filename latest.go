@@ -0,0 +1,104 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Latest diode is a register that only keeps the newest value, for
+// sensor-style state where intermediate values are worthless. Set always
+// overwrites the value and TryNext returns the newest value that was not
+// read yet, exactly once. Unlike a OneToOne diode of size 1, it is a single
+// pointer that is swapped atomically: there are no indexes and no buckets,
+// so neither Set nor TryNext allocates. It is safe for any number of
+// writers and readers. Every value is read by at most one of the readers.
+//
+// Values that were overwritten before they were read count as dropped, but
+// are not alerted, since losing them is the point. Closing a Poller or a
+// Waiter that wraps it overwrites the value that was not read yet.
+type Latest struct {
+	value  unsafe.Pointer
+	_      cacheLinePad
+	writes atomic.Uint64
+	diodeStats
+}
+
+var _ Diode = (*Latest)(nil)
+
+// noValue is stored in a Latest diode while it holds no unread value, so
+// that nil can be set like any other value. Its address can not be used by
+// any other value.
+var (
+	nv      byte
+	noValue = unsafe.Pointer(&nv)
+)
+
+// NewLatest creates a new Latest diode that holds no value.
+func NewLatest() *Latest {
+	d := &Latest{value: noValue}
+	d.diodeStats.init(time.Now(), defaultRateHalfLife)
+	return d
+}
+
+// Set overwrites the value, whether it was read or not.
+func (d *Latest) Set(data GenericDataType) {
+	d.writes.Add(1)
+	if atomic.SwapPointer(&d.value, unsafe.Pointer(data)) != noValue {
+		d.dropped.Add(1)
+	}
+}
+
+// TryNext returns the newest value if it was not read yet. Otherwise it
+// returns (nil, false).
+func (d *Latest) TryNext() (GenericDataType, bool) {
+	// A load is cheaper than a swap, and most reads of a register that is
+	// polled find it already read.
+	if atomic.LoadPointer(&d.value) == noValue {
+		return nil, false
+	}
+
+	data := atomic.SwapPointer(&d.value, noValue)
+	if data == noValue {
+		return nil, false
+	}
+	d.reads.Add(1)
+	raceReadPayload(GenericDataType(data))
+	return GenericDataType(data), true
+}
+
+// Peek returns the value TryNext would read next without reading it. If
+// there is no unread value, it will return (nil, false).
+func (d *Latest) Peek() (GenericDataType, bool) {
+	data := atomic.LoadPointer(&d.value)
+	if data == noValue {
+		return nil, false
+	}
+	return GenericDataType(data), true
+}
+
+// Dropped returns the total number of values that were overwritten before
+// they were read.
+func (d *Latest) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// Stats returns a snapshot of the diode's counters. The rates are updated
+// every time Stats is called.
+func (d *Latest) Stats() Stats {
+	var st Stats
+	d.ReadStatsInto(&st)
+	d.updateRates(&st)
+	return st
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero, without allocating or taking a lock. See
+// OneToOne.ReadStatsInto.
+func (d *Latest) ReadStatsInto(st *Stats) {
+	d.fillCounters(st, d.writes.Load())
+	st.Capacity, st.AllocatedSlots = 1, 1
+	if atomic.LoadPointer(&d.value) != noValue {
+		st.Lag = 1
+	}
+}
@@ -0,0 +1,149 @@
+package diodes_test
+
+import (
+	"sync"
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Latest", func() {
+	var d *diodes.Latest
+
+	BeforeEach(func() {
+		d = diodes.NewLatest()
+	})
+
+	set := func(i int) {
+		d.Set(diodes.GenericDataType(&i))
+	}
+
+	It("holds no value at first", func() {
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the newest value exactly once", func() {
+		set(1)
+		set(2)
+		set(3)
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(3))
+
+		_, ok = d.TryNext()
+		Expect(ok).To(BeFalse())
+
+		set(4)
+		data, ok = d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(4))
+	})
+
+	It("does not allocate on Set", func() {
+		value := 1
+		data := diodes.GenericDataType(&value)
+
+		Expect(testing.AllocsPerRun(100, func() {
+			d.Set(data)
+			d.TryNext()
+		})).To(BeZero())
+	})
+
+	It("counts the overwritten values as dropped", func() {
+		set(1)
+		set(2)
+		d.TryNext()
+		set(3)
+
+		Expect(d.Dropped()).To(Equal(uint64(1)))
+		st := d.Stats()
+		Expect(st.Writes).To(Equal(uint64(3)))
+		Expect(st.Reads).To(Equal(uint64(1)))
+		Expect(st.Dropped).To(Equal(uint64(1)))
+		Expect(st.Lag).To(Equal(uint64(1)))
+		Expect(st.Capacity).To(Equal(uint64(1)))
+	})
+
+	It("stores nil like any other value", func() {
+		d.Set(nil)
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(data == nil).To(BeTrue())
+	})
+
+	It("peeks at the value without reading it", func() {
+		set(1)
+
+		data, ok := d.Peek()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(1))
+
+		_, ok = d.TryNext()
+		Expect(ok).To(BeTrue())
+		_, ok = d.Peek()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("hands every value to at most one of many readers", func() {
+		const writes = 10000
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			reads = map[int]int{}
+			done  = make(chan struct{})
+		)
+
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if data, ok := d.TryNext(); ok {
+						mu.Lock()
+						reads[*(*int)(data)]++
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+
+		var writers sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			writers.Add(1)
+			go func(w int) {
+				defer writers.Done()
+				for i := 0; i < writes; i++ {
+					set(w*writes + i)
+				}
+			}(w)
+		}
+		writers.Wait()
+		close(done)
+		wg.Wait()
+
+		for _, n := range reads {
+			Expect(n).To(Equal(1))
+		}
+		st := d.Stats()
+		Expect(st.Reads + st.Dropped + st.Lag).To(Equal(uint64(4 * writes)))
+	})
+
+	It("ends the stream of a Waiter", func() {
+		w := diodes.NewWaiter(d)
+		w.Close()
+
+		Expect(w.Next() == nil).To(BeTrue())
+		Expect(w.Closed()).To(BeTrue())
+	})
+})
@@ -0,0 +1,21 @@
+package diodes
+
+import "unsafe"
+
+// ub is only used for its address.
+var ub byte
+
+// unbuilt stands in for the data of SetLazy until it is known that the
+// value is kept. Values that are dropped before that are counted, but not
+// handed to the drop handler or the callback of WithRetryBudget, since there
+// is no value to hand to them.
+var unbuilt = GenericDataType(unsafe.Pointer(&ub))
+
+// built returns the data, or the value f constructs if the data was not
+// built yet.
+func built(data GenericDataType, f func() GenericDataType) GenericDataType {
+	if data == unbuilt {
+		return f()
+	}
+	return data
+}
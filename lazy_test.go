@@ -0,0 +1,97 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type lazySetter interface {
+	diodes.Diode
+	SetLazy(f func() diodes.GenericDataType)
+}
+
+var _ = Describe("SetLazy", func() {
+	var built int
+
+	value := func(v int) func() diodes.GenericDataType {
+		return func() diodes.GenericDataType {
+			built++
+			return diodes.GenericDataType(&v)
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	BeforeEach(func() {
+		built = 0
+	})
+
+	DescribeTable("only builds the values that are kept",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) lazySetter) {
+			var handed int
+			d := newDiode(
+				diodes.WithDropNewest(),
+				diodes.WithDropHandler(diodes.DropFunc(func(diodes.GenericDataType) {
+					handed++
+				})),
+			)
+			for i := 0; i < 5; i++ {
+				d.SetLazy(value(i))
+			}
+
+			Expect(built).To(Equal(2))
+			Expect(handed).To(BeZero())
+			Expect(read(d)).To(Equal([]int{0, 1}))
+		},
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) lazySetter {
+			return diodes.NewOneToOne(2, nil, opts...)
+		}),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) lazySetter {
+			return diodes.NewManyToOne(2, nil, opts...)
+		}),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) lazySetter {
+			return diodes.NewManyToMany(2, nil, opts...)
+		}),
+	)
+
+	It("does not build values that exceed the rate limit", func() {
+		d := diodes.NewManyToOne(8, nil, diodes.WithWriteRateLimit(0.001, 3, nil))
+		for i := 0; i < 5; i++ {
+			d.SetLazy(value(i))
+		}
+
+		Expect(built).To(Equal(3))
+		Expect(read(d)).To(Equal([]int{0, 1, 2}))
+	})
+
+	It("builds overwriting values", func() {
+		d := diodes.NewOneToOne(2, nil)
+		for i := 0; i < 3; i++ {
+			d.SetLazy(value(i))
+		}
+
+		Expect(built).To(Equal(3))
+		Expect(read(d)).To(Equal([]int{2}))
+	})
+
+	It("does not build values of a tenant that exceeded its quota", func() {
+		q := diodes.NewTenantQuota(diodes.NewManyToOne(8, nil), 0.001, 2)
+		for i := 0; i < 5; i++ {
+			q.SetLazy("noisy", value(i))
+		}
+
+		Expect(built).To(Equal(2))
+		Expect(q.Dropped("noisy")).To(Equal(uint64(3)))
+	})
+})
@@ -0,0 +1,238 @@
+// Package loadgen drives configurable workloads against diodes and reports
+// how many writes were dropped and how long the data waited to be read. It
+// is meant for sizing diodes before they are rolled out.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// Diode kinds that can be driven.
+const (
+	OneToOne  = "one-to-one"
+	ManyToOne = "many-to-one"
+)
+
+// maxSamples bounds the number of latencies kept to compute percentiles.
+const maxSamples = 1 << 16
+
+// Config describes a workload.
+type Config struct {
+	// Diode is the kind of diode to drive, OneToOne or ManyToOne.
+	Diode string
+	// Size is the size of the diode.
+	Size int
+	// Writers is the number of concurrent writers.
+	Writers int
+	// PayloadSize is the number of bytes written per value.
+	PayloadSize int
+	// Rate is the number of writes per second per writer. Zero writes as
+	// fast as possible.
+	Rate int
+	// BurstSize is the number of writes per burst. Writers pause for
+	// BurstInterval after each burst. Zero disables bursts.
+	BurstSize     int
+	BurstInterval time.Duration
+	// ReaderDelay is how long the reader spends on each value, to simulate a
+	// slow consumer.
+	ReaderDelay time.Duration
+	// Duration is how long the writers run.
+	Duration time.Duration
+}
+
+// Report is the result of a workload.
+type Report struct {
+	Writes  uint64
+	Reads   uint64
+	Dropped uint64
+
+	LatencyP50 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+}
+
+// DropRatio returns the fraction of writes that were dropped.
+func (r Report) DropRatio() float64 {
+	if r.Writes == 0 {
+		return 0
+	}
+	return float64(r.Dropped) / float64(r.Writes)
+}
+
+type payload struct {
+	at   time.Time
+	data []byte
+}
+
+// Run drives the workload described by c until its duration has elapsed or
+// the context is done, and then drains what is left in the diode.
+func Run(ctx context.Context, c Config) (Report, error) {
+	if c.Size < 1 {
+		return Report{}, errors.New("loadgen: size must be positive")
+	}
+	if c.Writers < 1 {
+		return Report{}, errors.New("loadgen: writers must be positive")
+	}
+
+	var dropped uint64
+	alerter := diodes.AlertFunc(func(missed int) {
+		atomic.AddUint64(&dropped, uint64(missed))
+	})
+
+	var d diodes.Diode
+	switch c.Diode {
+	case OneToOne:
+		if c.Writers != 1 {
+			return Report{}, errors.New("loadgen: one-to-one diodes support a single writer")
+		}
+		d = diodes.NewOneToOne(c.Size, alerter)
+	case ManyToOne, "":
+		d = diodes.NewManyToOne(c.Size, alerter)
+	default:
+		return Report{}, errors.New("loadgen: unknown diode " + c.Diode)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Duration)
+	defer cancel()
+
+	var (
+		writes uint64
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < c.Writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			write(ctx, c, d, &writes)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	r := newRecorder()
+	p := diodes.NewPoller(d, diodes.WithPollingInterval(100*time.Microsecond), diodes.WithPollingContext(ctx))
+	for {
+		data := p.Next()
+		if data == nil {
+			break
+		}
+		r.record(data, c.ReaderDelay)
+	}
+
+	<-done
+	for {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		r.record(data, c.ReaderDelay)
+	}
+
+	report := r.report()
+	report.Writes = atomic.LoadUint64(&writes)
+	report.Dropped = atomic.LoadUint64(&dropped)
+	return report, nil
+}
+
+func write(ctx context.Context, c Config, d diodes.Diode, writes *uint64) {
+	var interval time.Duration
+	if c.Rate > 0 {
+		interval = time.Second / time.Duration(c.Rate)
+	}
+
+	next := time.Now()
+	for n := 1; ctx.Err() == nil; n++ {
+		p := &payload{
+			at:   time.Now(),
+			data: make([]byte, c.PayloadSize),
+		}
+		d.Set(diodes.GenericDataType(p))
+		atomic.AddUint64(writes, 1)
+
+		if c.BurstSize > 0 && n%c.BurstSize == 0 {
+			sleep(ctx, c.BurstInterval)
+		}
+
+		if interval > 0 {
+			next = next.Add(interval)
+			sleep(ctx, time.Until(next))
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// recorder keeps a uniform sample of read latencies.
+type recorder struct {
+	reads   uint64
+	max     time.Duration
+	samples []time.Duration
+	rand    *rand.Rand
+}
+
+func newRecorder() *recorder {
+	return &recorder{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+	}
+}
+
+func (r *recorder) record(data diodes.GenericDataType, delay time.Duration) {
+	latency := time.Since((*payload)(data).at)
+	r.reads++
+	if latency > r.max {
+		r.max = latency
+	}
+
+	if len(r.samples) < maxSamples {
+		r.samples = append(r.samples, latency)
+	} else if i := r.rand.Int63n(int64(r.reads)); i < maxSamples {
+		r.samples[i] = latency
+	}
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+func (r *recorder) report() Report {
+	sort.Slice(r.samples, func(i, j int) bool {
+		return r.samples[i] < r.samples[j]
+	})
+
+	return Report{
+		Reads:      r.reads,
+		LatencyP50: percentile(r.samples, 0.5),
+		LatencyP99: percentile(r.samples, 0.99),
+		LatencyMax: r.max,
+	}
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
@@ -0,0 +1,74 @@
+package loadgen_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes/loadgen"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	BeforeEach(func() {
+		log.SetOutput(io.Discard)
+	})
+
+	It("reads everything when the diode keeps up", func() {
+		r, err := loadgen.Run(context.Background(), loadgen.Config{
+			Diode:       loadgen.OneToOne,
+			Size:        1024,
+			Writers:     1,
+			PayloadSize: 8,
+			Rate:        1000,
+			Duration:    100 * time.Millisecond,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.Writes).ToNot(BeZero())
+		Expect(r.Reads).To(Equal(r.Writes))
+		Expect(r.Dropped).To(BeZero())
+		Expect(r.DropRatio()).To(BeZero())
+		Expect(r.LatencyMax).To(BeNumerically(">=", r.LatencyP99))
+		Expect(r.LatencyP99).To(BeNumerically(">=", r.LatencyP50))
+	})
+
+	It("reports drops when the reader falls behind", func() {
+		r, err := loadgen.Run(context.Background(), loadgen.Config{
+			Diode:         loadgen.ManyToOne,
+			Size:          8,
+			Writers:       2,
+			BurstSize:     100,
+			BurstInterval: 10 * time.Millisecond,
+			ReaderDelay:   time.Millisecond,
+			Duration:      100 * time.Millisecond,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.Dropped).ToNot(BeZero())
+		Expect(r.DropRatio()).To(BeNumerically(">", 0))
+	})
+
+	It("rejects a one-to-one diode with several writers", func() {
+		_, err := loadgen.Run(context.Background(), loadgen.Config{
+			Diode:    loadgen.OneToOne,
+			Size:     8,
+			Writers:  2,
+			Duration: time.Millisecond,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects an unknown diode", func() {
+		_, err := loadgen.Run(context.Background(), loadgen.Config{
+			Diode:    "unknown",
+			Size:     8,
+			Writers:  1,
+			Duration: time.Millisecond,
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,11 @@
+//go:build !diodes_nolog
+
+package diodes
+
+import "log"
+
+// logCollision logs that a writer collided with another writer or the
+// reader.
+func logCollision() {
+	log.Println("Diode set collision: consider using a larger diode")
+}
@@ -0,0 +1,270 @@
+package diodes
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ManyToMany diode is meant to be used by many writers and many readers.
+// Readers claim values with a compare-and-swap on the read index, so every
+// value is handed to at most one reader.
+type ManyToMany struct {
+	writeIndex atomic.Uint64
+	_          cacheLinePad
+	readIndex  atomic.Uint64
+	_          cacheLinePad
+	buffer     ring
+	alerter    DropAlerter
+	diodeConfig
+	diodeStats
+}
+
+var _ Diode = (*ManyToMany)(nil)
+
+// NewManyToMany creates a new diode (ring buffer) for many writers and
+// many readers. The alerter is invoked on the go-routine of the reader that
+// notices that writers have passed it and wrote over data, so it must be
+// safe for concurrent use. A nil can be used to ignore alerts. The options
+// can be used to enable optional behavior, except for
+// WithOccupancyTracking which is not supported.
+func NewManyToMany(size int, alerter Alerter, opts ...DiodeConfigOption) *ManyToMany {
+	return newManyToMany(size, alerter, newDiodeConfig(opts))
+}
+
+// NewManyToManyErr is like NewManyToMany but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots, see WithMinSize.
+func NewManyToManyErr(size int, alerter Alerter, opts ...DiodeConfigOption) (*ManyToMany, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(size); err != nil {
+		return nil, err
+	}
+	return newManyToMany(size, alerter, c), nil
+}
+
+func newManyToMany(size int, alerter Alerter, c diodeConfig) *ManyToMany {
+	d := &ManyToMany{
+		alerter:     dropAlerter(alerter),
+		diodeConfig: c,
+	}
+	d.buffer.init(size, &d.diodeConfig)
+
+	// Occupancy is observed by the reader, which is only safe with a single
+	// reader.
+	d.occupancy = nil
+
+	// Start write index at the value before 0
+	// to allow the first write to use AddUint64
+	// and still have a beginning index of 0
+	d.writeIndex.Store(^uint64(0))
+	d.diodeStats.init(time.Now(), d.rateHalfLife)
+	return d
+}
+
+// Set sets the data in the next slot of the ring buffer.
+func (d *ManyToMany) Set(data GenericDataType) {
+	setMany(nil, &d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data, nil)
+}
+
+// SetCtx sets the data like Set, but while the diode is full it waits for
+// the readers to make room until the context is done. See OneToOne.SetCtx.
+func (d *ManyToMany) SetCtx(ctx context.Context, data GenericDataType) error {
+	return setMany(ctx, &d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data, nil)
+}
+
+// SetLazy sets the value that f returns like Set, but only invokes f once
+// the value is kept. See ManyToOne.SetLazy.
+func (d *ManyToMany) SetLazy(f func() GenericDataType) {
+	setMany(nil, &d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, unbuilt, f)
+}
+
+// TrySet sets the data like Set, unless the diode is full. It returns false
+// without setting the data if it would overwrite a value that was not read
+// yet, if other writers kept claiming the next slot first or if the rate
+// limit of WithWriteRateLimit drops it, so that writers can shed load before
+// doing more work for it.
+func (d *ManyToMany) TrySet(data GenericDataType) bool {
+	return trySetMany(&d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data)
+}
+
+// TryNext will attempt to read from the next slot of the ring buffer.
+// If there is not data available, it will return (nil, false). It is safe
+// to call from many go-routines.
+func (d *ManyToMany) TryNext() (data GenericDataType, ok bool) {
+	b, ok := d.next()
+	if !ok {
+		return nil, false
+	}
+	return b.data, true
+}
+
+// TryNextLatency is like TryNext but also returns how long the value waited
+// in the diode since it was set. The latency is only measured with
+// WithEnqueueTime or WithDwellHistogram and is 0 otherwise.
+func (d *ManyToMany) TryNextLatency() (data GenericDataType, latency time.Duration, ok bool) {
+	b, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	return b.data, d.latency(b), true
+}
+
+// TryNextSeq is like TryNext but also returns the sequence number of the
+// value, i.e. the number of values that were set before it. A gap between
+// the sequence numbers of two reads is exactly the number of values that
+// were dropped in between, which lets consumers report and deduplicate
+// precisely, e.g. after a restart. Values discarded by WithDropNewest do
+// not get a sequence number.
+func (d *ManyToMany) TryNextSeq() (data GenericDataType, seq uint64, ok bool) {
+	b, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	return b.data, b.seq, true
+}
+
+// next reads the bucket of the next value that did not expire (see
+// WithMaxAge).
+func (d *ManyToMany) next() (*bucket, bool) {
+	if n := d.takeDiscarded(); n > 0 {
+		d.alert(discardedAlert(n))
+	}
+
+	for {
+		readIndex := d.readIndex.Load()
+
+		// A slot without a segment has never been written to.
+		slot := d.buffer.peek(readIndex)
+		if slot == nil {
+			return nil, false
+		}
+
+		p := atomic.LoadPointer(slot)
+		if p == nil {
+			return nil, false
+		}
+
+		// When the seq value is less than the read index, the writer of the
+		// read index has not stored its value yet.
+		result := (*bucket)(p)
+		if result.seq < readIndex {
+			return nil, false
+		}
+
+		// Claim every index up to the seq that was found. Values between
+		// the read index and the seq were overwritten by writers that lapped
+		// the readers and are dropped. Another reader claiming first means
+		// the read has to start over.
+		if !d.readIndex.CompareAndSwap(readIndex, result.seq+1) {
+			continue
+		}
+		dropped := result.seq - readIndex
+		if d.watermarks != nil {
+			d.observeBacklog(d.writeIndex.Load()+1, result.seq+1, d.buffer.size)
+		}
+
+		// Take the value out of the slot. If a writer overwrote it since it
+		// was loaded, the value was dropped as well and the read starts over
+		// at the next index.
+		if !atomic.CompareAndSwapPointer(slot, p, nil) {
+			d.alert(newDropAlert(DropLapped, readIndex, result.seq+1))
+			continue
+		}
+		d.release(p)
+
+		if dropped > 0 {
+			d.alert(newDropAlert(DropLapped, readIndex, result.seq))
+		}
+		if result.data == failedWrite {
+			continue
+		}
+		if d.expire(result) {
+			d.alerter.AlertDrop(newDropAlert(DropExpired, result.seq, result.seq+1))
+			continue
+		}
+
+		d.reads.Add(1)
+		d.observeRead(result)
+		raceReadPayload(result.data)
+		return result, true
+	}
+}
+
+// DrainInto reads the available data into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls.
+func (d *ManyToMany) DrainInto(dst []GenericDataType) int {
+	var n int
+	budget := readBudget{limit: d.drainYield}
+	for n < len(dst) {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		budget.spend()
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// Stats returns a snapshot of the diode's counters. It is safe to call
+// concurrently with the readers and writers. The rates are updated every
+// time Stats is called.
+func (d *ManyToMany) Stats() Stats {
+	var st Stats
+	d.ReadStatsInto(&st)
+	d.updateRates(&st)
+	return st
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero, without allocating or taking a lock. See
+// OneToOne.ReadStatsInto.
+func (d *ManyToMany) ReadStatsInto(st *Stats) {
+	d.fillCounters(st, d.writeIndex.Load()+1)
+	st.Lag = unread(st.Writes, d.readIndex.Load(), d.buffer.size)
+	d.fillStats(st)
+	d.buffer.fillStats(st)
+}
+
+// Len returns the approximate number of unread values, bounded by Cap. It
+// is safe to call concurrently with the readers and writers.
+func (d *ManyToMany) Len() int {
+	return int(unread(d.writeIndex.Load()+1, d.readIndex.Load(), d.buffer.size))
+}
+
+// drainMark implements drainMarker.
+func (d *ManyToMany) drainMark() uint64 {
+	return d.writeIndex.Load() + 1
+}
+
+// drained implements drainMarker.
+func (d *ManyToMany) drained(mark uint64) bool {
+	return d.readIndex.Load() >= mark
+}
+
+// Pressure returns how close the diode is to dropping values, between 0
+// and 1. See ManyToOne.Pressure.
+func (d *ManyToMany) Pressure() float64 {
+	return d.pressure(unread(d.writeIndex.Load()+1, d.readIndex.Load(), d.buffer.size), d.buffer.size)
+}
+
+// Cap returns the number of slots of the diode.
+func (d *ManyToMany) Cap() int {
+	return int(d.buffer.size)
+}
+
+// Dropped returns the total number of values the readers noticed were
+// overwritten before they were read. It is safe to call from any
+// go-routine.
+func (d *ManyToMany) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// alert counts the dropped values and reports them to the alerter.
+func (d *ManyToMany) alert(a DropAlert) {
+	d.addDropped(uint64(a.Dropped))
+	d.observeDrops(uint64(a.Dropped))
+	d.alerter.AlertDrop(a)
+}
@@ -0,0 +1,165 @@
+package diodes_test
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ManyToMany", func() {
+	var (
+		spy *spyAlerter
+		d   *diodes.ManyToMany
+	)
+
+	BeforeEach(func() {
+		spy = newSpyAlerter()
+		d = diodes.NewManyToMany(5, spy)
+	})
+
+	It("returns false when there is no data", func() {
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the data in order", func() {
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		for i := 0; i < 3; i++ {
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(i))
+		}
+
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("drops the oldest data when the writers lap the readers", func() {
+		for i := 0; i < 12; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(10))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(10)))
+		Expect(d.Dropped()).To(Equal(uint64(10)))
+
+		data, _ = d.TryNext()
+		Expect(*(*int)(data)).To(Equal(11))
+
+		_, ok = d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("hands every value to at most one reader", func() {
+		d = diodes.NewManyToMany(64, nil)
+
+		var (
+			writers sync.WaitGroup
+			readers sync.WaitGroup
+			done    atomic.Bool
+			seen    sync.Map
+			reads   atomic.Uint64
+			dupes   atomic.Uint64
+		)
+		for i := 0; i < 4; i++ {
+			writers.Add(1)
+			go func(w int) {
+				defer writers.Done()
+				for j := 0; j < 5000; j++ {
+					v := w*5000 + j
+					d.Set(diodes.GenericDataType(&v))
+				}
+			}(i)
+		}
+
+		read := func() bool {
+			data, ok := d.TryNext()
+			if !ok {
+				return false
+			}
+			if _, loaded := seen.LoadOrStore(*(*int)(data), true); loaded {
+				dupes.Add(1)
+			}
+			reads.Add(1)
+			return true
+		}
+		for i := 0; i < 4; i++ {
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				for !done.Load() {
+					read()
+				}
+			}()
+		}
+
+		writers.Wait()
+		done.Store(true)
+		readers.Wait()
+		for read() {
+		}
+
+		Expect(dupes.Load()).To(BeZero())
+		Expect(reads.Load() + d.Dropped()).To(Equal(uint64(20000)))
+		Expect(d.Stats().Writes).To(Equal(uint64(20000)))
+	})
+})
+
+var _ = Describe("ManyToMany TrySet()", func() {
+	It("does not overwrite unread values", func() {
+		d := diodes.NewManyToMany(3, nil)
+		for i := 0; i < 4; i++ {
+			j := i
+			Expect(d.TrySet(diodes.GenericDataType(&j))).To(Equal(i < 3))
+		}
+		Expect(d.Stats().Writes).To(Equal(uint64(3)))
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+
+		j := 3
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeTrue())
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("reports values dropped by the rate limit", func() {
+		d := diodes.NewManyToMany(3, nil, diodes.WithWriteRateLimit(1, 1, nil))
+		j := 0
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeTrue())
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeFalse())
+		Expect(d.Stats().RateLimited).To(Equal(uint64(1)))
+	})
+})
+
+var _ = Describe("ManyToMany Len() and Cap()", func() {
+	It("reports the unread values up to the capacity", func() {
+		d := diodes.NewManyToMany(5, nil)
+		Expect(d.Cap()).To(Equal(5))
+		Expect(d.Len()).To(BeZero())
+
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		Expect(d.Len()).To(Equal(2))
+
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.Len()).To(Equal(5))
+	})
+})
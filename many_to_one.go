@@ -1,83 +1,430 @@
 package diodes
 
 import (
-	"log"
+	"context"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 // ManyToOne diode is optimal for many writers (go-routines B-n) and a single
 // reader (go-routine A). It is not thread safe for multiple readers.
 type ManyToOne struct {
-	writeIndex uint64
-	buffer     []unsafe.Pointer
-	readIndex  uint64
-	alerter    Alerter
+	writeIndex atomic.Uint64
+	_          cacheLinePad
+	readIndex  atomic.Uint64
+	_          cacheLinePad
+	buffer     ring
+	alerter    DropAlerter
+	missing    missingWait
+	diodeConfig
+	diodeStats
 }
 
+var _ Diode = (*ManyToOne)(nil)
+
 // NewManyToOne creates a new diode (ring buffer). The ManyToOne diode
 // is optimzed for many writers (on go-routines B-n) and a single reader
 // (on go-routine A). The alerter is invoked on the read's go-routine. It is
 // called when it notices that the writer go-routine has passed it and wrote
-// over data. A nil can be used to ignore alerts.
-func NewManyToOne(size int, alerter Alerter) *ManyToOne {
-	if alerter == nil {
-		alerter = AlertFunc(func(int) {})
+// over data. A nil can be used to ignore alerts. The options can be used to
+// enable optional behavior.
+func NewManyToOne(size int, alerter Alerter, opts ...DiodeConfigOption) *ManyToOne {
+	return newManyToOne(size, alerter, newDiodeConfig(opts))
+}
+
+// NewManyToOneErr is like NewManyToOne but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots, see WithMinSize.
+func NewManyToOneErr(size int, alerter Alerter, opts ...DiodeConfigOption) (*ManyToOne, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(size); err != nil {
+		return nil, err
 	}
+	return newManyToOne(size, alerter, c), nil
+}
 
+func newManyToOne(size int, alerter Alerter, c diodeConfig) *ManyToOne {
 	d := &ManyToOne{
-		buffer:  make([]unsafe.Pointer, size),
-		alerter: alerter,
+		alerter:     dropAlerter(alerter),
+		diodeConfig: c,
 	}
+	d.buffer.init(size, &d.diodeConfig)
 
 	// Start write index at the value before 0
 	// to allow the first write to use AddUint64
 	// and still have a beginning index of 0
-	d.writeIndex = ^d.writeIndex
+	d.writeIndex.Store(^uint64(0))
+	d.diodeStats.init(time.Now(), d.rateHalfLife)
 	return d
 }
 
 // Set sets the data in the next slot of the ring buffer.
 func (d *ManyToOne) Set(data GenericDataType) {
-	for {
-		writeIndex := atomic.AddUint64(&d.writeIndex, 1)
-		idx := writeIndex % uint64(len(d.buffer))
-		old := atomic.LoadPointer(&d.buffer[idx])
-
-		if old != nil &&
-			(*bucket)(old) != nil &&
-			(*bucket)(old).seq > writeIndex-uint64(len(d.buffer)) {
-			log.Println("Diode set collision: consider using a larger diode")
-			continue
+	setMany(nil, &d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data, nil)
+}
+
+// SetCtx sets the data like Set, but while the diode is full it waits for
+// the reader to make room until the context is done. See OneToOne.SetCtx.
+func (d *ManyToOne) SetCtx(ctx context.Context, data GenericDataType) error {
+	return setMany(ctx, &d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data, nil)
+}
+
+// SetLazy sets the value that f returns like Set, but only invokes f on the
+// writer's go-routine once the value is kept, so that no work is done for
+// values the rate limit, WithDropNewest or the sampler drop right away.
+func (d *ManyToOne) SetLazy(f func() GenericDataType) {
+	setMany(nil, &d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, unbuilt, f)
+}
+
+// TrySet sets the data like Set, unless the diode is full. It returns false
+// without setting the data if it would overwrite a value that was not read
+// yet, if other writers kept claiming the next slot first or if the rate
+// limit of WithWriteRateLimit drops it, so that writers can shed load before
+// doing more work for it.
+func (d *ManyToOne) TrySet(data GenericDataType) bool {
+	return trySetMany(&d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data)
+}
+
+// setMany sets the data in the next slot of a ring buffer that is shared by
+// many writers. The write index is the last claimed index and the read index
+// the next index to be read. If the data is unbuilt, f constructs it once
+// the value is kept. A non-nil ctx bounds the wait for the reader instead of
+// the backpressure timeout, and its error is returned if it ended the wait.
+func setMany(ctx context.Context, writeIndex, readIndex *atomic.Uint64, buffer *ring, c *diodeConfig, s *diodeStats, data GenericDataType, f func() GenericDataType) error {
+	if !c.admitWrite(data) {
+		return nil
+	}
+	if c.byteLimit != nil {
+		data, f = built(data, f), nil
+		if c.overBytes(data) {
+			return nil
+		}
+	}
+
+	if c.dropsNewest(data) {
+		err := c.awaitReader(ctx, writeIndex.Load()+1, readIndex, buffer.size)
+		index, ok := claimMany(writeIndex, readIndex, buffer.size, c.retryBudget)
+		if !ok {
+			if fullMany(writeIndex.Load(), readIndex, buffer.size) {
+				c.discard(data)
+			} else {
+				c.failWrite(s, data)
+			}
+			return err
 		}
+		storeMany(index, readIndex, buffer, c, s, built(data, f))
+		observeBacklogMany(writeIndex, readIndex, buffer, c)
+		return nil
+	}
 
-		newBucket := &bucket{
-			data: data,
-			seq:  writeIndex,
+	if c.sampler != nil {
+		// SetCtx waits for room before the sampler decides.
+		var err error
+		if ctx != nil {
+			err = c.awaitReader(ctx, writeIndex.Load()+1, readIndex, buffer.size)
+		}
+		if c.sampleOut(fullMany(writeIndex.Load(), readIndex, buffer.size), data) {
+			return err
 		}
+	}
 
-		if !atomic.CompareAndSwapPointer(&d.buffer[idx], old, unsafe.Pointer(newBucket)) {
-			log.Println("Diode set collision: consider using a larger diode")
-			continue
+	data = built(data, f)
+	index := writeIndex.Add(1)
+	err := c.awaitReader(ctx, index, readIndex, buffer.size)
+	storeMany(index, readIndex, buffer, c, s, data)
+	observeBacklogMany(writeIndex, readIndex, buffer, c)
+	return err
+}
+
+// setBatchMany sets the values like setMany, but claims the write indexes
+// of all of them at once. Values that are dropped by the rate limit are
+// skipped. With options that decide per value whether it is set, every value
+// is set on its own.
+func setBatchMany(writeIndex, readIndex *atomic.Uint64, buffer *ring, c *diodeConfig, s *diodeStats, data []GenericDataType) {
+	if c.dropNewest || c.sampler != nil || c.byteLimit != nil {
+		for _, v := range data {
+			setMany(nil, writeIndex, readIndex, buffer, c, s, v, nil)
+		}
+		return
+	}
+
+	n := 0
+	for _, v := range data {
+		if c.admitWrite(v) {
+			data[n] = v
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+
+	last := writeIndex.Add(uint64(n))
+	for i, v := range data[:n] {
+		index := last - uint64(n-1-i)
+		c.awaitReader(nil, index, readIndex, buffer.size)
+		storeMany(index, readIndex, buffer, c, s, v)
+	}
+	observeBacklogMany(writeIndex, readIndex, buffer, c)
+}
+
+// trySetAttempts is how many times TrySet tries to claim a write index
+// before it gives up because of other writers.
+const trySetAttempts = 8
+
+// trySetMany is like setMany, but it only claims a write index if the value
+// does not overwrite unread data. It returns false if the ring buffer is
+// full, other writers kept claiming the index or the value was dropped.
+func trySetMany(writeIndex, readIndex *atomic.Uint64, buffer *ring, c *diodeConfig, s *diodeStats, data GenericDataType) bool {
+	if fullMany(writeIndex.Load(), readIndex, buffer.size) || !c.admitWrite(data) || c.overBytes(data) {
+		return false
+	}
+
+	index, ok := claimMany(writeIndex, readIndex, buffer.size, trySetAttempts)
+	if !ok {
+		return false
+	}
+	ok = storeMany(index, readIndex, buffer, c, s, data)
+	observeBacklogMany(writeIndex, readIndex, buffer, c)
+	return ok
+}
+
+// observeBacklogMany checks the backlog of a ring buffer that is shared by
+// many writers against the watermarks and the stall detector. The indexes
+// are only loaded with either of them, since the read index is on the
+// reader's cache line.
+func observeBacklogMany(writeIndex, readIndex *atomic.Uint64, buffer *ring, c *diodeConfig) {
+	if c.watermarks != nil || c.stall != nil {
+		nextWrite, nextRead := writeIndex.Load()+1, readIndex.Load()
+		c.observeBacklog(nextWrite, nextRead, buffer.size)
+		c.observeStall(nextWrite, nextRead, buffer.size)
+	}
+}
+
+// claimMany claims the next write index unless the value would overwrite
+// unread data. It gives up after the given number of attempts if other
+// writers keep claiming the index first, or never with 0 attempts.
+func claimMany(writeIndex, readIndex *atomic.Uint64, size uint64, attempts int) (uint64, bool) {
+	for i := 0; attempts == 0 || i < attempts; i++ {
+		last := writeIndex.Load()
+		if fullMany(last, readIndex, size) {
+			return 0, false
+		}
+
+		if writeIndex.CompareAndSwap(last, last+1) {
+			return last + 1, true
 		}
+	}
+	return 0, false
+}
+
+// fullMany reports whether the value after the last claimed write index
+// would overwrite unread data.
+func fullMany(last uint64, readIndex *atomic.Uint64, size uint64) bool {
+	return last+1 >= readIndex.Load()+size
+}
+
+// giveUp stores the bucket as a failed write in the slot of the write index,
+// without a retry, and reports the data as failed. The swap can displace a
+// newer value that a writer of a later lap stored meanwhile, which is then
+// put back or, if the slot changed once more, dropped.
+func giveUp(index uint64, slot *unsafe.Pointer, b *bucket, c *diodeConfig, s *diodeStats) {
+	c.failWrite(s, b.data)
+	b.data = failedWrite
 
+	old := atomic.SwapPointer(slot, unsafe.Pointer(b))
+	if old != nil && (*bucket)(old).seq > index {
+		if !atomic.CompareAndSwapPointer(slot, unsafe.Pointer(b), old) {
+			c.release(old)
+			c.drop(old)
+		}
 		return
 	}
+	c.release(old)
+	c.drop(old)
+}
+
+// storeMany stores the data in the slot of the claimed write index. It
+// returns false if the value was dropped because other writers lapped it.
+func storeMany(index uint64, readIndex *atomic.Uint64, buffer *ring, c *diodeConfig, s *diodeStats, data GenericDataType) bool {
+	slot := buffer.slot(index)
+
+	newBucket := &bucket{
+		data: data,
+		seq:  index,
+	}
+	if c.stampsTime() {
+		newBucket.at = nanotime()
+	}
+
+	for collisions := 0; ; collisions++ {
+		if c.exhausted(collisions) {
+			giveUp(index, slot, newBucket, c, s)
+			return false
+		}
+
+		old := atomic.LoadPointer(slot)
+
+		// When the slot already holds a newer seq, other writers have lapped
+		// this one before it could store its value. The value is older than
+		// everything in the ring buffer and is dropped. The write index is
+		// not abandoned: the reader notices the newer seq and accounts for
+		// this value as dropped, exactly once.
+		if old != nil && (*bucket)(old).seq > index {
+			s.collisions.Add(1)
+			c.lateWrite()
+			c.collide(index, collisions+1)
+			c.drop(unsafe.Pointer(newBucket))
+			return false
+		}
+
+		// An unread value of a previous lap is merged into this one with
+		// WithMergeOnOverwrite.
+		stored := data
+		od, merged := c.mergeable(slot, old, data, readIndex.Load())
+		if merged {
+			stored = c.merge(od, data)
+		}
+		newBucket.data = stored
+
+		// The value is late if the reader already moved past its index. The
+		// read index is loaded before the value is stored, since the reader
+		// may read it right after.
+		late := c.orderAudit != nil && index < readIndex.Load()
+
+		// The slot changed since it was loaded, either by the reader or by a
+		// writer from a previous lap. Retry the same slot so the write index
+		// is never left without a value. The buckets are never recycled
+		// (see bucketPool), so the slot cannot hold old again once it
+		// changed: the compare and swap is free of ABA.
+		if !atomic.CompareAndSwapPointer(slot, old, unsafe.Pointer(newBucket)) {
+			s.collisions.Add(1)
+			c.collide(index, collisions+1)
+			continue
+		}
+		if late {
+			c.lateWrite()
+		}
+
+		c.retain(stored)
+		c.release(old)
+		if merged {
+			c.merged.Add(1)
+		} else {
+			c.drop(old)
+		}
+		return true
+	}
 }
 
 // TryNext will attempt to read from the next slot of the ring buffer.
 // If there is not data available, it will return (nil, false).
+//
+// Values are delivered in the order their writers claimed a slot, so the
+// reader never observes a value older than one it was already handed, even
+// when it fast forwards while writers are lapping it.
 func (d *ManyToOne) TryNext() (data GenericDataType, ok bool) {
-	// Read a value from the ring buffer based on the readIndex.
-	idx := d.readIndex % uint64(len(d.buffer))
-	result := (*bucket)(atomic.SwapPointer(&d.buffer[idx], nil))
+	b, _, ok := d.next()
+	if !ok {
+		return nil, false
+	}
+	return b.data, true
+}
+
+// TryNextLatency is like TryNext but also returns how long the value waited
+// in the diode since it was set. The latency is only measured with
+// WithEnqueueTime or WithDwellHistogram and is 0 otherwise.
+func (d *ManyToOne) TryNextLatency() (data GenericDataType, latency time.Duration, ok bool) {
+	b, _, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	return b.data, d.latency(b), true
+}
+
+// TryNextWithDrop is like TryNext but also returns how many values were
+// dropped right before the value it read, i.e. the gap the alerter is told
+// about, so that consumers can mark the gap at its exact position in their
+// output. The alerter is still invoked.
+func (d *ManyToOne) TryNextWithDrop() (data GenericDataType, dropped int, ok bool) {
+	b, missed, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	return b.data, int(missed), true
+}
+
+// TryNextSeq is like TryNext but also returns the sequence number of the
+// value, i.e. the number of values that were set before it. A gap between
+// the sequence numbers of two reads is exactly the number of values that
+// were dropped in between, which lets consumers report and deduplicate
+// precisely, e.g. after a restart. Values discarded by WithDropNewest do
+// not get a sequence number.
+func (d *ManyToOne) TryNextSeq() (data GenericDataType, seq uint64, ok bool) {
+	b, _, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	return b.data, b.seq, true
+}
+
+// next reads the bucket of the next value that did not expire (see
+// WithMaxAge) along with the number of values that were dropped right before
+// it.
+func (d *ManyToOne) next() (result *bucket, dropped uint64, ok bool) {
+	for {
+		b, n, ok := d.readBucket()
+		dropped += n
+		if !ok {
+			return nil, dropped, false
+		}
+		if b.data == failedWrite {
+			continue
+		}
+		if d.expire(b) {
+			d.alerter.AlertDrop(newDropAlert(DropExpired, b.seq, b.seq+1))
+			continue
+		}
+
+		d.reads.Add(1)
+		d.observeRead(b)
+		raceReadPayload(b.data)
+		return b, dropped, true
+	}
+}
+
+// readBucket reads the bucket of the next value along with the number of
+// values that were dropped right before it.
+func (d *ManyToOne) readBucket() (result *bucket, dropped uint64, ok bool) {
+	if n := d.takeDiscarded(); n > 0 {
+		d.addDropped(n)
+		d.observeDrops(n)
+		d.alerter.AlertDrop(discardedAlert(n))
+	}
+
+	readIndex := d.readIndex.Load()
+	nextWrite := d.writeIndex.Load() + 1
+	d.observeUnread(nextWrite, readIndex, d.buffer.size)
+
+	// Read a value from the ring buffer based on the readIndex. A slot
+	// without a segment has never been written to.
+	slot := d.buffer.peek(readIndex)
+	if slot == nil {
+		return nil, 0, false
+	}
+	result = (*bucket)(atomic.SwapPointer(slot, nil))
 
 	// When the result is nil that means the writer has not had the
 	// opportunity to write a value into the diode. This value must be ignored
-	// and the read head must not increment.
+	// and the read head must not increment, unless the reader gave up
+	// waiting for it (see WithMissingTimeout).
 	if result == nil {
-		return nil, false
+		if d.skipMissing(readIndex, nextWrite) {
+			return d.readBucket()
+		}
+		return nil, 0, false
 	}
+	d.release(unsafe.Pointer(result))
 
 	// When the seq value is less than the current read index that means a
 	// value was read from idx that was previously written but has since has
@@ -92,8 +439,11 @@ func (d *ManyToOne) TryNext() (data GenericDataType, ok bool) {
 	//    effectively "dropped" so the read fails and the read head stays put.
 	//    `| 4 | 5 | 2 | 3 |` r: 7, w: 6
 	//
-	if result.seq < d.readIndex {
-		return nil, false
+	if result.seq < readIndex {
+		if d.skipMissing(readIndex, nextWrite) {
+			return d.readBucket()
+		}
+		return nil, 0, false
 	}
 
 	// When the seq value is greater than the current read index that means a
@@ -115,16 +465,108 @@ func (d *ManyToOne) TryNext() (data GenericDataType, ok bool) {
 	//    this forces the reader to fast forward to 5.
 	//    `| 4 | 5 | 2 | 3 |` r: 5, w: 6
 	//
-	if result.seq > d.readIndex {
-		dropped := result.seq - d.readIndex
-		d.readIndex = result.seq
-		d.alerter.Alert(int(dropped))
+	if result.seq > readIndex {
+		dropped = result.seq - readIndex
+		readIndex = result.seq
+		d.addDropped(dropped)
+		d.observeDrops(dropped)
 	}
 
 	// Only increment read index if a regular read occurred (where seq was
 	// equal to readIndex) or a value was read that caused a fast forward
 	// (where seq was greater than readIndex).
 	//
-	d.readIndex++
-	return result.data, true
+	d.readIndex.Store(readIndex + 1)
+	d.observeBacklog(nextWrite, readIndex+1, d.buffer.size)
+
+	// The alerter is invoked once the read is complete, so that it can set
+	// values on the diode, e.g. a marker for the dropped values.
+	if dropped > 0 {
+		d.alerter.AlertDrop(newDropAlert(DropLapped, readIndex-dropped, readIndex))
+	}
+	return result, dropped, true
+}
+
+// Peek returns the value TryNext would read next without reading it. If
+// there is no data available, it will return (nil, false). Writers can still
+// overwrite the value after Peek returned it, in which case TryNext returns a
+// newer value instead.
+func (d *ManyToOne) Peek() (data GenericDataType, ok bool) {
+	return peekNext(&d.buffer, d.readIndex.Load())
+}
+
+// DrainInto reads the available data into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls.
+func (d *ManyToOne) DrainInto(dst []GenericDataType) int {
+	var n int
+	budget := readBudget{limit: d.drainYield}
+	for n < len(dst) {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		budget.spend()
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// Stats returns a snapshot of the diode's counters. It is safe to call
+// concurrently with the reader and writers. The rates are updated every time
+// Stats is called.
+func (d *ManyToOne) Stats() Stats {
+	var st Stats
+	d.ReadStatsInto(&st)
+	d.updateRates(&st)
+	return st
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero, without allocating or taking a lock. See
+// OneToOne.ReadStatsInto.
+func (d *ManyToOne) ReadStatsInto(st *Stats) {
+	d.fillCounters(st, d.writeIndex.Load()+1)
+	st.Lag = unread(st.Writes, d.readIndex.Load(), d.buffer.size)
+	d.fillStats(st)
+	d.buffer.fillStats(st)
+}
+
+// Len returns the approximate number of unread values, bounded by Cap. It
+// is safe to call concurrently with the reader and writers.
+func (d *ManyToOne) Len() int {
+	return int(unread(d.writeIndex.Load()+1, d.readIndex.Load(), d.buffer.size))
+}
+
+// drainMark implements drainMarker.
+func (d *ManyToOne) drainMark() uint64 {
+	return d.writeIndex.Load() + 1
+}
+
+// drained implements drainMarker.
+func (d *ManyToOne) drained(mark uint64) bool {
+	return d.readIndex.Load() >= mark
+}
+
+// Pressure returns how close the diode is to dropping values, between 0
+// and 1, so that writers can shed load, e.g. by logging less, before the
+// reader loses values. It is the share of the slots that are unread, or 1
+// right after the reader noticed dropped values, decaying to 0 within a
+// second unless more values are dropped. It is safe to call concurrently
+// with the reader and writers.
+func (d *ManyToOne) Pressure() float64 {
+	return d.pressure(unread(d.writeIndex.Load()+1, d.readIndex.Load(), d.buffer.size), d.buffer.size)
+}
+
+// Cap returns the number of slots of the diode.
+func (d *ManyToOne) Cap() int {
+	return int(d.buffer.size)
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read. It is counted whether or not an
+// alerter is installed and is safe to call from any go-routine.
+func (d *ManyToOne) Dropped() uint64 {
+	return d.dropped.Load()
 }
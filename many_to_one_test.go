@@ -1,6 +1,15 @@
 package diodes_test
 
 import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
 	"code.cloudfoundry.org/go-diodes"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -55,6 +64,57 @@ var _ = Describe("ManyToOne", func() {
 			})
 		})
 
+		Describe("Peek()", func() {
+			It("returns the next value without reading it", func() {
+				result, ok := d.Peek()
+				Expect(ok).To(BeTrue())
+				Expect(*(*[]byte)(result)).To(Equal(data))
+
+				result, ok = d.TryNext()
+				Expect(ok).To(BeTrue())
+				Expect(*(*[]byte)(result)).To(Equal(data))
+
+				result, ok = d.Peek()
+				Expect(ok).To(BeTrue())
+				Expect(*(*[]byte)(result)).To(Equal(secondData))
+			})
+
+			It("returns false once every value was read", func() {
+				d.TryNext()
+				d.TryNext()
+
+				_, ok := d.Peek()
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Describe("DrainInto()", func() {
+			It("fills the slice with the available data", func() {
+				dst := make([]diodes.GenericDataType, 5)
+
+				Expect(d.DrainInto(dst)).To(Equal(2))
+				Expect(*(*[]byte)(dst[0])).To(Equal(data))
+				Expect(*(*[]byte)(dst[1])).To(Equal(secondData))
+			})
+
+			It("does not exceed the length of the slice", func() {
+				dst := make([]diodes.GenericDataType, 1)
+
+				Expect(d.DrainInto(dst)).To(Equal(1))
+				Expect(*(*[]byte)(dst[0])).To(Equal(data))
+				Expect(d.DrainInto(dst)).To(Equal(1))
+				Expect(*(*[]byte)(dst[0])).To(Equal(secondData))
+			})
+
+			It("does not allocate", func() {
+				dst := make([]diodes.GenericDataType, 5)
+
+				Expect(testing.AllocsPerRun(10, func() {
+					d.DrainInto(dst)
+				})).To(BeZero())
+			})
+		})
+
 		Context("buffer size exceeded", func() {
 			BeforeEach(func() {
 				for i := 0; i < 4; i++ {
@@ -111,6 +171,17 @@ var _ = Describe("ManyToOne", func() {
 				})
 			})
 
+			Context("nil data", func() {
+				It("is returned as is", func() {
+					d = diodes.NewManyToOne(5, nil)
+					d.Set(nil)
+
+					data, ok := d.TryNext()
+					Expect(ok).To(BeTrue())
+					Expect(data == nil).To(BeTrue())
+				})
+			})
+
 			Context("writer laps reader with nil alerter", func() {
 				It("drops the alert", func() {
 					d = diodes.NewManyToOne(5, nil)
@@ -127,6 +198,755 @@ var _ = Describe("ManyToOne", func() {
 	})
 })
 
+var _ = Describe("ManyToOne with a dwell histogram", func() {
+	It("records how long each value waited", func() {
+		h := diodes.NewHistogram()
+		d := diodes.NewManyToOne(5, nil, diodes.WithDwellHistogram(h))
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+		d.TryNext()
+
+		Expect(h.Count()).To(Equal(uint64(1)))
+		Expect(h.Percentile(1)).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+
+	It("does not record failed reads", func() {
+		h := diodes.NewHistogram()
+		d := diodes.NewManyToOne(5, nil, diodes.WithDwellHistogram(h))
+
+		d.TryNext()
+
+		Expect(h.Count()).To(BeZero())
+	})
+	It("reports the dwell time via Stats", func() {
+		h := diodes.NewHistogram()
+		d := diodes.NewManyToOne(5, nil, diodes.WithDwellHistogram(h))
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+		d.TryNext()
+
+		st := d.Stats()
+		Expect(st.DwellP50).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(st.DwellP99).To(Equal(st.DwellP50))
+		Expect(st.DwellMax).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+})
+
+var _ = Describe("ManyToOne with drop histograms", func() {
+	overload := func(d *diodes.ManyToOne) {
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		for {
+			if _, ok := d.TryNext(); !ok {
+				return
+			}
+		}
+	}
+
+	It("reports the time between drops and their sizes via Stats", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithDropHistograms())
+
+		overload(d)
+		time.Sleep(10 * time.Millisecond)
+		overload(d)
+
+		st := d.Stats()
+		Expect(st.Dropped).To(Equal(uint64(10)))
+		Expect(st.DropIntervalP50).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(st.DropIntervalMax).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(st.DropBurstP50).To(Equal(uint64(5)))
+		Expect(st.DropBurstP99).To(Equal(uint64(5)))
+		Expect(st.DropBurstMax).To(Equal(uint64(5)))
+	})
+
+	It("reports nothing without drops", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithDropHistograms())
+		d.Set(diodes.GenericDataType(new(int)))
+		d.TryNext()
+
+		st := d.Stats()
+		Expect(st.DropIntervalMax).To(BeZero())
+		Expect(st.DropBurstMax).To(BeZero())
+	})
+})
+
+var _ = Describe("ManyToOne with a latency observer", func() {
+	It("reports how long each value waited", func() {
+		var latencies []time.Duration
+		d := diodes.NewManyToOne(5, nil, diodes.WithLatencyObserver(func(l time.Duration) {
+			latencies = append(latencies, l)
+		}))
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+		d.TryNext()
+		d.TryNext()
+
+		Expect(latencies).To(HaveLen(1))
+		Expect(latencies[0]).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+})
+
+var _ = Describe("ManyToOne TryNextWithDrop()", func() {
+	It("reports the gap right before the value it reads", func() {
+		spy := newSpyAlerter()
+		d := diodes.NewManyToOne(4, spy)
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		data, dropped, ok := d.TryNextWithDrop()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(8))
+		Expect(dropped).To(Equal(8))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(8)))
+
+		data, dropped, ok = d.TryNextWithDrop()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(9))
+		Expect(dropped).To(BeZero())
+
+		_, _, ok = d.TryNextWithDrop()
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("ManyToOne with enqueue times", func() {
+	It("reports how long each value waited", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithEnqueueTime())
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+
+		v, latency, ok := d.TryNextLatency()
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(diodes.GenericDataType(&data)))
+		Expect(latency).To(BeNumerically(">=", 10*time.Millisecond))
+
+		_, _, ok = d.TryNextLatency()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports no latency without stamping the values", func() {
+		d := diodes.NewManyToOne(5, nil)
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+
+		_, latency, ok := d.TryNextLatency()
+		Expect(ok).To(BeTrue())
+		Expect(latency).To(BeZero())
+	})
+})
+
+var _ = Describe("ManyToOne Stats()", func() {
+	It("counts writes and reads", func() {
+		d := diodes.NewManyToOne(5, nil)
+		for i := 0; i < 7; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		d.TryNext()
+
+		stats := d.Stats()
+		Expect(stats.Writes).To(Equal(uint64(7)))
+		Expect(stats.Reads).To(Equal(uint64(2)))
+	})
+
+	It("reports the lag of the reader up to the capacity", func() {
+		d := diodes.NewManyToOne(5, nil)
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		Expect(d.Stats().Lag).To(Equal(uint64(2)))
+
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.Stats().Lag).To(Equal(uint64(5)))
+	})
+
+	It("reports write and read rates", func() {
+		d := diodes.NewManyToOne(5, nil)
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		d.TryNext()
+		time.Sleep(time.Millisecond)
+
+		stats := d.Stats()
+		Expect(stats.WriteRate).To(BeNumerically(">", 0))
+		Expect(stats.ReadRate).To(BeNumerically(">", 0))
+	})
+
+	It("counts drops without an alerter", func() {
+		d := diodes.NewManyToOne(5, nil)
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+
+		Expect(d.Stats().Dropped).To(Equal(uint64(5)))
+	})
+
+	It("reports a drop rate that decays with the configured half-life", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithRateHalfLife(time.Millisecond))
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		time.Sleep(time.Millisecond)
+
+		burst := d.Stats().DropRate
+		Expect(burst).To(BeNumerically(">", 0))
+
+		time.Sleep(20 * time.Millisecond)
+		Expect(d.Stats().DropRate).To(BeNumerically("<", burst/100))
+	})
+
+	It("reports occupancy percentiles when tracking is enabled", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithOccupancyTracking(time.Minute))
+		for i := 0; i < 4; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		for i := 0; i < 5; i++ {
+			d.TryNext()
+		}
+
+		stats := d.Stats()
+		Expect(stats.OccupancyP50).To(Equal(uint64(2)))
+		Expect(stats.OccupancyP95).To(Equal(uint64(4)))
+		Expect(stats.OccupancyMax).To(Equal(uint64(4)))
+	})
+
+	It("forgets occupancy older than two windows", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithOccupancyTracking(5*time.Millisecond))
+		for i := 0; i < 4; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.DrainInto(make([]diodes.GenericDataType, 5))
+		Expect(d.Stats().OccupancyMax).To(Equal(uint64(4)))
+
+		time.Sleep(5 * time.Millisecond)
+		d.TryNext()
+		time.Sleep(5 * time.Millisecond)
+		d.TryNext()
+
+		Expect(d.Stats().OccupancyMax).To(BeZero())
+	})
+
+	It("does not report occupancy when tracking is disabled", func() {
+		d := diodes.NewManyToOne(5, nil)
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		d.TryNext()
+
+		Expect(d.Stats().OccupancyMax).To(BeZero())
+	})
+
+	It("does not count failed reads", func() {
+		d := diodes.NewManyToOne(5, nil)
+		d.TryNext()
+
+		Expect(d.Stats().Reads).To(BeZero())
+	})
+})
+
+var _ = Describe("ManyToOne drop accounting", func() {
+	It("accounts for every write exactly under concurrent laps", func() {
+		var dropped uint64
+		d := diodes.NewManyToOne(8, diodes.AlertFunc(func(missed int) {
+			dropped += uint64(missed)
+		}))
+
+		var (
+			reads uint64
+			wg    sync.WaitGroup
+			done  = make(chan struct{})
+		)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 10000; j++ {
+					k := j
+					d.Set(diodes.GenericDataType(&k))
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		consume := func() {
+			for {
+				if _, ok := d.TryNext(); !ok {
+					return
+				}
+				reads++
+			}
+		}
+	loop:
+		for {
+			select {
+			case <-done:
+				break loop
+			default:
+				consume()
+			}
+		}
+		consume()
+
+		Expect(reads + dropped).To(Equal(uint64(80000)))
+		Expect(d.Stats().Dropped).To(Equal(dropped))
+		Expect(d.CheckInvariants()).To(Succeed())
+	})
+})
+
+var _ = Describe("ManyToOne with segments", func() {
+	It("reads and wraps like a single slice", func() {
+		d := diodes.NewManyToOne(10, nil, diodes.WithSegmentSize(3))
+		for i := 0; i < 25; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.CheckInvariants()).To(Succeed())
+
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				break
+			}
+			got = append(got, *(*int)(data))
+		}
+		Expect(got).To(Equal([]int{20, 21, 22, 23, 24}))
+		Expect(d.Dropped()).To(Equal(uint64(20)))
+	})
+
+	It("does not read from segments that were never written", func() {
+		d := diodes.NewManyToOne(1000, nil, diodes.WithSegmentSize(16))
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(d.CheckInvariants()).To(Succeed())
+	})
+
+	It("reports how many slots are allocated", func() {
+		d := diodes.NewManyToOne(40, nil, diodes.WithSegmentSize(16))
+		Expect(d.Stats().Capacity).To(Equal(uint64(40)))
+		Expect(d.Stats().AllocatedSlots).To(BeZero())
+
+		for i := 0; i < 17; i++ {
+			d.Set(diodes.GenericDataType(&i))
+		}
+		Expect(d.Stats().AllocatedSlots).To(Equal(uint64(32)))
+
+		for i := 0; i < 40; i++ {
+			d.Set(diodes.GenericDataType(&i))
+		}
+		Expect(d.Stats().AllocatedSlots).To(Equal(uint64(40)))
+	})
+
+	It("allocates every segment up front with WithPrefault", func() {
+		d := diodes.NewManyToOne(40, nil, diodes.WithSegmentSize(16), diodes.WithPrefault())
+		Expect(d.Stats().AllocatedSlots).To(Equal(uint64(40)))
+
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+		Expect(d.CheckInvariants()).To(Succeed())
+	})
+
+	It("allocates the segments with the slot allocator", func() {
+		var sizes []int
+		alloc := func(n int) []unsafe.Pointer {
+			sizes = append(sizes, n)
+			return make([]unsafe.Pointer, n)
+		}
+		d := diodes.NewManyToOne(40, nil, diodes.WithSegmentSize(16), diodes.WithSlotAllocator(alloc))
+		Expect(sizes).To(BeEmpty())
+
+		for i := 0; i < 40; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(sizes).To(Equal([]int{16, 16, 8}))
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+	})
+
+	It("accounts for every write under concurrent writers", func() {
+		d := diodes.NewManyToOne(64, nil, diodes.WithSegmentSize(8))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					k := j
+					d.Set(diodes.GenericDataType(&k))
+				}
+			}()
+		}
+		wg.Wait()
+
+		var reads uint64
+		for {
+			if _, ok := d.TryNext(); !ok {
+				break
+			}
+			reads++
+		}
+		Expect(reads + d.Dropped()).To(Equal(uint64(8000)))
+		Expect(d.CheckInvariants()).To(Succeed())
+	})
+})
+
+var _ = Describe("ManyToOne with a sizer", func() {
+	byteSize := func(data diodes.GenericDataType) int {
+		return len(*(*[]byte)(data))
+	}
+
+	It("tracks the bytes retained by the diode", func() {
+		d := diodes.NewManyToOne(3, nil, diodes.WithSizer(byteSize))
+		for _, s := range []string{"a", "bb", "ccc"} {
+			b := []byte(s)
+			d.Set(diodes.GenericDataType(&b))
+		}
+		Expect(d.Stats().RetainedBytes).To(Equal(uint64(6)))
+
+		d.TryNext()
+		Expect(d.Stats().RetainedBytes).To(Equal(uint64(5)))
+	})
+
+	It("releases the bytes of overwritten values", func() {
+		d := diodes.NewManyToOne(2, nil, diodes.WithSizer(byteSize))
+		for _, s := range []string{"aaaa", "bbbb", "c", "d"} {
+			b := []byte(s)
+			d.Set(diodes.GenericDataType(&b))
+		}
+		Expect(d.Stats().RetainedBytes).To(Equal(uint64(2)))
+
+		d.TryNext()
+		d.TryNext()
+		Expect(d.Stats().RetainedBytes).To(BeZero())
+	})
+
+	It("does not track bytes without a sizer", func() {
+		d := diodes.NewManyToOne(2, nil)
+		b := []byte("some-data")
+		d.Set(diodes.GenericDataType(&b))
+		Expect(d.Stats().RetainedBytes).To(BeZero())
+	})
+})
+
+var _ = Describe("ManyToOne with backpressure", func() {
+	It("makes writers wait for a slow reader instead of dropping", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithBackpressure(time.Minute))
+		for w := 0; w < 4; w++ {
+			go func() {
+				for i := 0; i < 25; i++ {
+					j := i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}()
+		}
+
+		var reads int
+		for reads < 100 {
+			if _, ok := d.TryNext(); !ok {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			reads++
+		}
+
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("overwrites data once the timeout passes", func() {
+		d := diodes.NewManyToOne(2, nil, diodes.WithBackpressure(10*time.Millisecond))
+		for i := 0; i < 2; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		start := time.Now()
+		j := 2
+		d.Set(diodes.GenericDataType(&j))
+		Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(2))
+		Expect(d.Dropped()).To(Equal(uint64(2)))
+	})
+})
+
+var _ = Describe("ManyToOne with a collision handler", func() {
+	It("reports collisions to the handler instead of logging them", func() {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(io.Discard)
+
+		var collisions atomic.Uint64
+		d := diodes.NewManyToOne(1, nil, diodes.WithCollisionHandler(func(uint64) {
+			collisions.Add(1)
+		}))
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					j := i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}()
+		}
+		go func() {
+			for d.Stats().Writes < 8000 {
+				d.TryNext()
+			}
+		}()
+		wg.Wait()
+
+		Expect(collisions.Load()).To(Equal(d.Stats().Collisions))
+		Expect(buf.String()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ManyToOne with a retry budget", func() {
+	It("does not fail writes without contention", func() {
+		d := diodes.NewManyToOne(4, nil, diodes.WithRetryBudget(1, nil))
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+		Expect(d.Stats().FailedWrites).To(BeZero())
+	})
+
+	It("reports the writes it gave up on and never hands them to the reader", func() {
+		var failed atomic.Uint64
+		d := diodes.NewManyToOne(1, nil, diodes.WithCollisionHandler(func(uint64) {}), diodes.WithRetryBudget(1, func(data diodes.GenericDataType) {
+			Expect(*(*int)(data)).To(BeNumerically("<", 1000))
+			failed.Add(1)
+		}))
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					j := i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		for {
+			if data, ok := d.TryNext(); ok {
+				Expect(*(*int)(data)).To(BeNumerically("<", 1000))
+			}
+			select {
+			case <-done:
+				Expect(failed.Load()).To(Equal(d.Stats().FailedWrites))
+				return
+			default:
+			}
+		}
+	})
+})
+
+var _ = Describe("ManyToOne with a drop handler", func() {
+	var (
+		d       *diodes.ManyToOne
+		dropped []int
+	)
+
+	BeforeEach(func() {
+		dropped = nil
+		d = diodes.NewManyToOne(2, nil, diodes.WithDropHandler(diodes.DropFunc(func(data diodes.GenericDataType) {
+			dropped = append(dropped, *(*int)(data))
+		})))
+	})
+
+	It("hands out the values that were overwritten", func() {
+		for i := 0; i < 5; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(dropped).To(Equal([]int{0, 1, 2}))
+	})
+
+	It("does not hand out values that were read", func() {
+		for i := 0; i < 5; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+			d.TryNext()
+		}
+
+		Expect(dropped).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ManyToOne TrySet()", func() {
+	It("does not overwrite unread values", func() {
+		d := diodes.NewManyToOne(3, nil)
+		for i := 0; i < 4; i++ {
+			j := i
+			Expect(d.TrySet(diodes.GenericDataType(&j))).To(Equal(i < 3))
+		}
+		Expect(d.Stats().Writes).To(Equal(uint64(3)))
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+
+		j := 3
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeTrue())
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("reports values dropped by the rate limit", func() {
+		d := diodes.NewManyToOne(3, nil, diodes.WithWriteRateLimit(1, 1, nil))
+		j := 0
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeTrue())
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeFalse())
+		Expect(d.Stats().RateLimited).To(Equal(uint64(1)))
+	})
+
+	It("fills the diode exactly with many writers", func() {
+		d := diodes.NewManyToOne(100, nil)
+
+		var (
+			wg  sync.WaitGroup
+			set atomic.Int64
+		)
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 50; i++ {
+					j := i
+					if d.TrySet(diodes.GenericDataType(&j)) {
+						set.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		Expect(set.Load()).To(Equal(int64(100)))
+		Expect(d.Len()).To(Equal(100))
+		Expect(d.Stats().Writes).To(Equal(uint64(100)))
+		Expect(d.Dropped()).To(BeZero())
+	})
+})
+
+var _ = Describe("ManyToOne Dropped()", func() {
+	It("counts drops regardless of the alerter", func() {
+		spy := newSpyAlerter()
+		for _, alerter := range []diodes.Alerter{nil, spy} {
+			d := diodes.NewManyToOne(5, alerter)
+			for i := 0; i < 10; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+			Expect(d.Dropped()).To(BeZero())
+
+			d.TryNext()
+			Expect(d.Dropped()).To(Equal(uint64(5)))
+		}
+	})
+})
+
+var _ = Describe("ManyToOne delivery order", func() {
+	It("never delivers a value older than one already delivered", func() {
+		type entry struct {
+			writer int
+			n      int
+		}
+
+		d := diodes.NewManyToOne(4, nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(writer int) {
+				defer wg.Done()
+				for n := 0; n < 10000; n++ {
+					d.Set(diodes.GenericDataType(&entry{writer: writer, n: n}))
+				}
+			}(i)
+		}
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		last := map[int]int{}
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				select {
+				case <-done:
+					return
+				default:
+					continue
+				}
+			}
+
+			e := (*entry)(data)
+			if n, seen := last[e.writer]; seen {
+				Expect(e.n).To(BeNumerically(">", n))
+			}
+			last[e.writer] = e.n
+		}
+	})
+})
+
 var _ = Describe("reader ahead of writer", func() {
 	It("must not occur after alerting", func() {
 		length := 4
@@ -157,3 +977,38 @@ var _ = Describe("reader ahead of writer", func() {
 		Expect(ok).To(BeTrue())
 	})
 })
+
+var _ = Describe("ManyToOne Len() and Cap()", func() {
+	It("reports the unread values up to the capacity", func() {
+		d := diodes.NewManyToOne(5, nil)
+		Expect(d.Cap()).To(Equal(5))
+		Expect(d.Len()).To(BeZero())
+
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		Expect(d.Len()).To(Equal(2))
+
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.Len()).To(Equal(5))
+	})
+
+	It("rounds the size up to a power of two", func() {
+		d := diodes.NewManyToOne(5, nil, diodes.WithPowerOfTwoSize())
+		Expect(d.Cap()).To(Equal(8))
+
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(8))
+		Expect(d.Dropped()).To(Equal(uint64(8)))
+	})
+})
@@ -0,0 +1,32 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithMaxAge makes the reader skip values that were set longer than maxAge
+// ago, for payloads such as metrics that are worse late than never. Skipped
+// values are counted by Stats.Expired, separately from the values that were
+// dropped, and are only reported to a DropAlerter. Enabling it makes every Set
+// read the clock. The OneToMany diode does not support it.
+func WithMaxAge(maxAge time.Duration) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.maxAge = int64(maxAge)
+		if c.expired == nil {
+			c.expired = new(atomic.Uint64)
+		}
+	})
+}
+
+// expire reports whether the value of the bucket is older than the maximum
+// age and counts it if so.
+func (c *diodeConfig) expire(b *bucket) bool {
+	// The end of the stream must not be lost, so it never expires.
+	if c.maxAge <= 0 || b.data == endOfStream || nanotime()-b.at <= c.maxAge {
+		return false
+	}
+
+	c.expired.Add(1)
+	return true
+}
@@ -0,0 +1,91 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithMaxAge", func() {
+	type statsDiode interface {
+		diodes.Diode
+		Stats() diodes.Stats
+	}
+
+	set := func(d diodes.Diode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewOneToOne(size, a, opts...)
+		}),
+		Entry("ManyToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToOne(size, a, opts...)
+		}),
+		Entry("ManyToMany", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToMany(size, a, opts...)
+		}),
+	}
+
+	DescribeTable("skips values older than the maximum age",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) statsDiode) {
+			spy := newSpyAlerter()
+			d := newDiode(8, spy, diodes.WithMaxAge(20*time.Millisecond))
+
+			set(d, 0, 3)
+			time.Sleep(30 * time.Millisecond)
+			set(d, 3, 5)
+
+			Expect(read(d)).To(Equal([]int{3, 4}))
+			Expect(spy.AlertInput.Missed).ToNot(Receive())
+
+			st := d.Stats()
+			Expect(st.Expired).To(Equal(uint64(3)))
+			Expect(st.Dropped).To(BeZero())
+			Expect(st.Reads).To(Equal(uint64(2)))
+		},
+		entries,
+	)
+
+	DescribeTable("returns values that are young enough",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) statsDiode) {
+			d := newDiode(8, nil, diodes.WithMaxAge(time.Minute))
+
+			set(d, 0, 3)
+			Expect(read(d)).To(Equal([]int{0, 1, 2}))
+			Expect(d.Stats().Expired).To(BeZero())
+		},
+		entries,
+	)
+
+	DescribeTable("does not expire the end of the stream",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) statsDiode) {
+			p := diodes.NewPoller(newDiode(8, nil, diodes.WithMaxAge(time.Millisecond)))
+			set(p, 0, 2)
+			p.Close()
+			time.Sleep(10 * time.Millisecond)
+
+			Expect(p.Next() == nil).To(BeTrue())
+			Expect(p.Closed()).To(BeTrue())
+		},
+		entries,
+	)
+})
@@ -0,0 +1,90 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// MergeFunc merges an unread value that is about to be overwritten into the
+// newer value that overwrites it and returns the value to store instead.
+type MergeFunc func(old, new GenericDataType) GenericDataType
+
+// WithMergeOnOverwrite invokes merge on the writer's go-routine when a value
+// is about to overwrite a value the reader has not read yet, and stores the
+// result in its place, e.g. to sum counters or keep the latest gauge. It
+// turns the loss of metric-style payloads into aggregation. The reader may
+// still read the old value while merge runs, so merge must not modify it.
+//
+// The position of the old value is lost all the same, so the reader still
+// counts it as dropped, and a lapped reader skips ahead to the newest value
+// as usual, along with the values merged into the ones it skips.
+// Stats.Merged counts the values that were merged into a newer one. The
+// OneToMany diode does not support it.
+func WithMergeOnOverwrite(merge MergeFunc) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.merge = merge
+		if c.merged == nil {
+			c.merged = new(atomic.Uint64)
+		}
+	})
+}
+
+// mergeable reports whether the bucket old points to holds a value that was
+// not read yet and can be merged into data. It returns the data of the old
+// value.
+func (c *diodeConfig) mergeable(slot *unsafe.Pointer, old unsafe.Pointer, data GenericDataType, readIndex uint64) (GenericDataType, bool) {
+	if c.merge == nil || old == nil || data == endOfStream {
+		return nil, false
+	}
+
+	od, ok := loadUnread(slot, old)
+	return od, ok && atomic.LoadUint64(&(*bucket)(old).seq) >= readIndex && od != endOfStream && od != failedWrite
+}
+
+// loadUnread is a versioned read of the data of the bucket old points to,
+// which was loaded from the slot. The reader of a OneToOne diode puts the
+// buckets it took back into a pool and clears them, so the data is only
+// valid if the slot still holds the same bucket with the same seq after it
+// was read. A bucket that was taken from the slot can only be stored in it
+// again by the writer that gets it from the pool, with a new seq, so the
+// pair of pointer and seq identifies the value even if its bucket is
+// recycled. Buckets of diodes with several writers are never recycled.
+func loadUnread(slot *unsafe.Pointer, old unsafe.Pointer) (GenericDataType, bool) {
+	b := (*bucket)(old)
+	seq := atomic.LoadUint64(&b.seq)
+	data := GenericDataType(atomic.LoadPointer((*unsafe.Pointer)(&b.data)))
+	if atomic.LoadPointer(slot) != old || atomic.LoadUint64(&b.seq) != seq {
+		return nil, false
+	}
+	return data, true
+}
+
+// swapMerge stores the bucket in the slot like a swap, but merges the data of
+// the unread value in the slot into it first. It returns the bucket that was
+// in the slot, the data that was stored and whether the old value was
+// merged, in which case it must not be dropped. It must only be called by a
+// single writer, before the bucket is visible to the reader.
+//
+// The compare and swap cannot be versioned like the read. It is still safe
+// from ABA since only the caller could store a recycled bucket in the slot
+// again: the writer of a OneToOne diode is the only one getting buckets from
+// its pool.
+func (c *diodeConfig) swapMerge(slot *unsafe.Pointer, b *bucket, readIndex uint64) (old unsafe.Pointer, data GenericDataType, merged bool) {
+	data = b.data
+	if c.merge != nil {
+		old = atomic.LoadPointer(slot)
+		if od, ok := c.mergeable(slot, old, data, readIndex); ok {
+			m := c.merge(od, data)
+			b.data = m
+			if atomic.CompareAndSwapPointer(slot, old, unsafe.Pointer(b)) {
+				c.merged.Add(1)
+				return old, m, true
+			}
+
+			// The reader took the old value meanwhile.
+			b.data = data
+		}
+	}
+
+	return atomic.SwapPointer(slot, unsafe.Pointer(b)), data, false
+}
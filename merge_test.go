@@ -0,0 +1,147 @@
+package diodes_test
+
+import (
+	"sync/atomic"
+	"unsafe"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithMergeOnOverwrite", func() {
+	sum := func(old, new diodes.GenericDataType) diodes.GenericDataType {
+		v := *(*int)(old) + *(*int)(new)
+		return diodes.GenericDataType(&v)
+	}
+
+	set := func(d diodes.Diode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	type statsDiode interface {
+		diodes.Diode
+		Stats() diodes.Stats
+	}
+
+	DescribeTable("merges unread values into the values overwriting them",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) statsDiode) {
+			d := newDiode(diodes.WithMergeOnOverwrite(sum))
+			set(d, 1, 7)
+
+			Expect(read(d)).To(Equal([]int{1 + 5, 2 + 6}))
+			st := d.Stats()
+			Expect(st.Merged).To(Equal(uint64(2)))
+			Expect(st.Dropped).To(Equal(uint64(4)))
+		},
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) statsDiode { return diodes.NewOneToOne(4, nil, opts...) }),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) statsDiode { return diodes.NewManyToOne(4, nil, opts...) }),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) statsDiode { return diodes.NewManyToMany(4, nil, opts...) }),
+	)
+
+	It("does not merge values that were read", func() {
+		d := diodes.NewManyToOne(2, nil, diodes.WithMergeOnOverwrite(sum))
+		set(d, 1, 3)
+		Expect(read(d)).To(Equal([]int{1, 2}))
+
+		set(d, 3, 5)
+		Expect(read(d)).To(Equal([]int{3, 4}))
+		Expect(d.Stats().Merged).To(BeZero())
+	})
+
+	It("does not merge into the end of the stream", func() {
+		p := diodes.NewPoller(diodes.NewOneToOne(1, nil, diodes.WithMergeOnOverwrite(sum)))
+		set(p, 1, 2)
+		p.Close()
+
+		Expect(p.Next() == nil).To(BeTrue())
+		Expect(p.Closed()).To(BeTrue())
+	})
+
+	It("never merges into a value the reader recycled", func() {
+		d := diodes.NewOneToOne(2, nil, diodes.WithMergeOnOverwrite(sum))
+
+		var done atomic.Bool
+		go func() {
+			set(d, 1, 100001)
+			done.Store(true)
+		}()
+
+		last, seen := uint64(0), false
+		for {
+			finished := done.Load()
+			data, seq, ok := d.TryNextSeq()
+			if !ok {
+				if finished {
+					break
+				}
+				continue
+			}
+			Expect(*(*int)(data)).To(BeNumerically(">", 0))
+			if seen {
+				Expect(seq).To(BeNumerically(">", last))
+			}
+			last, seen = seq, true
+		}
+
+		Expect(d.CheckInvariants()).To(Succeed())
+	})
+
+	// The compare and swaps on the slots only compare the address of the
+	// bucket they loaded, and mergeable and loadUnread rely on the pair of
+	// address and seq to tell values apart. These tests pin down what makes
+	// that safe from ABA.
+	Describe("the buckets in the slots", func() {
+		type slotKey struct {
+			b   unsafe.Pointer
+			seq uint64
+		}
+
+		// cycle sets and reads values on a diode of two slots and records
+		// the buckets that were stored in them. The map keeps the buckets
+		// alive, so their addresses can not be reused by new allocations.
+		cycle := func(d diodes.Diode) (buckets map[unsafe.Pointer]int, pairs map[slotKey]int) {
+			buckets, pairs = make(map[unsafe.Pointer]int), make(map[slotKey]int)
+			for i := 0; i < 100; i++ {
+				set(d, i, i+1)
+				b, seq := diodes.SlotBucket(d, uint64(i))
+				buckets[b]++
+				pairs[slotKey{b, seq}]++
+				if i%3 != 0 {
+					read(d)
+				}
+			}
+			return buckets, pairs
+		}
+
+		DescribeTable("are never stored twice by diodes with several writers",
+			func(d diodes.Diode) {
+				buckets, _ := cycle(d)
+				Expect(buckets).To(HaveLen(100))
+			},
+			Entry("ManyToOne", diodes.NewManyToOne(2, nil, diodes.WithMergeOnOverwrite(sum))),
+			Entry("ManyToMany", diodes.NewManyToMany(2, nil, diodes.WithMergeOnOverwrite(sum))),
+		)
+
+		It("are recycled by the OneToOne writer with a new seq", func() {
+			buckets, pairs := cycle(diodes.NewOneToOne(2, nil, diodes.WithMergeOnOverwrite(sum)))
+			Expect(len(buckets)).To(BeNumerically("<", 100))
+			Expect(pairs).To(HaveLen(100))
+		})
+	})
+})
@@ -0,0 +1,183 @@
+package diodes
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// SetFunc is the Set method of a diode, as seen by a Middleware.
+type SetFunc func(data GenericDataType)
+
+// TryNextFunc is the TryNext method of a diode, as seen by a Middleware.
+type TryNextFunc func() (GenericDataType, bool)
+
+// Middleware decorates the methods of a diode. Set and TryNext each wrap the
+// next function in the chain, which ends at the wrapped diode, and either of
+// them may be nil to leave the method alone. The end of the stream marker of
+// Close is handed past every middleware, so middlewares only ever see data.
+type Middleware struct {
+	Set     func(next SetFunc) SetFunc
+	TryNext func(next TryNextFunc) TryNextFunc
+}
+
+// Wrapped is a diode decorated by middlewares (see Wrap).
+type Wrapped struct {
+	d       Diode
+	set     SetFunc
+	tryNext TryNextFunc
+	closed  atomic.Bool
+}
+
+var _ Diode = (*Wrapped)(nil)
+
+// Wrap returns the given diode decorated by the middlewares. The first
+// middleware is the outermost one: its Set is invoked first and its TryNext
+// sees the value last.
+func Wrap(d Diode, mw ...Middleware) *Wrapped {
+	w := &Wrapped{d: d}
+
+	w.set = d.Set
+	w.tryNext = w.readData
+	for i := len(mw) - 1; i >= 0; i-- {
+		if mw[i].Set != nil {
+			w.set = mw[i].Set(w.set)
+		}
+		if mw[i].TryNext != nil {
+			w.tryNext = mw[i].TryNext(w.tryNext)
+		}
+	}
+	return w
+}
+
+// Set invokes the Set chain of the middlewares with the given data.
+func (w *Wrapped) Set(data GenericDataType) {
+	if data == endOfStream {
+		w.d.Set(data)
+		return
+	}
+	w.set(data)
+}
+
+// TryNext invokes the TryNext chain of the middlewares.
+func (w *Wrapped) TryNext() (GenericDataType, bool) {
+	data, ok := w.tryNext()
+	if !ok && w.closed.Swap(false) {
+		return endOfStream, true
+	}
+	return data, ok
+}
+
+// readData is the end of the TryNext chain. It keeps the end of the stream
+// from the middlewares and has TryNext hand it on instead.
+func (w *Wrapped) readData() (GenericDataType, bool) {
+	data, ok := w.d.TryNext()
+	if ok && data == endOfStream {
+		w.closed.Store(true)
+		return nil, false
+	}
+	return data, ok
+}
+
+// LogMiddleware logs every value that is set or read with the given logger,
+// which helps debugging the flow of data through a diode.
+func LogMiddleware(logger *log.Logger) Middleware {
+	return Middleware{
+		Set: func(next SetFunc) SetFunc {
+			return func(data GenericDataType) {
+				logger.Printf("diodes: set %p", data)
+				next(data)
+			}
+		},
+		TryNext: func(next TryNextFunc) TryNextFunc {
+			return func() (GenericDataType, bool) {
+				data, ok := next()
+				if ok {
+					logger.Printf("diodes: read %p", data)
+				}
+				return data, ok
+			}
+		},
+	}
+}
+
+// Counters are the counts of a CountMiddleware. They are safe to read while
+// the diode is in use.
+type Counters struct {
+	// Sets is the number of values that were set.
+	Sets atomic.Uint64
+	// Reads is the number of values that were read.
+	Reads atomic.Uint64
+	// Misses is the number of reads that found no data.
+	Misses atomic.Uint64
+}
+
+// CountMiddleware counts the values that are set and read in c, e.g. to
+// export them as metrics of a diode that does not have Stats.
+func CountMiddleware(c *Counters) Middleware {
+	return Middleware{
+		Set: func(next SetFunc) SetFunc {
+			return func(data GenericDataType) {
+				c.Sets.Add(1)
+				next(data)
+			}
+		},
+		TryNext: func(next TryNextFunc) TryNextFunc {
+			return func() (GenericDataType, bool) {
+				data, ok := next()
+				if ok {
+					c.Reads.Add(1)
+				} else {
+					c.Misses.Add(1)
+				}
+				return data, ok
+			}
+		},
+	}
+}
+
+// TraceMiddleware invokes start before every Set, with "Set" as the name of
+// the operation, and the function it returns once the value was set, so that
+// every Set can be recorded as a span by the tracer of your choice. Reads are
+// only traced once they returned data, as an instant "TryNext" operation,
+// since the Poller and the Waiter retry empty reads over and over.
+func TraceMiddleware(start func(op string) (end func())) Middleware {
+	return Middleware{
+		Set: func(next SetFunc) SetFunc {
+			return func(data GenericDataType) {
+				end := start("Set")
+				next(data)
+				end()
+			}
+		},
+		TryNext: func(next TryNextFunc) TryNextFunc {
+			return func() (GenericDataType, bool) {
+				data, ok := next()
+				if ok {
+					start("TryNext")()
+				}
+				return data, ok
+			}
+		},
+	}
+}
+
+// RateLimitMiddleware drops the values that are set faster than rps values
+// per second, with bursts of up to burst values, before they reach the
+// wrapped diode. It works like WithWriteRateLimit for any diode: the alerter
+// is invoked with 1 on the writer's go-routine for every value that is
+// dropped, and a nil can be used to ignore alerts.
+func RateLimitMiddleware(rps float64, burst int, alerter Alerter) Middleware {
+	l := newWriteLimiter(rps, burst, alerter)
+	return Middleware{
+		Set: func(next SetFunc) SetFunc {
+			return func(data GenericDataType) {
+				if _, ok := l.reserve(nanotime(), 0); !ok {
+					l.limited.Add(1)
+					l.alerter.Alert(1)
+					return
+				}
+				next(data)
+			}
+		},
+	}
+}
@@ -0,0 +1,146 @@
+package diodes_test
+
+import (
+	"bytes"
+	"log"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Wrap", func() {
+	record := func(calls *[]string, name string) diodes.Middleware {
+		return diodes.Middleware{
+			Set: func(next diodes.SetFunc) diodes.SetFunc {
+				return func(data diodes.GenericDataType) {
+					*calls = append(*calls, name+" set")
+					next(data)
+				}
+			},
+			TryNext: func(next diodes.TryNextFunc) diodes.TryNextFunc {
+				return func() (diodes.GenericDataType, bool) {
+					data, ok := next()
+					*calls = append(*calls, name+" read")
+					return data, ok
+				}
+			},
+		}
+	}
+
+	It("invokes the middlewares in order", func() {
+		var calls []string
+		d := diodes.Wrap(diodes.NewOneToOne(4, nil), record(&calls, "outer"), record(&calls, "inner"))
+
+		data := 1
+		d.Set(diodes.GenericDataType(&data))
+		got, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(got)).To(Equal(1))
+
+		Expect(calls).To(Equal([]string{"outer set", "inner set", "inner read", "outer read"}))
+	})
+
+	It("leaves methods without a middleware alone", func() {
+		var calls []string
+		d := diodes.Wrap(diodes.NewOneToOne(4, nil), diodes.Middleware{
+			Set: record(&calls, "mw").Set,
+		})
+
+		data := 1
+		d.Set(diodes.GenericDataType(&data))
+		d.TryNext()
+		Expect(calls).To(Equal([]string{"mw set"}))
+	})
+
+	It("hands the end of the stream past the middlewares", func() {
+		c := &diodes.Counters{}
+		p := diodes.NewPoller(diodes.Wrap(diodes.NewOneToOne(4, nil), diodes.CountMiddleware(c)))
+
+		data := 1
+		p.Set(diodes.GenericDataType(&data))
+		p.Close()
+
+		Expect(p.Next()).ToNot(BeNil())
+		Expect(p.Next() == nil).To(BeTrue())
+		Expect(p.Closed()).To(BeTrue())
+		Expect(c.Sets.Load()).To(Equal(uint64(1)))
+		Expect(c.Reads.Load()).To(Equal(uint64(1)))
+	})
+
+	Describe("LogMiddleware", func() {
+		It("logs every value that is set and read", func() {
+			var buf bytes.Buffer
+			d := diodes.Wrap(diodes.NewOneToOne(4, nil), diodes.LogMiddleware(log.New(&buf, "", 0)))
+
+			data := 1
+			d.Set(diodes.GenericDataType(&data))
+			d.TryNext()
+			d.TryNext()
+
+			Expect(buf.String()).To(MatchRegexp(`^diodes: set 0x[0-9a-f]+\ndiodes: read 0x[0-9a-f]+\n$`))
+		})
+	})
+
+	Describe("CountMiddleware", func() {
+		It("counts sets, reads and misses", func() {
+			c := &diodes.Counters{}
+			d := diodes.Wrap(diodes.NewOneToOne(4, nil), diodes.CountMiddleware(c))
+
+			data := 1
+			d.Set(diodes.GenericDataType(&data))
+			d.Set(diodes.GenericDataType(&data))
+			d.TryNext()
+			d.TryNext()
+			d.TryNext()
+
+			Expect(c.Sets.Load()).To(Equal(uint64(2)))
+			Expect(c.Reads.Load()).To(Equal(uint64(2)))
+			Expect(c.Misses.Load()).To(Equal(uint64(1)))
+		})
+	})
+
+	Describe("TraceMiddleware", func() {
+		It("traces every Set and every read that returns data", func() {
+			var ops []string
+			d := diodes.Wrap(diodes.NewOneToOne(4, nil), diodes.TraceMiddleware(func(op string) func() {
+				ops = append(ops, "start "+op)
+				return func() {
+					ops = append(ops, "end "+op)
+				}
+			}))
+
+			data := 1
+			d.Set(diodes.GenericDataType(&data))
+			d.TryNext()
+			d.TryNext()
+
+			Expect(ops).To(Equal([]string{"start Set", "end Set", "start TryNext", "end TryNext"}))
+		})
+	})
+
+	Describe("RateLimitMiddleware", func() {
+		It("drops values beyond the rate", func() {
+			spy := newSpyAlerter()
+			d := diodes.Wrap(diodes.NewOneToOne(8, nil), diodes.RateLimitMiddleware(1, 2, spy))
+
+			for i := 0; i < 4; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+
+			var got []int
+			for {
+				data, ok := d.TryNext()
+				if !ok {
+					break
+				}
+				got = append(got, *(*int)(data))
+			}
+			Expect(got).To(Equal([]int{0, 1}))
+			Expect(spy.AlertInput.Missed).To(Receive(Equal(1)))
+			Expect(spy.AlertInput.Missed).To(Receive(Equal(1)))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package diodes_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("nil data", func() {
+	DescribeTable("is stored and read like any other value",
+		func(d diodes.Diode) {
+			v := 1
+			d.Set(nil)
+			d.Set(diodes.GenericDataType(&v))
+			d.Set(nil)
+
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(data == nil).To(BeTrue())
+
+			data, ok = d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(1))
+
+			data, ok = d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(data == nil).To(BeTrue())
+
+			_, ok = d.TryNext()
+			Expect(ok).To(BeFalse())
+		},
+		Entry("OneToOne", diodes.NewOneToOne(4, nil)),
+		Entry("ManyToOne", diodes.NewManyToOne(4, nil)),
+		Entry("ManyToMany", diodes.NewManyToMany(4, nil)),
+		Entry("SPSC", diodes.NewSPSC(4, nil)),
+	)
+
+	It("is read by a OneToMany reader", func() {
+		d := diodes.NewOneToMany(4)
+		r := d.NewReader(nil)
+		d.Set(nil)
+
+		data, ok := r.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(data == nil).To(BeTrue())
+	})
+
+	It("is told apart from the end of the stream by NextCtx", func() {
+		p := diodes.NewPoller(diodes.NewManyToOne(4, nil))
+		p.Set(nil)
+		p.Close()
+
+		data, err := p.NextCtx(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data == nil).To(BeTrue())
+
+		_, err = p.NextCtx(context.Background())
+		Expect(err).To(MatchError(diodes.ErrClosed))
+	})
+
+	It("is counted by NextN", func() {
+		dst := make([]diodes.GenericDataType, 4)
+
+		p := diodes.NewPoller(diodes.NewManyToOne(4, nil))
+		p.Set(nil)
+		p.Set(nil)
+		Expect(p.NextN(dst)).To(Equal(2))
+
+		w := diodes.NewWaiter(diodes.NewManyToOne(4, nil))
+		w.Set(nil)
+		Expect(w.NextN(dst)).To(Equal(1))
+		w.Close()
+		Expect(w.NextN(dst)).To(BeZero())
+	})
+})
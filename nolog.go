@@ -0,0 +1,7 @@
+//go:build diodes_nolog
+
+package diodes
+
+// logCollision does nothing, since the diodes_nolog build tag compiles out
+// the collision log line, along with its branch and formatting.
+func logCollision() {}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package diodes
+
+func raceReadPayload(GenericDataType) {}
@@ -0,0 +1,47 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// occupancy tracks the distribution of the number of unread values over a
+// rolling window. Values are observed by the reader, the distribution can be
+// read from any go-routine.
+type occupancy struct {
+	window    int64
+	startedAt int64
+	current   atomic.Pointer[buckets]
+	previous  atomic.Pointer[buckets]
+}
+
+func newOccupancy(window time.Duration) *occupancy {
+	o := &occupancy{
+		window:    int64(window),
+		startedAt: nanotime(),
+	}
+	o.current.Store(new(buckets))
+	o.previous.Store(new(buckets))
+	return o
+}
+
+// observe records the number of unread values. It must only be called by
+// the reader.
+func (o *occupancy) observe(unread uint64) {
+	if now := nanotime(); now-o.startedAt >= o.window {
+		o.previous.Store(o.current.Load())
+		o.current.Store(new(buckets))
+		o.startedAt = now
+	}
+
+	o.current.Load().observe(unread)
+}
+
+// fill sets the occupancy stats covering the previous and current window.
+func (o *occupancy) fill(st *Stats) {
+	previous, current := o.previous.Load(), o.current.Load()
+
+	st.OccupancyP50 = percentile(0.5, previous, current)
+	st.OccupancyP95 = percentile(0.95, previous, current)
+	st.OccupancyMax = max(previous.maximum(), current.maximum())
+}
@@ -0,0 +1,52 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// OldestAge returns how long the oldest unread value has been waiting in the
+// diode, e.g. to alert on staleness without timestamps in every payload. It
+// is 0 if there is no unread value, and always 0 unless values are stamped
+// with the time they were set, see WithEnqueueTime. It is safe to call
+// concurrently with the reader and writer.
+func (d *OneToOne) OldestAge() time.Duration {
+	return d.oldestAge(&d.buffer, d.readIndex.Load())
+}
+
+// OldestAge returns how long the oldest unread value has been waiting in the
+// diode. See OneToOne.OldestAge.
+func (d *ManyToOne) OldestAge() time.Duration {
+	return d.oldestAge(&d.buffer, d.readIndex.Load())
+}
+
+// OldestAge returns how long the oldest unread value has been waiting in the
+// diode. See OneToOne.OldestAge.
+func (d *ManyToMany) OldestAge() time.Duration {
+	return d.oldestAge(&d.buffer, d.readIndex.Load())
+}
+
+// oldestAge returns the age of the value the reader reads next, which is
+// the oldest unread value even if the writers lapped the reader, since the
+// reader skips ahead to it.
+func (c *diodeConfig) oldestAge(buffer *ring, readIndex uint64) time.Duration {
+	if !c.stampsTime() {
+		return 0
+	}
+
+	slot := buffer.peek(readIndex)
+	if slot == nil {
+		return 0
+	}
+
+	// The reader of a OneToOne diode can hand the bucket to the writer
+	// meanwhile, so its fields are loaded atomically.
+	b := (*bucket)(atomic.LoadPointer(slot))
+	if b == nil || atomic.LoadUint64(&b.seq) < readIndex {
+		return 0
+	}
+	if at := atomic.LoadInt64(&b.at); at != 0 {
+		return time.Duration(max(nanotime()-at, 0))
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ageDiode interface {
+	diodes.Diode
+	OldestAge() time.Duration
+}
+
+var _ = Describe("OldestAge()", func() {
+	DescribeTable("reports how long the oldest unread value has been waiting",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) ageDiode) {
+			d := newDiode(diodes.WithEnqueueTime())
+			Expect(d.OldestAge()).To(BeZero())
+
+			v := 1
+			d.Set(diodes.GenericDataType(&v))
+			time.Sleep(20 * time.Millisecond)
+			d.Set(diodes.GenericDataType(&v))
+			Expect(d.OldestAge()).To(BeNumerically(">=", 20*time.Millisecond))
+
+			d.TryNext()
+			Expect(d.OldestAge()).To(BeNumerically("<", 20*time.Millisecond))
+
+			d.TryNext()
+			Expect(d.OldestAge()).To(BeZero())
+		},
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) ageDiode {
+			return diodes.NewOneToOne(4, nil, opts...)
+		}),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) ageDiode {
+			return diodes.NewManyToOne(4, nil, opts...)
+		}),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) ageDiode {
+			return diodes.NewManyToMany(4, nil, opts...)
+		}),
+	)
+
+	It("is 0 unless values are stamped", func() {
+		d := diodes.NewManyToOne(4, nil)
+		v := 1
+		d.Set(diodes.GenericDataType(&v))
+		time.Sleep(time.Millisecond)
+
+		Expect(d.OldestAge()).To(BeZero())
+	})
+
+	It("is safe to call while the reader and the writer are busy", func() {
+		d := diodes.NewOneToOne(8, nil, diodes.WithEnqueueTime())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			v := 1
+			for i := 0; i < 10000; i++ {
+				d.Set(diodes.GenericDataType(&v))
+				d.TryNext()
+			}
+		}()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				Expect(d.OldestAge()).To(BeNumerically(">=", 0))
+			}
+		}
+	})
+})
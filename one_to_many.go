@@ -0,0 +1,179 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// OneToMany diode is a broadcast diode for a single writer and many readers.
+// Every reader created via NewReader has its own read position over the
+// shared ring buffer and sees every value that was set after it was
+// created, unless the writer laps it. A slow reader drops data without
+// affecting the others. Values stay in the ring buffer until they are
+// overwritten, even once every reader has read them.
+type OneToMany struct {
+	writeIndex atomic.Uint64
+	buffer     ring
+	diodeConfig
+}
+
+var _ Writer = (*OneToMany)(nil)
+
+// NewOneToMany creates a new broadcast diode meant to be used by a single
+// writer and many readers. The options can be used to enable optional
+// behavior, except for WithOccupancyTracking, WithBackpressure and
+// WithDropHandler which are not supported.
+func NewOneToMany(size int, opts ...DiodeConfigOption) *OneToMany {
+	return newOneToMany(size, newDiodeConfig(opts))
+}
+
+// NewOneToManyErr is like NewOneToMany but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots, see WithMinSize.
+func NewOneToManyErr(size int, opts ...DiodeConfigOption) (*OneToMany, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(size); err != nil {
+		return nil, err
+	}
+	return newOneToMany(size, c), nil
+}
+
+func newOneToMany(size int, c diodeConfig) *OneToMany {
+	d := &OneToMany{
+		diodeConfig: c,
+	}
+	d.buffer.init(size, &d.diodeConfig)
+
+	// Occupancy and drops are different for every reader.
+	d.occupancy = nil
+	return d
+}
+
+// Set sets the data in the next slot of the ring buffer.
+func (d *OneToMany) Set(data GenericDataType) {
+	if d.writerCheck != nil && d.writerCheck.enter() {
+		defer d.writerCheck.exit()
+	}
+
+	if !d.admitWrite(data) {
+		return
+	}
+
+	index := d.writeIndex.Load()
+	slot := d.buffer.slot(index)
+
+	newBucket := &bucket{
+		data: data,
+		seq:  index,
+	}
+	if d.stampsTime() {
+		newBucket.at = nanotime()
+	}
+	d.writeIndex.Store(index + 1)
+
+	old := atomic.SwapPointer(slot, unsafe.Pointer(newBucket))
+	d.retain(data)
+	d.release(old)
+}
+
+// Cap returns the number of slots of the diode.
+func (d *OneToMany) Cap() int {
+	return int(d.buffer.size)
+}
+
+// NewReader returns a new reader that starts reading at the next value that
+// is set. The alerter is invoked on the reader's go-routine when it notices
+// that the writer has passed it and wrote over data. A nil can be used to
+// ignore alerts. It is safe to call concurrently with Set.
+func (d *OneToMany) NewReader(alerter Alerter) *OneToManyReader {
+	r := &OneToManyReader{
+		d:       d,
+		alerter: dropAlerter(alerter),
+	}
+	r.readIndex.Store(d.writeIndex.Load())
+	return r
+}
+
+// OneToManyReader is a single reader of a OneToMany diode. It is not thread
+// safe for multiple go-routines.
+type OneToManyReader struct {
+	d         *OneToMany
+	readIndex atomic.Uint64
+	alerter   DropAlerter
+	dropped   atomic.Uint64
+}
+
+var _ Reader = (*OneToManyReader)(nil)
+
+// TryNext will attempt to read the next value for this reader. If there is
+// no data available, it will return (nil, false).
+func (r *OneToManyReader) TryNext() (data GenericDataType, ok bool) {
+	data, _, ok = r.TryNextSeq()
+	return data, ok
+}
+
+// TryNextSeq is like TryNext but also returns the sequence number of the
+// value, i.e. the number of values that were set before it. A gap between
+// the sequence numbers of two reads is exactly the number of values this
+// reader missed in between.
+func (r *OneToManyReader) TryNextSeq() (data GenericDataType, seq uint64, ok bool) {
+	readIndex := r.readIndex.Load()
+	slot := r.d.buffer.peek(readIndex)
+	if slot == nil {
+		return nil, 0, false
+	}
+
+	// Unlike the other diodes, the value is left in the slot for the other
+	// readers.
+	result := (*bucket)(atomic.LoadPointer(slot))
+	if result == nil || result.seq < readIndex {
+		return nil, 0, false
+	}
+
+	// The writer lapped this reader, which catches up the same way the
+	// OneToOne reader does.
+	var dropped uint64
+	if result.seq > readIndex {
+		dropped = result.seq - readIndex
+		readIndex = result.seq
+		r.dropped.Add(dropped)
+	}
+
+	r.readIndex.Store(readIndex + 1)
+	if dropped > 0 {
+		r.alerter.AlertDrop(newDropAlert(DropLapped, readIndex-dropped, readIndex))
+	}
+	r.d.observeRead(result)
+	raceReadPayload(result.data)
+	return result.data, result.seq, true
+}
+
+// DrainInto reads the data available to this reader into dst until either
+// dst is full or there is no more data available. It returns the number of
+// values written to dst. It does not allocate, so dst can be reused across
+// calls.
+func (r *OneToManyReader) DrainInto(dst []GenericDataType) int {
+	var n int
+	budget := readBudget{limit: r.d.drainYield}
+	for n < len(dst) {
+		data, ok := r.TryNext()
+		if !ok {
+			break
+		}
+		budget.spend()
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// Len returns the approximate number of values this reader has not read
+// yet, bounded by Cap. It is safe to call from any go-routine.
+func (r *OneToManyReader) Len() int {
+	return int(unread(r.d.writeIndex.Load(), r.readIndex.Load(), r.d.buffer.size))
+}
+
+// Dropped returns the total number of values this reader noticed were
+// overwritten before it read them. It is safe to call from any go-routine.
+func (r *OneToManyReader) Dropped() uint64 {
+	return r.dropped.Load()
+}
@@ -0,0 +1,151 @@
+package diodes_test
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OneToMany", func() {
+	var d *diodes.OneToMany
+
+	BeforeEach(func() {
+		d = diodes.NewOneToMany(5)
+	})
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	readAll := func(r *diodes.OneToManyReader) []int {
+		var got []int
+		for {
+			data, ok := r.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	It("hands every value to every reader", func() {
+		a := d.NewReader(nil)
+		b := d.NewReader(nil)
+		set(0, 3)
+
+		Expect(readAll(a)).To(Equal([]int{0, 1, 2}))
+		Expect(readAll(b)).To(Equal([]int{0, 1, 2}))
+	})
+
+	It("only hands out values set after the reader was created", func() {
+		set(0, 2)
+		r := d.NewReader(nil)
+		set(2, 4)
+
+		Expect(readAll(r)).To(Equal([]int{2, 3}))
+	})
+
+	It("drops data for slow readers without affecting fast ones", func() {
+		fastSpy := newSpyAlerter()
+		slowSpy := newSpyAlerter()
+		fast := d.NewReader(fastSpy)
+		slow := d.NewReader(slowSpy)
+
+		set(0, 4)
+		Expect(readAll(fast)).To(Equal([]int{0, 1, 2, 3}))
+		set(4, 8)
+		Expect(readAll(fast)).To(Equal([]int{4, 5, 6, 7}))
+		Expect(fast.Dropped()).To(BeZero())
+		Expect(fastSpy.AlertCalled).ToNot(Receive())
+
+		Expect(readAll(slow)).To(Equal([]int{5, 6, 7}))
+		Expect(slowSpy.AlertInput.Missed).To(Receive(Equal(5)))
+		Expect(slow.Dropped()).To(Equal(uint64(5)))
+	})
+
+	Context("checkpoints", func() {
+		It("replays the values since a checkpoint", func() {
+			r := d.NewReader(nil)
+			set(0, 2)
+			Expect(readAll(r)).To(Equal([]int{0, 1}))
+
+			c := r.Checkpoint()
+			set(2, 5)
+			Expect(readAll(r)).To(Equal([]int{2, 3, 4}))
+
+			Expect(r.Rewind(c)).To(Succeed())
+			Expect(r.Len()).To(Equal(3))
+			Expect(readAll(r)).To(Equal([]int{2, 3, 4}))
+			Expect(r.Dropped()).To(BeZero())
+		})
+
+		It("fails once the writer lapped the checkpoint", func() {
+			r := d.NewReader(nil)
+			c := r.Checkpoint()
+			set(0, 6)
+			Expect(readAll(r)).To(Equal([]int{5}))
+
+			Expect(r.Rewind(c)).To(MatchError(diodes.ErrLapped))
+			_, ok := r.TryNext()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("does not affect the other readers", func() {
+			a := d.NewReader(nil)
+			b := d.NewReader(nil)
+			c := a.Checkpoint()
+			set(0, 2)
+			readAll(a)
+			readAll(b)
+
+			Expect(a.Rewind(c)).To(Succeed())
+			Expect(readAll(a)).To(Equal([]int{0, 1}))
+			Expect(readAll(b)).To(BeEmpty())
+		})
+	})
+
+	It("reports the unread values of every reader", func() {
+		a := d.NewReader(nil)
+		b := d.NewReader(nil)
+		set(0, 3)
+		a.TryNext()
+
+		Expect(d.Cap()).To(Equal(5))
+		Expect(a.Len()).To(Equal(2))
+		Expect(b.Len()).To(Equal(3))
+	})
+
+	It("supports readers on their own go-routines", func() {
+		var readers []*diodes.OneToManyReader
+		for i := 0; i < 4; i++ {
+			readers = append(readers, d.NewReader(nil))
+		}
+
+		var wg sync.WaitGroup
+		reads := make([]uint64, len(readers))
+		for i, r := range readers {
+			wg.Add(1)
+			go func(i int, r *diodes.OneToManyReader) {
+				defer wg.Done()
+				for reads[i]+r.Dropped() < 1000 {
+					if _, ok := r.TryNext(); ok {
+						reads[i]++
+					}
+				}
+			}(i, r)
+		}
+
+		set(0, 1000)
+		wg.Wait()
+
+		for i, r := range readers {
+			Expect(reads[i] + r.Dropped()).To(Equal(uint64(1000)))
+		}
+	})
+})
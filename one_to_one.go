@@ -1,15 +1,27 @@
 package diodes
 
 import (
+	"context"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
-// GenericDataType is the data type the diodes operate on.
+// GenericDataType is the data type the diodes operate on. Nil is legitimate
+// data: the diodes wrap every value in a bucket with its sequence number, so
+// an empty slot is never confused with a nil value. Only Next of the Poller
+// and the Waiter return nil for the end of the stream as well, which NextCtx
+// tells apart.
 type GenericDataType unsafe.Pointer
 
 // Alerter is used to report how many values were overwritten since the
-// last write.
+// last write. It is invoked by the reader once its read is complete, so it
+// may set values on the same diode, e.g. a marker for the dropped values,
+// as long as the reader's go-routine is allowed to write to the diode: any
+// diode with many writers, or a OneToOne or SPSC diode whose reader is also
+// its writer. With WithBackpressure, such a Set on a full diode waits out the
+// timeout, since the reader it waits for is the one setting the value. See
+// DropAlerter for an alerter that is also told why values were dropped.
 type Alerter interface {
 	Alert(missed int)
 }
@@ -23,60 +35,298 @@ func (f AlertFunc) Alert(missed int) {
 	f(missed)
 }
 
+// bucket keeps seq as its first field, since the OneToOne diode accesses it
+// atomically and only the first word of an allocated struct is guaranteed
+// to be 64-bit aligned on 32-bit platforms.
 type bucket struct {
-	data GenericDataType
 	seq  uint64 // seq is the recorded write index at the time of writing
+	at   int64  // at is the nanotime at the time of writing, if enabled
+	data GenericDataType
 }
 
 // OneToOne diode is meant to be used by a single reader and a single writer.
-// It is not thread safe if used otherwise.
+// It is not thread safe if used otherwise. Further readers can follow the
+// same stream at their own speed via NewCursor.
 type OneToOne struct {
-	buffer     []unsafe.Pointer
-	writeIndex uint64
-	readIndex  uint64
-	alerter    Alerter
+	writeIndex atomic.Uint64
+	_          cacheLinePad
+	readIndex  atomic.Uint64
+	_          cacheLinePad
+	buffer     ring
+	buckets    *bucketPool
+	alerter    DropAlerter
+	// cursors is set once NewCursor was called. Reads then leave the values
+	// in the slots for the cursors and stop recycling the buckets.
+	cursors atomic.Bool
+	diodeConfig
+	diodeStats
 }
 
+var _ Diode = (*OneToOne)(nil)
+
 // NewOneToOne creates a new diode is meant to be used by a single reader and
 // a single writer. The alerter is invoked on the read's go-routine. It is
 // called when it notices that the writer go-routine has passed it and wrote
-// over data. A nil can be used to ignore alerts.
-func NewOneToOne(size int, alerter Alerter) *OneToOne {
-	if alerter == nil {
-		alerter = AlertFunc(func(int) {})
+// over data. A nil can be used to ignore alerts. The options can be used to
+// enable optional behavior.
+func NewOneToOne(size int, alerter Alerter, opts ...DiodeConfigOption) *OneToOne {
+	return newOneToOne(size, alerter, newDiodeConfig(opts))
+}
+
+// NewOneToOneErr is like NewOneToOne but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots, see WithMinSize.
+func NewOneToOneErr(size int, alerter Alerter, opts ...DiodeConfigOption) (*OneToOne, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(size); err != nil {
+		return nil, err
 	}
+	return newOneToOne(size, alerter, c), nil
+}
 
-	return &OneToOne{
-		buffer:  make([]unsafe.Pointer, size),
-		alerter: alerter,
+func newOneToOne(size int, alerter Alerter, c diodeConfig) *OneToOne {
+	d := &OneToOne{
+		alerter:     dropAlerter(alerter),
+		diodeConfig: c,
 	}
+	d.buffer.init(size, &d.diodeConfig)
+	d.buckets = newBucketPool(int(d.buffer.size))
+	d.diodeStats.init(time.Now(), d.rateHalfLife)
+	return d
 }
 
-// Set sets the data in the next slot of the ring buffer.
+// Set sets the data in the next slot of the ring buffer. It reuses the
+// buckets the reader is done with, so it does not allocate once the diode is
+// in use, unless the reader falls behind.
 func (d *OneToOne) Set(data GenericDataType) {
-	idx := d.writeIndex % uint64(len(d.buffer))
+	d.setChecked(nil, data, nil)
+}
+
+// SetLazy sets the value that f returns like Set, but only invokes f on the
+// writer's go-routine once the value is kept, so that no work is done for
+// values the rate limit, WithDropNewest or the sampler drop right away.
+func (d *OneToOne) SetLazy(f func() GenericDataType) {
+	d.setChecked(nil, unbuilt, f)
+}
+
+// setChecked sets the data unless it is dropped by the write policies. If
+// the data is unbuilt, f constructs it once the value is kept. A non-nil ctx
+// bounds the wait for the reader, see SetCtx.
+func (d *OneToOne) setChecked(ctx context.Context, data GenericDataType, f func() GenericDataType) error {
+	if d.writerCheck != nil && d.writerCheck.enter() {
+		defer d.writerCheck.exit()
+	}
+
+	if !d.admitWrite(data) {
+		return nil
+	}
+	if d.byteLimit != nil {
+		data, f = built(data, f), nil
+		if d.overBytes(data) {
+			return nil
+		}
+	}
+
+	if d.dropsNewest(data) {
+		err := d.awaitReader(ctx, d.writeIndex.Load(), &d.readIndex, d.buffer.size)
+		if d.full() {
+			d.discard(data)
+			return err
+		}
+	} else if d.sampler != nil {
+		// SetCtx waits for room before the sampler decides.
+		var err error
+		if ctx != nil {
+			err = d.awaitReader(ctx, d.writeIndex.Load(), &d.readIndex, d.buffer.size)
+		}
+		if d.sampleOut(d.full(), data) {
+			return err
+		}
+	}
+	return d.set(ctx, built(data, f))
+}
+
+// SetCtx sets the data like Set, but while the diode is full it waits for
+// the reader to make room until the context is done, whether or not
+// WithBackpressure is used, so that a writer serving a request can bound
+// the wait by the request's deadline. If the context is done first, the
+// data is handled like Set handles it on a full diode: it overwrites the
+// oldest unread value, or is discarded with WithDropNewest, and the error
+// of the context is returned, matching ErrTimeout if its deadline passed.
+func (d *OneToOne) SetCtx(ctx context.Context, data GenericDataType) error {
+	return d.setChecked(ctx, data, nil)
+}
+
+// TrySet sets the data like Set, unless the diode is full. It returns false
+// without setting the data if it would overwrite a value that was not read
+// yet, or if the rate limit of WithWriteRateLimit drops it, so that writers
+// can shed load before doing more work for it.
+func (d *OneToOne) TrySet(data GenericDataType) bool {
+	if d.writerCheck != nil && d.writerCheck.enter() {
+		defer d.writerCheck.exit()
+	}
+
+	if d.full() || !d.admitWrite(data) || d.overBytes(data) {
+		return false
+	}
+	d.set(nil, data)
+	return true
+}
+
+// full reports whether the next value would overwrite unread data.
+func (d *OneToOne) full() bool {
+	return d.writeIndex.Load() >= d.readIndex.Load()+d.buffer.size
+}
 
-	newBucket := &bucket{
-		data: data,
-		seq:  d.writeIndex,
+// set stores the data in the slot of the write index. A non-nil ctx bounds
+// the wait for the reader, see SetCtx.
+func (d *OneToOne) set(ctx context.Context, data GenericDataType) error {
+	index := d.writeIndex.Load()
+	slot := d.buffer.slot(index)
+
+	newBucket := d.buckets.get()
+	if newBucket == nil {
+		newBucket = new(bucket)
+	}
+	newBucket.data = data
+	// The seq and the time are stored atomically since Snapshot and
+	// OldestAge can still be reading them from a reused bucket.
+	atomic.StoreUint64(&newBucket.seq, index)
+	var at int64
+	if d.stampsTime() {
+		at = nanotime()
 	}
-	d.writeIndex++
+	atomic.StoreInt64(&newBucket.at, at)
+	err := d.awaitReader(ctx, index, &d.readIndex, d.buffer.size)
+	d.writeIndex.Store(index + 1)
 
-	atomic.StorePointer(&d.buffer[idx], unsafe.Pointer(newBucket))
+	old, data, merged := d.swapMerge(slot, newBucket, d.readIndex.Load())
+	d.retain(data)
+	d.release(old)
+	if !merged && !d.wasRead(old) {
+		d.drop(old)
+	}
+	if d.watermarks != nil || d.stall != nil {
+		readIndex := d.readIndex.Load()
+		d.observeBacklog(index+1, readIndex, d.buffer.size)
+		d.observeStall(index+1, readIndex, d.buffer.size)
+	}
+	return err
 }
 
 // TryNext will attempt to read from the next slot of the ring buffer.
 // If there is no data available, it will return (nil, false).
 func (d *OneToOne) TryNext() (data GenericDataType, ok bool) {
-	// Read a value from the ring buffer based on the readIndex.
-	idx := d.readIndex % uint64(len(d.buffer))
-	result := (*bucket)(atomic.SwapPointer(&d.buffer[idx], nil))
+	b, _, ok := d.next()
+	if !ok {
+		return nil, false
+	}
+	data = b.data
+	d.recycle(b)
+	return data, true
+}
+
+// TryNextLatency is like TryNext but also returns how long the value waited
+// in the diode since it was set. The latency is only measured with
+// WithEnqueueTime or WithDwellHistogram and is 0 otherwise.
+func (d *OneToOne) TryNextLatency() (data GenericDataType, latency time.Duration, ok bool) {
+	b, _, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	data, latency = b.data, d.latency(b)
+	d.recycle(b)
+	return data, latency, true
+}
+
+// TryNextWithDrop is like TryNext but also returns how many values were
+// dropped right before the value it read, i.e. the gap the alerter is told
+// about, so that consumers can mark the gap at its exact position in their
+// output. The alerter is still invoked.
+func (d *OneToOne) TryNextWithDrop() (data GenericDataType, dropped int, ok bool) {
+	b, missed, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	data = b.data
+	d.recycle(b)
+	return data, int(missed), true
+}
+
+// TryNextSeq is like TryNext but also returns the sequence number of the
+// value, i.e. the number of values that were set before it. A gap between
+// the sequence numbers of two reads is exactly the number of values that
+// were dropped in between, which lets consumers report and deduplicate
+// precisely, e.g. after a restart. Values discarded by WithDropNewest do
+// not get a sequence number.
+func (d *OneToOne) TryNextSeq() (data GenericDataType, seq uint64, ok bool) {
+	b, _, ok := d.next()
+	if !ok {
+		return nil, 0, false
+	}
+	data, seq = b.data, b.seq
+	d.recycle(b)
+	return data, seq, true
+}
+
+// next reads the bucket of the next value that did not expire (see
+// WithMaxAge) along with the number of values that were dropped right before
+// it. The caller puts the bucket back into the pool once it is done with it.
+func (d *OneToOne) next() (result *bucket, dropped uint64, ok bool) {
+	for {
+		b, n, ok := d.readBucket()
+		dropped += n
+		if !ok {
+			return nil, dropped, false
+		}
+		if d.expire(b) {
+			d.alerter.AlertDrop(newDropAlert(DropExpired, b.seq, b.seq+1))
+			d.recycle(b)
+			continue
+		}
+
+		d.reads.Add(1)
+		d.observeRead(b)
+		raceReadPayload(b.data)
+		return b, dropped, true
+	}
+}
+
+// readBucket reads the bucket of the next value along with the number of
+// values that were dropped right before it.
+func (d *OneToOne) readBucket() (result *bucket, dropped uint64, ok bool) {
+	if n := d.takeDiscarded(); n > 0 {
+		d.addDropped(n)
+		d.observeDrops(n)
+		d.alerter.AlertDrop(discardedAlert(n))
+	}
+
+	readIndex := d.readIndex.Load()
+	nextWrite := d.writeIndex.Load()
+	d.observeUnread(nextWrite, readIndex, d.buffer.size)
+
+	// Read a value from the ring buffer based on the readIndex. A slot
+	// without a segment has never been written to.
+	slot := d.buffer.peek(readIndex)
+	if slot == nil {
+		return nil, 0, false
+	}
+	keep := d.cursors.Load()
+	if keep {
+		// The cursors may still read the value, so it is left in the slot
+		// until it is overwritten, like in a OneToMany diode.
+		result = (*bucket)(atomic.LoadPointer(slot))
+	} else {
+		result = (*bucket)(atomic.SwapPointer(slot, nil))
+	}
 
 	// When the result is nil that means the writer has not had the
 	// opportunity to write a value into the diode. This value must be ignored
 	// and the read head must not increment.
 	if result == nil {
-		return nil, false
+		return nil, 0, false
+	}
+	if !keep {
+		d.release(unsafe.Pointer(result))
 	}
 
 	// When the seq value is less than the current read index that means a
@@ -92,8 +342,9 @@ func (d *OneToOne) TryNext() (data GenericDataType, ok bool) {
 	//    effectively "dropped" so the read fails and the read head stays put.
 	//    `| 4 | 5 | 2 | 3 |` r: 7, w: 6
 	//
-	if result.seq < d.readIndex {
-		return nil, false
+	if result.seq < readIndex {
+		d.recycle(result)
+		return nil, 0, false
 	}
 
 	// When the seq value is greater than the current read index that means a
@@ -115,15 +366,137 @@ func (d *OneToOne) TryNext() (data GenericDataType, ok bool) {
 	//    this forces the reader to fast forward to 5.
 	//    `| 4 | 5 | 2 | 3 |` r: 5, w: 6
 	//
-	if result.seq > d.readIndex {
-		dropped := result.seq - d.readIndex
-		d.readIndex = result.seq
-		d.alerter.Alert(int(dropped))
+	if result.seq > readIndex {
+		dropped = result.seq - readIndex
+		readIndex = result.seq
+		d.addDropped(dropped)
+		d.observeDrops(dropped)
 	}
 
 	// Only increment read index if a regular read occurred (where seq was
 	// equal to readIndex) or a value was read that caused a fast forward
 	// (where seq was greater than readIndex).
-	d.readIndex++
+	d.readIndex.Store(readIndex + 1)
+	d.observeBacklog(nextWrite, readIndex+1, d.buffer.size)
+
+	// The alerter is invoked once the read is complete, so that it can set
+	// values on the diode, e.g. a marker for the dropped values.
+	if dropped > 0 {
+		d.alerter.AlertDrop(newDropAlert(DropLapped, readIndex-dropped, readIndex))
+	}
+	return result, dropped, true
+}
+
+// recycle puts the bucket back into the pool, unless cursors may still read
+// it.
+func (d *OneToOne) recycle(b *bucket) {
+	if !d.cursors.Load() {
+		d.buckets.put(b)
+	}
+}
+
+// wasRead reports whether the bucket old points to, which the writer took out
+// of its slot, holds a value the reader already read. Only the slots of a
+// diode with cursors keep the values that were read.
+func (d *OneToOne) wasRead(old unsafe.Pointer) bool {
+	return old != nil && d.cursors.Load() && (*bucket)(old).seq < d.readIndex.Load()
+}
+
+// Peek returns the value TryNext would read next without reading it. If
+// there is no data available, it will return (nil, false). The writer can
+// still overwrite the value after Peek returned it, in which case TryNext
+// returns a newer value instead.
+func (d *OneToOne) Peek() (data GenericDataType, ok bool) {
+	return peekNext(&d.buffer, d.readIndex.Load())
+}
+
+// peekNext returns the value in the slot of the read index, unless the slot
+// is empty or holds a stale value or a failed write that the reader will
+// skip.
+func peekNext(buffer *ring, readIndex uint64) (GenericDataType, bool) {
+	slot := buffer.peek(readIndex)
+	if slot == nil {
+		return nil, false
+	}
+
+	result := (*bucket)(atomic.LoadPointer(slot))
+	if result == nil || result.seq < readIndex || result.data == failedWrite {
+		return nil, false
+	}
+
+	raceReadPayload(result.data)
 	return result.data, true
 }
+
+// DrainInto reads the available data into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls.
+func (d *OneToOne) DrainInto(dst []GenericDataType) int {
+	var n int
+	budget := readBudget{limit: d.drainYield}
+	for n < len(dst) {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		budget.spend()
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// Stats returns a snapshot of the diode's counters. It is safe to call
+// concurrently with the reader and writer. The rates are updated every time
+// Stats is called.
+func (d *OneToOne) Stats() Stats {
+	var st Stats
+	d.ReadStatsInto(&st)
+	d.updateRates(&st)
+	return st
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero. It only loads atomics, so it neither allocates nor takes a
+// lock, which makes it cheap to call at a high frequency, e.g. from a metrics
+// scraper, while the reader and writer keep going.
+func (d *OneToOne) ReadStatsInto(st *Stats) {
+	d.fillCounters(st, d.writeIndex.Load())
+	st.Lag = unread(st.Writes, d.readIndex.Load(), d.buffer.size)
+	d.fillStats(st)
+	d.buffer.fillStats(st)
+}
+
+// Len returns the approximate number of unread values, bounded by Cap. It
+// is safe to call concurrently with the reader and writer.
+func (d *OneToOne) Len() int {
+	return int(unread(d.writeIndex.Load(), d.readIndex.Load(), d.buffer.size))
+}
+
+// drainMark implements drainMarker.
+func (d *OneToOne) drainMark() uint64 {
+	return d.writeIndex.Load()
+}
+
+// drained implements drainMarker.
+func (d *OneToOne) drained(mark uint64) bool {
+	return d.readIndex.Load() >= mark
+}
+
+// Pressure returns how close the diode is to dropping values, between 0
+// and 1. See ManyToOne.Pressure.
+func (d *OneToOne) Pressure() float64 {
+	return d.pressure(unread(d.writeIndex.Load(), d.readIndex.Load(), d.buffer.size), d.buffer.size)
+}
+
+// Cap returns the number of slots of the diode.
+func (d *OneToOne) Cap() int {
+	return int(d.buffer.size)
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read. It is counted whether or not an
+// alerter is installed and is safe to call from any go-routine.
+func (d *OneToOne) Dropped() uint64 {
+	return d.dropped.Load()
+}
@@ -1,6 +1,11 @@
 package diodes_test
 
 import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
 	"code.cloudfoundry.org/go-diodes"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -55,6 +60,57 @@ var _ = Describe("OneToOne", func() {
 			})
 		})
 
+		Describe("Peek()", func() {
+			It("returns the next value without reading it", func() {
+				result, ok := d.Peek()
+				Expect(ok).To(BeTrue())
+				Expect(*(*[]byte)(result)).To(Equal(data))
+
+				result, ok = d.TryNext()
+				Expect(ok).To(BeTrue())
+				Expect(*(*[]byte)(result)).To(Equal(data))
+
+				result, ok = d.Peek()
+				Expect(ok).To(BeTrue())
+				Expect(*(*[]byte)(result)).To(Equal(secondData))
+			})
+
+			It("returns false once every value was read", func() {
+				d.TryNext()
+				d.TryNext()
+
+				_, ok := d.Peek()
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Describe("DrainInto()", func() {
+			It("fills the slice with the available data", func() {
+				dst := make([]diodes.GenericDataType, 5)
+
+				Expect(d.DrainInto(dst)).To(Equal(2))
+				Expect(*(*[]byte)(dst[0])).To(Equal(data))
+				Expect(*(*[]byte)(dst[1])).To(Equal(secondData))
+			})
+
+			It("does not exceed the length of the slice", func() {
+				dst := make([]diodes.GenericDataType, 1)
+
+				Expect(d.DrainInto(dst)).To(Equal(1))
+				Expect(*(*[]byte)(dst[0])).To(Equal(data))
+				Expect(d.DrainInto(dst)).To(Equal(1))
+				Expect(*(*[]byte)(dst[0])).To(Equal(secondData))
+			})
+
+			It("does not allocate", func() {
+				dst := make([]diodes.GenericDataType, 5)
+
+				Expect(testing.AllocsPerRun(10, func() {
+					d.DrainInto(dst)
+				})).To(BeZero())
+			})
+		})
+
 		Context("buffer size exceeded", func() {
 			BeforeEach(func() {
 				for i := 0; i < 4; i++ {
@@ -109,6 +165,17 @@ var _ = Describe("OneToOne", func() {
 				})
 			})
 
+			Context("nil data", func() {
+				It("is returned as is", func() {
+					d = diodes.NewOneToOne(5, nil)
+					d.Set(nil)
+
+					data, ok := d.TryNext()
+					Expect(ok).To(BeTrue())
+					Expect(data == nil).To(BeTrue())
+				})
+			})
+
 			Context("writer laps reader with nil alerter", func() {
 				It("drops the alert", func() {
 					d = diodes.NewOneToOne(5, nil)
@@ -126,6 +193,502 @@ var _ = Describe("OneToOne", func() {
 
 })
 
+var _ = Describe("OneToOne with a dwell histogram", func() {
+	It("records how long each value waited", func() {
+		h := diodes.NewHistogram()
+		d := diodes.NewOneToOne(5, nil, diodes.WithDwellHistogram(h))
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+		d.TryNext()
+
+		Expect(h.Count()).To(Equal(uint64(1)))
+		Expect(h.Percentile(1)).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+
+	It("does not record failed reads", func() {
+		h := diodes.NewHistogram()
+		d := diodes.NewOneToOne(5, nil, diodes.WithDwellHistogram(h))
+
+		d.TryNext()
+
+		Expect(h.Count()).To(BeZero())
+	})
+	It("reports the dwell time via Stats", func() {
+		h := diodes.NewHistogram()
+		d := diodes.NewOneToOne(5, nil, diodes.WithDwellHistogram(h))
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+		d.TryNext()
+
+		st := d.Stats()
+		Expect(st.DwellP50).To(BeNumerically(">=", 10*time.Millisecond))
+		Expect(st.DwellP99).To(Equal(st.DwellP50))
+		Expect(st.DwellMax).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+})
+
+var _ = Describe("OneToOne TryNextWithDrop()", func() {
+	It("reports the gap right before the value it reads", func() {
+		spy := newSpyAlerter()
+		d := diodes.NewOneToOne(4, spy)
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		data, dropped, ok := d.TryNextWithDrop()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(8))
+		Expect(dropped).To(Equal(8))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(8)))
+
+		data, dropped, ok = d.TryNextWithDrop()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(9))
+		Expect(dropped).To(BeZero())
+
+		_, _, ok = d.TryNextWithDrop()
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("OneToOne with enqueue times", func() {
+	It("reports how long each value waited", func() {
+		d := diodes.NewOneToOne(5, nil, diodes.WithEnqueueTime())
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		time.Sleep(10 * time.Millisecond)
+
+		v, latency, ok := d.TryNextLatency()
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(diodes.GenericDataType(&data)))
+		Expect(latency).To(BeNumerically(">=", 10*time.Millisecond))
+
+		_, _, ok = d.TryNextLatency()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports no latency without stamping the values", func() {
+		d := diodes.NewOneToOne(5, nil)
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+
+		_, latency, ok := d.TryNextLatency()
+		Expect(ok).To(BeTrue())
+		Expect(latency).To(BeZero())
+	})
+})
+
+var _ = Describe("OneToOne Stats()", func() {
+	It("counts writes and reads", func() {
+		d := diodes.NewOneToOne(5, nil)
+		for i := 0; i < 7; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		d.TryNext()
+
+		stats := d.Stats()
+		Expect(stats.Writes).To(Equal(uint64(7)))
+		Expect(stats.Reads).To(Equal(uint64(2)))
+	})
+
+	It("reports the lag of the reader up to the capacity", func() {
+		d := diodes.NewOneToOne(5, nil)
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		Expect(d.Stats().Lag).To(Equal(uint64(2)))
+
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.Stats().Lag).To(Equal(uint64(5)))
+	})
+
+	It("reports write and read rates", func() {
+		d := diodes.NewOneToOne(5, nil)
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		d.TryNext()
+		time.Sleep(time.Millisecond)
+
+		stats := d.Stats()
+		Expect(stats.WriteRate).To(BeNumerically(">", 0))
+		Expect(stats.ReadRate).To(BeNumerically(">", 0))
+	})
+
+	It("counts drops without an alerter", func() {
+		d := diodes.NewOneToOne(5, nil)
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+
+		Expect(d.Stats().Dropped).To(Equal(uint64(5)))
+	})
+
+	It("reports a drop rate that decays with the configured half-life", func() {
+		d := diodes.NewOneToOne(5, nil, diodes.WithRateHalfLife(time.Millisecond))
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		time.Sleep(time.Millisecond)
+
+		burst := d.Stats().DropRate
+		Expect(burst).To(BeNumerically(">", 0))
+
+		time.Sleep(20 * time.Millisecond)
+		Expect(d.Stats().DropRate).To(BeNumerically("<", burst/100))
+	})
+
+	It("reports occupancy percentiles when tracking is enabled", func() {
+		d := diodes.NewOneToOne(5, nil, diodes.WithOccupancyTracking(time.Minute))
+		for i := 0; i < 4; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		for i := 0; i < 5; i++ {
+			d.TryNext()
+		}
+
+		stats := d.Stats()
+		Expect(stats.OccupancyP50).To(Equal(uint64(2)))
+		Expect(stats.OccupancyP95).To(Equal(uint64(4)))
+		Expect(stats.OccupancyMax).To(Equal(uint64(4)))
+	})
+
+	It("forgets occupancy older than two windows", func() {
+		d := diodes.NewOneToOne(5, nil, diodes.WithOccupancyTracking(5*time.Millisecond))
+		for i := 0; i < 4; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.DrainInto(make([]diodes.GenericDataType, 5))
+		Expect(d.Stats().OccupancyMax).To(Equal(uint64(4)))
+
+		time.Sleep(5 * time.Millisecond)
+		d.TryNext()
+		time.Sleep(5 * time.Millisecond)
+		d.TryNext()
+
+		Expect(d.Stats().OccupancyMax).To(BeZero())
+	})
+
+	It("does not report occupancy when tracking is disabled", func() {
+		d := diodes.NewOneToOne(5, nil)
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		d.TryNext()
+
+		Expect(d.Stats().OccupancyMax).To(BeZero())
+	})
+
+	It("does not count failed reads", func() {
+		d := diodes.NewOneToOne(5, nil)
+		d.TryNext()
+
+		Expect(d.Stats().Reads).To(BeZero())
+	})
+})
+
+var _ = Describe("OneToOne with segments", func() {
+	It("reads and wraps like a single slice", func() {
+		d := diodes.NewOneToOne(10, nil, diodes.WithSegmentSize(4))
+		for i := 0; i < 25; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.CheckInvariants()).To(Succeed())
+
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				break
+			}
+			got = append(got, *(*int)(data))
+		}
+		Expect(got).To(Equal([]int{20, 21, 22, 23, 24}))
+		Expect(d.Dropped()).To(Equal(uint64(20)))
+	})
+
+	It("does not read from segments that were never written", func() {
+		d := diodes.NewOneToOne(1000, nil, diodes.WithSegmentSize(16))
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reports how many slots are allocated", func() {
+		d := diodes.NewOneToOne(40, nil, diodes.WithSegmentSize(16))
+		Expect(d.Stats().AllocatedSlots).To(BeZero())
+
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		Expect(d.Stats().AllocatedSlots).To(Equal(uint64(16)))
+	})
+
+	It("reports every slot as allocated without segments", func() {
+		d := diodes.NewOneToOne(40, nil)
+		Expect(d.Stats().Capacity).To(Equal(uint64(40)))
+		Expect(d.Stats().AllocatedSlots).To(Equal(uint64(40)))
+	})
+})
+
+var _ = Describe("OneToOne with a sizer", func() {
+	byteSize := func(data diodes.GenericDataType) int {
+		return len(*(*[]byte)(data))
+	}
+
+	It("tracks the bytes retained by the diode", func() {
+		d := diodes.NewOneToOne(3, nil, diodes.WithSizer(byteSize))
+		for _, s := range []string{"a", "bb", "ccc"} {
+			b := []byte(s)
+			d.Set(diodes.GenericDataType(&b))
+		}
+		Expect(d.Stats().RetainedBytes).To(Equal(uint64(6)))
+
+		d.TryNext()
+		Expect(d.Stats().RetainedBytes).To(Equal(uint64(5)))
+	})
+
+	It("releases the bytes of overwritten values", func() {
+		d := diodes.NewOneToOne(2, nil, diodes.WithSizer(byteSize))
+		for _, s := range []string{"aaaa", "bbbb", "c", "d"} {
+			b := []byte(s)
+			d.Set(diodes.GenericDataType(&b))
+		}
+		Expect(d.Stats().RetainedBytes).To(Equal(uint64(2)))
+
+		d.TryNext()
+		d.TryNext()
+		Expect(d.Stats().RetainedBytes).To(BeZero())
+	})
+
+	It("does not track bytes without a sizer", func() {
+		d := diodes.NewOneToOne(2, nil)
+		b := []byte("some-data")
+		d.Set(diodes.GenericDataType(&b))
+		Expect(d.Stats().RetainedBytes).To(BeZero())
+	})
+})
+
+var _ = Describe("OneToOne with a drain yield", func() {
+	It("yields to other go-routines while draining", func() {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+		d := diodes.NewOneToOne(100, nil, diodes.WithDrainYield(10))
+		for i := 0; i < 100; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		var ran atomic.Bool
+		go ran.Store(true)
+
+		dst := make([]diodes.GenericDataType, 100)
+		Expect(d.DrainInto(dst)).To(Equal(100))
+		Expect(ran.Load()).To(BeTrue())
+	})
+})
+
+var _ = Describe("OneToOne with backpressure", func() {
+	It("makes writers wait for a slow reader instead of dropping", func() {
+		d := diodes.NewOneToOne(5, nil, diodes.WithBackpressure(time.Minute))
+		go func() {
+			for i := 0; i < 100; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+		}()
+
+		var got []int
+		for len(got) < 100 {
+			data, ok := d.TryNext()
+			if !ok {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			got = append(got, *(*int)(data))
+		}
+
+		for i, v := range got {
+			Expect(v).To(Equal(i))
+		}
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("overwrites data once the timeout passes", func() {
+		d := diodes.NewOneToOne(2, nil, diodes.WithBackpressure(10*time.Millisecond))
+		for i := 0; i < 2; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		start := time.Now()
+		j := 2
+		d.Set(diodes.GenericDataType(&j))
+		Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(2))
+		Expect(d.Dropped()).To(Equal(uint64(2)))
+	})
+})
+
+var _ = Describe("OneToOne with a drop handler", func() {
+	var (
+		d       *diodes.OneToOne
+		dropped []int
+	)
+
+	BeforeEach(func() {
+		dropped = nil
+		d = diodes.NewOneToOne(2, nil, diodes.WithDropHandler(diodes.DropFunc(func(data diodes.GenericDataType) {
+			dropped = append(dropped, *(*int)(data))
+		})))
+	})
+
+	It("hands out the values that were overwritten", func() {
+		for i := 0; i < 5; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(dropped).To(Equal([]int{0, 1, 2}))
+	})
+
+	It("does not hand out values that were read", func() {
+		for i := 0; i < 5; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+			d.TryNext()
+		}
+
+		Expect(dropped).To(BeEmpty())
+	})
+
+	It("does not hand out an overwritten end of stream marker", func() {
+		diodes.NewPoller(d).Close()
+		for i := 0; i < 2; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(dropped).To(BeEmpty())
+	})
+})
+
+var _ = Describe("OneToOne TrySet()", func() {
+	It("does not overwrite unread values", func() {
+		d := diodes.NewOneToOne(3, nil)
+		for i := 0; i < 4; i++ {
+			j := i
+			Expect(d.TrySet(diodes.GenericDataType(&j))).To(Equal(i < 3))
+		}
+		Expect(d.Stats().Writes).To(Equal(uint64(3)))
+
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+
+		j := 3
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeTrue())
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("reports values dropped by the rate limit", func() {
+		d := diodes.NewOneToOne(3, nil, diodes.WithWriteRateLimit(1, 1, nil))
+		j := 0
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeTrue())
+		Expect(d.TrySet(diodes.GenericDataType(&j))).To(BeFalse())
+		Expect(d.Stats().RateLimited).To(Equal(uint64(1)))
+	})
+})
+
+var _ = Describe("OneToOne Dropped()", func() {
+	It("counts drops regardless of the alerter", func() {
+		spy := newSpyAlerter()
+		for _, alerter := range []diodes.Alerter{nil, spy} {
+			d := diodes.NewOneToOne(5, alerter)
+			for i := 0; i < 10; i++ {
+				j := i
+				d.Set(diodes.GenericDataType(&j))
+			}
+			Expect(d.Dropped()).To(BeZero())
+
+			d.TryNext()
+			Expect(d.Dropped()).To(Equal(uint64(5)))
+		}
+	})
+})
+
+var _ = Describe("OneToOne Set()", func() {
+	It("does not allocate once the diode is in use", func() {
+		d := diodes.NewOneToOne(5, nil)
+		data := []byte("some-data")
+		d.Set(diodes.GenericDataType(&data))
+		d.TryNext()
+
+		Expect(testing.AllocsPerRun(100, func() {
+			d.Set(diodes.GenericDataType(&data))
+			d.TryNext()
+		})).To(BeZero())
+	})
+
+	It("does not mix up values when it reuses buckets", func() {
+		d := diodes.NewOneToOne(16, nil)
+		values := make([]int, 10000)
+		for i := range values {
+			values[i] = i
+		}
+
+		go func() {
+			for i := range values {
+				d.Set(diodes.GenericDataType(&values[i]))
+			}
+		}()
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					d.Snapshot()
+				}
+			}
+		}()
+
+		last := -1
+		for last < len(values)-1 {
+			data, ok := d.TryNext()
+			if !ok {
+				runtime.Gosched()
+				continue
+			}
+			Expect(*(*int)(data)).To(BeNumerically(">", last))
+			last = *(*int)(data)
+		}
+	})
+})
+
 var _ = Describe("reader ahead of writer", func() {
 	It("must not occur after alerting", func() {
 		length := 4
@@ -174,3 +737,24 @@ func (m *spyAlerter) Alert(missed int) {
 	m.AlertCalled <- true
 	m.AlertInput.Missed <- missed
 }
+
+var _ = Describe("OneToOne Len() and Cap()", func() {
+	It("reports the unread values up to the capacity", func() {
+		d := diodes.NewOneToOne(5, nil)
+		Expect(d.Cap()).To(Equal(5))
+		Expect(d.Len()).To(BeZero())
+
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+		Expect(d.Len()).To(Equal(2))
+
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		Expect(d.Len()).To(Equal(5))
+	})
+})
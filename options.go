@@ -0,0 +1,445 @@
+package diodes
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// DiodeConfigOption can be used to setup a OneToOne or ManyToOne diode.
+type DiodeConfigOption func(*diodeConfig)
+
+// diodeConfig holds the optional behavior that is shared by the diodes.
+type diodeConfig struct {
+	dwell        *Histogram
+	onLatency    func(time.Duration)
+	enqueueTime  bool
+	rateHalfLife time.Duration
+	occupancy    *occupancy
+	onWriterLeak func(stack string)
+	segmentSize  int
+	prefault     bool
+	powerOfTwo   bool
+	minSize      int
+	slotAlloc    SlotAllocator
+	sizer        SizeFunc
+	retained     *atomic.Int64
+	payloadSizes *payloadSizes
+	byteLimit    *byteLimit
+	drainYield   int
+	backpressure time.Duration
+	dropNewest   bool
+	discarded    *atomic.Uint64
+	sampler      *sampler
+	onCollision  func(index uint64)
+	logger       *slog.Logger
+	retryBudget  int
+	onWriteFail  func(GenericDataType)
+	onDrop       DropHandler
+	deadLetter   Diode
+	merge        MergeFunc
+	merged       *atomic.Uint64
+	dropShape    *dropShape
+
+	writeLimit     *writeLimiter
+	writeLimitWait time.Duration
+	writerCheck    *writerCheck
+	watermarks     *watermarks
+	stall          *stallDetector
+	maxAge         int64
+	expired        *atomic.Uint64
+	orderAudit     *orderAudit
+	missingTimeout int64
+}
+
+func newDiodeConfig(opts []DiodeConfigOption) diodeConfig {
+	var c diodeConfig
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
+
+// WithDwellHistogram records how long each value waited in the diode, from
+// Set to the read that returned it, in the given histogram. Enabling it
+// makes every Set read the clock.
+func WithDwellHistogram(h *Histogram) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.dwell = h
+	})
+}
+
+// WithLatencyObserver invokes observe on the reader's go-routine with how
+// long every value it reads waited in the diode, from Set to the read. It
+// lets the latency be recorded by a metrics library of your choice, e.g. in
+// an OpenTelemetry histogram, without the diodes depending on it. Enabling
+// it makes every Set read the clock.
+func WithLatencyObserver(observe func(time.Duration)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.onLatency = observe
+	})
+}
+
+// WithEnqueueTime stamps every value with the time it was set, so that
+// TryNextLatency can report how long it waited in the diode without the
+// payload carrying its own timestamp. Enabling it makes every Set read the
+// clock. WithDwellHistogram implies it.
+func WithEnqueueTime() DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.enqueueTime = true
+	})
+}
+
+// WithRateHalfLife sets the half-life of the rates reported by Stats. A
+// short half-life reacts quickly to bursts, a long one only moves with
+// sustained changes. The default is one minute.
+func WithRateHalfLife(halfLife time.Duration) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.rateHalfLife = halfLife
+	})
+}
+
+// WithOccupancyTracking tracks how many values are unread every time the
+// reader tries to read and reports the distribution over a rolling window
+// of the given duration via Stats.
+func WithOccupancyTracking(window time.Duration) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.occupancy = newOccupancy(window)
+	})
+}
+
+// WithSegmentSize stores the slots of the diode in a chain of segments of
+// (at least) the given size instead of a single slice. Segments are
+// allocated the first time they are written to, so very large diodes do not
+// need one contiguous allocation up front. The size is rounded up to a power
+// of two. The default is a single slice.
+func WithSegmentSize(size int) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.segmentSize = size
+	})
+}
+
+// WithPrefault allocates and writes to all the slots of the diode when it
+// is created, so that the page faults of a very large ring are paid up front
+// instead of by the writers on their first lap. With WithSegmentSize every
+// segment is allocated up front as well. Payloads are still allocated by
+// their writers.
+func WithPrefault() DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.prefault = true
+	})
+}
+
+// WithPowerOfTwoSize rounds the size of the diode up to the next power of
+// two, e.g. 1000 to 1024, so that every Set and read locates its slot with a
+// mask instead of a modulo. Diodes whose size already is a power of two use
+// a mask without the option. Cap and Stats report the rounded size.
+func WithPowerOfTwoSize() DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.powerOfTwo = true
+	})
+}
+
+// SlotAllocator returns n zeroed slots for the ring buffer of a diode.
+type SlotAllocator func(n int) []unsafe.Pointer
+
+// WithSlotAllocator allocates the slots of the diode with alloc instead of
+// make, e.g. to place them on the NUMA node of a pinned reader. The slots
+// hold Go pointers, so they must be allocated by Go, but alloc can run on a
+// go-routine locked to a thread on the right node and write to every page,
+// which places them there under the first-touch policy of Linux. alloc is
+// invoked once when the diode is created or, with WithSegmentSize, for every
+// segment on the go-routine of the first writer that reaches it.
+func WithSlotAllocator(alloc SlotAllocator) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.slotAlloc = alloc
+	})
+}
+
+// WithSizer tracks the approximate number of payload bytes retained by the
+// diode, as measured by size, and reports it via Stats. Every value is
+// measured when it is set and again when it leaves the diode, so size must
+// return the same result for a value as long as it is in the diode. Stats
+// also reports the smallest and the largest value that was set.
+func WithSizer(size SizeFunc) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.sizer = size
+		c.retained = new(atomic.Int64)
+		c.payloadSizes = newPayloadSizes()
+	})
+}
+
+// WithDrainYield makes DrainInto yield the processor to other go-routines
+// after every n values, so that draining a large backlog does not starve
+// them. The default is to never yield.
+func WithDrainYield(n int) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.drainYield = n
+	})
+}
+
+// WithBackpressure makes Set wait for the reader to read the value in the
+// slot it writes to instead of overwriting it while the diode is full. Once
+// the timeout passes Set falls back to overwriting the value, so a stalled
+// reader can not block writers indefinitely. The default is to never wait.
+// SetCtx waits until its context is done instead of the timeout.
+func WithBackpressure(timeout time.Duration) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.backpressure = timeout
+	})
+}
+
+// WithDropNewest makes Set discard the value it is given instead of
+// overwriting unread data while the diode is full, so that the older values
+// survive an overload burst. Discarded values count as dropped and are
+// reported to the alerter on the reader's go-routine the next time it tries
+// to read. Combined with WithBackpressure, Set only discards the value once
+// the timeout passed. The OneToMany diode does not support it.
+func WithDropNewest() DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.dropNewest = true
+		if c.discarded == nil {
+			c.discarded = new(atomic.Uint64)
+		}
+	})
+}
+
+// WithCollisionHandler invokes handle on the writer's go-routine, with the
+// write index of the value, every time a writer of a diode with many writers
+// collides with another writer or the reader (see Stats.Collisions). It
+// replaces the default of logging every collision, so handle can count,
+// sample or ignore them instead. It must be safe for concurrent use.
+func WithCollisionHandler(handle func(index uint64)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.onCollision = handle
+	})
+}
+
+// DropHandler is used to hand out values that were dropped because they were
+// overwritten before they were read.
+type DropHandler interface {
+	Dropped(data GenericDataType)
+}
+
+// DropFunc type is an adapter to allow the use of ordinary functions as
+// DropHandlers.
+type DropFunc func(data GenericDataType)
+
+// Dropped calls f(data)
+func (f DropFunc) Dropped(data GenericDataType) {
+	f(data)
+}
+
+// WithDropHandler hands every value that is dropped to h, e.g. to log which
+// values were lost or to send them to a dead letter sink. It is invoked on
+// the go-routine of the writer that dropped the value, so with many writers
+// it must be safe for concurrent use, and it should return quickly since it
+// delays the writer. It is not supported by the OneToMany diode.
+func WithDropHandler(h DropHandler) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.onDrop = h
+	})
+}
+
+// stampsTime reports whether buckets need to record when they were set.
+func (c *diodeConfig) stampsTime() bool {
+	return c.dwell != nil || c.enqueueTime || c.maxAge > 0 || c.onLatency != nil
+}
+
+// latency returns how long the value of the bucket waited since it was set,
+// or 0 if buckets are not stamped.
+func (c *diodeConfig) latency(b *bucket) time.Duration {
+	if !c.stampsTime() {
+		return 0
+	}
+	return time.Duration(nanotime() - b.at)
+}
+
+// awaitReader waits until the reader has read far enough for the value with
+// the given index to not overwrite unread data. Without a context, it gives
+// up once the backpressure timeout passed. With one, as passed to SetCtx, it
+// waits until the context is done instead, and returns its error if the
+// reader did not make room before.
+func (c *diodeConfig) awaitReader(ctx context.Context, index uint64, readIndex *atomic.Uint64, size uint64) error {
+	if index < readIndex.Load()+size {
+		return nil
+	}
+
+	if ctx == nil {
+		if c.backpressure <= 0 {
+			return nil
+		}
+		deadline := nanotime() + int64(c.backpressure)
+		for index >= readIndex.Load()+size && nanotime() < deadline {
+			runtime.Gosched()
+		}
+		return nil
+	}
+
+	for index >= readIndex.Load()+size {
+		select {
+		case <-ctx.Done():
+			return contextErr(ctx)
+		default:
+		}
+		runtime.Gosched()
+	}
+	return nil
+}
+
+// collide reports that the writer of index collided with another writer or
+// the reader, for the given number of times in a row.
+func (c *diodeConfig) collide(index uint64, collisions int) {
+	if c.onCollision != nil {
+		c.onCollision(index)
+		return
+	}
+	if c.logger != nil {
+		logCollisionTo(c.logger, index, collisions)
+		return
+	}
+	logCollision()
+}
+
+// observeRead records a successful read of b.
+func (c *diodeConfig) observeRead(b *bucket) {
+	if c.dwell == nil && c.onLatency == nil {
+		return
+	}
+
+	latency := time.Duration(nanotime() - b.at)
+	if c.dwell != nil {
+		c.dwell.Observe(latency)
+	}
+	if c.onLatency != nil && b.data != endOfStream {
+		c.onLatency(latency)
+	}
+}
+
+// observeUnread records how many values are unread, bounded by the size of
+// the diode. It must only be called by the reader.
+func (c *diodeConfig) observeUnread(nextWrite, nextRead, size uint64) {
+	if c.occupancy == nil {
+		return
+	}
+
+	c.occupancy.observe(unread(nextWrite, nextRead, size))
+}
+
+// retain records that data was stored in the diode.
+func (c *diodeConfig) retain(data GenericDataType) {
+	if c.sizer != nil && data != endOfStream && data != failedWrite {
+		n := c.sizeOf(data)
+		c.retained.Add(n)
+		c.payloadSizes.observe(n)
+	}
+}
+
+// release records that the bucket b points to, if any, left the diode,
+// either by being read or by being overwritten.
+func (c *diodeConfig) release(b unsafe.Pointer) {
+	if c.sizer != nil && b != nil {
+		c.retained.Add(-c.sizeOf((*bucket)(b).data))
+	}
+}
+
+// drop hands the data of the bucket b points to, if any, to the drop
+// handler and the dead letter diode. It must only be called for buckets
+// that were never read.
+func (c *diodeConfig) drop(b unsafe.Pointer) {
+	if (c.onDrop == nil && c.deadLetter == nil) || b == nil {
+		return
+	}
+
+	data := (*bucket)(b).data
+	if data == endOfStream || data == failedWrite {
+		return
+	}
+	c.handOff(data)
+}
+
+// dropsNewest reports whether the given data is discarded rather than
+// overwriting unread data.
+func (c *diodeConfig) dropsNewest(data GenericDataType) bool {
+	// The end of the stream must not be lost, so it always overwrites.
+	return c.dropNewest && data != endOfStream
+}
+
+// discard counts the data as discarded and hands it to the drop handler and
+// the dead letter diode.
+func (c *diodeConfig) discard(data GenericDataType) {
+	c.discarded.Add(1)
+	if data != unbuilt {
+		c.handOff(data)
+	}
+}
+
+// takeDiscarded returns the number of values that were discarded since the
+// last call.
+func (c *diodeConfig) takeDiscarded() uint64 {
+	if c.discarded == nil || c.discarded.Load() == 0 {
+		return 0
+	}
+	return c.discarded.Swap(0)
+}
+
+// fillStats sets the stats that are tracked by optional behavior.
+func (c *diodeConfig) fillStats(st *Stats) {
+	if c.dwell != nil {
+		st.DwellP50 = c.dwell.Percentile(0.5)
+		st.DwellP99 = c.dwell.Percentile(0.99)
+		st.DwellMax = c.dwell.Max()
+	}
+
+	if c.occupancy != nil {
+		c.occupancy.fill(st)
+	}
+
+	if c.dropShape != nil {
+		c.dropShape.fill(st)
+	}
+
+	if c.writeLimit != nil {
+		st.RateLimited = c.writeLimit.limited.Load()
+	}
+
+	if c.expired != nil {
+		st.Expired = c.expired.Load()
+	}
+
+	if c.merged != nil {
+		st.Merged = c.merged.Load()
+	}
+
+	if c.orderAudit != nil {
+		st.LateWrites = c.orderAudit.lateWrites.Load()
+		st.OrderWaits = c.orderAudit.waits.Load()
+	}
+
+	if c.sampler != nil {
+		st.Sampling = c.sampler.active.Load()
+		st.SampledOut = c.sampler.sampledOut.Load()
+	}
+
+	if c.retained != nil {
+		// A value can be released by the reader before its writer retained
+		// it, so the count may briefly be negative.
+		st.RetainedBytes = uint64(max(c.retained.Load(), 0))
+		c.payloadSizes.fill(st)
+	}
+
+	if c.byteLimit != nil {
+		st.ByteLimited = c.byteLimit.limited.Load()
+	}
+}
+
+// epoch is the reference point of nanotime.
+var epoch = time.Now()
+
+// nanotime returns a monotonic timestamp in nanoseconds.
+func nanotime() int64 {
+	return int64(time.Since(epoch))
+}
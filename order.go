@@ -0,0 +1,90 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithOrderAudit counts how often the writers of a ManyToOne diode deliver
+// their values out of the order of their indexes, to quantify what a
+// preempted writer costs. Stats.LateWrites counts the values that arrived
+// too late to be read, because writers of later indexes lapped their writer
+// before it stored them, or the reader had already moved past their index.
+// They are dropped. Stats.OrderWaits counts how many times the reader found
+// the value it reads next missing while writers had already claimed later
+// indexes, so that it had to wait for it. The reader never returns a value
+// older than one it already returned either way.
+func WithOrderAudit() DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.orderAudit = &orderAudit{}
+	})
+}
+
+// WithMissingTimeout bounds how long the reader of a ManyToOne diode waits
+// for a value whose writer claimed its index but did not store it yet, e.g.
+// because it was preempted, while writers store the values after it. By
+// default the reader waits for the value until the writers lap it, which
+// keeps every value in order but holds up the values behind it. Once the
+// timeout passed, the reader skips the index instead, which counts as a
+// drop with the reason DropLate. A value that is stored after its index was
+// skipped is never read. Measuring the wait makes such reads read the
+// clock.
+func WithMissingTimeout(timeout time.Duration) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.missingTimeout = int64(timeout)
+	})
+}
+
+// orderAudit counts the out of order deliveries of WithOrderAudit.
+type orderAudit struct {
+	lateWrites atomic.Uint64
+	waits      atomic.Uint64
+}
+
+// missingWait is the wait of the reader for the value of an index that was
+// claimed but not stored yet. It is only used by the reader.
+type missingWait struct {
+	// index is the read index plus one, so that the zero value waits for
+	// nothing.
+	index uint64
+	since int64
+}
+
+// lateWrite counts a write that arrived too late to be read.
+func (c *diodeConfig) lateWrite() {
+	if c.orderAudit != nil {
+		c.orderAudit.lateWrites.Add(1)
+	}
+}
+
+// skipMissing is invoked by the reader when the value of the read index is
+// missing. It returns true once it skipped the index because the value did
+// not arrive within the timeout of WithMissingTimeout.
+func (d *ManyToOne) skipMissing(readIndex, nextWrite uint64) bool {
+	// Without a later index claimed, the reader merely caught up with the
+	// writers.
+	if nextWrite <= readIndex+1 || (d.orderAudit == nil && d.missingTimeout <= 0) {
+		return false
+	}
+
+	if d.missing.index != readIndex+1 {
+		d.missing = missingWait{index: readIndex + 1}
+		if d.missingTimeout > 0 {
+			d.missing.since = nanotime()
+		}
+		if d.orderAudit != nil {
+			d.orderAudit.waits.Add(1)
+		}
+		return false
+	}
+
+	if d.missingTimeout <= 0 || nanotime()-d.missing.since < d.missingTimeout {
+		return false
+	}
+
+	d.readIndex.Store(readIndex + 1)
+	d.addDropped(1)
+	d.observeDrops(1)
+	d.alerter.AlertDrop(newDropAlert(DropLate, readIndex, readIndex+1))
+	return true
+}
@@ -0,0 +1,99 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ManyToOne order", func() {
+	var (
+		d       *diodes.ManyToOne
+		release chan struct{}
+	)
+
+	// preempt claims the first index with a writer that only stores its
+	// value once it is released. With WithDropNewest, SetLazy builds the
+	// value after the index was claimed.
+	preempt := func(opts ...diodes.DiodeConfigOption) {
+		d = diodes.NewManyToOne(8, nil, append(opts, diodes.WithDropNewest())...)
+		release = make(chan struct{})
+
+		go d.SetLazy(func() diodes.GenericDataType {
+			<-release
+			j := 100
+			return diodes.GenericDataType(&j)
+		})
+		Eventually(func() uint64 { return d.Stats().Writes }).Should(Equal(uint64(1)))
+
+		for i := 1; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	It("waits for a missing value and counts the wait", func() {
+		preempt(diodes.WithOrderAudit())
+
+		Expect(read()).To(BeEmpty())
+		Expect(read()).To(BeEmpty())
+		Expect(d.Stats().OrderWaits).To(Equal(uint64(1)))
+
+		close(release)
+		Eventually(read).Should(Equal([]int{100, 1, 2}))
+		Expect(d.Stats().LateWrites).To(BeZero())
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("skips a missing value once the timeout passed", func() {
+		preempt(diodes.WithOrderAudit(), diodes.WithMissingTimeout(20*time.Millisecond))
+		Expect(read()).To(BeEmpty())
+
+		time.Sleep(30 * time.Millisecond)
+		Expect(read()).To(Equal([]int{1, 2}))
+		Expect(d.Dropped()).To(Equal(uint64(1)))
+
+		close(release)
+		Eventually(func() uint64 { return d.Stats().LateWrites }).Should(Equal(uint64(1)))
+		Expect(read()).To(BeEmpty())
+	})
+
+	It("reports the skipped value as late", func() {
+		var alerts []diodes.DropAlert
+		d = diodes.NewManyToOne(8, diodes.DropAlertFunc(func(a diodes.DropAlert) {
+			alerts = append(alerts, a)
+		}), diodes.WithDropNewest(), diodes.WithMissingTimeout(time.Millisecond))
+
+		release := make(chan struct{})
+		defer close(release)
+		go d.SetLazy(func() diodes.GenericDataType {
+			<-release
+			return nil
+		})
+		Eventually(func() uint64 { return d.Stats().Writes }).Should(Equal(uint64(1)))
+		d.Set(diodes.GenericDataType(new(int)))
+
+		Eventually(func() bool {
+			_, ok := d.TryNext()
+			return ok
+		}).Should(BeTrue())
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0].Reason).To(Equal(diodes.DropLate))
+		Expect(alerts[0].FromSeq).To(BeZero())
+		Expect(alerts[0].ToSeq).To(Equal(uint64(1)))
+	})
+})
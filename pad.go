@@ -0,0 +1,10 @@
+package diodes
+
+// cacheLineSize is the size of a cache line on most processors. Some arm64
+// processors use 128 byte lines, where the padding still helps.
+const cacheLineSize = 64
+
+// cacheLinePad keeps the fields before and after it on separate cache lines,
+// so that the writers and the reader of a diode do not invalidate each
+// other's cache lines when they update their own index.
+type cacheLinePad [cacheLineSize]byte
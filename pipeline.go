@@ -0,0 +1,232 @@
+package diodes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pipeline wires a typed diode to a pool of workers that hand batches of its
+// values to a sink, such as a client that forwards envelopes to a server. It
+// takes the place of the read loop, the batching and the retries that every
+// agent built around a diode would otherwise write itself. The workers share
+// the diode through a WaiterPool, so every value is handed to exactly one
+// of them and the diode only needs to be safe for its writers.
+//
+// Values the diode drops before they are read are reported by its alerter.
+// Batches the sink could not take are reported by the drop handler, see
+// WithPipelineDropHandler.
+type Pipeline[T any] struct {
+	pool    *WaiterPool
+	sink    func(ctx context.Context, batch []T) error
+	workers int
+	pipelineConfig
+
+	batches   atomic.Uint64
+	delivered atomic.Uint64
+	retries   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// PipelineConfigOption can be used to setup a pipeline.
+type PipelineConfigOption func(*pipelineConfig)
+
+type pipelineConfig struct {
+	batchSize int
+	attempts  int
+	backoff   time.Duration
+	onDrop    func(n int, err error)
+	consume   []ConsumeConfigOption
+}
+
+// WithPipelineBatchSize sets how many values a worker hands to the sink at
+// a time. Like ConsumeBatch, a worker does not wait for a batch to fill up:
+// it takes whatever is available once it found a value. The default is 64.
+func WithPipelineBatchSize(n int) PipelineConfigOption {
+	return PipelineConfigOption(func(c *pipelineConfig) {
+		c.batchSize = max(n, 1)
+	})
+}
+
+// WithPipelineRetry makes a worker retry a batch the sink returned an error
+// for up to attempts times, waiting backoff before the first retry and
+// twice as long before every further one. The default is to not retry.
+func WithPipelineRetry(attempts int, backoff time.Duration) PipelineConfigOption {
+	return PipelineConfigOption(func(c *pipelineConfig) {
+		c.attempts = max(attempts, 0)
+		c.backoff = backoff
+	})
+}
+
+// WithPipelineDropHandler sets the function that is invoked on the worker's
+// go-routine with the number of values of a batch that is given up on and
+// the last error of the sink, once the retries are used up or the context
+// of the pipeline is done while the worker waits to retry. It must be safe
+// for concurrent use by the workers.
+func WithPipelineDropHandler(onDrop func(n int, err error)) PipelineConfigOption {
+	return PipelineConfigOption(func(c *pipelineConfig) {
+		c.onDrop = onDrop
+	})
+}
+
+// WithPipelinePanicHandler sets the function that is invoked on the
+// worker's go-routine when the sink panics, see WithPanicHandler. The batch
+// the sink panicked on is lost without being retried, counted or reported
+// to the drop handler.
+func WithPipelinePanicHandler(handle func(recovered any, stack []byte)) PipelineConfigOption {
+	return PipelineConfigOption(func(c *pipelineConfig) {
+		c.consume = append(c.consume, WithPanicHandler(handle))
+	})
+}
+
+// NewPipeline returns a new Pipeline that reads from the given diode with
+// the given number of workers and hands the values to sink. sink is invoked
+// on the workers' go-routines, so it must be safe for concurrent use. It
+// must not keep the batch, since each worker reuses it. The workers are
+// started by Run.
+func NewPipeline[T any](d UntypedDiodeT[T], workers int, sink func(ctx context.Context, batch []T) error, opts ...PipelineConfigOption) *Pipeline[T] {
+	p := &Pipeline[T]{
+		sink:    sink,
+		workers: max(workers, 1),
+		pipelineConfig: pipelineConfig{
+			batchSize: 64,
+		},
+	}
+
+	for _, o := range opts {
+		o(&p.pipelineConfig)
+	}
+
+	p.pool = NewWaiterPool(d.Untyped(), p.workers)
+	return p
+}
+
+// Set sets the value on the diode and wakes up one of the workers.
+func (p *Pipeline[T]) Set(v T) {
+	p.pool.Set(GenericDataType(&v))
+}
+
+// Close marks the end of the stream. Run returns once the workers have
+// handed everything that was set before Close to the sink. No values should
+// be set after Close.
+func (p *Pipeline[T]) Close() {
+	p.pool.Close()
+}
+
+// Closed reports whether the workers reached the end of the stream. Along
+// with Close, it lets a Pipeline be a stage of a DrainGroup or a Group.
+func (p *Pipeline[T]) Closed() bool {
+	return p.pool.Closed()
+}
+
+// Run runs the workers and blocks until they return: with nil once they
+// reached the end of the stream, or with the error of the context once it
+// is done, in which case the values that were not read yet are left in the
+// diode. It must only be called once. Its signature lets it be the consumer
+// of a Group stage:
+//
+//	g.Go("envelopes", p, p.Run)
+func (p *Pipeline[T]) Run(ctx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+
+	for range p.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if e := p.work(ctx); e != nil {
+				once.Do(func() { err = e })
+			}
+		}()
+	}
+
+	wg.Wait()
+	return err
+}
+
+// work is the read loop of a worker.
+func (p *Pipeline[T]) work(ctx context.Context) error {
+	batch := make([]T, 0, p.batchSize)
+
+	return consume(ctx, p.pool, p.batchSize, func(data []GenericDataType) {
+		for _, d := range data {
+			batch = append(batch, *(*T)(d))
+		}
+		defer func() {
+			clear(batch)
+			batch = batch[:0]
+		}()
+
+		p.deliver(ctx, batch)
+	}, p.consume)
+}
+
+// deliver hands the batch to the sink and retries it until the sink takes
+// it or the retries are used up.
+func (p *Pipeline[T]) deliver(ctx context.Context, batch []T) {
+	wait := p.backoff
+	for attempt := 0; ; attempt++ {
+		err := p.sink(ctx, batch)
+		if err == nil {
+			p.batches.Add(1)
+			p.delivered.Add(uint64(len(batch)))
+			return
+		}
+
+		if attempt == p.attempts || !sleepCtx(ctx, wait) {
+			p.failed.Add(uint64(len(batch)))
+			if p.onDrop != nil {
+				p.onDrop(len(batch), err)
+			}
+			return
+		}
+		p.retries.Add(1)
+		wait *= 2
+	}
+}
+
+// sleepCtx waits for the duration to pass and reports false if the context
+// is done before.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// PipelineStats are the counters of a Pipeline. The counters of its diode,
+// including the values it dropped, are reported by the diode itself.
+type PipelineStats struct {
+	// Batches is the number of batches the sink took.
+	Batches uint64
+
+	// Delivered is the number of values the sink took.
+	Delivered uint64
+
+	// Retries is the number of times a batch was handed to the sink again
+	// after it returned an error.
+	Retries uint64
+
+	// Failed is the number of values of the batches that were given up on.
+	Failed uint64
+}
+
+// Stats returns a snapshot of the pipeline's counters.
+func (p *Pipeline[T]) Stats() PipelineStats {
+	return PipelineStats{
+		Batches:   p.batches.Load(),
+		Delivered: p.delivered.Load(),
+		Retries:   p.retries.Load(),
+		Failed:    p.failed.Load(),
+	}
+}
@@ -0,0 +1,178 @@
+package diodes_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pipeline", func() {
+	var (
+		mu      sync.Mutex
+		got     []int
+		batches [][]int
+	)
+
+	collect := func(_ context.Context, batch []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, batch...)
+		batches = append(batches, append([]int(nil), batch...))
+		return nil
+	}
+
+	run := func(p *diodes.Pipeline[int]) chan error {
+		errs := make(chan error, 1)
+		go func() { errs <- p.Run(context.Background()) }()
+		return errs
+	}
+
+	BeforeEach(func() {
+		got, batches = nil, nil
+	})
+
+	It("hands every value to the sink once and returns at the end of the stream", func() {
+		p := diodes.NewPipeline[int](diodes.NewManyToOneT[int](1024, nil), 4, collect)
+		errs := run(p)
+
+		for i := 0; i < 500; i++ {
+			p.Set(i)
+		}
+		p.Close()
+
+		Eventually(errs).Should(Receive(BeNil()))
+		Expect(p.Closed()).To(BeTrue())
+
+		sort.Ints(got)
+		want := make([]int, 500)
+		for i := range want {
+			want[i] = i
+		}
+		Expect(got).To(Equal(want))
+
+		st := p.Stats()
+		Expect(st.Delivered).To(Equal(uint64(500)))
+		Expect(st.Batches).To(Equal(uint64(len(batches))))
+		Expect(st.Failed).To(BeZero())
+	})
+
+	It("hands the values that are available to the sink in batches", func() {
+		p := diodes.NewPipeline[int](diodes.NewOneToOneT[int](16, nil), 1, collect, diodes.WithPipelineBatchSize(4))
+		for i := 0; i < 10; i++ {
+			p.Set(i)
+		}
+		p.Close()
+
+		Expect(p.Run(context.Background())).To(Succeed())
+		Expect(batches).To(Equal([][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}))
+	})
+
+	It("retries a batch the sink failed on", func() {
+		var calls atomic.Int32
+		p := diodes.NewPipeline[int](diodes.NewOneToOneT[int](16, nil), 1, func(ctx context.Context, batch []int) error {
+			if calls.Add(1) < 3 {
+				return errors.New("unavailable")
+			}
+			return collect(ctx, batch)
+		}, diodes.WithPipelineRetry(3, time.Millisecond))
+		p.Set(1)
+		p.Close()
+
+		Expect(p.Run(context.Background())).To(Succeed())
+		Expect(got).To(Equal([]int{1}))
+		Expect(p.Stats().Retries).To(Equal(uint64(2)))
+		Expect(p.Stats().Failed).To(BeZero())
+	})
+
+	It("reports a batch once the retries are used up", func() {
+		var (
+			dropped int
+			dropErr error
+		)
+		sinkErr := errors.New("unavailable")
+		p := diodes.NewPipeline[int](diodes.NewOneToOneT[int](16, nil), 1, func(context.Context, []int) error {
+			return sinkErr
+		}, diodes.WithPipelineRetry(2, time.Millisecond), diodes.WithPipelineDropHandler(func(n int, err error) {
+			dropped, dropErr = n, err
+		}))
+		p.Set(1)
+		p.Set(2)
+		p.Close()
+
+		Expect(p.Run(context.Background())).To(Succeed())
+		Expect(dropped).To(Equal(2))
+		Expect(dropErr).To(MatchError(sinkErr))
+
+		st := p.Stats()
+		Expect(st.Retries).To(Equal(uint64(2)))
+		Expect(st.Failed).To(Equal(uint64(2)))
+		Expect(st.Delivered).To(BeZero())
+	})
+
+	It("stops retrying and returns once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		var (
+			calls   atomic.Int32
+			dropped atomic.Int64
+		)
+		p := diodes.NewPipeline[int](diodes.NewOneToOneT[int](16, nil), 2, func(context.Context, []int) error {
+			calls.Add(1)
+			return errors.New("unavailable")
+		}, diodes.WithPipelineRetry(10, time.Hour), diodes.WithPipelineDropHandler(func(n int, _ error) {
+			dropped.Add(int64(n))
+		}))
+		p.Set(1)
+
+		errs := make(chan error, 1)
+		go func() { errs <- p.Run(ctx) }()
+		Eventually(calls.Load).Should(Equal(int32(1)))
+		cancel()
+
+		Eventually(errs).Should(Receive(MatchError(context.Canceled)))
+		Expect(dropped.Load()).To(Equal(int64(1)))
+		Expect(p.Closed()).To(BeFalse())
+	})
+
+	It("hands a panic of the sink to the panic handler and goes on", func() {
+		var recovered any
+		p := diodes.NewPipeline[int](diodes.NewOneToOneT[int](16, nil), 1, func(ctx context.Context, batch []int) error {
+			if batch[0] == 0 {
+				panic("boom")
+			}
+			return collect(ctx, batch)
+		}, diodes.WithPipelineBatchSize(1), diodes.WithPipelinePanicHandler(func(r any, _ []byte) {
+			recovered = r
+		}))
+		p.Set(0)
+		p.Set(1)
+		p.Close()
+
+		Expect(p.Run(context.Background())).To(Succeed())
+		Expect(recovered).To(Equal("boom"))
+		Expect(got).To(Equal([]int{1}))
+	})
+
+	It("is a stage of a Group", func() {
+		g, _ := diodes.NewGroup(context.Background())
+		p := diodes.NewPipeline[int](diodes.NewManyToOneT[int](16, nil), 2, collect)
+		g.Go("values", p, p.Run)
+
+		p.Set(1)
+		p.Set(2)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Expect(g.Shutdown(shutdownCtx)).To(Succeed())
+
+		sort.Ints(got)
+		Expect(got).To(Equal([]int{1, 2}))
+	})
+})
@@ -0,0 +1,96 @@
+package diodes
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PollTicker wakes up many Pollers from a single timer. Processes that run
+// hundreds of Pollers can share one PollTicker (see WithPollTicker) instead
+// of every Poller arming its own timer while it waits.
+type PollTicker struct {
+	next     atomic.Pointer[pollTick]
+	stopped  atomic.Bool
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// pollTick is a single tick of a PollTicker along with the number of
+// pollers that are waiting for it.
+type pollTick struct {
+	c       chan struct{}
+	waiting atomic.Int64
+}
+
+// NewPollTicker returns a new PollTicker that wakes up its pollers at the
+// given interval. It must be stopped via Stop once it is no longer needed.
+func NewPollTicker(interval time.Duration) *PollTicker {
+	t := &PollTicker{
+		done: make(chan struct{}),
+	}
+	t.next.Store(&pollTick{c: make(chan struct{})})
+
+	go t.run(interval)
+
+	return t
+}
+
+// NewManualPollTicker returns a new PollTicker that only ticks when Tick is
+// called. Pollers that use it only poll again when the test driving them
+// says so, which makes them deterministic in tests. It must be stopped via
+// Stop once it is no longer needed.
+func NewManualPollTicker() *PollTicker {
+	return NewPollTicker(0)
+}
+
+// Tick wakes up the pollers that are waiting for the next tick right away.
+func (t *PollTicker) Tick() {
+	close(t.next.Swap(&pollTick{c: make(chan struct{})}).c)
+}
+
+// Waiting returns the number of pollers that are waiting for the next tick.
+// Pollers that were woken up by a tick are not counted until they wait
+// again.
+func (t *PollTicker) Waiting() int {
+	return int(t.next.Load().waiting.Load())
+}
+
+// Stop stops the ticker. Pollers that still use it fall back to sleeping
+// for their own polling interval.
+func (t *PollTicker) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+// tick returns the next tick, whose channel is closed once it happened, or
+// false if the ticker was stopped.
+func (t *PollTicker) tick() (*pollTick, bool) {
+	if t.stopped.Load() {
+		return nil, false
+	}
+	return t.next.Load(), true
+}
+
+// run ticks at the given interval until the ticker is stopped. An interval
+// of 0 only waits for the ticker to be stopped.
+func (t *PollTicker) run(interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-t.done:
+			t.stopped.Store(true)
+			t.Tick()
+			return
+		case <-tick:
+			t.Tick()
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package diodes_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PollTicker", func() {
+	var t *diodes.PollTicker
+
+	BeforeEach(func() {
+		t = diodes.NewPollTicker(time.Millisecond)
+	})
+
+	AfterEach(func() {
+		t.Stop()
+	})
+
+	It("wakes up every poller that shares it", func() {
+		var pollers []*diodes.Poller
+		for i := 0; i < 10; i++ {
+			pollers = append(pollers, diodes.NewPoller(
+				diodes.NewOneToOne(5, nil),
+				diodes.WithPollTicker(t),
+				diodes.WithPollingInterval(time.Hour),
+			))
+		}
+
+		done := make(chan struct{}, len(pollers))
+		for _, p := range pollers {
+			go func(p *diodes.Poller) {
+				p.Next()
+				done <- struct{}{}
+			}(p)
+		}
+
+		data := []byte("some-data")
+		for _, p := range pollers {
+			p.Set(diodes.GenericDataType(&data))
+		}
+
+		for range pollers {
+			Eventually(done).Should(Receive())
+		}
+	})
+
+	It("stops waiting when the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		slow := diodes.NewPollTicker(time.Hour)
+		defer slow.Stop()
+
+		p := diodes.NewPoller(
+			diodes.NewOneToOne(5, nil),
+			diodes.WithPollTicker(slow),
+			diodes.WithPollingContext(ctx),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Next()
+		}()
+
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("falls back to the polling interval once stopped", func() {
+		p := diodes.NewPoller(
+			diodes.NewOneToOne(5, nil),
+			diodes.WithPollTicker(t),
+			diodes.WithPollingInterval(time.Millisecond),
+		)
+		t.Stop()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Next()
+		}()
+
+		data := []byte("some-data")
+		p.Set(diodes.GenericDataType(&data))
+		Eventually(done).Should(BeClosed())
+	})
+	It("only ticks when told to if it is manual", func() {
+		manual := diodes.NewManualPollTicker()
+		defer manual.Stop()
+
+		p := diodes.NewPoller(
+			diodes.NewOneToOne(5, nil),
+			diodes.WithPollTicker(manual),
+			diodes.WithPollingInterval(time.Millisecond),
+		)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Next()
+		}()
+
+		Eventually(manual.Waiting).Should(Equal(1))
+		data := []byte("some-data")
+		p.Set(diodes.GenericDataType(&data))
+		Consistently(done).ShouldNot(BeClosed())
+
+		manual.Tick()
+		Eventually(done).Should(BeClosed())
+		Expect(manual.Waiting()).To(BeZero())
+	})
+})
@@ -2,22 +2,36 @@ package diodes
 
 import (
 	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
 	"time"
 )
 
-// Diode is any implementation of a diode.
-type Diode interface {
-	Set(GenericDataType)
-	TryNext() (GenericDataType, bool)
-}
-
 // Poller will poll a diode until a value is available.
 type Poller struct {
 	Diode
 	interval time.Duration
+	ticker   *PollTicker
 	ctx      context.Context
+	closed   atomic.Bool
+	end      drainEnd
+	budget   readBudget
+	filter   readFilter
+
+	spins       int
+	yields      int
+	maxInterval time.Duration
+	idle        int
+	sleep       time.Duration
+	onIdle      func()
+	clock       Clock
+	timer       Timer
+	stop        *stopper
 }
 
+var _ Diode = (*Poller)(nil)
+
 // PollerConfigOption can be used to setup the poller.
 type PollerConfigOption func(*Poller)
 
@@ -38,12 +52,87 @@ func WithPollingContext(ctx context.Context) PollerConfigOption {
 	})
 }
 
+// WithPollTicker makes the poller wait for the ticks of the given shared
+// ticker instead of sleeping for its polling interval. The polling interval
+// is only used once the ticker is stopped.
+func WithPollTicker(t *PollTicker) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.ticker = t
+	})
+}
+
+// WithPollingReadBudget limits how many values Next returns in a row while
+// data is backlogged before it yields the processor to other go-routines,
+// so that catching up on a backlog does not monopolize it. The default is
+// no limit.
+func WithPollingReadBudget(n int) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.budget.limit = n
+	})
+}
+
+// WithPollingBackoff makes the poller back off gradually while there is no
+// data: it retries right away for the first spins polls and yields the
+// processor for the next yields polls, and only then starts sleeping. The
+// sleep starts at the polling interval and doubles with every empty poll up
+// to maxInterval. Any read resets the backoff. This lets low latency readers
+// pick up data without waiting for a wakeup while idle readers poll rarely.
+// The default is to always sleep for the polling interval.
+func WithPollingBackoff(spins, yields int, maxInterval time.Duration) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.spins = spins
+		c.yields = yields
+		c.maxInterval = maxInterval
+	})
+}
+
+// WithOnIdle sets a function that is invoked on the reader's go-routine
+// every time a poll finds no data, right before the poller waits. Consumers
+// can use it to flush partially filled batches downstream or to update idle
+// metrics. It is not invoked once the context is done or the end of the
+// stream was reached.
+func WithOnIdle(f func()) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.onIdle = f
+	})
+}
+
+// WithPollingFilter drops every value for which keep returns false before
+// it is returned by the read methods, so that consumers do not need a layer
+// of their own to discard most of the data. keep is invoked on the reader's
+// go-routine. Filtered values are counted by Filtered, apart from the values
+// the diode dropped.
+func WithPollingFilter(keep func(GenericDataType) bool) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.filter.keep = keep
+	})
+}
+
+// WithPollingTransform replaces every value with the result of transform
+// before it is returned by the read methods. transform is invoked on the
+// reader's go-routine, after the filter of WithPollingFilter.
+func WithPollingTransform(transform func(GenericDataType) GenericDataType) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.filter.transform = transform
+	})
+}
+
+// WithPollingClock sets the clock the poller sleeps with while there is no
+// data, e.g. a fake clock in tests. The default is the system clock.
+func WithPollingClock(clock Clock) PollerConfigOption {
+	return PollerConfigOption(func(c *Poller) {
+		c.clock = clock
+	})
+}
+
 // NewPoller returns a new Poller that wraps the given diode.
 func NewPoller(d Diode, opts ...PollerConfigOption) *Poller {
 	p := &Poller{
 		Diode:    d,
 		interval: 10 * time.Millisecond,
 		ctx:      context.Background(),
+		clock:    systemClock{},
+		stop:     newStopper(),
 	}
 
 	for _, o := range opts {
@@ -53,28 +142,233 @@ func NewPoller(d Diode, opts ...PollerConfigOption) *Poller {
 	return p
 }
 
-// Next polls the diode until data is available or until the context is done.
-// If the context is done, then nil will be returned.
-func (p *Poller) Next() GenericDataType {
+// Close marks the end of the stream by setting a sentinel value on the diode.
+// Once the reader has read everything that was set before Close, Next
+// returns nil and TryNext returns (nil, false). No data should be set after
+// Close.
+func (p *Poller) Close() {
+	p.Diode.Set(endOfStream)
+}
+
+// Drain reads everything that was set before it was called, waiting for
+// values that writers are still storing, and then ends the stream like
+// Close without setting anything on the diode, so it is safe for diodes
+// with a single writer. Diodes that can not tell how far to read, e.g.
+// wrappers, are read until they are empty. It returns once the end of the
+// stream was reached or, with the values read so far and the context's error
+// (see NextCtx), once the context is done. It must be called by the reader.
+func (p *Poller) Drain(ctx context.Context) ([]GenericDataType, error) {
+	p.end.start(p.Diode)
+
+	var data []GenericDataType
 	for {
+		v, err := p.next(ctx)
+		if errors.Is(err, ErrClosed) {
+			return data, nil
+		}
+		if err != nil {
+			return data, err
+		}
+		data = append(data, v)
+	}
+}
+
+// Closed reports whether the reader has reached the end of the stream.
+func (p *Poller) Closed() bool {
+	return p.closed.Load()
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return
+// (nil, false). Values dropped by the filter are skipped.
+func (p *Poller) TryNext() (GenericDataType, bool) {
+	if p.closed.Load() {
+		return nil, false
+	}
+
+	for {
+		if p.end.reached() {
+			p.closed.Store(true)
+			return nil, false
+		}
+
 		data, ok := p.Diode.TryNext()
 		if !ok {
-			if p.isDone() {
-				return nil
+			if p.end.emptied() {
+				p.closed.Store(true)
 			}
+			return nil, false
+		}
+		if data == endOfStream {
+			p.closed.Store(true)
+			return nil, false
+		}
 
-			time.Sleep(p.interval)
-			continue
+		if data, ok = p.filter.apply(data); ok {
+			return data, true
 		}
-		return data
 	}
 }
 
-func (p *Poller) isDone() bool {
+// Filtered returns the number of values the filter of WithPollingFilter
+// dropped. It is safe to call from any go-routine.
+func (p *Poller) Filtered() uint64 {
+	return p.filter.filtered.Load()
+}
+
+// Next polls the diode until data is available or until the context is done.
+// If the context is done or the end of the stream was reached, then nil will
+// be returned.
+func (p *Poller) Next() GenericDataType {
+	data, _ := p.next(p.ctx)
+	return data
+}
+
+// NextCtx is like Next but also returns once the given context is done. It
+// returns ErrClosed once the end of the stream was reached, ErrStopped once
+// Stop was called and the error of whichever context is done otherwise,
+// matching ErrTimeout if its deadline passed. Unlike Next, a nil value with
+// a nil error is legitimate data.
+func (p *Poller) NextCtx(ctx context.Context) (GenericDataType, error) {
+	return p.next(ctx)
+}
+
+func (p *Poller) next(ctx context.Context) (GenericDataType, error) {
+	p.stop.enter()
+	defer p.stop.exit()
+
+	for {
+		if p.stop.stopped() {
+			return nil, ErrStopped
+		}
+
+		data, ok := p.TryNext()
+		if ok {
+			p.idle = 0
+			p.sleep = 0
+			p.budget.spend()
+			return data, nil
+		}
+		if p.closed.Load() {
+			return nil, ErrClosed
+		}
+		if err := p.ctx.Err(); err != nil {
+			return nil, contextErr(p.ctx)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, contextErr(ctx)
+		}
+
+		if p.onIdle != nil {
+			p.onIdle()
+		}
+		p.wait(ctx)
+		p.budget.reset()
+	}
+}
+
+// NextN waits like Next until data is available and then reads up to
+// len(dst) values into dst without waiting for more. It returns the number
+// of values written to dst, which is only 0 if the context is done or the
+// end of the stream was reached, so nil data is counted as well. It does
+// not allocate, so dst can be reused across calls.
+func (p *Poller) NextN(dst []GenericDataType) int {
+	if len(dst) == 0 {
+		return 0
+	}
+
+	data, err := p.next(p.ctx)
+	if err != nil {
+		return 0
+	}
+	dst[0] = data
+
+	n := 1
+	for n < len(dst) {
+		data, ok := p.TryNext()
+		if !ok {
+			break
+		}
+		p.budget.spend()
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// wait backs off according to the number of empty polls in a row. Once it
+// sleeps, it waits for the next tick of the shared ticker or, without one,
+// for the polling interval or the backoff.
+func (p *Poller) wait(ctx context.Context) {
+	p.idle++
+	switch {
+	case p.idle <= p.spins:
+		return
+	case p.idle <= p.spins+p.yields:
+		runtime.Gosched()
+		return
+	}
+
+	if p.ticker != nil {
+		if tick, ok := p.ticker.tick(); ok {
+			tick.waiting.Add(1)
+			defer tick.waiting.Add(-1)
+
+			select {
+			case <-tick.c:
+			case <-ctx.Done():
+			case <-p.ctx.Done():
+			case <-p.stop.c:
+			}
+			return
+		}
+	}
+
+	if p.maxInterval <= p.interval {
+		p.sleepFor(ctx, p.interval)
+		return
+	}
+
+	if p.sleep == 0 {
+		p.sleep = p.interval
+	} else {
+		p.sleep = min(2*p.sleep, p.maxInterval)
+	}
+	p.sleepFor(ctx, p.sleep)
+}
+
+// sleepFor sleeps for the given duration or until either context is done or
+// the poller is stopped. It reuses a single timer across calls.
+func (p *Poller) sleepFor(ctx context.Context, d time.Duration) {
+	if p.timer == nil {
+		p.timer = p.clock.NewTimer(d)
+	} else {
+		p.timer.Reset(d)
+	}
+
 	select {
+	case <-p.timer.C():
+		return
+	case <-ctx.Done():
 	case <-p.ctx.Done():
-		return true
-	default:
-		return false
+	case <-p.stop.c:
 	}
+	stopTimer(p.timer)
+}
+
+// Stop makes the blocking reads return right away, interrupting any wait
+// for data in progress, including the one of a reader that was given a
+// context. Next then returns nil and NextCtx ErrStopped, no matter whether
+// data is left in the diode, and the idle hook of WithOnIdle is not invoked
+// anymore once the reads in flight returned. TryNext keeps reading the
+// diode. It is safe to call from any go-routine, more than once.
+func (p *Poller) Stop() {
+	p.stop.stop()
+}
+
+// Shutdown stops the poller like Stop and waits until the reads in flight
+// returned, after which no idle hook is invoked anymore, or until the
+// context is done, in which case it returns the context's error.
+func (p *Poller) Shutdown(ctx context.Context) error {
+	return p.stop.shutdown(ctx)
 }
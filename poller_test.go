@@ -2,7 +2,11 @@ package diodes_test
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"code.cloudfoundry.org/go-diodes"
@@ -54,6 +58,67 @@ var _ = Describe("Poller", func() {
 	})
 })
 
+var _ = Describe("Poller Close()", func() {
+	var p *diodes.Poller
+
+	BeforeEach(func() {
+		p = diodes.NewPoller(diodes.NewManyToOne(5, nil), diodes.WithPollingInterval(time.Millisecond))
+	})
+
+	It("returns the data set before Close and then nil", func() {
+		data := []byte("some-data")
+		p.Set(diodes.GenericDataType(&data))
+		p.Close()
+
+		Expect(*(*[]byte)(p.Next())).To(Equal(data))
+		Expect(p.Closed()).To(BeFalse())
+		Expect(p.Next() == nil).To(BeTrue())
+		Expect(p.Closed()).To(BeTrue())
+	})
+
+	It("keeps returning nil after the end of the stream", func() {
+		p.Close()
+
+		Expect(p.Next() == nil).To(BeTrue())
+		_, ok := p.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(p.Next() == nil).To(BeTrue())
+	})
+
+	It("stops a blocked reader", func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Next()
+		}()
+
+		p.Close()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("Poller with a read budget", func() {
+	It("yields to other go-routines while catching up on a backlog", func() {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+		p := diodes.NewPoller(diodes.NewOneToOne(100, nil), diodes.WithPollingReadBudget(10))
+		for i := 0; i < 100; i++ {
+			j := i
+			p.Set(diodes.GenericDataType(&j))
+		}
+
+		var ran atomic.Bool
+		go ran.Store(true)
+
+		// The scheduler occasionally resumes the yielding go-routine first,
+		// so the backlog spans several budgets.
+		for i := 0; i < 100; i++ {
+			Expect(*(*int)(p.Next())).To(Equal(i))
+		}
+		Expect(ran.Load()).To(BeTrue())
+	})
+})
+
 type spyDiode struct {
 	diodes.Diode
 	mu       sync.Mutex
@@ -80,3 +145,402 @@ func (s *spyDiode) TryNext() (diodes.GenericDataType, bool) {
 	s.dataList = s.dataList[1:]
 	return diodes.GenericDataType(&next), true
 }
+
+var _ = Describe("Poller NextN()", func() {
+	var p *diodes.Poller
+
+	BeforeEach(func() {
+		p = diodes.NewPoller(diodes.NewManyToOne(10, nil), diodes.WithPollingInterval(time.Millisecond))
+	})
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			p.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	It("reads up to len(dst) available values", func() {
+		set(0, 5)
+
+		dst := make([]diodes.GenericDataType, 3)
+		Expect(p.NextN(dst)).To(Equal(3))
+		Expect(*(*int)(dst[2])).To(Equal(2))
+		Expect(p.NextN(dst)).To(Equal(2))
+		Expect(*(*int)(dst[1])).To(Equal(4))
+	})
+
+	It("waits for the first value only", func() {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			set(0, 1)
+		}()
+
+		Expect(p.NextN(make([]diodes.GenericDataType, 3))).To(Equal(1))
+	})
+
+	It("returns 0 at the end of the stream", func() {
+		set(0, 2)
+		p.Close()
+
+		dst := make([]diodes.GenericDataType, 3)
+		Expect(p.NextN(dst)).To(Equal(2))
+		Expect(p.NextN(dst)).To(Equal(0))
+		Expect(p.Closed()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Poller NextCtx()", func() {
+	var p *diodes.Poller
+
+	BeforeEach(func() {
+		p = diodes.NewPoller(diodes.NewManyToOne(10, nil), diodes.WithPollingInterval(time.Millisecond))
+	})
+
+	It("returns nil data as data", func() {
+		p.Set(nil)
+
+		data, err := p.NextCtx(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data == nil).To(BeTrue())
+	})
+
+	It("returns the error of the given context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := p.NextCtx(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("returns ErrTimeout once the deadline passes", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := p.NextCtx(ctx)
+		Expect(err).To(MatchError(diodes.ErrTimeout))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("returns ErrClosed at the end of the stream", func() {
+		p.Close()
+
+		_, err := p.NextCtx(context.Background())
+		Expect(err).To(MatchError(diodes.ErrClosed))
+	})
+})
+
+var _ = Describe("Poller with a backoff", func() {
+	var spy *spyDiode
+
+	BeforeEach(func() {
+		spy = new(spyDiode)
+	})
+
+	It("spins instead of sleeping for the first empty polls", func() {
+		p := diodes.NewPoller(spy,
+			diodes.WithPollingInterval(time.Hour),
+			diodes.WithPollingBackoff(math.MaxInt, 0, time.Hour),
+		)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			spy.Set(diodes.GenericDataType(&[]byte{'a'}))
+		}()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Next()
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("sleeps longer the longer there is no data", func() {
+		p := diodes.NewPoller(spy,
+			diodes.WithPollingInterval(time.Millisecond),
+			diodes.WithPollingBackoff(0, 0, 64*time.Millisecond),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := p.NextCtx(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		spy.mu.Lock()
+		defer spy.mu.Unlock()
+		Expect(spy.called).To(BeNumerically("<", 20))
+	})
+
+	It("starts over at the polling interval after a read", func() {
+		p := diodes.NewPoller(spy,
+			diodes.WithPollingInterval(time.Millisecond),
+			diodes.WithPollingBackoff(0, 0, time.Hour),
+		)
+
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			spy.Set(diodes.GenericDataType(&[]byte{'a'}))
+		}()
+		p.Next()
+
+		go func() {
+			time.Sleep(5 * time.Millisecond)
+			spy.Set(diodes.GenericDataType(&[]byte{'b'}))
+		}()
+
+		start := time.Now()
+		Expect(*(*[]byte)(p.Next())).To(Equal([]byte{'b'}))
+		Expect(time.Since(start)).To(BeNumerically("<", 60*time.Millisecond))
+	})
+})
+
+var _ = Describe("Poller with an idle hook", func() {
+	var (
+		spy   *spyDiode
+		idles int
+		p     *diodes.Poller
+	)
+
+	BeforeEach(func() {
+		spy = new(spyDiode)
+		idles = 0
+		p = diodes.NewPoller(spy,
+			diodes.WithPollingInterval(time.Millisecond),
+			diodes.WithOnIdle(func() { idles++ }),
+		)
+	})
+
+	It("is not invoked while data is available", func() {
+		spy.dataList = [][]byte{[]byte("a"), []byte("b")}
+
+		p.Next()
+		p.Next()
+		Expect(idles).To(BeZero())
+	})
+
+	It("is invoked for every empty poll", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := p.NextCtx(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+
+		spy.mu.Lock()
+		defer spy.mu.Unlock()
+		Expect(idles).To(BeNumerically(">", 0))
+		Expect(idles).To(Equal(spy.called - 1))
+	})
+
+	It("runs before the poller waits for more data", func() {
+		p = diodes.NewPoller(spy,
+			diodes.WithPollingInterval(time.Millisecond),
+			diodes.WithOnIdle(func() {
+				spy.Set(diodes.GenericDataType(&[]byte{'a'}))
+			}),
+		)
+
+		Expect(*(*[]byte)(p.Next())).To(Equal([]byte{'a'}))
+	})
+
+	It("is not invoked at the end of the stream", func() {
+		p = diodes.NewPoller(diodes.NewManyToOne(4, nil), diodes.WithOnIdle(func() { idles++ }))
+		p.Close()
+
+		Expect(p.Next() == nil).To(BeTrue())
+		Expect(idles).To(BeZero())
+	})
+})
+
+var _ = Describe("Poller Drain()", func() {
+	var p *diodes.Poller
+
+	BeforeEach(func() {
+		p = diodes.NewPoller(diodes.NewManyToOne(10, nil), diodes.WithPollingInterval(time.Millisecond))
+	})
+
+	It("returns everything that was set before", func() {
+		for i := 0; i < 3; i++ {
+			j := i
+			p.Set(diodes.GenericDataType(&j))
+		}
+		p.Next()
+
+		data, err := p.Drain(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(2))
+		Expect(*(*int)(data[0])).To(Equal(1))
+		Expect(*(*int)(data[1])).To(Equal(2))
+		Expect(p.Closed()).To(BeTrue())
+	})
+
+	It("returns the error of the context if the end is not reached", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// A writer claimed the first slot but never stores its value.
+		d := diodes.NewManyToOne(10, nil)
+		diodes.SetIndexes(d, 1, 0)
+		p = diodes.NewPoller(d, diodes.WithPollingInterval(time.Millisecond))
+
+		_, err := p.Drain(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("waits for values that writers are still storing", func() {
+		d := diodes.NewManyToOne(10, nil)
+		diodes.SetIndexes(d, 1, 0)
+		p = diodes.NewPoller(d, diodes.WithPollingInterval(time.Millisecond))
+
+		done := make(chan []diodes.GenericDataType)
+		go func() {
+			defer GinkgoRecover()
+			data, err := p.Drain(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			done <- data
+		}()
+
+		Consistently(done).ShouldNot(Receive())
+		diodes.StoreSeq(d, 0, 0)
+		Eventually(done).Should(Receive(HaveLen(1)))
+	})
+
+	It("does not set anything on the diode", func() {
+		d := diodes.NewOneToOne(10, nil)
+		p = diodes.NewPoller(d)
+		v := 1
+		p.Set(diodes.GenericDataType(&v))
+
+		data, err := p.Drain(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(1))
+		Expect(d.Stats().Writes).To(Equal(uint64(1)))
+
+		p.Set(diodes.GenericDataType(&v))
+		_, ok := p.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("reads a diode that can not tell how far to read until it is empty", func() {
+		p = diodes.NewPoller(blackHole{}, diodes.WithPollingInterval(time.Millisecond))
+
+		data, err := p.Drain(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(BeEmpty())
+		Expect(p.Closed()).To(BeTrue())
+	})
+})
+
+// blackHole is a diode that drops everything.
+type blackHole struct{}
+
+func (blackHole) Set(diodes.GenericDataType) {}
+
+func (blackHole) TryNext() (diodes.GenericDataType, bool) {
+	return nil, false
+}
+
+var _ = Describe("Poller with a filter", func() {
+	var d *diodes.ManyToOne
+
+	BeforeEach(func() {
+		d = diodes.NewManyToOne(16, nil)
+		for i := 0; i < 6; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	})
+
+	even := func(data diodes.GenericDataType) bool {
+		return *(*int)(data)%2 == 0
+	}
+
+	It("skips and counts the values the filter drops", func() {
+		p := diodes.NewPoller(d, diodes.WithPollingFilter(even))
+		p.Close()
+
+		data, err := p.Drain(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(3))
+		Expect(*(*int)(data[0])).To(Equal(0))
+		Expect(*(*int)(data[1])).To(Equal(2))
+		Expect(*(*int)(data[2])).To(Equal(4))
+		Expect(p.Filtered()).To(Equal(uint64(3)))
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("transforms the values that pass the filter", func() {
+		p := diodes.NewPoller(d,
+			diodes.WithPollingFilter(even),
+			diodes.WithPollingTransform(func(data diodes.GenericDataType) diodes.GenericDataType {
+				s := fmt.Sprint(*(*int)(data))
+				return diodes.GenericDataType(&s)
+			}),
+		)
+
+		dst := make([]diodes.GenericDataType, 8)
+		Expect(p.NextN(dst)).To(Equal(3))
+		Expect(*(*string)(dst[0])).To(Equal("0"))
+		Expect(*(*string)(dst[2])).To(Equal("4"))
+	})
+
+	It("waits for a value that passes the filter", func() {
+		p := diodes.NewPoller(d,
+			diodes.WithPollingInterval(time.Millisecond),
+			diodes.WithPollingFilter(func(data diodes.GenericDataType) bool {
+				return *(*int)(data) > 5
+			}),
+		)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			j := 6
+			d.Set(diodes.GenericDataType(&j))
+		}()
+
+		Expect(*(*int)(p.Next())).To(Equal(6))
+		Expect(p.Filtered()).To(Equal(uint64(6)))
+	})
+})
+
+var _ = Describe("Poller Stop()", func() {
+	It("interrupts a blocked reader right away", func() {
+		p := diodes.NewPoller(diodes.NewOneToOne(4, nil), diodes.WithPollingInterval(time.Hour))
+
+		done := make(chan error)
+		go func() {
+			_, err := p.NextCtx(context.Background())
+			done <- err
+		}()
+		Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+		p.Stop()
+		Eventually(done).Should(Receive(MatchError(diodes.ErrStopped)))
+		Expect(p.Next() == nil).To(BeTrue())
+	})
+
+	It("stops the idle hook once Shutdown returned", func() {
+		var idles atomic.Int64
+		p := diodes.NewPoller(diodes.NewOneToOne(4, nil),
+			diodes.WithPollingInterval(time.Millisecond),
+			diodes.WithOnIdle(func() { idles.Add(1) }),
+		)
+		go p.Next()
+		Eventually(idles.Load).Should(BeNumerically(">", 0))
+
+		Expect(p.Shutdown(context.Background())).To(Succeed())
+		n := idles.Load()
+		Consistently(idles.Load, 20*time.Millisecond).Should(Equal(n))
+	})
+
+	It("returns the error of the context of Shutdown", func() {
+		p := diodes.NewPoller(diodes.NewOneToOne(4, nil), diodes.WithOnIdle(func() {
+			time.Sleep(100 * time.Millisecond)
+		}))
+		go p.Next()
+		time.Sleep(10 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		Expect(p.Shutdown(ctx)).To(MatchError(diodes.ErrTimeout))
+	})
+})
@@ -0,0 +1,25 @@
+package diodes
+
+import "time"
+
+// pressureDecay is how long it takes for the pressure of dropped values to
+// decay once the reader no longer notices any.
+const pressureDecay = time.Second
+
+// addDropped counts the values the reader noticed were dropped.
+func (s *diodeStats) addDropped(n uint64) {
+	s.dropped.Add(n)
+	s.droppedAt.Store(nanotime())
+}
+
+// pressure returns the pressure on a diode with the given number of unread
+// values and slots: the share of the slots that are unread, or, if it is
+// higher, how recently the reader noticed dropped values, from 1 right after
+// a drop to 0 once pressureDecay passed.
+func (s *diodeStats) pressure(unread, size uint64) float64 {
+	p := float64(unread) / float64(size)
+	if at := s.droppedAt.Load(); at != 0 {
+		p = max(p, 1-float64(nanotime()-at)/float64(pressureDecay))
+	}
+	return min(max(p, 0), 1)
+}
@@ -0,0 +1,55 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type pressureDiode interface {
+	diodes.Diode
+	Len() int
+	Pressure() float64
+}
+
+var _ = Describe("Pressure()", func() {
+	set := func(d diodes.Diode, n int) {
+		for i := 0; i < n; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	DescribeTable("rises with the backlog and the dropped values",
+		func(d pressureDiode) {
+			Expect(d.Pressure()).To(BeZero())
+
+			set(d, 2)
+			Expect(d.Pressure()).To(Equal(0.5))
+
+			set(d, 4)
+			Expect(d.Pressure()).To(Equal(1.0))
+
+			// The reader skips to the newest values and notices the drop.
+			d.TryNext()
+			Expect(d.Len()).To(Equal(1))
+			Expect(d.Pressure()).To(BeNumerically(">", 0.9))
+		},
+		Entry("OneToOne", diodes.NewOneToOne(4, nil)),
+		Entry("ManyToOne", diodes.NewManyToOne(4, nil)),
+		Entry("ManyToMany", diodes.NewManyToMany(4, nil)),
+	)
+
+	It("decays once no more values are dropped", func() {
+		d := diodes.NewManyToOne(4, nil)
+		set(d, 6)
+		for _, ok := d.TryNext(); ok; _, ok = d.TryNext() {
+		}
+		Expect(d.Dropped()).ToNot(BeZero())
+
+		Eventually(d.Pressure, 2*time.Second, 10*time.Millisecond).Should(BeZero())
+	})
+})
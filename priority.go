@@ -0,0 +1,110 @@
+package diodes
+
+import "fmt"
+
+// LaneAlerter is used to report how many values of a lane of a
+// ManyToOnePriority diode were overwritten since the last read.
+type LaneAlerter interface {
+	AlertLane(lane, missed int)
+}
+
+// LaneAlertFunc type is an adapter to allow the use of ordinary functions as
+// LaneAlerters.
+type LaneAlertFunc func(lane, missed int)
+
+// AlertLane calls f(lane, missed)
+func (f LaneAlertFunc) AlertLane(lane, missed int) {
+	f(lane, missed)
+}
+
+// ManyToOnePriority diode holds a ManyToOne diode per priority lane. The
+// reader always reads the lanes of higher priority first, so that under
+// overload the lanes of lower priority fill up and drop their data while
+// the important data still gets through. It is safe for many writers and a
+// single reader.
+type ManyToOnePriority struct {
+	lanes []*ManyToOne
+}
+
+var _ Reader = (*ManyToOnePriority)(nil)
+
+// NewManyToOnePriority creates a new priority diode with a lane for every
+// size. Lane 0 has the highest priority. The alerter is invoked on the
+// reader's go-routine with the lane that dropped data. A nil can be used to
+// ignore alerts. The options are applied to every lane.
+func NewManyToOnePriority(sizes []int, alerter LaneAlerter, opts ...DiodeConfigOption) *ManyToOnePriority {
+	if alerter == nil {
+		alerter = LaneAlertFunc(func(int, int) {})
+	}
+
+	d := &ManyToOnePriority{
+		lanes: make([]*ManyToOne, len(sizes)),
+	}
+	for i, size := range sizes {
+		lane := i
+		d.lanes[i] = NewManyToOne(size, AlertFunc(func(missed int) {
+			alerter.AlertLane(lane, missed)
+		}), opts...)
+	}
+
+	return d
+}
+
+// NewManyToOnePriorityErr is like NewManyToOnePriority but returns an
+// error matching ErrInvalidSize instead of lanes that have no slots, see
+// WithMinSize.
+func NewManyToOnePriorityErr(sizes []int, alerter LaneAlerter, opts ...DiodeConfigOption) (*ManyToOnePriority, error) {
+	c := newDiodeConfig(opts)
+	for i, size := range sizes {
+		if err := c.checkSize(size); err != nil {
+			return nil, fmt.Errorf("lane %d: %w", i, err)
+		}
+	}
+	return NewManyToOnePriority(sizes, alerter, opts...), nil
+}
+
+// Set sets the data in the lane of the given priority, which must be less
+// than the number of lanes.
+func (d *ManyToOnePriority) Set(priority int, data GenericDataType) {
+	d.lanes[priority].Set(data)
+}
+
+// TryNext will attempt to read from the lane of the highest priority that
+// has data available. If there is no data available in any lane, it will
+// return (nil, false).
+func (d *ManyToOnePriority) TryNext() (data GenericDataType, ok bool) {
+	for _, l := range d.lanes {
+		if data, ok := l.TryNext(); ok {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the approximate number of unread values across all lanes.
+func (d *ManyToOnePriority) Len() int {
+	var n int
+	for _, l := range d.lanes {
+		n += l.Len()
+	}
+	return n
+}
+
+// Stats returns a snapshot of the counters of the lane of the given
+// priority.
+func (d *ManyToOnePriority) Stats(priority int) Stats {
+	return d.lanes[priority].Stats()
+}
+
+// ReadStatsInto fills st with the counters of the lane of the given
+// priority like Stats, but leaves the rates zero. See
+// ManyToOne.ReadStatsInto.
+func (d *ManyToOnePriority) ReadStatsInto(priority int, st *Stats) {
+	d.lanes[priority].ReadStatsInto(st)
+}
+
+// Dropped returns the total number of values of the lane of the given
+// priority that the reader noticed were overwritten before they were read.
+func (d *ManyToOnePriority) Dropped(priority int) uint64 {
+	return d.lanes[priority].Dropped()
+}
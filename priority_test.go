@@ -0,0 +1,59 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ManyToOnePriority", func() {
+	var (
+		d      *diodes.ManyToOnePriority
+		alerts [][2]int
+	)
+
+	BeforeEach(func() {
+		alerts = nil
+		d = diodes.NewManyToOnePriority([]int{2, 4}, diodes.LaneAlertFunc(func(lane, missed int) {
+			alerts = append(alerts, [2]int{lane, missed})
+		}))
+	})
+
+	set := func(priority, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(priority, diodes.GenericDataType(&j))
+		}
+	}
+
+	readAll := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	It("reads the lanes of higher priority first", func() {
+		set(1, 0, 3)
+		set(0, 10, 12)
+
+		Expect(d.Len()).To(Equal(5))
+		Expect(readAll()).To(Equal([]int{10, 11, 0, 1, 2}))
+	})
+
+	It("drops and alerts per lane", func() {
+		set(0, 10, 12)
+		set(1, 0, 10)
+
+		Expect(readAll()).To(Equal([]int{10, 11, 8, 9}))
+		Expect(alerts).To(Equal([][2]int{{1, 8}}))
+		Expect(d.Dropped(0)).To(BeZero())
+		Expect(d.Dropped(1)).To(Equal(uint64(8)))
+		Expect(d.Stats(1).Writes).To(Equal(uint64(10)))
+	})
+})
@@ -0,0 +1,140 @@
+package diodes
+
+import (
+	"sync"
+	"time"
+)
+
+// Producer stages the values of a single writer and sets them on a diode in
+// batches, so that the writers of a busy diode contend on its write index
+// once per batch instead of once per value. A batch is set once it is full,
+// once the flush interval passed since its first value or on Flush and
+// Close, whichever comes first. Staging trades a little latency for fewer
+// contended atomics, and values that are still staged are not visible to the
+// reader.
+//
+// A Producer is meant to be used by a single go-routine. Every writer should
+// have its own.
+type Producer struct {
+	setBatch func([]GenericDataType)
+	batch    []GenericDataType
+	interval time.Duration
+
+	// mu guards the batch against the timer, that flushes it on its own
+	// go-routine.
+	mu     sync.Mutex
+	timer  *time.Timer
+	closed bool
+}
+
+var _ Writer = (*Producer)(nil)
+
+// ProducerConfigOption can be used to setup a producer.
+type ProducerConfigOption func(*Producer)
+
+// WithProducerBatchSize sets how many values are staged before they are set
+// on the diode. The default is 16.
+func WithProducerBatchSize(n int) ProducerConfigOption {
+	return ProducerConfigOption(func(p *Producer) {
+		p.batch = make([]GenericDataType, 0, max(n, 1))
+	})
+}
+
+// WithProducerFlushInterval sets how long a value may be staged before it is
+// set on the diode, even if its batch is not full. A value of 0 only sets
+// full batches and those of Flush and Close. The default is 1 millisecond.
+func WithProducerFlushInterval(interval time.Duration) ProducerConfigOption {
+	return ProducerConfigOption(func(p *Producer) {
+		p.interval = interval
+	})
+}
+
+func newProducer(setBatch func([]GenericDataType), opts []ProducerConfigOption) *Producer {
+	p := &Producer{
+		setBatch: setBatch,
+		batch:    make([]GenericDataType, 0, 16),
+		interval: time.Millisecond,
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	if p.interval > 0 {
+		p.timer = time.AfterFunc(p.interval, p.Flush)
+		p.timer.Stop()
+	}
+
+	return p
+}
+
+// Set stages the data and sets the batch on the diode if it is full.
+// Values that are set after Close are set on the diode right away.
+func (p *Producer) Set(data GenericDataType) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		p.setBatch([]GenericDataType{data})
+		return
+	}
+
+	p.batch = append(p.batch, data)
+	if len(p.batch) == cap(p.batch) {
+		p.flush()
+		return
+	}
+	if len(p.batch) == 1 && p.timer != nil {
+		p.timer.Reset(p.interval)
+	}
+}
+
+// Flush sets the staged values on the diode.
+func (p *Producer) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flush()
+}
+
+func (p *Producer) flush() {
+	if len(p.batch) == 0 {
+		return
+	}
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+
+	p.setBatch(p.batch)
+	clear(p.batch)
+	p.batch = p.batch[:0]
+}
+
+// Close sets the staged values on the diode and stops the flush timer. It
+// is safe to call Close more than once.
+func (p *Producer) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flush()
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.closed = true
+}
+
+// NewProducer returns a Producer that stages the values of a writer and sets
+// them on the diode in batches.
+func (d *ManyToOne) NewProducer(opts ...ProducerConfigOption) *Producer {
+	return newProducer(func(data []GenericDataType) {
+		setBatchMany(&d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data)
+	}, opts)
+}
+
+// NewProducer returns a Producer that stages the values of a writer and sets
+// them on the diode in batches.
+func (d *ManyToMany) NewProducer(opts ...ProducerConfigOption) *Producer {
+	return newProducer(func(data []GenericDataType) {
+		setBatchMany(&d.writeIndex, &d.readIndex, &d.buffer, &d.diodeConfig, &d.diodeStats, data)
+	}, opts)
+}
@@ -0,0 +1,100 @@
+package diodes_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Producer", func() {
+	var d *diodes.ManyToOne
+
+	set := func(p *diodes.Producer, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			p.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	BeforeEach(func() {
+		d = diodes.NewManyToOne(100, nil)
+	})
+
+	It("sets the values once a batch is full", func() {
+		p := d.NewProducer(diodes.WithProducerBatchSize(4), diodes.WithProducerFlushInterval(0))
+		set(p, 0, 3)
+		Expect(read()).To(BeEmpty())
+
+		set(p, 3, 6)
+		Expect(read()).To(Equal([]int{0, 1, 2, 3}))
+
+		p.Flush()
+		Expect(read()).To(Equal([]int{4, 5}))
+	})
+
+	It("sets the values once the flush interval passed", func() {
+		p := d.NewProducer(diodes.WithProducerFlushInterval(10 * time.Millisecond))
+		defer p.Close()
+		set(p, 0, 2)
+
+		Eventually(func() int { return d.Len() }).Should(Equal(2))
+		Expect(read()).To(Equal([]int{0, 1}))
+	})
+
+	It("sets the staged values on Close and every value after it", func() {
+		p := d.NewProducer()
+		set(p, 0, 2)
+		p.Close()
+		Expect(read()).To(Equal([]int{0, 1}))
+
+		set(p, 2, 3)
+		Expect(read()).To(Equal([]int{2}))
+	})
+
+	It("accounts for every write of concurrent producers", func() {
+		d = diodes.NewManyToOne(64, nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p := d.NewProducer(diodes.WithProducerBatchSize(7))
+				defer p.Close()
+				set(p, 0, 1000)
+			}()
+		}
+		wg.Wait()
+
+		reads := uint64(len(read()))
+		Expect(reads + d.Dropped()).To(Equal(uint64(8000)))
+		Expect(d.CheckInvariants()).To(Succeed())
+	})
+
+	It("works with a ManyToMany diode", func() {
+		m := diodes.NewManyToMany(10, nil)
+		p := m.NewProducer()
+		set(p, 0, 3)
+		p.Flush()
+
+		data, ok := m.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+		Expect(m.Len()).To(Equal(2))
+	})
+})
@@ -0,0 +1,5 @@
+//go:build race
+
+package diodes_test
+
+const raceEnabled = true
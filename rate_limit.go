@@ -0,0 +1,100 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// writeLimiter is a token bucket, implemented as the generic cell rate
+// algorithm so that many writers can share it without a lock. Instead of
+// counting tokens it tracks the time at which the bucket would be full
+// again.
+type writeLimiter struct {
+	// interval is the time it takes to earn a single token and tolerance how
+	// far the full time may be ahead of now, i.e. the size of the bucket.
+	interval  int64
+	tolerance int64
+	full      atomic.Int64
+
+	alerter Alerter
+	limited atomic.Uint64
+}
+
+func newWriteLimiter(rps float64, burst int, alerter Alerter) *writeLimiter {
+	if alerter == nil {
+		alerter = AlertFunc(func(int) {})
+	}
+
+	interval := int64(float64(time.Second) / rps)
+	return &writeLimiter{
+		interval:  interval,
+		tolerance: interval * int64(max(burst, 1)),
+		alerter:   alerter,
+	}
+}
+
+// reserve takes a token and returns how long the writer has to wait before
+// it may use it. It does not take a token and returns false if the wait
+// would exceed maxWait.
+func (l *writeLimiter) reserve(now int64, maxWait time.Duration) (time.Duration, bool) {
+	for {
+		full := l.full.Load()
+		next := max(full, now) + l.interval
+
+		wait := time.Duration(next - l.tolerance - now)
+		if wait > maxWait {
+			return 0, false
+		}
+
+		if l.full.CompareAndSwap(full, next) {
+			return max(wait, 0), true
+		}
+	}
+}
+
+// WithWriteRateLimit limits the rate at which values are set to rps values
+// per second, which must be positive, with bursts of up to burst values.
+// Values beyond the rate are dropped before they reach the diode, so they
+// never displace values of well-behaved writers. The alerter is invoked with
+// 1 on the writer's go-routine for every value that is dropped this way,
+// separately from the alerter of the diode which reports values that were
+// overwritten, so with many writers it must be safe for concurrent use. A
+// nil can be used to ignore alerts. The dropped values are counted in
+// Stats.RateLimited.
+func WithWriteRateLimit(rps float64, burst int, alerter Alerter) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.writeLimit = newWriteLimiter(rps, burst, alerter)
+	})
+}
+
+// WithWriteRateLimitWait makes Set wait up to maxWait for the rate limit of
+// WithWriteRateLimit instead of dropping the value right away, which smooths
+// out short bursts at the cost of delaying the writer. Values that would
+// have to wait longer are still dropped. The default is to never wait.
+func WithWriteRateLimitWait(maxWait time.Duration) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.writeLimitWait = maxWait
+	})
+}
+
+// admitWrite reports whether the data may be set under the rate limit,
+// waiting for it if needed. Values that are not admitted are counted and
+// alerted. The end of the stream is always admitted, so that Close is never
+// lost to the rate limit.
+func (c *diodeConfig) admitWrite(data GenericDataType) bool {
+	if c.writeLimit == nil || data == endOfStream {
+		return true
+	}
+
+	wait, ok := c.writeLimit.reserve(nanotime(), c.writeLimitWait)
+	if !ok {
+		c.writeLimit.limited.Add(1)
+		c.writeLimit.alerter.Alert(1)
+		return false
+	}
+
+	if wait > 0 {
+		time.Sleep(wait)
+	}
+	return true
+}
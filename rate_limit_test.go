@@ -0,0 +1,118 @@
+package diodes_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithWriteRateLimit", func() {
+	type statsDiode interface {
+		diodes.Diode
+		Stats() diodes.Stats
+	}
+
+	set := func(d diodes.Diode, n int) {
+		for i := 0; i < n; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	DescribeTable("drops values beyond the burst",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) statsDiode) {
+			var limited atomic.Int64
+			alerter := diodes.AlertFunc(func(missed int) { limited.Add(int64(missed)) })
+			d := newDiode(diodes.WithWriteRateLimit(1, 3, alerter))
+			set(d, 5)
+
+			Expect(limited.Load()).To(Equal(int64(2)))
+			st := d.Stats()
+			Expect(st.Writes).To(Equal(uint64(3)))
+			Expect(st.RateLimited).To(Equal(uint64(2)))
+			Expect(st.Dropped).To(BeZero())
+
+			for i := 0; i < 3; i++ {
+				data, ok := d.TryNext()
+				Expect(ok).To(BeTrue())
+				Expect(*(*int)(data)).To(Equal(i))
+			}
+			_, ok := d.TryNext()
+			Expect(ok).To(BeFalse())
+		},
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewOneToOne(8, nil, opts...)
+		}),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToOne(8, nil, opts...)
+		}),
+	)
+
+	It("refills at the given rate", func() {
+		d := diodes.NewOneToOne(8, nil, diodes.WithWriteRateLimit(100, 1, nil))
+		set(d, 2)
+		Expect(d.Stats().Writes).To(Equal(uint64(1)))
+
+		Eventually(func() uint64 {
+			set(d, 1)
+			return d.Stats().Writes
+		}).Should(Equal(uint64(2)))
+	})
+
+	It("waits for the rate limit with WithWriteRateLimitWait", func() {
+		d := diodes.NewOneToOne(8, nil,
+			diodes.WithWriteRateLimit(100, 1, nil),
+			diodes.WithWriteRateLimitWait(time.Second),
+		)
+
+		start := time.Now()
+		set(d, 3)
+
+		Expect(time.Since(start)).To(BeNumerically(">=", 15*time.Millisecond))
+		st := d.Stats()
+		Expect(st.Writes).To(Equal(uint64(3)))
+		Expect(st.RateLimited).To(BeZero())
+	})
+
+	It("still drops values that would wait too long", func() {
+		d := diodes.NewOneToOne(8, nil,
+			diodes.WithWriteRateLimit(1, 1, nil),
+			diodes.WithWriteRateLimitWait(time.Millisecond),
+		)
+		set(d, 3)
+
+		st := d.Stats()
+		Expect(st.Writes).To(Equal(uint64(1)))
+		Expect(st.RateLimited).To(Equal(uint64(2)))
+	})
+
+	DescribeTable("never drops the end of the stream",
+		func(newDiode func(opts ...diodes.DiodeConfigOption) statsDiode) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			p := diodes.NewPoller(newDiode(diodes.WithWriteRateLimit(1, 1, nil)))
+			set(p, 1)
+			p.Close()
+
+			_, err := p.NextCtx(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = p.NextCtx(ctx)
+			Expect(err).To(MatchError(diodes.ErrClosed))
+		},
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewOneToOne(16, nil, opts...)
+		}),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToOne(16, nil, opts...)
+		}),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToMany(16, nil, opts...)
+		}),
+	)
+})
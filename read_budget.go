@@ -0,0 +1,29 @@
+package diodes
+
+import "runtime"
+
+// readBudget limits how many values a reader returns in a row without
+// yielding the processor. It must only be used by the reader.
+type readBudget struct {
+	limit int
+	used  int
+}
+
+// spend records a value that is returned to the reader and yields the
+// processor once the budget is used up.
+func (b *readBudget) spend() {
+	if b.limit <= 0 {
+		return
+	}
+
+	b.used++
+	if b.used > b.limit {
+		b.used = 1
+		runtime.Gosched()
+	}
+}
+
+// reset starts a new cycle, e.g. after the reader had to wait for data.
+func (b *readBudget) reset() {
+	b.used = 0
+}
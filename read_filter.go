@@ -0,0 +1,25 @@
+package diodes
+
+import "sync/atomic"
+
+// readFilter filters and transforms the values a reader reads before they
+// are returned. Only the counter is safe to read from other go-routines.
+type readFilter struct {
+	keep      func(GenericDataType) bool
+	transform func(GenericDataType) GenericDataType
+	filtered  atomic.Uint64
+}
+
+// apply returns the value to hand to the reader, or false if the filter
+// dropped it.
+func (f *readFilter) apply(data GenericDataType) (GenericDataType, bool) {
+	if f.keep != nil && !f.keep(data) {
+		f.filtered.Add(1)
+		return nil, false
+	}
+
+	if f.transform != nil {
+		data = f.transform(data)
+	}
+	return data, true
+}
@@ -0,0 +1,55 @@
+package diodes
+
+// ReadHandle is a value read via OneToOne.TryNextHandle along with the
+// bucket it was stored in. The bucket goes back to the diode's pool once
+// the handle is released, so that the writer can reuse it for a later
+// value. The zero value holds no value.
+type ReadHandle struct {
+	d *OneToOne
+	b *bucket
+}
+
+// TryNextHandle is like TryNext, but rather than putting the bucket of the
+// value back into the pool right away, it lends it to the returned handle.
+// Data and Seq read the value from the bucket without copying it until
+// Release gives the bucket back to the writer, so the value stays valid for
+// as long as the reader holds on to it, e.g. while it hands out a batch. A
+// handle that is not released leaves its bucket to the garbage collector.
+// If there is no data available, it will return a zero handle and false.
+func (d *OneToOne) TryNextHandle() (ReadHandle, bool) {
+	b, _, ok := d.next()
+	if !ok {
+		return ReadHandle{}, false
+	}
+	return ReadHandle{d: d, b: b}, true
+}
+
+// Data returns the value of the handle, or nil once it was released.
+func (h *ReadHandle) Data() GenericDataType {
+	if h.b == nil {
+		return nil
+	}
+	return h.b.data
+}
+
+// Seq returns the sequence number of the value, see OneToOne.TryNextSeq, or
+// 0 once the handle was released.
+func (h *ReadHandle) Seq() uint64 {
+	if h.b == nil {
+		return 0
+	}
+	return h.b.seq
+}
+
+// Release puts the bucket of the value back into the pool of the diode. The
+// value itself still belongs to the caller. It must be called by the reader
+// and only once for every value, since a copy of the handle that is
+// released again would hand the same bucket to the writer twice. Releasing
+// the handle it was called on again does nothing.
+func (h *ReadHandle) Release() {
+	if h.b == nil {
+		return
+	}
+	h.d.recycle(h.b)
+	h.d, h.b = nil, nil
+}
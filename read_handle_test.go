@@ -0,0 +1,98 @@
+package diodes_test
+
+import (
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadHandle", func() {
+	var d *diodes.OneToOne
+
+	value := func(i int) diodes.GenericDataType {
+		return diodes.GenericDataType(&i)
+	}
+
+	BeforeEach(func() {
+		d = diodes.NewOneToOne(4, nil)
+	})
+
+	It("hands out the value along with its sequence number", func() {
+		d.Set(value(7))
+		d.Set(value(8))
+		d.TryNext()
+
+		h, ok := d.TryNextHandle()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(h.Data())).To(Equal(8))
+		Expect(h.Seq()).To(Equal(uint64(1)))
+	})
+
+	It("returns false without a handle when there is no data", func() {
+		h, ok := d.TryNextHandle()
+		Expect(ok).To(BeFalse())
+		Expect(h.Data() == nil).To(BeTrue())
+		h.Release()
+	})
+
+	It("no longer holds the value once it was released", func() {
+		d.Set(value(7))
+		h, _ := d.TryNextHandle()
+		h.Release()
+		h.Release()
+
+		Expect(h.Data() == nil).To(BeTrue())
+		Expect(h.Seq()).To(BeZero())
+	})
+
+	It("does not mix up values when the writer reuses the released buckets", func() {
+		for i := 0; i < 100; i++ {
+			d.Set(value(2 * i))
+			d.Set(value(2*i + 1))
+
+			a, _ := d.TryNextHandle()
+			b, _ := d.TryNextHandle()
+			Expect(*(*int)(a.Data())).To(Equal(2 * i))
+			Expect(*(*int)(b.Data())).To(Equal(2*i + 1))
+			a.Release()
+			b.Release()
+		}
+	})
+
+	It("does not allocate once the reader releases what it holds", func() {
+		data := value(1)
+		handles := make([]diodes.ReadHandle, 4)
+		cycle := func() {
+			for range handles {
+				d.Set(data)
+			}
+			for i := range handles {
+				handles[i], _ = d.TryNextHandle()
+			}
+			for i := range handles {
+				handles[i].Release()
+			}
+		}
+		cycle()
+
+		Expect(testing.AllocsPerRun(100, cycle)).To(BeZero())
+	})
+
+	It("leaves the values to the cursors of the diode", func() {
+		c := d.NewCursor(nil)
+		d.Set(value(1))
+		h, _ := d.TryNextHandle()
+		h.Release()
+		d.Set(value(2))
+
+		data, ok := c.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(1))
+		data, ok = c.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(2))
+	})
+})
@@ -0,0 +1,72 @@
+package diodes
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// registry holds the diodes of the process by name, see Register.
+var registry = struct {
+	mu     sync.Mutex
+	diodes map[string]Diode
+}{
+	diodes: make(map[string]Diode),
+}
+
+// Register adds the diode to the registry of the process under the given
+// name, so that debugging endpoints can enumerate every diode of the process
+// via Range instead of being wired to every one of them. It returns
+// ErrRegistered if the name is taken. A diode that is no longer used should
+// be unregistered via Unregister, since the registry keeps it alive.
+func Register(name string, d Diode) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, ok := registry.diodes[name]; ok {
+		return fmt.Errorf("%w: %q", ErrRegistered, name)
+	}
+	registry.diodes[name] = d
+	return nil
+}
+
+// Unregister removes the diode with the given name from the registry of the
+// process, if there is one.
+func Unregister(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.diodes, name)
+}
+
+// Lookup returns the diode that is registered under the given name.
+func Lookup(name string) (Diode, bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	d, ok := registry.diodes[name]
+	return d, ok
+}
+
+// Range invokes fn for every registered diode, sorted by name, until fn
+// returns false. Diodes that report their state can be inspected with a
+// type assertion, e.g. for Stats() or Snapshot(). fn is invoked outside of
+// the lock of the registry, so it may register and unregister diodes, which
+// Range may or may not visit.
+func Range(fn func(name string, d Diode) bool) {
+	registry.mu.Lock()
+	names := make([]string, 0, len(registry.diodes))
+	for name := range registry.diodes {
+		names = append(names, name)
+	}
+	registry.mu.Unlock()
+
+	sort.Strings(names)
+	for _, name := range names {
+		d, ok := Lookup(name)
+		if !ok {
+			continue
+		}
+		if !fn(name, d) {
+			return
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package diodes_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Register", func() {
+	names := func() []string {
+		var got []string
+		diodes.Range(func(name string, _ diodes.Diode) bool {
+			got = append(got, name)
+			return true
+		})
+		return got
+	}
+
+	AfterEach(func() {
+		diodes.Unregister("egress")
+		diodes.Unregister("ingress")
+	})
+
+	It("enumerates the registered diodes by name", func() {
+		ingress := diodes.NewManyToOne(8, nil)
+		Expect(diodes.Register("ingress", ingress)).To(Succeed())
+		Expect(diodes.Register("egress", diodes.NewOneToOne(8, nil))).To(Succeed())
+
+		Expect(names()).To(Equal([]string{"egress", "ingress"}))
+
+		d, ok := diodes.Lookup("ingress")
+		Expect(ok).To(BeTrue())
+		Expect(d).To(BeIdenticalTo(ingress))
+
+		diodes.Unregister("ingress")
+		Expect(names()).To(Equal([]string{"egress"}))
+		_, ok = diodes.Lookup("ingress")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects a name that is taken", func() {
+		Expect(diodes.Register("ingress", diodes.NewManyToOne(8, nil))).To(Succeed())
+
+		err := diodes.Register("ingress", diodes.NewManyToOne(8, nil))
+		Expect(errors.Is(err, diodes.ErrRegistered)).To(BeTrue())
+	})
+
+	It("stops once the function returns false", func() {
+		Expect(diodes.Register("ingress", diodes.NewManyToOne(8, nil))).To(Succeed())
+		Expect(diodes.Register("egress", diodes.NewOneToOne(8, nil))).To(Succeed())
+
+		var visited int
+		diodes.Range(func(string, diodes.Diode) bool {
+			visited++
+			return false
+		})
+		Expect(visited).To(Equal(1))
+	})
+})
@@ -0,0 +1,39 @@
+package diodes
+
+import "unsafe"
+
+// WithRetryBudget makes a writer of a diode with many writers give up on a
+// value once it collided with other writers or the reader n times in a row,
+// instead of retrying until it succeeds, so that writers are not stalled by
+// extreme contention. The value is then neither stored nor reported to the
+// alerter, but counted in Stats.FailedWrites and handed to onFail, which may
+// be nil, on the writer's go-routine. It must be safe for concurrent use.
+// The default is to retry without a limit.
+func WithRetryBudget(n int, onFail func(data GenericDataType)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.retryBudget = max(n, 0)
+		c.onWriteFail = onFail
+	})
+}
+
+// fw is only used for its address.
+var fw byte
+
+// failedWrite is stored in place of a value that a writer gave up on, so
+// that the reader does not wait for the value of the write index forever.
+// It is skipped by the reader.
+var failedWrite = GenericDataType(unsafe.Pointer(&fw))
+
+// failWrite counts the data as a failed write and hands it to the callback.
+func (c *diodeConfig) failWrite(s *diodeStats, data GenericDataType) {
+	s.failedWrites.Add(1)
+	if c.onWriteFail != nil && data != unbuilt {
+		c.onWriteFail(data)
+	}
+}
+
+// exhausted reports whether a writer ran out of retries after the given
+// number of collisions.
+func (c *diodeConfig) exhausted(collisions int) bool {
+	return c.retryBudget > 0 && collisions >= c.retryBudget
+}
@@ -0,0 +1,143 @@
+package diodes
+
+import (
+	"math/bits"
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ring holds the slots of a diode. By default the slots are a single slice.
+// With WithSegmentSize they are a chain of fixed size segments that are
+// allocated the first time a writer reaches them.
+type ring struct {
+	size uint64
+	flat []unsafe.Pointer
+
+	// pow2 reports whether the size is a power of two, in which case
+	// positions are masked with sizeMask rather than computed with a modulo.
+	pow2     bool
+	sizeMask uint64
+
+	// segments holds a *[]unsafe.Pointer for every segment, or nil for
+	// segments that have not been written to yet.
+	segments  []unsafe.Pointer
+	segSize   int
+	shift     uint
+	mask      uint64
+	allocated atomic.Uint64
+	alloc     SlotAllocator
+}
+
+func (r *ring) init(size int, c *diodeConfig) {
+	size = max(size, c.minSize)
+	if c.powerOfTwo && size > 1 {
+		size = 1 << bits.Len(uint(size-1))
+	}
+	r.size = uint64(size)
+	r.pow2 = size > 0 && size&(size-1) == 0
+	r.sizeMask = r.size - 1
+	r.alloc = c.slotAlloc
+	if r.alloc == nil {
+		r.alloc = func(n int) []unsafe.Pointer {
+			return make([]unsafe.Pointer, n)
+		}
+	}
+
+	segmentSize := c.segmentSize
+	if segmentSize <= 0 || segmentSize >= size {
+		r.flat = r.allocSlots(size)
+		r.allocated.Store(uint64(size))
+		if c.prefault {
+			touch(r.flat)
+		}
+		return
+	}
+
+	// Round the segment size up to a power of two so that locating a slot
+	// is a shift and a mask.
+	r.shift = uint(bits.Len(uint(segmentSize - 1)))
+	r.segSize = 1 << r.shift
+	r.mask = uint64(r.segSize - 1)
+	r.segments = make([]unsafe.Pointer, (size+r.segSize-1)/r.segSize)
+
+	if c.prefault {
+		for i := range r.segments {
+			r.slot(uint64(i * r.segSize))
+			touch(*(*[]unsafe.Pointer)(r.segments[i]))
+		}
+	}
+}
+
+// allocSlots returns n slots from the allocator. It panics if the allocator
+// does not return exactly n slots, as the ring could not use them.
+func (r *ring) allocSlots(n int) []unsafe.Pointer {
+	slots := r.alloc(n)
+	if len(slots) != n {
+		panic("diodes: slot allocator returned the wrong number of slots")
+	}
+	return slots
+}
+
+// touch writes to every page of the slots, so that the operating system
+// maps them now rather than on the first lap of the writers.
+func touch(slots []unsafe.Pointer) {
+	stride := max(os.Getpagesize()/int(unsafe.Sizeof(unsafe.Pointer(nil))), 1)
+	for i := 0; i < len(slots); i += stride {
+		slots[i] = nil
+	}
+}
+
+// slot returns the slot for the given write index, allocating its segment
+// if needed. It is safe to call from multiple writers.
+func (r *ring) slot(index uint64) *unsafe.Pointer {
+	idx := r.position(index)
+	if r.segments == nil {
+		return &r.flat[idx]
+	}
+
+	s := &r.segments[idx>>r.shift]
+	seg := (*[]unsafe.Pointer)(atomic.LoadPointer(s))
+	if seg == nil {
+		// The last segment only needs to hold what is left of the ring.
+		newSeg := r.allocSlots(int(min(uint64(r.segSize), r.size-(idx&^r.mask))))
+		if atomic.CompareAndSwapPointer(s, nil, unsafe.Pointer(&newSeg)) {
+			seg = &newSeg
+			r.allocated.Add(uint64(len(newSeg)))
+		} else {
+			seg = (*[]unsafe.Pointer)(atomic.LoadPointer(s))
+		}
+	}
+
+	return &(*seg)[idx&r.mask]
+}
+
+// peek returns the slot for the given index, or nil if its segment has not
+// been allocated yet, in which case nothing was written to it.
+func (r *ring) peek(index uint64) *unsafe.Pointer {
+	idx := r.position(index)
+	if r.segments == nil {
+		return &r.flat[idx]
+	}
+
+	seg := (*[]unsafe.Pointer)(atomic.LoadPointer(&r.segments[idx>>r.shift]))
+	if seg == nil {
+		return nil
+	}
+
+	return &(*seg)[idx&r.mask]
+}
+
+// position returns the position of the index in the ring.
+func (r *ring) position(index uint64) uint64 {
+	if r.pow2 {
+		return index & r.sizeMask
+	}
+	return index % r.size
+}
+
+// fillStats sets the capacity and the number of allocated slots.
+func (r *ring) fillStats(st *Stats) {
+	st.Capacity = r.size
+	st.AllocatedSlots = r.allocated.Load()
+}
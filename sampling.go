@@ -0,0 +1,67 @@
+package diodes
+
+import "sync/atomic"
+
+// WithOverloadSampling makes Set keep only one in every n values while the
+// diode is full and discard the others, instead of overwriting the oldest
+// unread value with every one of them. The reader then sees a sample spread
+// over an overload burst rather than only its tail. Discarded values count
+// as dropped and are reported to the alerter on the reader's go-routine the
+// next time it tries to read, along with the values that were overwritten.
+// Stats tells whether the diode is sampling and how many values were
+// discarded. An n of 1 or less disables it, and WithDropNewest takes
+// precedence over it. The OneToMany diode does not support it.
+func WithOverloadSampling(n int) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		if n <= 1 {
+			c.sampler = nil
+			return
+		}
+
+		c.sampler = &sampler{every: uint64(n)}
+		if c.discarded == nil {
+			c.discarded = new(atomic.Uint64)
+		}
+	})
+}
+
+// sampler counts the values set while a diode is full to keep one in every
+// n of them.
+type sampler struct {
+	every      uint64
+	count      atomic.Uint64
+	active     atomic.Bool
+	sampledOut atomic.Uint64
+}
+
+// sampleOut reports whether the data was discarded because the diode is full
+// and the data is not one of the values the sample keeps. The sample starts
+// over once the diode is no longer full.
+func (c *diodeConfig) sampleOut(full bool, data GenericDataType) bool {
+	s := c.sampler
+	if !full {
+		// Only store when the state changes, so that writers do not keep
+		// invalidating each others cache lines.
+		if s.active.Load() {
+			s.active.Store(false)
+			s.count.Store(0)
+		}
+		return false
+	}
+
+	// The end of the stream must not be lost, so it is always kept.
+	if data == endOfStream {
+		return false
+	}
+
+	if !s.active.Load() {
+		s.active.Store(true)
+	}
+	if s.count.Add(1)%s.every == 0 {
+		return false
+	}
+
+	s.sampledOut.Add(1)
+	c.discard(data)
+	return true
+}
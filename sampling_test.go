@@ -0,0 +1,108 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithOverloadSampling", func() {
+	type statsDiode interface {
+		diodes.Diode
+		Stats() diodes.Stats
+	}
+
+	set := func(d diodes.Diode, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d diodes.Diode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewOneToOne(size, a, opts...)
+		}),
+		Entry("ManyToOne", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToOne(size, a, opts...)
+		}),
+		Entry("ManyToMany", func(size int, a diodes.Alerter, opts ...diodes.DiodeConfigOption) statsDiode {
+			return diodes.NewManyToMany(size, a, opts...)
+		}),
+	}
+
+	DescribeTable("keeps a sample of the burst and alerts the reader",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) statsDiode) {
+			spy := newSpyAlerter()
+			d := newDiode(4, spy, diodes.WithOverloadSampling(3))
+
+			set(d, 0, 4)
+			Expect(d.Stats().Sampling).To(BeFalse())
+
+			set(d, 4, 13)
+			st := d.Stats()
+			Expect(st.Sampling).To(BeTrue())
+			Expect(st.SampledOut).To(Equal(uint64(6)))
+
+			// The kept values overwrote the oldest ones, so the reader that
+			// was lapped skips to the first of them.
+			Expect(read(d)).To(Equal([]int{6, 9, 12}))
+			Expect(d.Stats().Dropped).To(Equal(uint64(10)))
+
+			var missed int
+			for len(spy.AlertInput.Missed) > 0 {
+				missed += <-spy.AlertInput.Missed
+			}
+			Expect(missed).To(Equal(10))
+		},
+		entries,
+	)
+
+	DescribeTable("stops sampling once the diode is no longer full",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) statsDiode) {
+			d := newDiode(4, nil, diodes.WithOverloadSampling(2))
+
+			set(d, 0, 6)
+			Expect(d.Stats().Sampling).To(BeTrue())
+			read(d)
+
+			set(d, 10, 13)
+			Expect(d.Stats().Sampling).To(BeFalse())
+			Expect(read(d)).To(Equal([]int{10, 11, 12}))
+		},
+		entries,
+	)
+
+	DescribeTable("does not sample out the end of the stream",
+		func(newDiode func(int, diodes.Alerter, ...diodes.DiodeConfigOption) statsDiode) {
+			p := diodes.NewPoller(newDiode(2, nil, diodes.WithOverloadSampling(4)))
+			set(p, 0, 2)
+			p.Close()
+
+			for p.Next() != nil {
+			}
+			Expect(p.Closed()).To(BeTrue())
+		},
+		entries,
+	)
+
+	It("defers to WithDropNewest", func() {
+		d := diodes.NewOneToOne(2, nil, diodes.WithOverloadSampling(2), diodes.WithDropNewest())
+
+		set(d, 0, 6)
+		Expect(read(d)).To(Equal([]int{0, 1}))
+		Expect(d.Stats().SampledOut).To(BeZero())
+	})
+})
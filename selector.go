@@ -0,0 +1,75 @@
+package diodes
+
+import "context"
+
+// Selector reads from several Waiters on a single go-routine, like a select
+// statement over channels. It blocks until any of them has data instead of
+// polling each one. It is not thread safe for multiple readers.
+type Selector struct {
+	waiters []*Waiter
+	c       chan struct{}
+	next    int
+}
+
+// NewSelector returns a new Selector that reads from the given waiters. A
+// waiter can only belong to a single Selector, and it should only be read
+// through the Selector from then on.
+func NewSelector(waiters ...*Waiter) *Selector {
+	s := &Selector{
+		waiters: waiters,
+		c:       make(chan struct{}, 1),
+	}
+
+	for _, w := range waiters {
+		w.selector.Store(&s.c)
+	}
+
+	return s
+}
+
+// TryNext will attempt to read from the waiters, starting after the one
+// that was read last so that a busy waiter can not starve the others. It
+// returns the data along with the index of the waiter it was read from. If
+// there is no data available, it will return (nil, -1, false).
+func (s *Selector) TryNext() (data GenericDataType, index int, ok bool) {
+	for i := range s.waiters {
+		index = (s.next + i) % len(s.waiters)
+		if data, ok = s.waiters[index].TryNext(); ok {
+			s.next = index + 1
+			return data, index, true
+		}
+	}
+	return nil, -1, false
+}
+
+// Next returns the next data point of any of the waiters along with the
+// index of the waiter it was read from. If there is no data, it waits for
+// Set to be called on any of them or the context to be done. It returns
+// ErrClosed once every waiter reached the end of its stream and the
+// context's error otherwise (see Waiter.NextCtx).
+func (s *Selector) Next(ctx context.Context) (GenericDataType, int, error) {
+	for {
+		data, index, ok := s.TryNext()
+		if ok {
+			return data, index, nil
+		}
+		if s.closed() {
+			return nil, -1, ErrClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, -1, contextErr(ctx)
+		case <-s.c:
+		}
+	}
+}
+
+func (s *Selector) closed() bool {
+	for _, w := range s.waiters {
+		if !w.Closed() {
+			return false
+		}
+	}
+	return true
+}
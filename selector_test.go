@@ -0,0 +1,90 @@
+package diodes_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selector", func() {
+	var (
+		a, b *diodes.Waiter
+		s    *diodes.Selector
+	)
+
+	BeforeEach(func() {
+		a = diodes.NewWaiter(diodes.NewManyToOne(5, nil))
+		b = diodes.NewWaiter(diodes.NewManyToOne(5, nil))
+		s = diodes.NewSelector(a, b)
+	})
+
+	set := func(w *diodes.Waiter, v int) {
+		w.Set(diodes.GenericDataType(&v))
+	}
+
+	It("returns data with the index of its waiter", func() {
+		set(b, 1)
+
+		data, index, err := s.Next(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*(*int)(data)).To(Equal(1))
+		Expect(index).To(Equal(1))
+
+		_, index, ok := s.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(index).To(Equal(-1))
+	})
+
+	It("takes turns between the waiters", func() {
+		for i := 0; i < 2; i++ {
+			set(a, i)
+			set(b, i)
+		}
+
+		var indexes []int
+		for i := 0; i < 4; i++ {
+			_, index, _ := s.TryNext()
+			indexes = append(indexes, index)
+		}
+		Expect(indexes).To(Equal([]int{0, 1, 0, 1}))
+	})
+
+	It("waits for Set on any waiter", func() {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			set(b, 1)
+		}()
+
+		_, index, err := s.Next(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(index).To(Equal(1))
+	})
+
+	It("returns the error of the context", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, _, err := s.Next(ctx)
+		Expect(err).To(MatchError(diodes.ErrTimeout))
+	})
+
+	It("returns ErrClosed once every waiter is closed", func() {
+		a.Close()
+		set(b, 1)
+
+		_, _, err := s.Next(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			b.Close()
+		}()
+
+		_, _, err = s.Next(context.Background())
+		Expect(err).To(MatchError(diodes.ErrClosed))
+	})
+})
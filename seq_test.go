@@ -0,0 +1,62 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TryNextSeq", func() {
+	type seqDiode interface {
+		diodes.Diode
+		TryNextSeq() (diodes.GenericDataType, uint64, bool)
+	}
+
+	set := func(d diodes.Writer, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	DescribeTable("returns the sequence number of every value, with gaps for the drops",
+		func(newDiode func() seqDiode) {
+			d := newDiode()
+			_, _, ok := d.TryNextSeq()
+			Expect(ok).To(BeFalse())
+
+			set(d, 0, 2)
+			for i := 0; i < 2; i++ {
+				data, seq, ok := d.TryNextSeq()
+				Expect(ok).To(BeTrue())
+				Expect(*(*int)(data)).To(Equal(i))
+				Expect(seq).To(Equal(uint64(i)))
+			}
+
+			set(d, 2, 10)
+			data, seq, ok := d.TryNextSeq()
+			Expect(ok).To(BeTrue())
+			Expect(seq).To(Equal(uint64(*(*int)(data))))
+			Expect(seq).To(BeNumerically(">", 2))
+		},
+		Entry("OneToOne", func() seqDiode { return diodes.NewOneToOne(4, nil) }),
+		Entry("ManyToOne", func() seqDiode { return diodes.NewManyToOne(4, nil) }),
+		Entry("ManyToMany", func() seqDiode { return diodes.NewManyToMany(4, nil) }),
+	)
+
+	It("returns the sequence number for a OneToMany reader", func() {
+		d := diodes.NewOneToMany(4)
+		set(d, 0, 3)
+
+		r := d.NewReader(nil)
+		set(d, 3, 5)
+
+		for i := 3; i < 5; i++ {
+			data, seq, ok := r.TryNextSeq()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(i))
+			Expect(seq).To(Equal(uint64(i)))
+		}
+	})
+})
@@ -0,0 +1,141 @@
+package diodes_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SetCtx", func() {
+	type ctxDiode interface {
+		diodes.Diode
+		SetCtx(context.Context, diodes.GenericDataType) error
+		Dropped() uint64
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(opts ...diodes.DiodeConfigOption) ctxDiode { return diodes.NewOneToOne(2, nil, opts...) }),
+		Entry("ManyToOne", func(opts ...diodes.DiodeConfigOption) ctxDiode { return diodes.NewManyToOne(2, nil, opts...) }),
+		Entry("ManyToMany", func(opts ...diodes.DiodeConfigOption) ctxDiode { return diodes.NewManyToMany(2, nil, opts...) }),
+	}
+
+	value := func(i int) diodes.GenericDataType {
+		return diodes.GenericDataType(&i)
+	}
+
+	read := func(d ctxDiode) []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	fill := func(d ctxDiode) {
+		d.Set(value(0))
+		d.Set(value(1))
+	}
+
+	DescribeTable("sets the data right away while there is room",
+		func(newDiode func(...diodes.DiodeConfigOption) ctxDiode) {
+			d := newDiode()
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			Expect(d.SetCtx(ctx, value(0))).To(Succeed())
+			Expect(read(d)).To(Equal([]int{0}))
+		},
+		entries,
+	)
+
+	DescribeTable("waits for the reader to make room",
+		func(newDiode func(...diodes.DiodeConfigOption) ctxDiode) {
+			d := newDiode()
+			fill(d)
+
+			errs := make(chan error, 1)
+			go func() { errs <- d.SetCtx(context.Background(), value(2)) }()
+			Consistently(errs, 20*time.Millisecond).ShouldNot(Receive())
+
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(0))
+
+			Eventually(errs).Should(Receive(BeNil()))
+			Expect(read(d)).To(Equal([]int{1, 2}))
+			Expect(d.Dropped()).To(BeZero())
+		},
+		entries,
+	)
+
+	DescribeTable("overwrites the oldest value once the deadline passed",
+		func(newDiode func(...diodes.DiodeConfigOption) ctxDiode) {
+			d := newDiode(diodes.WithBackpressure(time.Hour))
+			fill(d)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := d.SetCtx(ctx, value(2))
+			Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Hour))
+			Expect(err).To(MatchError(diodes.ErrTimeout))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+
+			Expect(read(d)).To(ContainElement(2))
+			Expect(d.Dropped()).ToNot(BeZero())
+		},
+		entries,
+	)
+
+	DescribeTable("discards the data once the context is canceled with WithDropNewest",
+		func(newDiode func(...diodes.DiodeConfigOption) ctxDiode) {
+			d := newDiode(diodes.WithDropNewest())
+			fill(d)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error, 1)
+			go func() { errs <- d.SetCtx(ctx, value(2)) }()
+			Consistently(errs, 20*time.Millisecond).ShouldNot(Receive())
+			cancel()
+
+			var err error
+			Eventually(errs).Should(Receive(&err))
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(err).ToNot(MatchError(diodes.ErrTimeout))
+
+			Expect(read(d)).To(Equal([]int{0, 1}))
+			Expect(d.Dropped()).To(Equal(uint64(1)))
+		},
+		entries,
+	)
+
+	It("wakes up the reader of a Waiter", func() {
+		w := diodes.NewWaiter(diodes.NewManyToOne(2, nil))
+		datas := make(chan diodes.GenericDataType, 1)
+		go func() { datas <- w.Next() }()
+
+		Expect(w.SetCtx(context.Background(), value(7))).To(Succeed())
+
+		var data diodes.GenericDataType
+		Eventually(datas).Should(Receive(&data))
+		Expect(*(*int)(data)).To(Equal(7))
+	})
+
+	It("sets the data on a Waiter of a diode that never waits", func() {
+		w := diodes.NewWaiter(diodes.NewLatest())
+		Expect(w.SetCtx(context.Background(), value(1))).To(Succeed())
+		Expect(w.SetCtx(context.Background(), value(2))).To(Succeed())
+
+		data, ok := w.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(2))
+	})
+})
@@ -0,0 +1,132 @@
+package diodes
+
+import (
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// ShardedManyToOne diode spreads its writers over several ManyToOne diodes
+// (shards), so that many writers do not all contend on a single write index.
+// The reader takes turns reading from the shards. It is safe for many
+// writers and a single reader.
+//
+// Values are only read in the order they were set within a shard. Set picks
+// a random shard for every value, so writers that need their values to be
+// read in order should set them via RegisterWriter, which binds the writer
+// to a single shard.
+type ShardedManyToOne struct {
+	shards     []*ManyToOne
+	registered atomic.Uint64
+
+	// next is the shard the reader reads from next and is only used by the
+	// reader.
+	next int
+}
+
+var _ Diode = (*ShardedManyToOne)(nil)
+
+// NewShardedManyToOne creates a new sharded diode with the given number of
+// shards of the given size each. A number of shards of 0 or less uses
+// GOMAXPROCS shards. The alerter is invoked on the reader's go-routine when
+// it notices that writers have passed it and wrote over data in any of the
+// shards. A nil can be used to ignore alerts. The options are applied to
+// every shard.
+func NewShardedManyToOne(shards, size int, alerter Alerter, opts ...DiodeConfigOption) *ShardedManyToOne {
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	d := &ShardedManyToOne{
+		shards: make([]*ManyToOne, shards),
+	}
+	for i := range d.shards {
+		d.shards[i] = NewManyToOne(size, alerter, opts...)
+	}
+
+	return d
+}
+
+// NewShardedManyToOneErr is like NewShardedManyToOne but returns an error
+// matching ErrInvalidSize instead of shards that have no slots, see
+// WithMinSize.
+func NewShardedManyToOneErr(shards, size int, alerter Alerter, opts ...DiodeConfigOption) (*ShardedManyToOne, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(size); err != nil {
+		return nil, err
+	}
+	return NewShardedManyToOne(shards, size, alerter, opts...), nil
+}
+
+// Set sets the data in a random shard.
+func (d *ShardedManyToOne) Set(data GenericDataType) {
+	d.shards[rand.IntN(len(d.shards))].Set(data)
+}
+
+// RegisterWriter returns a handle for a writer that sets all of its data in
+// the same shard. The shards are assigned to the writers in turn.
+func (d *ShardedManyToOne) RegisterWriter() *WriterHandle {
+	n := d.registered.Add(1) - 1
+	return d.shards[n%uint64(len(d.shards))].RegisterWriter()
+}
+
+// TryNext will attempt to read from the shards, starting with the one after
+// the shard that was read from last, so that a busy shard does not starve
+// the others. If there is no data available in any shard, it will return
+// (nil, false).
+func (d *ShardedManyToOne) TryNext() (data GenericDataType, ok bool) {
+	for range d.shards {
+		s := d.shards[d.next]
+		d.next = (d.next + 1) % len(d.shards)
+
+		if data, ok := s.TryNext(); ok {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// Shards returns the number of shards.
+func (d *ShardedManyToOne) Shards() int {
+	return len(d.shards)
+}
+
+// Stats returns a snapshot of the counters of the given shard.
+func (d *ShardedManyToOne) Stats(shard int) Stats {
+	return d.shards[shard].Stats()
+}
+
+// ReadStatsInto fills st with the counters of the given shard like Stats,
+// but leaves the rates zero. See ManyToOne.ReadStatsInto.
+func (d *ShardedManyToOne) ReadStatsInto(shard int, st *Stats) {
+	d.shards[shard].ReadStatsInto(st)
+}
+
+// Len returns the approximate number of unread values across all shards.
+// It is safe to call concurrently with the reader and writers.
+func (d *ShardedManyToOne) Len() int {
+	var n int
+	for _, s := range d.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+// Cap returns the number of slots across all shards.
+func (d *ShardedManyToOne) Cap() int {
+	var n int
+	for _, s := range d.shards {
+		n += s.Cap()
+	}
+	return n
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read, across all shards.
+func (d *ShardedManyToOne) Dropped() uint64 {
+	var n uint64
+	for _, s := range d.shards {
+		n += s.Dropped()
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+package diodes_test
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ShardedManyToOne", func() {
+	var d *diodes.ShardedManyToOne
+
+	BeforeEach(func() {
+		d = diodes.NewShardedManyToOne(4, 32, nil)
+	})
+
+	readAll := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	It("defaults to a shard per processor", func() {
+		d = diodes.NewShardedManyToOne(0, 32, nil)
+		Expect(d.Cap() % 32).To(BeZero())
+		Expect(d.Cap()).To(BeNumerically(">=", 32))
+	})
+
+	It("reads every value set across the shards", func() {
+		for i := 0; i < 20; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(d.Cap()).To(Equal(128))
+		Expect(d.Len()).To(Equal(20))
+		Expect(readAll()).To(ConsistOf(func() []int {
+			var want []int
+			for i := 0; i < 20; i++ {
+				want = append(want, i)
+			}
+			return want
+		}()))
+		Expect(d.Len()).To(BeZero())
+	})
+
+	It("keeps the order of registered writers and takes turns reading the shards", func() {
+		a := d.RegisterWriter()
+		b := d.RegisterWriter()
+		defer a.Close()
+		defer b.Close()
+
+		for i := 0; i < 3; i++ {
+			j, k := i, 10+i
+			a.Set(diodes.GenericDataType(&j))
+			b.Set(diodes.GenericDataType(&k))
+		}
+
+		Expect(readAll()).To(Equal([]int{0, 10, 1, 11, 2, 12}))
+	})
+
+	It("drops per shard", func() {
+		a := d.RegisterWriter()
+		defer a.Close()
+
+		for i := 0; i < 34; i++ {
+			j := i
+			a.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(readAll()).To(Equal([]int{32, 33}))
+		Expect(d.Dropped()).To(Equal(uint64(32)))
+	})
+
+	It("is safe for many writers", func() {
+		d = diodes.NewShardedManyToOne(4, 1024, nil)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					j := w*100 + i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		Expect(readAll()).To(HaveLen(800))
+	})
+})
@@ -0,0 +1,30 @@
+package diodes
+
+import "fmt"
+
+// WithMinSize raises the size of the diode to n if it is smaller, including
+// sizes of 0 or less, so that a misconfigured size degrades to a small
+// diode instead of one that has no slots. Without it, NewManyToOneErr and
+// the other constructors ending in Err reject such sizes. It is applied
+// before WithPowerOfTwoSize rounds the size up. Cap and Stats report the
+// raised size.
+func WithMinSize(n int) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.minSize = n
+	})
+}
+
+// checkSize returns an error if a diode of the given size would not have
+// any slots.
+func (c *diodeConfig) checkSize(size int) error {
+	return checkSize(size, c.minSize)
+}
+
+// checkSize returns an error if a diode of the given size would not have
+// any slots once it was raised to minSize.
+func checkSize(size, minSize int) error {
+	if max(size, minSize) < 1 {
+		return fmt.Errorf("%w: %d, must be at least 1", ErrInvalidSize, size)
+	}
+	return nil
+}
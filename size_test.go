@@ -0,0 +1,162 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating constructors", func() {
+	type sizedDiode interface {
+		diodes.Diode
+		Cap() int
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int, opts ...diodes.DiodeConfigOption) (sizedDiode, error) {
+			return diodes.NewOneToOneErr(size, nil, opts...)
+		}),
+		Entry("ManyToOne", func(size int, opts ...diodes.DiodeConfigOption) (sizedDiode, error) {
+			return diodes.NewManyToOneErr(size, nil, opts...)
+		}),
+		Entry("ManyToMany", func(size int, opts ...diodes.DiodeConfigOption) (sizedDiode, error) {
+			return diodes.NewManyToManyErr(size, nil, opts...)
+		}),
+	}
+
+	DescribeTable("reject sizes without any slots",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) (sizedDiode, error)) {
+			for _, size := range []int{0, -1} {
+				_, err := newDiode(size)
+				Expect(err).To(MatchError(diodes.ErrInvalidSize))
+			}
+		},
+		entries,
+	)
+
+	DescribeTable("create diodes of valid sizes",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) (sizedDiode, error)) {
+			d, err := newDiode(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Cap()).To(Equal(3))
+
+			j := 1
+			d.Set(diodes.GenericDataType(&j))
+			data, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(*(*int)(data)).To(Equal(1))
+		},
+		entries,
+	)
+
+	DescribeTable("raise small sizes to the minimum size",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) (sizedDiode, error)) {
+			for _, size := range []int{-1, 0, 2} {
+				d, err := newDiode(size, diodes.WithMinSize(4))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(d.Cap()).To(Equal(4))
+			}
+
+			d, err := newDiode(5, diodes.WithMinSize(4))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Cap()).To(Equal(5))
+		},
+		entries,
+	)
+
+	DescribeTable("round raised sizes up to a power of two",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) (sizedDiode, error)) {
+			d, err := newDiode(0, diodes.WithMinSize(5), diodes.WithPowerOfTwoSize())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.Cap()).To(Equal(8))
+		},
+		entries,
+	)
+
+	It("rejects sizes without any slots for OneToMany", func() {
+		_, err := diodes.NewOneToManyErr(0)
+		Expect(err).To(MatchError(diodes.ErrInvalidSize))
+
+		d, err := diodes.NewOneToManyErr(0, diodes.WithMinSize(2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(d).ToNot(BeNil())
+	})
+
+	composite := []TableEntry{
+		Entry("SPSC", func(size int, _ ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewSPSCErr(size, nil)
+			return err
+		}),
+		Entry("Coalescing", func(size int, _ ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewCoalescingErr(size, nil, nil)
+			return err
+		}),
+		Entry("Elastic", func(size int, _ ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewElasticErr(size, 8, nil)
+			return err
+		}),
+		Entry("ShardedManyToOne", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewShardedManyToOneErr(2, size, nil, opts...)
+			return err
+		}),
+		Entry("KeyedDiodes", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewKeyedDiodesErr(2, size, nil, opts...)
+			return err
+		}),
+		Entry("ManyToOnePriority", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewManyToOnePriorityErr([]int{4, size}, nil, opts...)
+			return err
+		}),
+		Entry("TwoTier hot", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewTwoTierErr(size, 4, nil, opts...)
+			return err
+		}),
+		Entry("TwoTier cold", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewTwoTierErr(4, size, nil, opts...)
+			return err
+		}),
+		Entry("OneToOneT", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewOneToOneTErr[int](size, nil, opts...)
+			return err
+		}),
+		Entry("ManyToOneT", func(size int, opts ...diodes.DiodeConfigOption) error {
+			_, err := diodes.NewManyToOneTErr[int](size, nil, opts...)
+			return err
+		}),
+	}
+
+	DescribeTable("reject sizes without any slots in the other diodes",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) error) {
+			for _, size := range []int{0, -1} {
+				Expect(newDiode(size)).To(MatchError(diodes.ErrInvalidSize))
+			}
+			Expect(newDiode(1)).To(Succeed())
+		},
+		composite,
+	)
+
+	DescribeTable("raise small sizes of the other diodes to the minimum size",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) error) {
+			Expect(newDiode(0, diodes.WithMinSize(2))).To(Succeed())
+		},
+		composite[3:],
+	)
+
+	It("creates SPSC diodes that can be set", func() {
+		d, err := diodes.NewSPSCErr(1, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		j := 1
+		d.Set(diodes.GenericDataType(&j))
+		data, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(1))
+	})
+
+	It("lets the other constructors degrade with a minimum size", func() {
+		d := diodes.NewManyToOne(0, nil, diodes.WithMinSize(1))
+		Expect(d.CheckInvariants()).To(Succeed())
+		Expect(d.Stats().Capacity).To(Equal(uint64(1)))
+	})
+})
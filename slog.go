@@ -0,0 +1,39 @@
+package diodes
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithLogger routes the diagnostics of the diode to the given logger with
+// structured fields, instead of the standard logger: collisions of writers
+// are logged at the warning level with the write index and the number of
+// collisions of the write so far, and stalls of the reader detected by
+// WithStallDetection with how long it has been stalled and its backlog.
+// Attach the name of the diode with logger.With("diode", name) to tell the
+// diodes of a process apart. A collision handler set via
+// WithCollisionHandler takes precedence over the logger, and unlike the
+// standard logger, the logger is not compiled out by the diodes_nolog build
+// tag.
+func WithLogger(logger *slog.Logger) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.logger = logger
+	})
+}
+
+// logCollisionTo logs a collision of the writer of index to the logger.
+func logCollisionTo(logger *slog.Logger, index uint64, collisions int) {
+	logger.LogAttrs(context.Background(), slog.LevelWarn, "Diode set collision: consider using a larger diode",
+		slog.Uint64("index", index),
+		slog.Int("collisions", collisions),
+	)
+}
+
+// logStallTo logs a stall of the reader to the logger.
+func logStallTo(logger *slog.Logger, stalled time.Duration, backlog int) {
+	logger.LogAttrs(context.Background(), slog.LevelWarn, "Diode reader stalled",
+		slog.Duration("stalled", stalled),
+		slog.Int("backlog", backlog),
+	)
+}
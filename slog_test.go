@@ -0,0 +1,99 @@
+package diodes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithLogger", func() {
+	var (
+		buf    bytes.Buffer
+		logger *slog.Logger
+	)
+
+	records := func() []map[string]any {
+		var rs []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var r map[string]any
+			Expect(json.Unmarshal([]byte(line), &r)).To(Succeed())
+			rs = append(rs, r)
+		}
+		return rs
+	}
+
+	BeforeEach(func() {
+		buf.Reset()
+		logger = slog.New(slog.NewJSONHandler(&buf, nil)).With("diode", "ingress")
+	})
+
+	It("logs collisions to the logger instead of the standard logger", func() {
+		var std bytes.Buffer
+		log.SetOutput(&std)
+		defer log.SetOutput(io.Discard)
+
+		d := diodes.NewManyToOne(1, nil, diodes.WithLogger(logger))
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					j := i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}()
+		}
+		go func() {
+			for d.Stats().Writes < 8000 {
+				d.TryNext()
+			}
+		}()
+		wg.Wait()
+
+		rs := records()
+		Expect(rs).To(HaveLen(int(d.Stats().Collisions)))
+		for _, r := range rs {
+			Expect(r).To(HaveKeyWithValue("level", "WARN"))
+			Expect(r).To(HaveKeyWithValue("diode", "ingress"))
+			Expect(r).To(HaveKey("index"))
+			Expect(r["collisions"]).To(BeNumerically(">=", 1))
+		}
+		Expect(std.String()).To(BeEmpty())
+	})
+
+	It("logs stalls of the reader", func() {
+		d := diodes.NewOneToOne(8, nil,
+			diodes.WithLogger(logger),
+			diodes.WithStallDetection(20*time.Millisecond, nil),
+		)
+
+		for i := 0; i < 3; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		time.Sleep(30 * time.Millisecond)
+		d.Set(diodes.GenericDataType(new(int)))
+
+		rs := records()
+		Expect(rs).To(HaveLen(1))
+		Expect(rs[0]).To(HaveKeyWithValue("msg", "Diode reader stalled"))
+		Expect(rs[0]).To(HaveKeyWithValue("diode", "ingress"))
+		Expect(rs[0]).To(HaveKeyWithValue("backlog", BeNumerically("==", 4)))
+		Expect(rs[0]).To(HaveKey("stalled"))
+	})
+})
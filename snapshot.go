@@ -0,0 +1,115 @@
+package diodes
+
+import "sync/atomic"
+
+// snapshotAttempts is how often a snapshot is retaken when the indexes moved
+// while the slots were being walked.
+const snapshotAttempts = 3
+
+// Snapshot is a view of the ring buffer of a diode for debugging. It tells
+// whether data is piling up because the reader stalls or is overwritten
+// because the writers burst past it.
+type Snapshot struct {
+	// WriteIndex is the index the next value will be written to.
+	WriteIndex uint64
+
+	// ReadIndex is the index the reader will read next.
+	ReadIndex uint64
+
+	// Dropped is the total number of values the reader noticed were
+	// overwritten before they were read.
+	Dropped uint64
+
+	// Slots holds the state of every slot of the ring buffer, in slot
+	// order.
+	Slots []SlotSnapshot
+
+	// Consistent is false when the indexes kept moving while the slots were
+	// being walked, in which case the slots might not match the indexes.
+	Consistent bool
+}
+
+// SlotSnapshot is the state of a single slot of a ring buffer.
+type SlotSnapshot struct {
+	// Seq is the index the value in the slot was written at. It is only
+	// meaningful if the slot is occupied.
+	Seq uint64
+
+	// Occupied is true if the slot holds a value. Slots are emptied when
+	// their value is read.
+	Occupied bool
+
+	// Unread is true if the slot holds a value the reader has not reached
+	// yet. An occupied slot that is not unread holds a value the reader
+	// skipped when it was lapped.
+	Unread bool
+}
+
+// Snapshot returns a view of the ring buffer without reading from it. It is
+// safe to call concurrently with the reader and writer and does not move
+// either of them.
+func (d *OneToOne) Snapshot() Snapshot {
+	return takeSnapshot(&d.buffer, &d.readIndex, &d.dropped, func() uint64 {
+		return d.writeIndex.Load()
+	})
+}
+
+// Snapshot returns a view of the ring buffer without reading from it. It is
+// safe to call concurrently with the reader and writers and does not move
+// any of them.
+func (d *ManyToOne) Snapshot() Snapshot {
+	return takeSnapshot(&d.buffer, &d.readIndex, &d.dropped, func() uint64 {
+		return d.writeIndex.Load() + 1
+	})
+}
+
+// Snapshot returns a view of the ring buffer without reading from it. It is
+// safe to call concurrently with the readers and writers and does not move
+// any of them.
+func (d *ManyToMany) Snapshot() Snapshot {
+	return takeSnapshot(&d.buffer, &d.readIndex, &d.dropped, func() uint64 {
+		return d.writeIndex.Load() + 1
+	})
+}
+
+// takeSnapshot walks the slots of the buffer until the indexes stayed put
+// for a whole walk or it ran out of attempts. nextWrite returns the index
+// that will be written next.
+func takeSnapshot(buffer *ring, readIndex *atomic.Uint64, dropped *atomic.Uint64, nextWrite func() uint64) Snapshot {
+	s := Snapshot{
+		Slots: make([]SlotSnapshot, buffer.size),
+	}
+
+	for i := 0; i < snapshotAttempts && !s.Consistent; i++ {
+		s.WriteIndex = nextWrite()
+		s.ReadIndex = readIndex.Load()
+
+		for j := range s.Slots {
+			s.Slots[j] = SlotSnapshot{}
+
+			slot := buffer.peek(uint64(j))
+			if slot == nil {
+				continue
+			}
+
+			b := (*bucket)(atomic.LoadPointer(slot))
+			if b == nil {
+				continue
+			}
+
+			// The OneToOne diode reuses buckets, so the seq can change
+			// while it is read.
+			seq := atomic.LoadUint64(&b.seq)
+			s.Slots[j] = SlotSnapshot{
+				Seq:      seq,
+				Occupied: true,
+				Unread:   seq >= s.ReadIndex,
+			}
+		}
+
+		s.Consistent = nextWrite() == s.WriteIndex && readIndex.Load() == s.ReadIndex
+	}
+
+	s.Dropped = dropped.Load()
+	return s
+}
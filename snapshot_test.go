@@ -0,0 +1,88 @@
+package diodes_test
+
+import (
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot()", func() {
+	type snapshotDiode interface {
+		diodes.Diode
+		Snapshot() diodes.Snapshot
+	}
+
+	set := func(d snapshotDiode, n int) {
+		for i := 0; i < n; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	DescribeTable("reports the ring buffer",
+		func(newDiode func(size int) snapshotDiode) {
+			d := newDiode(4)
+
+			s := d.Snapshot()
+			Expect(s.WriteIndex).To(BeZero())
+			Expect(s.ReadIndex).To(BeZero())
+			Expect(s.Consistent).To(BeTrue())
+			Expect(s.Slots).To(Equal(make([]diodes.SlotSnapshot, 4)))
+
+			set(d, 3)
+			_, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+
+			s = d.Snapshot()
+			Expect(s.WriteIndex).To(Equal(uint64(3)))
+			Expect(s.ReadIndex).To(Equal(uint64(1)))
+			Expect(s.Slots).To(Equal([]diodes.SlotSnapshot{
+				{},
+				{Seq: 1, Occupied: true, Unread: true},
+				{Seq: 2, Occupied: true, Unread: true},
+				{},
+			}))
+		},
+		Entry("OneToOne", func(size int) snapshotDiode { return diodes.NewOneToOne(size, nil) }),
+		Entry("ManyToOne", func(size int) snapshotDiode { return diodes.NewManyToOne(size, nil) }),
+		Entry("ManyToMany", func(size int) snapshotDiode { return diodes.NewManyToMany(size, nil) }),
+	)
+
+	DescribeTable("does not move the reader",
+		func(newDiode func(size int) snapshotDiode) {
+			d := newDiode(4)
+			set(d, 2)
+			d.Snapshot()
+
+			for i := 0; i < 2; i++ {
+				data, ok := d.TryNext()
+				Expect(ok).To(BeTrue())
+				Expect(*(*int)(data)).To(Equal(i))
+			}
+		},
+		Entry("OneToOne", func(size int) snapshotDiode { return diodes.NewOneToOne(size, nil) }),
+		Entry("ManyToOne", func(size int) snapshotDiode { return diodes.NewManyToOne(size, nil) }),
+		Entry("ManyToMany", func(size int) snapshotDiode { return diodes.NewManyToMany(size, nil) }),
+	)
+
+	It("tells values skipped by a lapped reader apart from unread ones", func() {
+		d := diodes.NewManyToOne(4, nil)
+		set(d, 6)
+
+		// The reader finds seq 4 in slot 0 and fast forwards past the older
+		// values in slots 2 and 3.
+		_, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+
+		s := d.Snapshot()
+		Expect(s.ReadIndex).To(Equal(uint64(5)))
+		Expect(s.Dropped).To(Equal(uint64(4)))
+		Expect(s.Slots).To(Equal([]diodes.SlotSnapshot{
+			{},
+			{Seq: 5, Occupied: true, Unread: true},
+			{Seq: 2, Occupied: true},
+			{Seq: 3, Occupied: true},
+		}))
+	})
+})
@@ -0,0 +1,217 @@
+package soak
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// ChaosConfig describes the overload patterns a Chaos diode injects. The
+// chances are per call, between 0 and 1, and 0 disables the pattern.
+type ChaosConfig struct {
+	// ReadStall is the chance that a read stalls the reader for up to
+	// MaxReadStall first, which lets the writers lap it.
+	ReadStall    float64
+	MaxReadStall time.Duration
+	// Burst is the chance that Set holds back its value, along with the
+	// values set after it, and then sets up to MaxBurst of them at once.
+	Burst    float64
+	MaxBurst int
+	// Yield is the chance that a read or a write yields the processor
+	// first, which shuffles the interleaving of the go-routines.
+	Yield float64
+	// Seed seeds the injected patterns so failures can be reproduced. Zero
+	// uses the current time.
+	Seed int64
+}
+
+// invariantsChecker is implemented by the diodes that can check their own
+// invariants.
+type invariantsChecker interface {
+	CheckInvariants() error
+}
+
+// dropCounter is implemented by the diodes that count their drops.
+type dropCounter interface {
+	Dropped() uint64
+}
+
+// Chaos wraps a diode and injects reader stalls, writer bursts and yields
+// into the reads and writes of the consumer under test, so that the
+// consumer can be soaked against realistic overload. After every stall and
+// every burst it checks the invariants of the wrapped diode, if it has
+// any, and that the reads and drops do not add up to more than the writes,
+// and records every violation. It is safe to use from as many go-routines
+// as the wrapped diode is.
+type Chaos struct {
+	d diodes.Diode
+	c ChaosConfig
+
+	mu         sync.Mutex
+	rng        *rand.Rand
+	held       []diodes.GenericDataType
+	burst      int
+	violations []error
+
+	writes atomic.Uint64
+	reads  atomic.Uint64
+}
+
+// NewChaos wraps the diode with the given overload patterns.
+func NewChaos(d diodes.Diode, c ChaosConfig) *Chaos {
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	c.Seed = seed
+
+	return &Chaos{
+		d:   d,
+		c:   c,
+		rng: rand.New(rand.NewSource(seed)), //nolint:gosec
+	}
+}
+
+// Seed returns the seed of the injected patterns.
+func (c *Chaos) Seed() int64 {
+	return c.c.Seed
+}
+
+// Set sets the data on the wrapped diode, unless it is held back for a
+// burst. Held back values are set by the Set that completes the burst, or
+// by Flush.
+func (c *Chaos) Set(data diodes.GenericDataType) {
+	if c.chance(c.c.Yield) {
+		runtime.Gosched()
+	}
+
+	c.mu.Lock()
+	if c.burst == 0 && c.c.MaxBurst > 1 && c.c.Burst > 0 && c.rng.Float64() < c.c.Burst {
+		c.burst = 2 + c.rng.Intn(c.c.MaxBurst-1)
+	}
+	if c.burst == 0 {
+		c.mu.Unlock()
+		c.set(data)
+		return
+	}
+
+	c.held = append(c.held, data)
+	if len(c.held) < c.burst {
+		c.mu.Unlock()
+		return
+	}
+	burst := c.takeHeld()
+	c.mu.Unlock()
+
+	for _, data := range burst {
+		c.set(data)
+	}
+	c.check()
+}
+
+// Flush sets the values that are held back for a burst. It must be called
+// by a writer, and before the end of the stream is marked, e.g. by the
+// Close of a wrapping Poller, so that no value is held back past it.
+func (c *Chaos) Flush() {
+	c.mu.Lock()
+	burst := c.takeHeld()
+	c.mu.Unlock()
+
+	for _, data := range burst {
+		c.set(data)
+	}
+}
+
+// takeHeld returns the held back values and ends the burst. The mutex must
+// be held.
+func (c *Chaos) takeHeld() []diodes.GenericDataType {
+	burst := c.held
+	c.held = nil
+	c.burst = 0
+	return burst
+}
+
+func (c *Chaos) set(data diodes.GenericDataType) {
+	// The write is counted first, so that a read can never be counted
+	// before the write of its value.
+	c.writes.Add(1)
+	c.d.Set(data)
+}
+
+// TryNext reads from the wrapped diode, after stalling the reader at
+// random.
+func (c *Chaos) TryNext() (diodes.GenericDataType, bool) {
+	if c.chance(c.c.Yield) {
+		runtime.Gosched()
+	}
+	if c.c.MaxReadStall > 0 && c.chance(c.c.ReadStall) {
+		c.mu.Lock()
+		stall := time.Duration(c.rng.Int63n(int64(c.c.MaxReadStall)))
+		c.mu.Unlock()
+
+		time.Sleep(stall)
+		c.check()
+	}
+
+	data, ok := c.d.TryNext()
+	if ok {
+		c.reads.Add(1)
+	}
+	return data, ok
+}
+
+// Writes returns the number of values that were set on the wrapped diode.
+// Values that are held back are not counted.
+func (c *Chaos) Writes() uint64 {
+	return c.writes.Load()
+}
+
+// Reads returns the number of values that were read from the wrapped
+// diode.
+func (c *Chaos) Reads() uint64 {
+	return c.reads.Load()
+}
+
+// Violations returns the invariant violations that were recorded so far.
+func (c *Chaos) Violations() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.violations...)
+}
+
+// check records the violations of the invariants of the wrapped diode.
+func (c *Chaos) check() {
+	var err error
+	if ic, ok := c.d.(invariantsChecker); ok {
+		err = ic.CheckInvariants()
+	}
+	if dc, ok := c.d.(dropCounter); ok && err == nil {
+		reads, dropped := c.reads.Load(), dc.Dropped()
+		if writes := c.writes.Load(); reads+dropped > writes {
+			err = fmt.Errorf("%d reads and %d drops exceed %d writes", reads, dropped, writes)
+		}
+	}
+	if err == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.violations = append(c.violations, err)
+}
+
+// chance reports whether an event of the given chance happens.
+func (c *Chaos) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.Float64() < p
+}
@@ -0,0 +1,101 @@
+package soak_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodestest"
+	"code.cloudfoundry.org/go-diodes/soak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// overcountingDiode claims to have dropped more values than were set.
+type overcountingDiode struct {
+	*diodestest.Diode
+}
+
+func (overcountingDiode) Dropped() uint64 {
+	return 100
+}
+
+var _ = Describe("Chaos", func() {
+	It("injects overload without violating the invariants of the diode", func() {
+		d := diodes.NewManyToOne(16, nil)
+		c := soak.NewChaos(d, soak.ChaosConfig{
+			ReadStall:    0.01,
+			MaxReadStall: time.Millisecond,
+			Burst:        0.1,
+			MaxBurst:     32,
+			Yield:        0.1,
+		})
+
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				diodestest.SetInts(c, 0, 2000)
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+	read:
+		for {
+			select {
+			case <-done:
+				break read
+			default:
+				c.TryNext()
+			}
+		}
+
+		c.Flush()
+		for {
+			if _, ok := c.TryNext(); !ok {
+				break
+			}
+		}
+
+		Expect(c.Violations()).To(BeEmpty(), "seed %d", c.Seed())
+		Expect(c.Writes()).To(Equal(uint64(8000)))
+		Expect(c.Reads() + d.Dropped()).To(Equal(c.Writes()))
+	})
+
+	It("holds values back for a burst until it is flushed", func() {
+		d := diodestest.NewDiode(nil)
+		c := soak.NewChaos(d, soak.ChaosConfig{Burst: 1, MaxBurst: 1000})
+
+		c.Set(diodestest.Int(1))
+		Expect(d.Sets()).To(BeEmpty())
+		Expect(c.Writes()).To(BeZero())
+
+		c.Flush()
+		Expect(d.Sets()).To(HaveLen(1))
+		Expect(c.Writes()).To(Equal(uint64(1)))
+	})
+
+	It("records violations", func() {
+		c := soak.NewChaos(overcountingDiode{diodestest.NewDiode(nil)}, soak.ChaosConfig{
+			ReadStall:    1,
+			MaxReadStall: time.Microsecond,
+		})
+
+		c.Set(diodestest.Int(1))
+		c.TryNext()
+		Expect(c.Violations()).To(HaveLen(1))
+		Expect(c.Violations()[0]).To(MatchError(ContainSubstring("exceed 1 writes")))
+	})
+
+	It("uses the seed it was given", func() {
+		c := soak.NewChaos(diodestest.NewDiode(nil), soak.ChaosConfig{Seed: 42})
+		Expect(c.Seed()).To(Equal(int64(42)))
+	})
+})
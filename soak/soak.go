@@ -0,0 +1,208 @@
+// Package soak runs long, randomized workloads against diodes while
+// continuously checking their invariants and drop accounting. It is meant
+// to build confidence in a diode configuration by sustained adversarial
+// use, both in CI and against downstream configurations. Chaos injects the
+// same kind of overload into the diode of a consumer under test.
+package soak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// Diode kinds that can be soaked.
+const (
+	OneToOne  = "one-to-one"
+	ManyToOne = "many-to-one"
+)
+
+// Config describes a soak run.
+type Config struct {
+	// Diode is the kind of diode to soak, OneToOne or ManyToOne.
+	Diode string
+	// Size is the size of the diode.
+	Size int
+	// SegmentSize enables segmented storage (see diodes.WithSegmentSize)
+	// when positive.
+	SegmentSize int
+	// MaxWriters is the largest number of writers in a round. Every round
+	// picks a new number of writers between one and MaxWriters. It is
+	// ignored for OneToOne diodes, which always have a single writer.
+	MaxWriters int
+	// MaxStall is the longest the reader stalls at a time, which lets the
+	// writers lap it. Zero never stalls the reader.
+	MaxStall time.Duration
+	// Duration is how long to keep starting new rounds.
+	Duration time.Duration
+	// Seed seeds the random workload so failures can be reproduced. Zero
+	// uses the current time.
+	Seed int64
+}
+
+// Report is the result of a soak run.
+type Report struct {
+	Seed    int64
+	Rounds  int
+	Writes  uint64
+	Reads   uint64
+	Dropped uint64
+}
+
+// diode is what the soak needs from the diode under test.
+type diode interface {
+	diodes.Diode
+	CheckInvariants() error
+	Dropped() uint64
+}
+
+type value struct {
+	writer int
+	seq    int
+}
+
+// Run runs rounds of randomized writes and reads until the duration has
+// elapsed or the context is done. Every round churns the writers, stalls the
+// reader at random and then drains the diode, after which the invariants
+// and the drop accounting are checked. It returns the first violation as an
+// error along with the report of the rounds so far.
+func Run(ctx context.Context, c Config) (Report, error) {
+	if c.Size < 1 {
+		return Report{}, errors.New("soak: size must be positive")
+	}
+
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed)) //nolint:gosec
+
+	var opts []diodes.DiodeConfigOption
+	if c.SegmentSize > 0 {
+		opts = append(opts, diodes.WithSegmentSize(c.SegmentSize))
+	}
+
+	var (
+		d          diode
+		maxWriters = max(c.MaxWriters, 1)
+	)
+	switch c.Diode {
+	case OneToOne:
+		d = diodes.NewOneToOne(c.Size, nil, opts...)
+		maxWriters = 1
+	case ManyToOne, "":
+		d = diodes.NewManyToOne(c.Size, nil, opts...)
+	default:
+		return Report{}, errors.New("soak: unknown diode " + c.Diode)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Duration)
+	defer cancel()
+
+	r := Report{Seed: seed}
+	for ctx.Err() == nil {
+		if err := round(d, &r, rng, c, maxWriters); err != nil {
+			return r, fmt.Errorf("soak: round %d (seed %d): %w", r.Rounds, seed, err)
+		}
+	}
+
+	return r, nil
+}
+
+// round runs a single round and updates the report.
+func round(d diode, r *Report, rng *rand.Rand, c Config, maxWriters int) error {
+	writers := 1 + rng.Intn(maxWriters)
+
+	// Every writer writes up to a few laps worth of values so that the
+	// writers regularly lap the reader.
+	counts := make([]int, writers)
+	for i := range counts {
+		counts[i] = 1 + rng.Intn(4*c.Size)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done atomic.Bool
+	)
+	for w, n := range counts {
+		wg.Add(1)
+		go func(w, n int) {
+			defer wg.Done()
+			for seq := 0; seq < n; seq++ {
+				d.Set(diodes.GenericDataType(&value{writer: w, seq: seq}))
+				if seq%64 == 0 {
+					runtime.Gosched()
+				}
+			}
+		}(w, n)
+	}
+	go func() {
+		wg.Wait()
+		done.Store(true)
+	}()
+
+	last := make([]int, writers)
+	for i := range last {
+		last[i] = -1
+	}
+
+	read := func() error {
+		data, ok := d.TryNext()
+		if !ok {
+			return errEmpty
+		}
+
+		v := (*value)(data)
+		if v.seq <= last[v.writer] {
+			return fmt.Errorf("writer %d: read seq %d after seq %d", v.writer, v.seq, last[v.writer])
+		}
+		last[v.writer] = v.seq
+		r.Reads++
+		return nil
+	}
+
+	for !done.Load() {
+		if c.MaxStall > 0 && rng.Intn(100) == 0 {
+			time.Sleep(time.Duration(rng.Int63n(int64(c.MaxStall))))
+		}
+
+		if err := read(); err != nil && err != errEmpty {
+			return err
+		}
+	}
+
+	for {
+		err := read()
+		if err == errEmpty {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, n := range counts {
+		r.Writes += uint64(n)
+	}
+	r.Dropped = d.Dropped()
+	r.Rounds++
+
+	if err := d.CheckInvariants(); err != nil {
+		return err
+	}
+
+	if r.Reads+r.Dropped != r.Writes {
+		return fmt.Errorf("%d reads and %d drops do not add up to %d writes", r.Reads, r.Dropped, r.Writes)
+	}
+
+	return nil
+}
+
+var errEmpty = errors.New("empty")
@@ -0,0 +1,53 @@
+package soak_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes/soak"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	BeforeEach(func() {
+		log.SetOutput(io.Discard)
+	})
+
+	DescribeTable("finds no violations",
+		func(c soak.Config) {
+			c.Duration = 200 * time.Millisecond
+			r, err := soak.Run(context.Background(), c)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(r.Rounds).ToNot(BeZero())
+			Expect(r.Reads + r.Dropped).To(Equal(r.Writes))
+		},
+		Entry("one-to-one", soak.Config{Diode: soak.OneToOne, Size: 16, MaxStall: time.Millisecond}),
+		Entry("many-to-one", soak.Config{Diode: soak.ManyToOne, Size: 16, MaxWriters: 8, MaxStall: time.Millisecond}),
+		Entry("segmented many-to-one", soak.Config{Diode: soak.ManyToOne, Size: 50, SegmentSize: 8, MaxWriters: 8}),
+	)
+
+	It("reports the seed it used", func() {
+		r, err := soak.Run(context.Background(), soak.Config{
+			Size:     16,
+			Duration: 10 * time.Millisecond,
+			Seed:     42,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.Seed).To(Equal(int64(42)))
+	})
+
+	It("returns an error for an unknown diode", func() {
+		_, err := soak.Run(context.Background(), soak.Config{Diode: "unknown", Size: 1})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("returns an error for an invalid size", func() {
+		_, err := soak.Run(context.Background(), soak.Config{})
+		Expect(err).To(HaveOccurred())
+	})
+})
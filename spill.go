@@ -0,0 +1,407 @@
+package diodes
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// spillHeaderSize is the size of the header of a spill file, which holds
+// the capacity and the head and tail offsets as big endian uint64s.
+const spillHeaderSize = 24
+
+// spillFrameHeaderSize is the size of the big endian uint32 length prefix
+// of each spilled value.
+const spillFrameHeaderSize = 4
+
+// spillChecksumFlag is set in the length prefix of a value that is followed
+// by the big endian CRC-32C of the value, see WithSpillChecksums.
+const spillChecksumFlag = 1 << 31
+
+// spillChecksumSize is the size of the checksum of a spilled value.
+const spillChecksumSize = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// SpillEncodeFunc serializes a value that is spilled to disk.
+type SpillEncodeFunc func(GenericDataType) []byte
+
+// SpillDecodeFunc deserializes a value that is read back from disk. The
+// given slice is reused for the next value and must be copied if it is
+// retained.
+type SpillDecodeFunc func([]byte) GenericDataType
+
+// Spill is a diode that keeps its values in a bounded ring file on disk.
+// Its values are serialized with the encode function and handed back by
+// TryNext in the order they were set. Once the file is full, the oldest
+// values are dropped to make room. It is safe for many writers and a single
+// reader and is usually not used on its own but as overflow of a SpillDiode.
+//
+// The offsets are kept in the file, so values that were set but not read
+// yet are replayed after a restart. The file is not synced to disk until
+// Close, so a crash of the machine can lose values that were set shortly
+// before.
+type Spill struct {
+	mu       sync.Mutex
+	f        *os.File
+	capacity int64
+	head     int64
+	tail     int64
+	header   [spillHeaderSize]byte
+	buf      []byte
+	err      error
+	ended    bool
+
+	encode  SpillEncodeFunc
+	decode  SpillDecodeFunc
+	len     atomic.Int64
+	lost    atomic.Uint64
+	corrupt atomic.Uint64
+	spillConfig
+}
+
+var _ Diode = (*Spill)(nil)
+
+// SpillConfigOption can be used to setup a spill.
+type SpillConfigOption func(*spillConfig)
+
+type spillConfig struct {
+	checksums bool
+}
+
+// WithSpillChecksums stores the CRC-32C of every value that is set along
+// with it, so that values that are damaged on disk, e.g. by a write that was
+// torn by a power loss, are skipped and counted by Corrupt instead of being
+// handed to the decode function. Values are checked on read whenever they
+// have a checksum, so a file can hold values with and without one.
+func WithSpillChecksums() SpillConfigOption {
+	return SpillConfigOption(func(c *spillConfig) {
+		c.checksums = true
+	})
+}
+
+// OpenSpill opens the spill file at path or creates it with room for size
+// bytes of values. An existing file keeps the size it was created with and
+// its unread values are replayed.
+func OpenSpill(path string, size int64, encode SpillEncodeFunc, decode SpillDecodeFunc, opts ...SpillConfigOption) (*Spill, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spill{
+		f:        f,
+		capacity: size,
+		encode:   encode,
+		decode:   decode,
+	}
+
+	for _, o := range opts {
+		o(&s.spillConfig)
+	}
+
+	if err := s.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the header of an existing file or writes it to a new one.
+func (s *Spill) load() error {
+	_, err := io.ReadFull(io.NewSectionReader(s.f, 0, spillHeaderSize), s.header[:])
+	if errors.Is(err, io.EOF) {
+		if s.capacity <= spillFrameHeaderSize {
+			return fmt.Errorf("diodes: spill size %d is too small", s.capacity)
+		}
+		return s.storeHeader()
+	}
+	if err != nil {
+		return fmt.Errorf("diodes: reading spill header: %w", err)
+	}
+
+	s.capacity = int64(binary.BigEndian.Uint64(s.header[0:]))
+	s.head = int64(binary.BigEndian.Uint64(s.header[8:]))
+	s.tail = int64(binary.BigEndian.Uint64(s.header[16:]))
+	if s.capacity <= spillFrameHeaderSize || s.head < 0 || s.tail < s.head || s.tail-s.head > s.capacity {
+		return errors.New("diodes: corrupt spill header")
+	}
+
+	for off := s.head; off < s.tail; {
+		n, _, err := s.frameSize(off)
+		if err != nil {
+			return fmt.Errorf("diodes: reading spill file: %w", err)
+		}
+		off += n
+		s.len.Add(1)
+	}
+	return nil
+}
+
+// Set writes the data to the spill file. The end of the stream that Close
+// sets is not encoded but kept in memory and handed back by TryNext once
+// every value before it was read.
+func (s *Spill) Set(data GenericDataType) {
+	if data == endOfStream {
+		s.mu.Lock()
+		s.ended = true
+		s.mu.Unlock()
+		return
+	}
+
+	payload := s.encode(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := int64(spillFrameHeaderSize + len(payload))
+	if s.checksums {
+		size += spillChecksumSize
+	}
+	if s.err != nil || size > s.capacity || uint64(len(payload)) >= spillChecksumFlag {
+		s.lost.Add(1)
+		return
+	}
+
+	// Make room by dropping the oldest values.
+	for s.tail+size-s.head > s.capacity {
+		n, _, err := s.frameSize(s.head)
+		if err != nil {
+			s.fail(err)
+			s.lost.Add(1)
+			return
+		}
+		s.head += n
+		s.len.Add(-1)
+		s.lost.Add(1)
+	}
+
+	if s.checksums {
+		s.buf = binary.BigEndian.AppendUint32(s.buf[:0], uint32(len(payload))|spillChecksumFlag)
+		s.buf = binary.BigEndian.AppendUint32(s.buf, crc32.Checksum(payload, castagnoli))
+	} else {
+		s.buf = binary.BigEndian.AppendUint32(s.buf[:0], uint32(len(payload)))
+	}
+	s.buf = append(s.buf, payload...)
+	if err := s.writeAt(s.tail, s.buf); err != nil {
+		s.fail(err)
+		s.lost.Add(1)
+		return
+	}
+	s.tail += size
+	s.len.Add(1)
+
+	if err := s.storeHeader(); err != nil {
+		s.fail(err)
+	}
+}
+
+// TryNext will attempt to read the oldest value. If there is no data
+// available, it will return (nil, false). Values whose checksum does not
+// match are skipped.
+func (s *Spill) TryNext() (data GenericDataType, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.err != nil || s.head == s.tail {
+			if s.ended {
+				s.ended = false
+				return endOfStream, true
+			}
+			return nil, false
+		}
+
+		size, checksummed, err := s.frameSize(s.head)
+		if err != nil {
+			s.fail(err)
+			return nil, false
+		}
+
+		if cap(s.buf) < int(size) {
+			s.buf = make([]byte, size)
+		}
+		payload := s.buf[:size-spillFrameHeaderSize]
+		if err := s.readAt(s.head+spillFrameHeaderSize, payload); err != nil {
+			s.fail(err)
+			return nil, false
+		}
+		s.head += size
+		s.len.Add(-1)
+
+		if err := s.storeHeader(); err != nil {
+			s.fail(err)
+		}
+
+		if checksummed {
+			sum := binary.BigEndian.Uint32(payload)
+			payload = payload[spillChecksumSize:]
+			if crc32.Checksum(payload, castagnoli) != sum {
+				s.corrupt.Add(1)
+				continue
+			}
+		}
+		return s.decode(payload), true
+	}
+}
+
+// Len returns the number of unread values. It is safe to call from any
+// go-routine.
+func (s *Spill) Len() int {
+	return int(s.len.Load())
+}
+
+// Lost returns the total number of values that could not be written to the
+// spill file or were dropped from it to make room for newer ones.
+func (s *Spill) Lost() uint64 {
+	return s.lost.Load()
+}
+
+// Corrupt returns the total number of values that were skipped because
+// their checksum did not match, see WithSpillChecksums.
+func (s *Spill) Corrupt() uint64 {
+	return s.corrupt.Load()
+}
+
+// Err returns the first error the spill file ran into. Once it failed,
+// values are no longer written to or read from it.
+func (s *Spill) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close syncs the spill file to disk and closes it.
+func (s *Spill) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = os.ErrClosed
+	}
+	return errors.Join(s.f.Sync(), s.f.Close())
+}
+
+func (s *Spill) fail(err error) {
+	if s.err == nil {
+		s.err = fmt.Errorf("diodes: spill file: %w", err)
+	}
+}
+
+// frameSize returns the size of the spilled value at off, including its
+// length prefix and its checksum, and whether it has a checksum.
+func (s *Spill) frameSize(off int64) (int64, bool, error) {
+	var header [spillFrameHeaderSize]byte
+	if err := s.readAt(off, header[:]); err != nil {
+		return 0, false, err
+	}
+
+	prefix := binary.BigEndian.Uint32(header[:])
+	checksummed := prefix&spillChecksumFlag != 0
+	size := spillFrameHeaderSize + int64(prefix&^spillChecksumFlag)
+	if checksummed {
+		size += spillChecksumSize
+	}
+	if size > s.tail-off {
+		return 0, false, errors.New("corrupt frame")
+	}
+	return size, checksummed, nil
+}
+
+func (s *Spill) storeHeader() error {
+	binary.BigEndian.PutUint64(s.header[0:], uint64(s.capacity))
+	binary.BigEndian.PutUint64(s.header[8:], uint64(s.head))
+	binary.BigEndian.PutUint64(s.header[16:], uint64(s.tail))
+	_, err := s.f.WriteAt(s.header[:], 0)
+	return err
+}
+
+// writeAt writes p at the logical offset off, wrapping around the end of
+// the file.
+func (s *Spill) writeAt(off int64, p []byte) error {
+	pos := off % s.capacity
+	n := min(int64(len(p)), s.capacity-pos)
+	if _, err := s.f.WriteAt(p[:n], spillHeaderSize+pos); err != nil {
+		return err
+	}
+	if n < int64(len(p)) {
+		_, err := s.f.WriteAt(p[n:], spillHeaderSize)
+		return err
+	}
+	return nil
+}
+
+// readAt reads p from the logical offset off, wrapping around the end of
+// the file.
+func (s *Spill) readAt(off int64, p []byte) error {
+	pos := off % s.capacity
+	n := min(int64(len(p)), s.capacity-pos)
+	if _, err := s.f.ReadAt(p[:n], spillHeaderSize+pos); err != nil {
+		return err
+	}
+	if n < int64(len(p)) {
+		_, err := s.f.ReadAt(p[n:], spillHeaderSize)
+		return err
+	}
+	return nil
+}
+
+// SpillDiode wraps a diode and a Spill it overflows to. Once the reader
+// lags by more than a threshold, writers set their values on the spill
+// instead, and they keep doing so until the reader caught up with the spill,
+// which is read once the wrapped diode is empty. Under overload values are
+// therefore delayed instead of dropped, as long as the spill file has room.
+// Values set while the reader switches between the diode and the spill can
+// be read out of order.
+type SpillDiode struct {
+	d         spillableDiode
+	spill     *Spill
+	threshold int
+}
+
+var _ Diode = (*SpillDiode)(nil)
+
+// spillableDiode is a diode that reports how many values it holds.
+type spillableDiode interface {
+	Diode
+	Len() int
+}
+
+// NewSpillDiode returns a new SpillDiode that overflows from d to the spill
+// once d holds threshold unread values. The threshold should stay below the
+// size of d, so that bursts of many writers do not lap the reader before
+// they notice.
+func NewSpillDiode(d interface {
+	Diode
+	Len() int
+}, spill *Spill, threshold int) *SpillDiode {
+	return &SpillDiode{
+		d:         d,
+		spill:     spill,
+		threshold: threshold,
+	}
+}
+
+// Set sets the data on the wrapped diode, or on the spill if the reader
+// lags behind.
+func (d *SpillDiode) Set(data GenericDataType) {
+	if d.spill.Len() > 0 || d.d.Len() >= d.threshold {
+		d.spill.Set(data)
+		return
+	}
+	d.d.Set(data)
+}
+
+// TryNext will attempt to read from the wrapped diode and then from the
+// spill. If there is no data available, it will return (nil, false).
+func (d *SpillDiode) TryNext() (data GenericDataType, ok bool) {
+	if data, ok := d.d.TryNext(); ok {
+		return data, true
+	}
+	return d.spill.TryNext()
+}
@@ -0,0 +1,185 @@
+package diodes_test
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Spill", func() {
+	var (
+		path  string
+		spill *diodes.Spill
+	)
+
+	encode := func(data diodes.GenericDataType) []byte {
+		return binary.BigEndian.AppendUint64(nil, uint64(*(*int)(data)))
+	}
+
+	decode := func(p []byte) diodes.GenericDataType {
+		i := int(binary.BigEndian.Uint64(p))
+		return diodes.GenericDataType(&i)
+	}
+
+	open := func(size int64, opts ...diodes.SpillConfigOption) *diodes.Spill {
+		s, err := diodes.OpenSpill(path, size, encode, decode, opts...)
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	set := func(set func(diodes.GenericDataType), from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(r diodes.Reader) []int {
+		var values []int
+		for {
+			data, ok := r.TryNext()
+			if !ok {
+				return values
+			}
+			values = append(values, *(*int)(data))
+		}
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "diode.spill")
+		spill = open(1024)
+		DeferCleanup(func() {
+			spill.Close()
+		})
+	})
+
+	It("delays values instead of dropping them once the reader lags", func() {
+		d := diodes.NewSpillDiode(diodes.NewOneToOne(4, nil), spill, 3)
+		set(d.Set, 0, 10)
+		Expect(spill.Len()).To(Equal(7))
+
+		Expect(read(d)).To(Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+		Expect(spill.Len()).To(BeZero())
+		Expect(spill.Lost()).To(BeZero())
+	})
+
+	It("keeps setting values on the spill until the reader caught up", func() {
+		d := diodes.NewSpillDiode(diodes.NewManyToOne(4, nil), spill, 3)
+		set(d.Set, 0, 6)
+
+		for i := 0; i < 3; i++ {
+			_, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+		}
+
+		set(d.Set, 6, 7)
+		Expect(spill.Len()).To(Equal(4))
+		Expect(read(d)).To(Equal([]int{3, 4, 5, 6}))
+
+		set(d.Set, 7, 8)
+		Expect(spill.Len()).To(BeZero())
+		Expect(read(d)).To(Equal([]int{7}))
+	})
+
+	It("drops the oldest values once the file is full", func() {
+		spill.Close()
+		Expect(os.Remove(path)).To(Succeed())
+
+		// Room for 3 values of 12 bytes each, which wraps around the end of
+		// the file.
+		spill = open(40)
+		set(spill.Set, 0, 5)
+
+		Expect(spill.Lost()).To(Equal(uint64(2)))
+		Expect(read(spill)).To(Equal([]int{2, 3, 4}))
+
+		set(spill.Set, 5, 9)
+		Expect(read(spill)).To(Equal([]int{6, 7, 8}))
+		Expect(spill.Lost()).To(Equal(uint64(3)))
+	})
+
+	It("drops values that do not fit into the file", func() {
+		spill.Close()
+		Expect(os.Remove(path)).To(Succeed())
+
+		spill = open(8)
+		set(spill.Set, 0, 1)
+		Expect(spill.Lost()).To(Equal(uint64(1)))
+		Expect(spill.Len()).To(BeZero())
+	})
+
+	It("replays unread values after it was reopened", func() {
+		set(spill.Set, 0, 3)
+		_, ok := spill.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(spill.Close()).To(Succeed())
+
+		spill = open(16)
+		Expect(spill.Len()).To(Equal(2))
+		Expect(read(spill)).To(Equal([]int{1, 2}))
+	})
+
+	It("rejects a corrupt file", func() {
+		spill.Close()
+		Expect(os.WriteFile(path, make([]byte, 24), 0o600)).To(Succeed())
+
+		_, err := diodes.OpenSpill(path, 1024, encode, decode)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("WithSpillChecksums()", func() {
+		BeforeEach(func() {
+			spill.Close()
+			path = filepath.Join(GinkgoT().TempDir(), "checksums.spill")
+		})
+
+		It("skips and counts values that were damaged on disk", func() {
+			spill = open(1024, diodes.WithSpillChecksums())
+			set(spill.Set, 0, 3)
+			Expect(spill.Close()).To(Succeed())
+
+			// The value of the second frame follows the 24 byte header,
+			// the first frame of 16 bytes and its own length prefix and
+			// checksum.
+			f, err := os.OpenFile(path, os.O_RDWR, 0)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = f.WriteAt([]byte{0xff}, 24+16+8+7)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Close()).To(Succeed())
+
+			spill = open(1024, diodes.WithSpillChecksums())
+			Expect(read(spill)).To(Equal([]int{0, 2}))
+			Expect(spill.Corrupt()).To(Equal(uint64(1)))
+			Expect(spill.Err()).ToNot(HaveOccurred())
+		})
+
+		It("reads values with and without a checksum from the same file", func() {
+			spill = open(1024)
+			set(spill.Set, 0, 2)
+			Expect(spill.Close()).To(Succeed())
+
+			spill = open(1024, diodes.WithSpillChecksums())
+			set(spill.Set, 2, 4)
+			Expect(spill.Close()).To(Succeed())
+
+			spill = open(1024)
+			Expect(spill.Len()).To(Equal(4))
+			Expect(read(spill)).To(Equal([]int{0, 1, 2, 3}))
+			Expect(spill.Corrupt()).To(BeZero())
+		})
+	})
+
+	It("stops once it is closed", func() {
+		set(spill.Set, 0, 1)
+		Expect(spill.Close()).To(Succeed())
+
+		_, ok := spill.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(spill.Err()).To(MatchError(os.ErrClosed))
+	})
+})
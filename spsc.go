@@ -0,0 +1,157 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// SPSC diode is a OneToOne diode reduced to its fast path. The writer and
+// the reader never swap or compare-and-swap a slot; they only load and store
+// atomically. Values are stored in place, so Set never allocates. Every slot
+// carries a sequence number that is odd while the writer stores a value,
+// which tells the reader that it was lapped.
+//
+// It is meant to be used by a single reader and a single writer and does not
+// take any of the options of the other diodes. The slots keep a reference to
+// the values until they are overwritten.
+type SPSC struct {
+	writeIndex atomic.Uint64
+	_          cacheLinePad
+	readIndex  atomic.Uint64
+	_          cacheLinePad
+	slots      []spscSlot
+	size       uint64
+	alerter    DropAlerter
+	dropped    atomic.Uint64
+}
+
+var _ Diode = (*SPSC)(nil)
+
+// spscSlot holds the value of the write index i with a seq of 2*i+2. The seq
+// is 2*i+1 while the value is stored.
+type spscSlot struct {
+	seq  atomic.Uint64
+	data unsafe.Pointer
+}
+
+// NewSPSC creates a new SPSC diode with the given size. The alerter is
+// invoked on the reader's go-routine when it notices that the writer has
+// passed it and wrote over data. A nil can be used to ignore alerts.
+func NewSPSC(size int, alerter Alerter) *SPSC {
+	return &SPSC{
+		slots:   make([]spscSlot, size),
+		size:    uint64(size),
+		alerter: dropAlerter(alerter),
+	}
+}
+
+// NewSPSCErr is like NewSPSC but returns an error matching ErrInvalidSize
+// instead of a diode that has no slots.
+func NewSPSCErr(size int, alerter Alerter) (*SPSC, error) {
+	if err := checkSize(size, 0); err != nil {
+		return nil, err
+	}
+	return NewSPSC(size, alerter), nil
+}
+
+// Set sets the data in the next slot of the ring buffer.
+func (d *SPSC) Set(data GenericDataType) {
+	index := d.writeIndex.Load()
+	s := &d.slots[index%d.size]
+
+	s.seq.Store(2*index + 1)
+	atomic.StorePointer(&s.data, unsafe.Pointer(data))
+	s.seq.Store(2*index + 2)
+	d.writeIndex.Store(index + 1)
+}
+
+// TryNext will attempt to read from the next slot of the ring buffer.
+// If there is no data available, it will return (nil, false).
+func (d *SPSC) TryNext() (data GenericDataType, ok bool) {
+	readIndex := d.readIndex.Load()
+
+	var dropped uint64
+	for {
+		writeIndex := d.writeIndex.Load()
+		if readIndex >= writeIndex {
+			d.readIndex.Store(readIndex)
+			d.alert(readIndex-dropped, readIndex)
+			return nil, false
+		}
+
+		// The writer lapped the reader. Everything older than the last
+		// size values was overwritten.
+		if writeIndex-readIndex > d.size {
+			dropped += writeIndex - d.size - readIndex
+			readIndex = writeIndex - d.size
+		}
+
+		// The value was stored before the write index moved past it, so
+		// the seq can only differ if the writer is overwriting the slot
+		// since, in which case the value is dropped as well.
+		s := &d.slots[readIndex%d.size]
+		seq := s.seq.Load()
+		p := atomic.LoadPointer(&s.data)
+		if seq == 2*readIndex+2 && s.seq.Load() == seq {
+			d.readIndex.Store(readIndex + 1)
+			d.alert(readIndex-dropped, readIndex)
+			raceReadPayload(GenericDataType(p))
+			return GenericDataType(p), true
+		}
+
+		readIndex++
+		dropped++
+	}
+}
+
+// DrainInto reads the available data into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls.
+func (d *SPSC) DrainInto(dst []GenericDataType) int {
+	var n int
+	for n < len(dst) {
+		data, ok := d.TryNext()
+		if !ok {
+			break
+		}
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// alert reports the values from up to but not including to as dropped.
+func (d *SPSC) alert(from, to uint64) {
+	if from == to {
+		return
+	}
+	d.dropped.Add(to - from)
+	d.alerter.AlertDrop(newDropAlert(DropLapped, from, to))
+}
+
+// Len returns the approximate number of unread values, bounded by Cap. It is
+// safe to call concurrently with the reader and the writer.
+func (d *SPSC) Len() int {
+	return int(unread(d.writeIndex.Load(), d.readIndex.Load(), d.size))
+}
+
+// drainMark implements drainMarker.
+func (d *SPSC) drainMark() uint64 {
+	return d.writeIndex.Load()
+}
+
+// drained implements drainMarker.
+func (d *SPSC) drained(mark uint64) bool {
+	return d.readIndex.Load() >= mark
+}
+
+// Cap returns the number of slots of the diode.
+func (d *SPSC) Cap() int {
+	return int(d.size)
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read.
+func (d *SPSC) Dropped() uint64 {
+	return d.dropped.Load()
+}
@@ -0,0 +1,137 @@
+package diodes_test
+
+import (
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SPSC", func() {
+	var (
+		d   *diodes.SPSC
+		spy *spyAlerter
+	)
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	BeforeEach(func() {
+		spy = newSpyAlerter()
+		d = diodes.NewSPSC(4, spy)
+	})
+
+	It("returns the values in order", func() {
+		set(0, 3)
+		Expect(d.Len()).To(Equal(3))
+		Expect(d.Cap()).To(Equal(4))
+
+		Expect(read()).To(Equal([]int{0, 1, 2}))
+		Expect(d.Len()).To(BeZero())
+		Expect(spy.AlertInput.Missed).ToNot(Receive())
+	})
+
+	It("does not allocate on Set", func() {
+		value := 1
+		data := diodes.GenericDataType(&value)
+
+		Expect(testing.AllocsPerRun(100, func() {
+			d.Set(data)
+			d.TryNext()
+		})).To(BeZero())
+	})
+
+	It("returns false when there is nothing to read", func() {
+		_, ok := d.TryNext()
+		Expect(ok).To(BeFalse())
+
+		set(0, 1)
+		read()
+
+		_, ok = d.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	Context("the writer laps the reader", func() {
+		BeforeEach(func() {
+			set(0, 10)
+		})
+
+		It("keeps the newest values and alerts the reader", func() {
+			Expect(d.Len()).To(Equal(4))
+			Expect(read()).To(Equal([]int{6, 7, 8, 9}))
+			Expect(spy.AlertInput.Missed).To(Receive(Equal(6)))
+			Expect(d.Dropped()).To(Equal(uint64(6)))
+		})
+
+		It("keeps reading new values", func() {
+			read()
+			Expect(spy.AlertInput.Missed).To(Receive())
+
+			set(10, 12)
+			Expect(read()).To(Equal([]int{10, 11}))
+			Expect(spy.AlertInput.Missed).ToNot(Receive())
+		})
+	})
+
+	It("works with a Poller", func() {
+		p := diodes.NewPoller(d)
+		set(0, 2)
+		p.Close()
+
+		Expect(*(*int)(p.Next())).To(Equal(0))
+		Expect(*(*int)(p.Next())).To(Equal(1))
+		Expect(p.Next() == nil).To(BeTrue())
+		Expect(p.Closed()).To(BeTrue())
+	})
+
+	It("accounts for every write with a concurrent writer", func() {
+		const writes = 10000
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			set(0, writes)
+		}()
+
+		var (
+			reads int
+			last  = -1
+		)
+		check := func() {
+			for _, v := range read() {
+				Expect(v).To(BeNumerically(">", last))
+				last = v
+				reads++
+			}
+		}
+		for {
+			select {
+			case <-done:
+				check()
+				Expect(last).To(Equal(writes - 1))
+				Expect(uint64(reads) + d.Dropped()).To(Equal(uint64(writes)))
+				return
+			default:
+				check()
+			}
+		}
+	})
+})
@@ -0,0 +1,72 @@
+package diodes
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithStallDetection invokes onStall once the reader has not read anything
+// for longer than timeout while values keep being set, with how long the
+// reader has been stalled and the number of unread values. Unlike the
+// alerter, which reports drops that also occur when writers merely burst
+// past a healthy reader, it tells a stuck or dead reader apart from a
+// traffic spike. onStall is invoked on the go-routine of the writer that
+// noticed, once per stall, and again only after the reader made progress.
+// Idle periods without writes do not count as a stall. Enabling it makes
+// every Set read the clock. With WithLogger, the stall is logged as well and
+// onStall may be nil. The OneToMany diode does not support it.
+func WithStallDetection(timeout time.Duration, onStall func(stalled time.Duration, backlog int)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.stall = &stallDetector{
+			timeout: int64(timeout),
+			onStall: onStall,
+		}
+		c.stall.since.Store(nanotime())
+	})
+}
+
+// stallDetector tracks since when the reader has not moved, as seen by the
+// writers.
+type stallDetector struct {
+	timeout int64
+	onStall func(time.Duration, int)
+
+	nextRead atomic.Uint64
+	since    atomic.Int64
+	fired    atomic.Bool
+}
+
+// observeStall checks whether the reader stalled, if stalls are detected.
+// It must be called after a value was set.
+func (c *diodeConfig) observeStall(nextWrite, nextRead, size uint64) {
+	s := c.stall
+	if s == nil {
+		return
+	}
+
+	now := nanotime()
+	backlog := unread(nextWrite, nextRead, size)
+
+	// The reader made progress, or it had read everything before this value
+	// and was just idle.
+	if last := s.nextRead.Load(); nextRead != last || backlog <= 1 {
+		if s.nextRead.CompareAndSwap(last, nextRead) || backlog <= 1 {
+			s.since.Store(now)
+			s.fired.Store(false)
+		}
+		return
+	}
+
+	stalled := now - s.since.Load()
+	if stalled <= s.timeout || s.fired.Load() {
+		return
+	}
+	if s.fired.CompareAndSwap(false, true) {
+		if c.logger != nil {
+			logStallTo(c.logger, time.Duration(stalled), int(backlog))
+		}
+		if s.onStall != nil {
+			s.onStall(time.Duration(stalled), int(backlog))
+		}
+	}
+}
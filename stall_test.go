@@ -0,0 +1,103 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithStallDetection", func() {
+	type stall struct {
+		stalled time.Duration
+		backlog int
+	}
+
+	set := func(d diodes.Diode, n int) {
+		for i := 0; i < n; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewOneToOne(size, nil, opts...)
+		}),
+		Entry("ManyToOne", func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToOne(size, nil, opts...)
+		}),
+		Entry("ManyToMany", func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToMany(size, nil, opts...)
+		}),
+	}
+
+	var stalls chan stall
+
+	BeforeEach(func() {
+		stalls = make(chan stall, 10)
+	})
+
+	onStall := func(stalled time.Duration, backlog int) {
+		stalls <- stall{stalled, backlog}
+	}
+
+	DescribeTable("reports a reader that stopped reading while values are set",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) diodes.Diode) {
+			d := newDiode(8, diodes.WithStallDetection(20*time.Millisecond, onStall))
+
+			set(d, 3)
+			Expect(stalls).ToNot(Receive())
+
+			time.Sleep(30 * time.Millisecond)
+			set(d, 2)
+
+			var s stall
+			Expect(stalls).To(Receive(&s))
+			Expect(s.stalled).To(BeNumerically(">", 20*time.Millisecond))
+			Expect(s.backlog).To(Equal(4))
+
+			// Once per stall.
+			set(d, 10)
+			Expect(stalls).ToNot(Receive())
+		},
+		entries,
+	)
+
+	DescribeTable("reports the next stall once the reader made progress",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) diodes.Diode) {
+			d := newDiode(8, diodes.WithStallDetection(20*time.Millisecond, onStall))
+
+			set(d, 3)
+			time.Sleep(30 * time.Millisecond)
+			set(d, 1)
+			Expect(stalls).To(Receive())
+
+			d.TryNext()
+			set(d, 1)
+			Expect(stalls).ToNot(Receive())
+
+			time.Sleep(30 * time.Millisecond)
+			set(d, 1)
+			Expect(stalls).To(Receive())
+		},
+		entries,
+	)
+
+	DescribeTable("does not report a reader that was idle",
+		func(newDiode func(int, ...diodes.DiodeConfigOption) diodes.Diode) {
+			d := newDiode(8, diodes.WithStallDetection(20*time.Millisecond, onStall))
+
+			set(d, 2)
+			d.TryNext()
+			d.TryNext()
+
+			time.Sleep(30 * time.Millisecond)
+			set(d, 2)
+			Expect(stalls).ToNot(Receive())
+		},
+		entries,
+	)
+})
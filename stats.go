@@ -0,0 +1,204 @@
+package diodes
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRateHalfLife is the default half-life of the rates reported by
+// Stats.
+const defaultRateHalfLife = time.Minute
+
+// Stats is a snapshot of a diode's counters.
+type Stats struct {
+	// Writes is the total number of values that were set.
+	Writes uint64
+	// Reads is the total number of values that were read.
+	Reads uint64
+	// Dropped is the total number of values the reader noticed were
+	// overwritten before they were read.
+	Dropped uint64
+	// Lag is the number of values that were set but not read yet, bounded
+	// by the capacity of the diode.
+	Lag uint64
+	// Collisions is the total number of times a writer found its slot
+	// changed by another writer or the reader and had to retry or drop its
+	// value. Only diodes with many writers have collisions.
+	Collisions uint64
+	// FailedWrites is the total number of values writers gave up on after
+	// they ran out of the retries of WithRetryBudget. They are counted as
+	// writes, but not as drops.
+	FailedWrites uint64
+	// RateLimited is the total number of values that were dropped by the
+	// rate limit of WithWriteRateLimit. They are not counted as writes.
+	RateLimited uint64
+	// Expired is the total number of values the reader skipped because they
+	// were older than the maximum age of WithMaxAge. They are not counted as
+	// reads or drops.
+	Expired uint64
+	// Merged is the total number of unread values that were merged into the
+	// value overwriting them by WithMergeOnOverwrite. They are included in
+	// Dropped once the reader noticed.
+	Merged uint64
+	// LateWrites is the total number of values that arrived too late to be
+	// read, since writers of later indexes lapped their writer or the reader
+	// had moved past their index, and OrderWaits how
+	// many times the reader waited for a value whose index was claimed
+	// while later indexes were claimed as well. They are only tracked when
+	// WithOrderAudit is used.
+	LateWrites uint64
+	OrderWaits uint64
+	// Sampling reports whether the diode is full and only keeps a sample of
+	// the values that are set, and SampledOut is the total number of values
+	// that were discarded meanwhile. They are only tracked when
+	// WithOverloadSampling is used. SampledOut is included in Dropped once
+	// the reader noticed.
+	Sampling   bool
+	SampledOut uint64
+	// ActiveWriters is the number of writers registered via RegisterWriter
+	// that have not been closed.
+	ActiveWriters int64
+
+	// Capacity is the number of slots of the diode and AllocatedSlots how
+	// many of them are backed by memory. They only differ for diodes using
+	// WithSegmentSize that have not been written all the way around yet.
+	Capacity       uint64
+	AllocatedSlots uint64
+
+	// RetainedBytes is the approximate number of payload bytes held by the
+	// diode, and MinPayloadBytes and MaxPayloadBytes the sizes of the
+	// smallest and the largest value that was set. They are only tracked
+	// when WithSizer or WithByteLimit is used. ByteLimited is the total
+	// number of values that were discarded by the byte limit of
+	// WithByteLimit. It is included in Dropped once the reader noticed.
+	RetainedBytes   uint64
+	MinPayloadBytes uint64
+	MaxPayloadBytes uint64
+	ByteLimited     uint64
+
+	// WriteRate, ReadRate and DropRate are exponentially weighted moving
+	// averages of the writes, reads and drops per second.
+	WriteRate float64
+	ReadRate  float64
+	DropRate  float64
+
+	// OccupancyP50, OccupancyP95 and OccupancyMax describe the number of
+	// unread values seen by the reader over the last one to two windows.
+	// They are only tracked when WithOccupancyTracking is used.
+	OccupancyP50 uint64
+	OccupancyP95 uint64
+	OccupancyMax uint64
+
+	// DwellP50, DwellP99 and DwellMax describe how long values waited in the
+	// diode before they were read. They are only tracked when
+	// WithDwellHistogram is used.
+	DwellP50 time.Duration
+	DwellP99 time.Duration
+	DwellMax time.Duration
+
+	// DropIntervalP50, DropIntervalP99 and DropIntervalMax describe the time
+	// between the times the reader noticed dropped values, and DropBurstP50,
+	// DropBurstP99 and DropBurstMax how many values were dropped each time.
+	// They are only tracked when WithDropHistograms is used.
+	DropIntervalP50 time.Duration
+	DropIntervalP99 time.Duration
+	DropIntervalMax time.Duration
+	DropBurstP50    uint64
+	DropBurstP99    uint64
+	DropBurstMax    uint64
+}
+
+// diodeStats holds the counters and rates that are shared by the diodes.
+type diodeStats struct {
+	reads        atomic.Uint64
+	dropped      atomic.Uint64
+	collisions   atomic.Uint64
+	failedWrites atomic.Uint64
+	writers      atomic.Int64
+
+	// droppedAt is when the reader last noticed dropped values.
+	droppedAt atomic.Int64
+
+	mu        sync.Mutex
+	writeRate rate
+	readRate  rate
+	dropRate  rate
+}
+
+func (s *diodeStats) init(now time.Time, halfLife time.Duration) {
+	if halfLife <= 0 {
+		halfLife = defaultRateHalfLife
+	}
+
+	s.writeRate = rate{halfLife: halfLife, lastAt: now}
+	s.readRate = rate{halfLife: halfLife, lastAt: now}
+	s.dropRate = rate{halfLife: halfLife, lastAt: now}
+}
+
+// snapshot returns the stats given the total number of writes. It updates
+// the rates, so they move forward every time stats are taken.
+func (s *diodeStats) snapshot(writes uint64) Stats {
+	var st Stats
+	s.fillCounters(&st, writes)
+	s.updateRates(&st)
+	return st
+}
+
+// fillCounters resets st to the counters given the total number of writes.
+// It only loads atomics, so it neither blocks nor allocates.
+func (s *diodeStats) fillCounters(st *Stats, writes uint64) {
+	*st = Stats{
+		Writes:        writes,
+		Reads:         s.reads.Load(),
+		Dropped:       s.dropped.Load(),
+		Collisions:    s.collisions.Load(),
+		FailedWrites:  s.failedWrites.Load(),
+		ActiveWriters: s.writers.Load(),
+	}
+}
+
+// updateRates moves the rates forward to the counters in st and sets them.
+func (s *diodeStats) updateRates(st *Stats) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.WriteRate = s.writeRate.update(st.Writes, now)
+	st.ReadRate = s.readRate.update(st.Reads, now)
+	st.DropRate = s.dropRate.update(st.Dropped, now)
+}
+
+// unread returns the number of values between the next read and the next
+// write, bounded by the size of the diode.
+func unread(nextWrite, nextRead, size uint64) uint64 {
+	if nextWrite <= nextRead {
+		return 0
+	}
+	return min(nextWrite-nextRead, size)
+}
+
+// rate is an exponentially weighted moving average of how fast a counter
+// increases per second.
+type rate struct {
+	halfLife time.Duration
+	last     uint64
+	lastAt   time.Time
+	value    float64
+}
+
+func (r *rate) update(count uint64, now time.Time) float64 {
+	dt := now.Sub(r.lastAt).Seconds()
+	if dt <= 0 || count < r.last {
+		return r.value
+	}
+
+	instant := float64(count-r.last) / dt
+	alpha := 1 - math.Exp2(-dt/r.halfLife.Seconds())
+	r.value += alpha * (instant - r.value)
+	r.last = count
+	r.lastAt = now
+
+	return r.value
+}
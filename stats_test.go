@@ -0,0 +1,182 @@
+package diodes_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadStatsInto", func() {
+	type statsDiode interface {
+		diodes.Diode
+		Stats() diodes.Stats
+		ReadStatsInto(*diodes.Stats)
+	}
+
+	opts := func() []diodes.DiodeConfigOption {
+		return []diodes.DiodeConfigOption{
+			diodes.WithDwellHistogram(diodes.NewHistogram()),
+			diodes.WithOccupancyTracking(time.Second),
+			diodes.WithDropHistograms(),
+			diodes.WithSizer(func(diodes.GenericDataType) int { return 1 }),
+		}
+	}
+
+	entries := []TableEntry{
+		Entry("OneToOne", func() statsDiode { return diodes.NewOneToOne(4, nil, opts()...) }),
+		Entry("ManyToOne", func() statsDiode { return diodes.NewManyToOne(4, nil, opts()...) }),
+		Entry("ManyToMany", func() statsDiode { return diodes.NewManyToMany(4, nil, opts()...) }),
+		Entry("Latest", func() statsDiode { return diodes.NewLatest() }),
+		Entry("Unbounded", func() statsDiode {
+			return diodes.NewUnbounded(2, 1024, func(diodes.GenericDataType) int { return 8 }, nil)
+		}),
+		Entry("OneToOneT", func() statsDiode {
+			d := diodes.NewOneToOneT[int](4, nil, opts()...)
+			return statsAdapter{
+				set:      func(data diodes.GenericDataType) { d.Set(*(*int)(data)) },
+				tryNext:  d.Untyped().TryNext,
+				stats:    d.Stats,
+				readInto: d.ReadStatsInto,
+			}
+		}),
+		Entry("ManyToOneT", func() statsDiode {
+			d := diodes.NewManyToOneT[int](4, nil, opts()...)
+			return statsAdapter{
+				set:      func(data diodes.GenericDataType) { d.Set(*(*int)(data)) },
+				tryNext:  d.Untyped().TryNext,
+				stats:    d.Stats,
+				readInto: d.ReadStatsInto,
+			}
+		}),
+		Entry("Coalescing", func() statsDiode {
+			d := diodes.NewCoalescing(4, nil, nil)
+			return statsAdapter{
+				set:      func(data diodes.GenericDataType) { d.Set(strconv.Itoa(*(*int)(data)), data) },
+				tryNext:  d.TryNext,
+				stats:    d.Stats,
+				readInto: d.ReadStatsInto,
+			}
+		}),
+		Entry("ShardedManyToOne", func() statsDiode {
+			d := diodes.NewShardedManyToOne(1, 4, nil, opts()...)
+			return statsAdapter{
+				set:      d.Set,
+				tryNext:  d.TryNext,
+				stats:    func() diodes.Stats { return d.Stats(0) },
+				readInto: func(st *diodes.Stats) { d.ReadStatsInto(0, st) },
+			}
+		}),
+		Entry("KeyedDiodes", func() statsDiode {
+			d := diodes.NewKeyedDiodes(1, 4, nil, opts()...)
+			return statsAdapter{
+				set:      func(data diodes.GenericDataType) { d.Set("key", data) },
+				tryNext:  d.TryNext,
+				stats:    func() diodes.Stats { return d.Stats(0) },
+				readInto: func(st *diodes.Stats) { d.ReadStatsInto(0, st) },
+			}
+		}),
+		Entry("ManyToOnePriority", func() statsDiode {
+			d := diodes.NewManyToOnePriority([]int{4}, nil, opts()...)
+			return statsAdapter{
+				set:      func(data diodes.GenericDataType) { d.Set(0, data) },
+				tryNext:  d.TryNext,
+				stats:    func() diodes.Stats { return d.Stats(0) },
+				readInto: func(st *diodes.Stats) { d.ReadStatsInto(0, st) },
+			}
+		}),
+	}
+
+	set := func(d statsDiode, n int) {
+		for i := 0; i < n; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	DescribeTable("fills the counters of Stats without the rates",
+		func(newDiode func() statsDiode) {
+			d := newDiode()
+			set(d, 6)
+			d.TryNext()
+
+			st := diodes.Stats{WriteRate: 1, Expired: 1}
+			d.ReadStatsInto(&st)
+
+			want := d.Stats()
+			want.WriteRate, want.ReadRate, want.DropRate = 0, 0, 0
+			Expect(st).To(Equal(want))
+		},
+		entries,
+	)
+
+	DescribeTable("does not allocate",
+		func(newDiode func() statsDiode) {
+			d := newDiode()
+			set(d, 6)
+			d.TryNext()
+
+			var st diodes.Stats
+			Expect(testing.AllocsPerRun(10, func() {
+				d.ReadStatsInto(&st)
+			})).To(BeZero())
+		},
+		entries,
+	)
+
+	DescribeTable("can be called while the diode is in use",
+		func(newDiode func() statsDiode) {
+			d := newDiode()
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					set(d, 1)
+					d.TryNext()
+				}
+			}()
+
+			var st diodes.Stats
+			for i := 0; i < 1000; i++ {
+				d.ReadStatsInto(&st)
+			}
+			wg.Wait()
+
+			d.ReadStatsInto(&st)
+			Expect(st.Writes).To(Equal(uint64(1000)))
+		},
+		entries,
+	)
+})
+
+// statsAdapter lets the diodes whose Set or Stats take other arguments, or
+// that hold typed values, run through the tables of untyped diodes.
+type statsAdapter struct {
+	set      func(diodes.GenericDataType)
+	tryNext  func() (diodes.GenericDataType, bool)
+	stats    func() diodes.Stats
+	readInto func(*diodes.Stats)
+}
+
+func (a statsAdapter) Set(data diodes.GenericDataType) {
+	a.set(data)
+}
+
+func (a statsAdapter) TryNext() (diodes.GenericDataType, bool) {
+	return a.tryNext()
+}
+
+func (a statsAdapter) Stats() diodes.Stats {
+	return a.stats()
+}
+
+func (a statsAdapter) ReadStatsInto(st *diodes.Stats) {
+	a.readInto(st)
+}
@@ -0,0 +1,47 @@
+package diodes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// stopper lets the Poller and the Waiter be stopped while a reader is
+// blocked in Next and tracks the reads that are still in flight.
+type stopper struct {
+	once   sync.Once
+	c      chan struct{}
+	active atomic.Int64
+}
+
+func newStopper() *stopper {
+	return &stopper{c: make(chan struct{})}
+}
+
+// stop wakes up blocked readers and makes every further blocking read
+// return ErrStopped.
+func (s *stopper) stop() {
+	s.once.Do(func() { close(s.c) })
+}
+
+func (s *stopper) stopped() bool {
+	select {
+	case <-s.c:
+		return true
+	default:
+		return false
+	}
+}
+
+// enter and exit bracket a blocking read.
+func (s *stopper) enter() { s.active.Add(1) }
+func (s *stopper) exit()  { s.active.Add(-1) }
+
+// shutdown stops the readers and waits until every blocking read returned,
+// or until the context is done.
+func (s *stopper) shutdown(ctx context.Context) error {
+	s.stop()
+	return pollUntil(ctx, func() bool {
+		return s.active.Load() == 0
+	})
+}
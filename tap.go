@@ -0,0 +1,136 @@
+package diodes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Tap wraps a diode and lets observers see a sample of the data that is set
+// on it without consuming it from the wrapped diode. Observers receive the
+// same data as the reader of the wrapped diode and must not modify it.
+type Tap struct {
+	Diode
+	mu        sync.Mutex
+	observers atomic.Pointer[[]*TapObserver]
+}
+
+var _ Diode = (*Tap)(nil)
+
+// NewTap returns a new Tap that wraps the given diode.
+func NewTap(d Diode) *Tap {
+	return &Tap{
+		Diode: d,
+	}
+}
+
+// Set invokes the wrapped diode's Set with the given data and offers it to
+// every attached observer.
+func (t *Tap) Set(data GenericDataType) {
+	t.Diode.Set(data)
+
+	observers := t.observers.Load()
+	if observers == nil {
+		return
+	}
+
+	for _, o := range *observers {
+		o.offer(data)
+	}
+}
+
+// Attach attaches an observer that receives every nth value set on the tap.
+// Observed values are buffered in a diode of the given size so that a slow
+// observer drops data instead of slowing down the writers. The observer's
+// Next returns nil once the context is done. The observer must be detached
+// via Close.
+func (t *Tap) Attach(ctx context.Context, every, size int) *TapObserver {
+	if every < 1 {
+		every = 1
+	}
+
+	o := &TapObserver{
+		w:     NewWaiter(NewManyToOne(size, nil), WithWaiterContext(ctx)),
+		tap:   t,
+		every: uint64(every),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var observers []*TapObserver
+	if old := t.observers.Load(); old != nil {
+		observers = append(observers, *old...)
+	}
+	observers = append(observers, o)
+	t.observers.Store(&observers)
+
+	return o
+}
+
+func (t *Tap) detach(o *TapObserver) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	old := t.observers.Load()
+	if old == nil {
+		return
+	}
+
+	var observers []*TapObserver
+	for _, current := range *old {
+		if current != o {
+			observers = append(observers, current)
+		}
+	}
+
+	if len(observers) == 0 {
+		t.observers.Store(nil)
+		return
+	}
+	t.observers.Store(&observers)
+}
+
+// TapObserver receives a sample of the data set on a Tap.
+type TapObserver struct {
+	w     *Waiter
+	tap   *Tap
+	every uint64
+	count atomic.Uint64
+}
+
+var _ Reader = (*TapObserver)(nil)
+
+// Next returns the next observed value. If there is none, it waits until
+// one is observed or the context is done. If the context is done, then nil
+// will be returned.
+func (o *TapObserver) Next() GenericDataType {
+	return o.w.Next()
+}
+
+// NextCtx is like Next but also returns once the given context is done. It
+// returns the error of whichever context is done, see Waiter.NextCtx.
+// Unlike Next, a nil value with a nil error is legitimate data.
+func (o *TapObserver) NextCtx(ctx context.Context) (GenericDataType, error) {
+	return o.w.NextCtx(ctx)
+}
+
+// TryNext will attempt to read the next observed value. If there is none, it
+// will return (nil, false).
+func (o *TapObserver) TryNext() (GenericDataType, bool) {
+	return o.w.TryNext()
+}
+
+// Close detaches the observer from its tap. Set calls that are already in
+// flight may still offer data to the observer.
+func (o *TapObserver) Close() {
+	o.tap.detach(o)
+}
+
+func (o *TapObserver) offer(data GenericDataType) {
+	if o.count.Add(1)%o.every != 0 {
+		return
+	}
+
+	o.w.Set(data)
+}
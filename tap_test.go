@@ -0,0 +1,88 @@
+package diodes_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tap", func() {
+	var (
+		d *diodes.OneToOne
+		t *diodes.Tap
+	)
+
+	BeforeEach(func() {
+		d = diodes.NewOneToOne(10, nil)
+		t = diodes.NewTap(d)
+	})
+
+	It("sets data on the wrapped diode", func() {
+		data := []byte("some-data")
+		t.Set(diodes.GenericDataType(&data))
+
+		result, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*[]byte)(result)).To(Equal(data))
+	})
+
+	It("offers every nth value to attached observers", func() {
+		o := t.Attach(context.Background(), 2, 10)
+		defer o.Close()
+
+		for i := 0; i < 5; i++ {
+			j := i
+			t.Set(diodes.GenericDataType(&j))
+		}
+
+		Expect(*(*int)(o.Next())).To(Equal(1))
+		Expect(*(*int)(o.Next())).To(Equal(3))
+		_, ok := o.TryNext()
+		Expect(ok).To(BeFalse())
+
+		By("not consuming from the wrapped diode")
+		Expect(d.DrainInto(make([]diodes.GenericDataType, 10))).To(Equal(5))
+	})
+
+	It("stops offering values once the observer is closed", func() {
+		o := t.Attach(context.Background(), 1, 10)
+		other := t.Attach(context.Background(), 1, 10)
+		defer other.Close()
+		o.Close()
+
+		data := []byte("some-data")
+		t.Set(diodes.GenericDataType(&data))
+
+		_, ok := o.TryNext()
+		Expect(ok).To(BeFalse())
+		_, ok = other.TryNext()
+		Expect(ok).To(BeTrue())
+	})
+
+	It("returns nil from Next once the observer's context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		o := t.Attach(ctx, 1, 10)
+		defer o.Close()
+		cancel()
+
+		Expect(o.Next() == nil).To(BeTrue())
+	})
+
+	It("tells nil data apart from a done context with NextCtx", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		o := t.Attach(ctx, 1, 10)
+		defer o.Close()
+		t.Set(nil)
+
+		data, err := o.NextCtx(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data == nil).To(BeTrue())
+
+		cancel()
+		_, err = o.NextCtx(context.Background())
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
@@ -0,0 +1,167 @@
+package diodes
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TenantQuota limits the share of a diode every tenant, e.g. the source app
+// of a value, gets. Every tenant has a rate limit of its own, so a tenant
+// bursting past it only drops its own values instead of overwriting the
+// values of everyone else. The burst bounds how many slots of the diode a
+// single tenant can fill at once. It is safe for many writers, and reads are
+// passed on to the wrapped diode.
+type TenantQuota struct {
+	d       Diode
+	rps     float64
+	burst   int
+	idle    int64
+	swept   atomic.Int64
+	tenants sync.Map // map[string]*tenantLimiter
+}
+
+var _ Reader = (*TenantQuota)(nil)
+
+// tenantLimiter is the rate limit of a tenant along with the nanotime of its
+// latest value.
+type tenantLimiter struct {
+	*writeLimiter
+	seen atomic.Int64
+}
+
+// defaultTenantIdleTimeout is how long a tenant is tracked after its latest
+// value by default.
+const defaultTenantIdleTimeout = 10 * time.Minute
+
+// TenantQuotaConfigOption can be used to setup a tenant quota.
+type TenantQuotaConfigOption func(*TenantQuota)
+
+// WithTenantIdleTimeout sets how long a tenant is tracked after its latest
+// value. Tenants that were idle for longer are evicted by a writer, at most
+// once per timeout, so the quota does not hold on to every tenant it has
+// ever seen. An evicted tenant starts over with a full burst and its dropped
+// values are no longer reported. The default is 10 minutes, and a timeout
+// that is not positive keeps every tenant until it is forgotten.
+func WithTenantIdleTimeout(idle time.Duration) TenantQuotaConfigOption {
+	return TenantQuotaConfigOption(func(q *TenantQuota) {
+		q.idle = int64(idle)
+	})
+}
+
+// NewTenantQuota wraps the diode with a quota of rps values per second,
+// which must be positive, with bursts of up to burst values for every
+// tenant. Tenants are tracked from their first value on until they are idle
+// (see WithTenantIdleTimeout).
+func NewTenantQuota(d Diode, rps float64, burst int, opts ...TenantQuotaConfigOption) *TenantQuota {
+	q := &TenantQuota{
+		d:     d,
+		rps:   rps,
+		burst: burst,
+		idle:  int64(defaultTenantIdleTimeout),
+	}
+
+	for _, o := range opts {
+		o(q)
+	}
+
+	q.swept.Store(nanotime())
+	return q
+}
+
+// Set sets the data on the wrapped diode, unless the tenant exceeded its
+// quota, in which case the data is dropped and counted for the tenant.
+func (q *TenantQuota) Set(tenant string, data GenericDataType) {
+	if q.admit(tenant) {
+		q.d.Set(data)
+	}
+}
+
+// SetLazy sets the value that f returns like Set, but only invokes f if the
+// tenant did not exceed its quota.
+func (q *TenantQuota) SetLazy(tenant string, f func() GenericDataType) {
+	if q.admit(tenant) {
+		q.d.Set(f())
+	}
+}
+
+// admit reports whether the tenant may set a value under its quota, and
+// counts the value as dropped otherwise.
+func (q *TenantQuota) admit(tenant string) bool {
+	now := nanotime()
+	q.sweep(now)
+
+	l := q.limiter(tenant, now)
+	l.seen.Store(now)
+	if _, ok := l.reserve(now, 0); !ok {
+		l.limited.Add(1)
+		return false
+	}
+	return true
+}
+
+func (q *TenantQuota) limiter(tenant string, now int64) *tenantLimiter {
+	if l, ok := q.tenants.Load(tenant); ok {
+		return l.(*tenantLimiter)
+	}
+
+	l := &tenantLimiter{writeLimiter: newWriteLimiter(q.rps, q.burst, nil)}
+	l.seen.Store(now)
+	actual, _ := q.tenants.LoadOrStore(tenant, l)
+	return actual.(*tenantLimiter)
+}
+
+// sweep evicts the tenants that were idle for longer than the idle timeout.
+// Only the writer that notices first that the timeout passed since the
+// last sweep walks the tenants.
+func (q *TenantQuota) sweep(now int64) {
+	if q.idle <= 0 {
+		return
+	}
+
+	last := q.swept.Load()
+	if now-last < q.idle || !q.swept.CompareAndSwap(last, now) {
+		return
+	}
+
+	q.tenants.Range(func(tenant, l any) bool {
+		if now-l.(*tenantLimiter).seen.Load() > q.idle {
+			q.tenants.CompareAndDelete(tenant, l)
+		}
+		return true
+	})
+}
+
+// TryNext will attempt to read from the wrapped diode.
+func (q *TenantQuota) TryNext() (GenericDataType, bool) {
+	return q.d.TryNext()
+}
+
+// Dropped returns the number of values of the tenant that were dropped
+// because it exceeded its quota. Values the wrapped diode dropped are not
+// included.
+func (q *TenantQuota) Dropped(tenant string) uint64 {
+	if l, ok := q.tenants.Load(tenant); ok {
+		return l.(*tenantLimiter).limited.Load()
+	}
+	return 0
+}
+
+// DroppedByTenant returns the number of values that were dropped for every
+// tenant that exceeded its quota.
+func (q *TenantQuota) DroppedByTenant() map[string]uint64 {
+	dropped := make(map[string]uint64)
+	q.tenants.Range(func(tenant, l any) bool {
+		if n := l.(*tenantLimiter).limited.Load(); n > 0 {
+			dropped[tenant.(string)] = n
+		}
+		return true
+	})
+	return dropped
+}
+
+// Forget stops tracking the tenant, e.g. once its app was deleted, without
+// waiting for it to be idle. Its next value starts with a full burst.
+func (q *TenantQuota) Forget(tenant string) {
+	q.tenants.Delete(tenant)
+}
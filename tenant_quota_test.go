@@ -0,0 +1,101 @@
+package diodes_test
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TenantQuota", func() {
+	var (
+		d *diodes.ManyToOne
+		q *diodes.TenantQuota
+	)
+
+	set := func(tenant string, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			q.Set(tenant, diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func() []int {
+		var got []int
+		for {
+			data, ok := q.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	BeforeEach(func() {
+		d = diodes.NewManyToOne(10, nil)
+		q = diodes.NewTenantQuota(d, 0.001, 3)
+	})
+
+	It("keeps a noisy tenant from displacing the others", func() {
+		set("noisy", 0, 100)
+		set("quiet", 100, 102)
+
+		Expect(read()).To(Equal([]int{0, 1, 2, 100, 101}))
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("counts the dropped values of every tenant", func() {
+		set("noisy", 0, 100)
+		set("quiet", 100, 102)
+
+		Expect(q.Dropped("noisy")).To(Equal(uint64(97)))
+		Expect(q.Dropped("quiet")).To(BeZero())
+		Expect(q.Dropped("unknown")).To(BeZero())
+		Expect(q.DroppedByTenant()).To(Equal(map[string]uint64{"noisy": 97}))
+	})
+
+	It("starts over for a tenant that was forgotten", func() {
+		set("noisy", 0, 5)
+		q.Forget("noisy")
+		set("noisy", 5, 10)
+
+		Expect(read()).To(Equal([]int{0, 1, 2, 5, 6, 7}))
+		Expect(q.Dropped("noisy")).To(Equal(uint64(2)))
+	})
+
+	It("evicts the tenants that are idle", func() {
+		q = diodes.NewTenantQuota(d, 0.001, 3, diodes.WithTenantIdleTimeout(10*time.Millisecond))
+		set("noisy", 0, 5)
+		Expect(q.Dropped("noisy")).To(Equal(uint64(2)))
+
+		i := 0
+		Eventually(func() map[string]uint64 {
+			i++
+			q.Set(fmt.Sprint("quiet-", i), diodes.GenericDataType(&i))
+			return q.DroppedByTenant()
+		}).ShouldNot(HaveKey("noisy"))
+		Expect(q.Dropped("noisy")).To(BeZero())
+	})
+
+	It("keeps the tenants that are not idle", func() {
+		q = diodes.NewTenantQuota(d, 0.001, 3, diodes.WithTenantIdleTimeout(50*time.Millisecond))
+		set("noisy", 0, 5)
+
+		for start := time.Now(); time.Since(start) < 200*time.Millisecond; time.Sleep(5 * time.Millisecond) {
+			set("noisy", 5, 6)
+		}
+		Expect(q.Dropped("noisy")).To(BeNumerically(">", 2))
+	})
+
+	It("keeps every tenant without an idle timeout", func() {
+		q = diodes.NewTenantQuota(d, 0.001, 3, diodes.WithTenantIdleTimeout(0))
+		set("noisy", 0, 5)
+		time.Sleep(20 * time.Millisecond)
+		set("quiet", 100, 101)
+
+		Expect(q.Dropped("noisy")).To(Equal(uint64(2)))
+	})
+})
@@ -0,0 +1,81 @@
+package diodes
+
+import "fmt"
+
+// TwoTier diode chains a small hot ManyToOne diode with a larger cold one.
+// Values are set on the hot diode until it is full, and on the cold diode
+// from then on until the reader drained it, so bursts up to the size of both
+// diodes are absorbed while the common case only touches the small hot ring.
+// Values are only dropped once the cold diode overflows as well. It is safe
+// for many writers and a single reader.
+//
+// The reader drains the hot diode first, which holds the older values, and
+// then the cold one. Values set by concurrent writers while the hot diode
+// fills up can be read out of order.
+type TwoTier struct {
+	hot  *ManyToOne
+	cold *ManyToOne
+}
+
+var _ Diode = (*TwoTier)(nil)
+
+// NewTwoTier creates a new two tier diode with a hot diode of hotSize and a
+// cold diode of coldSize slots. The alerter is invoked on the reader's
+// go-routine when it notices that writers have passed it and wrote over
+// data. A nil can be used to ignore alerts. The options are applied to both
+// diodes.
+func NewTwoTier(hotSize, coldSize int, alerter Alerter, opts ...DiodeConfigOption) *TwoTier {
+	return &TwoTier{
+		hot:  NewManyToOne(hotSize, alerter, opts...),
+		cold: NewManyToOne(coldSize, alerter, opts...),
+	}
+}
+
+// NewTwoTierErr is like NewTwoTier but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots, see WithMinSize.
+func NewTwoTierErr(hotSize, coldSize int, alerter Alerter, opts ...DiodeConfigOption) (*TwoTier, error) {
+	c := newDiodeConfig(opts)
+	if err := c.checkSize(hotSize); err != nil {
+		return nil, fmt.Errorf("hot diode: %w", err)
+	}
+	if err := c.checkSize(coldSize); err != nil {
+		return nil, fmt.Errorf("cold diode: %w", err)
+	}
+	return NewTwoTier(hotSize, coldSize, alerter, opts...), nil
+}
+
+// Set sets the data on the hot diode, or on the cold diode if the hot diode
+// is full or the cold diode still holds unread values.
+func (d *TwoTier) Set(data GenericDataType) {
+	if d.cold.Len() > 0 || d.hot.Len() >= d.hot.Cap() {
+		d.cold.Set(data)
+		return
+	}
+	d.hot.Set(data)
+}
+
+// TryNext will attempt to read from the hot diode and then from the cold
+// diode. If there is no data available, it will return (nil, false).
+func (d *TwoTier) TryNext() (data GenericDataType, ok bool) {
+	if data, ok := d.hot.TryNext(); ok {
+		return data, true
+	}
+	return d.cold.TryNext()
+}
+
+// Len returns the approximate number of unread values in both diodes. It is
+// safe to call concurrently with the reader and writers.
+func (d *TwoTier) Len() int {
+	return d.hot.Len() + d.cold.Len()
+}
+
+// Cap returns the number of slots of both diodes.
+func (d *TwoTier) Cap() int {
+	return d.hot.Cap() + d.cold.Cap()
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read, in either diode.
+func (d *TwoTier) Dropped() uint64 {
+	return d.hot.Dropped() + d.cold.Dropped()
+}
@@ -0,0 +1,98 @@
+package diodes_test
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TwoTier", func() {
+	var d *diodes.TwoTier
+
+	BeforeEach(func() {
+		d = diodes.NewTwoTier(4, 16, nil)
+	})
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	readAll := func() []int {
+		var got []int
+		for {
+			data, ok := d.TryNext()
+			if !ok {
+				return got
+			}
+			got = append(got, *(*int)(data))
+		}
+	}
+
+	values := func(from, to int) []int {
+		var want []int
+		for i := from; i < to; i++ {
+			want = append(want, i)
+		}
+		return want
+	}
+
+	It("absorbs bursts up to the size of both tiers in order", func() {
+		set(0, 20)
+
+		Expect(d.Cap()).To(Equal(20))
+		Expect(d.Len()).To(Equal(20))
+		Expect(readAll()).To(Equal(values(0, 20)))
+		Expect(d.Dropped()).To(BeZero())
+	})
+
+	It("keeps setting on the cold tier until the reader drained it", func() {
+		set(0, 6)
+		Expect(readAll()[:5]).To(Equal(values(0, 5)))
+
+		set(6, 8)
+		Expect(readAll()).To(Equal(values(6, 8)))
+
+		// Both tiers are empty, so values go to the hot tier again.
+		set(8, 20)
+		Expect(readAll()).To(Equal(values(8, 20)))
+	})
+
+	It("only drops once the cold tier overflows", func() {
+		spy := newSpyAlerter()
+		d = diodes.NewTwoTier(4, 16, spy)
+		set(0, 24)
+
+		// The lapped cold tier skips ahead to its newest values, like any
+		// other diode.
+		got := readAll()
+		Expect(got[:4]).To(Equal(values(0, 4)))
+		Expect(got[4:]).To(Equal(values(20, 24)))
+		Expect(d.Dropped()).To(Equal(uint64(16)))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(16)))
+	})
+
+	It("is safe for many writers", func() {
+		d = diodes.NewTwoTier(16, 1024, nil)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					j := w*100 + i
+					d.Set(diodes.GenericDataType(&j))
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		Expect(readAll()).To(HaveLen(800))
+	})
+})
@@ -0,0 +1,442 @@
+package diodes
+
+import (
+	"context"
+	"time"
+)
+
+// DiodeT is any implementation of a diode of values of type T.
+type DiodeT[T any] interface {
+	Set(T)
+	TryNext() (T, bool)
+}
+
+// UntypedDiodeT is a DiodeT that is backed by an untyped Diode of *T values,
+// such as OneToOneT and ManyToOneT. The access layers wrap the untyped Diode.
+type UntypedDiodeT[T any] interface {
+	DiodeT[T]
+	Untyped() Diode
+}
+
+// OneToOneT is a OneToOne diode of values of type T. Values are copied into
+// the diode on Set, so no unsafe conversions are needed by the caller.
+//
+// Set stores a pointer to a copy of the value on the heap rather than the
+// value itself. A writer that laps the reader would overwrite a value in a
+// slot while the reader copies it, and a value of any type, unlike a
+// pointer, can not be stored and loaded atomically.
+type OneToOneT[T any] struct {
+	d *OneToOne
+}
+
+// NewOneToOneT creates a new OneToOneT diode. See NewOneToOne.
+func NewOneToOneT[T any](size int, alerter Alerter, opts ...DiodeConfigOption) *OneToOneT[T] {
+	return &OneToOneT[T]{
+		d: NewOneToOne(size, alerter, opts...),
+	}
+}
+
+// NewOneToOneTErr is like NewOneToOneT but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots. See NewOneToOneErr.
+func NewOneToOneTErr[T any](size int, alerter Alerter, opts ...DiodeConfigOption) (*OneToOneT[T], error) {
+	d, err := NewOneToOneErr(size, alerter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &OneToOneT[T]{d: d}, nil
+}
+
+// Set sets the value in the next slot of the ring buffer.
+func (d *OneToOneT[T]) Set(v T) {
+	d.d.Set(GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the next slot of the ring buffer. If
+// there is no data available, it will return the zero value and false.
+func (d *OneToOneT[T]) TryNext() (T, bool) {
+	return fromGeneric[T](d.d.TryNext())
+}
+
+// TryNextInto is like TryNext but copies the value into dst instead of
+// returning it, so that a large T is copied once rather than twice. If there
+// is no data available, it leaves dst alone and returns false.
+func (d *OneToOneT[T]) TryNextInto(dst *T) bool {
+	data, ok := d.d.TryNext()
+	return intoGeneric(dst, data, ok)
+}
+
+// DrainInto reads the available values into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls. See
+// OneToOne.DrainInto.
+func (d *OneToOneT[T]) DrainInto(dst []T) int {
+	budget := readBudget{limit: d.d.drainYield}
+	return drainInto(dst, d.TryNextInto, &budget)
+}
+
+// Stats returns a snapshot of the diode's counters. See OneToOne.Stats.
+func (d *OneToOneT[T]) Stats() Stats {
+	return d.d.Stats()
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero. See OneToOne.ReadStatsInto.
+func (d *OneToOneT[T]) ReadStatsInto(st *Stats) {
+	d.d.ReadStatsInto(st)
+}
+
+// Len returns the approximate number of unread values. See OneToOne.Len.
+func (d *OneToOneT[T]) Len() int {
+	return d.d.Len()
+}
+
+// Pressure returns how close the diode is to dropping values, between 0
+// and 1. See ManyToOne.Pressure.
+func (d *OneToOneT[T]) Pressure() float64 {
+	return d.d.Pressure()
+}
+
+// Cap returns the number of slots of the diode.
+func (d *OneToOneT[T]) Cap() int {
+	return d.d.Cap()
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read.
+func (d *OneToOneT[T]) Dropped() uint64 {
+	return d.d.Dropped()
+}
+
+// Untyped returns the untyped diode that holds *T values, e.g. to use it
+// with a BatchReader.
+func (d *OneToOneT[T]) Untyped() Diode {
+	return d.d
+}
+
+// ManyToOneT is a ManyToOne diode of values of type T. Values are copied
+// into the diode on Set like for OneToOneT, so no unsafe conversions are
+// needed by the caller.
+type ManyToOneT[T any] struct {
+	d *ManyToOne
+}
+
+// NewManyToOneT creates a new ManyToOneT diode. See NewManyToOne.
+func NewManyToOneT[T any](size int, alerter Alerter, opts ...DiodeConfigOption) *ManyToOneT[T] {
+	return &ManyToOneT[T]{
+		d: NewManyToOne(size, alerter, opts...),
+	}
+}
+
+// NewManyToOneTErr is like NewManyToOneT but returns an error matching
+// ErrInvalidSize instead of a diode that has no slots. See NewManyToOneErr.
+func NewManyToOneTErr[T any](size int, alerter Alerter, opts ...DiodeConfigOption) (*ManyToOneT[T], error) {
+	d, err := NewManyToOneErr(size, alerter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ManyToOneT[T]{d: d}, nil
+}
+
+// Set sets the value in the next slot of the ring buffer.
+func (d *ManyToOneT[T]) Set(v T) {
+	d.d.Set(GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the next slot of the ring buffer. If
+// there is no data available, it will return the zero value and false.
+func (d *ManyToOneT[T]) TryNext() (T, bool) {
+	return fromGeneric[T](d.d.TryNext())
+}
+
+// TryNextInto is like TryNext but copies the value into dst instead of
+// returning it, so that a large T is copied once rather than twice. If there
+// is no data available, it leaves dst alone and returns false.
+func (d *ManyToOneT[T]) TryNextInto(dst *T) bool {
+	data, ok := d.d.TryNext()
+	return intoGeneric(dst, data, ok)
+}
+
+// DrainInto reads the available values into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls. See
+// ManyToOne.DrainInto.
+func (d *ManyToOneT[T]) DrainInto(dst []T) int {
+	budget := readBudget{limit: d.d.drainYield}
+	return drainInto(dst, d.TryNextInto, &budget)
+}
+
+// Stats returns a snapshot of the diode's counters. See ManyToOne.Stats.
+func (d *ManyToOneT[T]) Stats() Stats {
+	return d.d.Stats()
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero. See ManyToOne.ReadStatsInto.
+func (d *ManyToOneT[T]) ReadStatsInto(st *Stats) {
+	d.d.ReadStatsInto(st)
+}
+
+// Len returns the approximate number of unread values. See ManyToOne.Len.
+func (d *ManyToOneT[T]) Len() int {
+	return d.d.Len()
+}
+
+// Pressure returns how close the diode is to dropping values, between 0
+// and 1. See ManyToOne.Pressure.
+func (d *ManyToOneT[T]) Pressure() float64 {
+	return d.d.Pressure()
+}
+
+// Cap returns the number of slots of the diode.
+func (d *ManyToOneT[T]) Cap() int {
+	return d.d.Cap()
+}
+
+// Dropped returns the total number of values the reader noticed were
+// overwritten before they were read.
+func (d *ManyToOneT[T]) Dropped() uint64 {
+	return d.d.Dropped()
+}
+
+// Untyped returns the untyped diode that holds *T values, e.g. to use it
+// with a BatchReader.
+func (d *ManyToOneT[T]) Untyped() Diode {
+	return d.d
+}
+
+// PollerT will poll a typed diode until a value is available. See Poller.
+type PollerT[T any] struct {
+	p *Poller
+}
+
+// NewPollerT returns a new PollerT that wraps the given diode.
+func NewPollerT[T any](d UntypedDiodeT[T], opts ...PollerConfigOption) *PollerT[T] {
+	return &PollerT[T]{
+		p: NewPoller(d.Untyped(), opts...),
+	}
+}
+
+// Set sets the value on the wrapped diode.
+func (p *PollerT[T]) Set(v T) {
+	p.p.Set(GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return the zero
+// value and false.
+func (p *PollerT[T]) TryNext() (T, bool) {
+	return fromGeneric[T](p.p.TryNext())
+}
+
+// TryNextInto is like TryNext but copies the value into dst instead of
+// returning it, so that a large T is copied once rather than twice. If there
+// is no data available, it leaves dst alone and returns false.
+func (p *PollerT[T]) TryNextInto(dst *T) bool {
+	data, ok := p.p.TryNext()
+	return intoGeneric(dst, data, ok)
+}
+
+// DrainInto reads the available values into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls. Like
+// TryNext, it does not wait for data.
+func (p *PollerT[T]) DrainInto(dst []T) int {
+	budget := readBudget{limit: p.p.budget.limit}
+	return drainInto(dst, p.TryNextInto, &budget)
+}
+
+// Next polls the diode until data is available or until the context is done.
+// If the context is done or the end of the stream was reached, it returns
+// the zero value and false.
+func (p *PollerT[T]) Next() (T, bool) {
+	data := p.p.Next()
+	return fromGeneric[T](data, data != nil)
+}
+
+// NextCtx is like Next but also returns once the given context is done, with
+// an error that tells why no value was returned. See Poller.NextCtx.
+func (p *PollerT[T]) NextCtx(ctx context.Context) (T, error) {
+	data, err := p.p.NextCtx(ctx)
+	v, _ := fromGeneric[T](data, err == nil)
+	return v, err
+}
+
+// NextN waits like Next until a value is available and then reads up to
+// len(dst) values into dst without waiting for more. See Poller.NextN.
+func (p *PollerT[T]) NextN(dst []T) int {
+	return nextN(dst, p.Next, p.p.TryNext, &p.p.budget)
+}
+
+// Close marks the end of the stream. See Poller.Close.
+func (p *PollerT[T]) Close() {
+	p.p.Close()
+}
+
+// Drain reads everything that was set before it was called and then ends
+// the stream on the reader's side. See Poller.Drain.
+func (p *PollerT[T]) Drain(ctx context.Context) ([]T, error) {
+	return fromGenerics[T](p.p.Drain(ctx))
+}
+
+// Closed reports whether the reader has reached the end of the stream.
+func (p *PollerT[T]) Closed() bool {
+	return p.p.Closed()
+}
+
+// WaiterT will use a channel signal to alert the reader to when data is
+// available on a typed diode. See Waiter.
+type WaiterT[T any] struct {
+	w *Waiter
+}
+
+// NewWaiterT returns a new WaiterT that wraps the given diode.
+func NewWaiterT[T any](d UntypedDiodeT[T], opts ...WaiterConfigOption) *WaiterT[T] {
+	return &WaiterT[T]{
+		w: NewWaiter(d.Untyped(), opts...),
+	}
+}
+
+// Set sets the value on the wrapped diode and wakes up the reader.
+func (w *WaiterT[T]) Set(v T) {
+	w.w.Set(GenericDataType(&v))
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return the zero
+// value and false.
+func (w *WaiterT[T]) TryNext() (T, bool) {
+	return fromGeneric[T](w.w.TryNext())
+}
+
+// TryNextInto is like TryNext but copies the value into dst instead of
+// returning it, so that a large T is copied once rather than twice. If there
+// is no data available, it leaves dst alone and returns false.
+func (w *WaiterT[T]) TryNextInto(dst *T) bool {
+	data, ok := w.w.TryNext()
+	return intoGeneric(dst, data, ok)
+}
+
+// DrainInto reads the available values into dst until either dst is full or
+// there is no more data available. It returns the number of values written
+// to dst. It does not allocate, so dst can be reused across calls. Like
+// TryNext, it does not wait for data.
+func (w *WaiterT[T]) DrainInto(dst []T) int {
+	budget := readBudget{limit: w.w.budget.limit}
+	return drainInto(dst, w.TryNextInto, &budget)
+}
+
+// Next returns the next value on the wrapped diode. If there is none, it
+// waits for Set to be called or the context to be done. If the context is
+// done or the end of the stream was reached, it returns the zero value and
+// false.
+func (w *WaiterT[T]) Next() (T, bool) {
+	data := w.w.Next()
+	return fromGeneric[T](data, data != nil)
+}
+
+// NextCtx is like Next but also returns once the given context is done, with
+// an error that tells why no value was returned. See Waiter.NextCtx.
+func (w *WaiterT[T]) NextCtx(ctx context.Context) (T, error) {
+	data, err := w.w.NextCtx(ctx)
+	v, _ := fromGeneric[T](data, err == nil)
+	return v, err
+}
+
+// NextWithTimeout is like Next but also returns the zero value and false
+// once the timeout passed. See Waiter.NextWithTimeout.
+func (w *WaiterT[T]) NextWithTimeout(timeout time.Duration) (T, bool) {
+	return fromGeneric[T](w.w.NextWithTimeout(timeout))
+}
+
+// NextN waits like Next until a value is available and then reads up to
+// len(dst) values into dst without waiting for more. See Waiter.NextN.
+func (w *WaiterT[T]) NextN(dst []T) int {
+	return nextN(dst, w.Next, w.w.TryNext, &w.w.budget)
+}
+
+// Close marks the end of the stream and wakes up the reader. See
+// Waiter.Close.
+func (w *WaiterT[T]) Close() {
+	w.w.Close()
+}
+
+// Drain reads everything that was set before it was called and then ends
+// the stream on the reader's side. See Waiter.Drain.
+func (w *WaiterT[T]) Drain(ctx context.Context) ([]T, error) {
+	return fromGenerics[T](w.w.Drain(ctx))
+}
+
+// Closed reports whether the reader has reached the end of the stream.
+func (w *WaiterT[T]) Closed() bool {
+	return w.w.Closed()
+}
+
+// nextN waits for the first value with next and reads the values that are
+// available right away with tryNext, spending the read budget for each.
+func nextN[T any](dst []T, next func() (T, bool), tryNext func() (GenericDataType, bool), budget *readBudget) int {
+	if len(dst) == 0 {
+		return 0
+	}
+
+	v, ok := next()
+	if !ok {
+		return 0
+	}
+	dst[0] = v
+
+	n := 1
+	for n < len(dst) {
+		v, ok := fromGeneric[T](tryNext())
+		if !ok {
+			break
+		}
+		budget.spend()
+		dst[n] = v
+		n++
+	}
+	return n
+}
+
+// fromGenerics converts the values of an untyped drain of values that were
+// set by a typed diode.
+func fromGenerics[T any](data []GenericDataType, err error) ([]T, error) {
+	if data == nil {
+		return nil, err
+	}
+
+	values := make([]T, len(data))
+	for i, d := range data {
+		values[i] = *(*T)(d)
+	}
+	return values, err
+}
+
+// drainInto reads values with tryNextInto until dst is full or there is no
+// more data available, spending the read budget for each.
+func drainInto[T any](dst []T, tryNextInto func(*T) bool, budget *readBudget) int {
+	var n int
+	for n < len(dst) && tryNextInto(&dst[n]) {
+		budget.spend()
+		n++
+	}
+	return n
+}
+
+// intoGeneric copies the result of an untyped read of a value that was set
+// as a *T into dst, if there was one.
+func intoGeneric[T any](dst *T, data GenericDataType, ok bool) bool {
+	if ok {
+		*dst = *(*T)(data)
+	}
+	return ok
+}
+
+// fromGeneric converts the result of an untyped read of a value that was
+// set by a typed diode.
+func fromGeneric[T any](data GenericDataType, ok bool) (T, bool) {
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return *(*T)(data), true
+}
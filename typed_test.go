@@ -0,0 +1,173 @@
+package diodes_test
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type envelope struct {
+	origin string
+	value  int
+}
+
+var _ = Describe("typed diodes", func() {
+	DescribeTable("round trip typed values",
+		func(d diodes.DiodeT[envelope]) {
+			_, ok := d.TryNext()
+			Expect(ok).To(BeFalse())
+
+			d.Set(envelope{origin: "a", value: 1})
+			d.Set(envelope{origin: "b", value: 2})
+
+			e, ok := d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(e).To(Equal(envelope{origin: "a", value: 1}))
+
+			e, ok = d.TryNext()
+			Expect(ok).To(BeTrue())
+			Expect(e).To(Equal(envelope{origin: "b", value: 2}))
+		},
+		Entry("OneToOneT", diodes.NewOneToOneT[envelope](5, nil)),
+		Entry("ManyToOneT", diodes.NewManyToOneT[envelope](5, nil)),
+		Entry("PollerT", diodes.NewPollerT[envelope](diodes.NewOneToOneT[envelope](5, nil))),
+		Entry("WaiterT", diodes.NewWaiterT[envelope](diodes.NewManyToOneT[envelope](5, nil))),
+	)
+
+	DescribeTable("read typed values into the given value",
+		func(d interface {
+			diodes.DiodeT[envelope]
+			TryNextInto(*envelope) bool
+		}) {
+			e := envelope{origin: "unchanged"}
+			Expect(d.TryNextInto(&e)).To(BeFalse())
+			Expect(e).To(Equal(envelope{origin: "unchanged"}))
+
+			d.Set(envelope{origin: "a", value: 1})
+			Expect(d.TryNextInto(&e)).To(BeTrue())
+			Expect(e).To(Equal(envelope{origin: "a", value: 1}))
+		},
+		Entry("OneToOneT", diodes.NewOneToOneT[envelope](5, nil)),
+		Entry("ManyToOneT", diodes.NewManyToOneT[envelope](5, nil)),
+		Entry("PollerT", diodes.NewPollerT[envelope](diodes.NewOneToOneT[envelope](5, nil))),
+		Entry("WaiterT", diodes.NewWaiterT[envelope](diodes.NewManyToOneT[envelope](5, nil))),
+	)
+
+	It("alerts and counts drops", func() {
+		spy := newSpyAlerter()
+		d := diodes.NewManyToOneT[int](2, spy)
+		for i := 0; i < 5; i++ {
+			d.Set(i)
+		}
+
+		v, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(v).To(Equal(4))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(4)))
+		Expect(d.Dropped()).To(Equal(uint64(4)))
+		Expect(d.Stats().Writes).To(Equal(uint64(5)))
+	})
+
+	Describe("PollerT", func() {
+		It("returns false once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			p := diodes.NewPollerT[int](diodes.NewOneToOneT[int](5, nil), diodes.WithPollingContext(ctx))
+			_, ok := p.Next()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("returns the zero value as a regular value", func() {
+			p := diodes.NewPollerT[int](diodes.NewOneToOneT[int](5, nil))
+			p.Set(0)
+
+			v, ok := p.Next()
+			Expect(ok).To(BeTrue())
+			Expect(v).To(BeZero())
+		})
+
+		It("returns the error of the context given to NextCtx", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			p := diodes.NewPollerT[int](diodes.NewOneToOneT[int](5, nil))
+			_, err := p.NextCtx(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+
+		It("reads the available values with NextN", func() {
+			p := diodes.NewPollerT[int](diodes.NewOneToOneT[int](5, nil))
+			p.Set(1)
+			p.Set(2)
+			p.Set(3)
+
+			dst := make([]int, 2)
+			Expect(p.NextN(dst)).To(Equal(2))
+			Expect(dst).To(Equal([]int{1, 2}))
+			Expect(p.NextN(dst)).To(Equal(1))
+			Expect(dst[0]).To(Equal(3))
+		})
+
+		It("drains the values set before the end of the stream", func() {
+			p := diodes.NewPollerT[int](diodes.NewOneToOneT[int](5, nil))
+			p.Set(1)
+			p.Set(2)
+
+			values, err := p.Drain(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]int{1, 2}))
+			Expect(p.Closed()).To(BeTrue())
+		})
+	})
+
+	Describe("WaiterT", func() {
+		It("returns false at the end of the stream", func() {
+			w := diodes.NewWaiterT[int](diodes.NewManyToOneT[int](5, nil))
+			w.Set(1)
+			w.Close()
+
+			v, ok := w.Next()
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal(1))
+
+			_, ok = w.Next()
+			Expect(ok).To(BeFalse())
+			Expect(w.Closed()).To(BeTrue())
+		})
+
+		It("returns false once the timeout of NextWithTimeout passed", func() {
+			w := diodes.NewWaiterT[int](diodes.NewManyToOneT[int](5, nil))
+			_, ok := w.NextWithTimeout(time.Millisecond)
+			Expect(ok).To(BeFalse())
+
+			w.Set(1)
+			v, ok := w.NextWithTimeout(time.Minute)
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal(1))
+		})
+
+		It("reads the available values with NextN", func() {
+			w := diodes.NewWaiterT[int](diodes.NewManyToOneT[int](5, nil))
+			w.Set(1)
+			w.Set(2)
+
+			dst := make([]int, 5)
+			Expect(w.NextN(dst)).To(Equal(2))
+			Expect(dst[:2]).To(Equal([]int{1, 2}))
+		})
+
+		It("drains the values set before the end of the stream", func() {
+			w := diodes.NewWaiterT[int](diodes.NewManyToOneT[int](5, nil))
+			w.Set(1)
+
+			values, err := w.Drain(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(values).To(Equal([]int{1}))
+		})
+	})
+})
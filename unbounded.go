@@ -0,0 +1,304 @@
+package diodes
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SizeFunc returns the size of data in bytes.
+type SizeFunc func(GenericDataType) int
+
+// Unbounded diode grows by chaining fixed size segments instead of
+// overwriting unread data. It only starts dropping the oldest values once
+// the unread values add up to more than its byte cap. It is safe for many
+// writers and a single reader. Unlike OneToOne and ManyToOne, writers and
+// the reader synchronize on a mutex.
+type Unbounded struct {
+	mu          sync.Mutex
+	head        *segment
+	tail        *segment
+	spare       *segment
+	segmentSize int
+	bytes       int
+	maxBytes    int
+	missed      int
+	count       int
+
+	// lag and retained mirror count and bytes, so that stats can be read
+	// without taking the mutex the writers and the reader share.
+	lag      atomic.Int64
+	retained atomic.Int64
+
+	spillAt    int
+	maxAge     time.Duration
+	watermarks *watermarks
+	spilled    atomic.Uint64
+	expired    atomic.Uint64
+	size       SizeFunc
+	alerter    Alerter
+	writes     atomic.Uint64
+	diodeStats
+}
+
+var _ Diode = (*Unbounded)(nil)
+
+type segment struct {
+	entries []entry
+	read    int
+	next    *segment
+}
+
+type entry struct {
+	data    GenericDataType
+	size    int
+	spilled bool
+	at      int64
+}
+
+// UnboundedConfigOption can be used to setup the unbounded diode.
+type UnboundedConfigOption func(*Unbounded)
+
+// WithSpillThreshold makes the diode behave like a bounded ring of the given
+// size during normal operation. Values that are set while threshold values
+// are unread spill into overflow segments and are counted separately (see
+// Spilled). If maxAge is positive, spilled values that waited longer than
+// maxAge are dropped instead of being returned (see Expired), so that bursts
+// shorter than maxAge are lossless and longer ones are lossy.
+func WithSpillThreshold(threshold int, maxAge time.Duration) UnboundedConfigOption {
+	return UnboundedConfigOption(func(d *Unbounded) {
+		d.spillAt = threshold
+		d.maxAge = maxAge
+	})
+}
+
+// WithUnboundedWatermarks invokes cb with WatermarkHigh once the number of
+// unread values reaches high, and with WatermarkLow once it falls back to
+// low or below, like WithWatermarks does for the other diodes. It warns
+// about a growing backlog long before the byte cap drops data. cb is
+// invoked on the go-routine of the writer or the reader that crossed the
+// watermark, outside of the mutex of the diode.
+func WithUnboundedWatermarks(high, low int, cb func(WatermarkState)) UnboundedConfigOption {
+	return UnboundedConfigOption(func(d *Unbounded) {
+		d.watermarks = &watermarks{
+			high: uint64(max(high, 1)),
+			low:  uint64(max(min(low, high-1), 0)),
+			cb:   cb,
+		}
+	})
+}
+
+// NewUnbounded creates a new Unbounded diode that allocates segments of
+// segmentSize values as it grows and drops the oldest values once the
+// values it holds would exceed maxBytes, as measured by size. The alerter
+// is invoked on the reader's go-routine with the number of values that
+// were dropped since the last read. A nil can be used to ignore alerts.
+func NewUnbounded(segmentSize, maxBytes int, size SizeFunc, alerter Alerter, opts ...UnboundedConfigOption) *Unbounded {
+	if alerter == nil {
+		alerter = AlertFunc(func(int) {})
+	}
+	segmentSize = max(segmentSize, 1)
+
+	s := &segment{entries: make([]entry, 0, segmentSize)}
+	d := &Unbounded{
+		head:        s,
+		tail:        s,
+		segmentSize: segmentSize,
+		maxBytes:    maxBytes,
+		size:        size,
+		alerter:     alerter,
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	d.diodeStats.init(time.Now(), defaultRateHalfLife)
+	return d
+}
+
+// Set appends the data, chaining a new segment if the last one is full. If
+// the data does not fit within the byte cap, the oldest values are dropped
+// until it does. Data that is larger than the byte cap on its own is
+// dropped.
+func (d *Unbounded) Set(data GenericDataType) {
+	var n int
+	if data != endOfStream {
+		n = d.size(data)
+	}
+	d.writes.Add(1)
+
+	d.mu.Lock()
+	d.set(data, n)
+	count := d.count
+	d.mu.Unlock()
+
+	d.observeBacklog(count)
+}
+
+// set appends the data of size n. d.mu must be held.
+func (d *Unbounded) set(data GenericDataType, n int) {
+	if n > d.maxBytes {
+		d.drop(1)
+		return
+	}
+
+	for d.bytes+n > d.maxBytes {
+		if _, ok := d.pop(); !ok {
+			break
+		}
+		d.drop(1)
+	}
+
+	if len(d.tail.entries) == cap(d.tail.entries) {
+		s := d.spare
+		if s == nil {
+			s = &segment{entries: make([]entry, 0, d.segmentSize)}
+		}
+		d.spare = nil
+		d.tail.next = s
+		d.tail = s
+	}
+
+	e := entry{data: data, size: n}
+	if d.spillAt > 0 && d.count >= d.spillAt {
+		e.spilled = true
+		e.at = nanotime()
+		d.spilled.Add(1)
+	}
+
+	d.tail.entries = append(d.tail.entries, e)
+	d.bytes += n
+	d.count++
+	d.lag.Store(int64(d.count))
+	d.retained.Store(int64(d.bytes))
+}
+
+// TryNext will attempt to read the oldest value. If there is no data
+// available, it will return (nil, false).
+func (d *Unbounded) TryNext() (GenericDataType, bool) {
+	d.mu.Lock()
+	e, ok := d.pop()
+	for ok && d.isExpired(e) {
+		d.expired.Add(1)
+		d.drop(1)
+		e, ok = d.pop()
+	}
+	missed := d.missed
+	d.missed = 0
+	count := d.count
+	d.mu.Unlock()
+
+	if missed > 0 {
+		d.alerter.Alert(missed)
+	}
+	d.observeBacklog(count)
+
+	if !ok {
+		return nil, false
+	}
+
+	d.reads.Add(1)
+	return e.data, true
+}
+
+// observeBacklog checks the number of unread values against the watermarks,
+// if there are any.
+func (d *Unbounded) observeBacklog(count int) {
+	if d.watermarks != nil {
+		d.watermarks.observe(uint64(count))
+	}
+}
+
+// Bytes returns the total size of the values that have not been read.
+func (d *Unbounded) Bytes() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.bytes
+}
+
+// Len returns the number of unread values.
+func (d *Unbounded) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+// Stats returns a snapshot of the diode's counters. The rates are updated
+// every time Stats is called.
+func (d *Unbounded) Stats() Stats {
+	var st Stats
+	d.ReadStatsInto(&st)
+	d.updateRates(&st)
+	return st
+}
+
+// ReadStatsInto fills st with the diode's counters like Stats, but leaves
+// the rates zero. It does not take the mutex of the diode, so it does not
+// hold up Set and TryNext. See OneToOne.ReadStatsInto.
+func (d *Unbounded) ReadStatsInto(st *Stats) {
+	d.fillCounters(st, d.writes.Load())
+	st.Lag = uint64(d.lag.Load())
+	st.RetainedBytes = uint64(d.retained.Load())
+}
+
+// Dropped returns the total number of values that were dropped because of
+// the byte cap.
+func (d *Unbounded) Dropped() uint64 {
+	return d.dropped.Load()
+}
+
+// Spilled returns the total number of values that were set while the spill
+// threshold was exceeded. It is always zero without WithSpillThreshold.
+func (d *Unbounded) Spilled() uint64 {
+	return d.spilled.Load()
+}
+
+// Expired returns the total number of spilled values that were dropped
+// because they waited longer than the spill max age. They are also counted
+// as dropped.
+func (d *Unbounded) Expired() uint64 {
+	return d.expired.Load()
+}
+
+func (d *Unbounded) isExpired(e entry) bool {
+	return e.spilled && d.maxAge > 0 && time.Duration(nanotime()-e.at) > d.maxAge
+}
+
+// pop removes the oldest entry. It releases segments once they have been
+// read, keeping one around to avoid allocating under steady load. d.mu must
+// be held.
+func (d *Unbounded) pop() (entry, bool) {
+	s := d.head
+	if s.read == len(s.entries) {
+		if s.next == nil {
+			// The only segment is drained, so it can be reused from the
+			// start.
+			s.entries = s.entries[:0]
+			s.read = 0
+			return entry{}, false
+		}
+
+		d.head = s.next
+		s.entries = s.entries[:0]
+		s.read = 0
+		s.next = nil
+		d.spare = s
+		s = d.head
+	}
+
+	e := s.entries[s.read]
+	s.entries[s.read] = entry{}
+	s.read++
+	d.bytes -= e.size
+	d.count--
+	d.lag.Store(int64(d.count))
+	d.retained.Store(int64(d.bytes))
+	return e, true
+}
+
+// drop records that n values were dropped. d.mu must be held.
+func (d *Unbounded) drop(n int) {
+	d.missed += n
+	d.dropped.Add(uint64(n))
+}
@@ -0,0 +1,212 @@
+package diodes_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unbounded", func() {
+	var (
+		spy *spyAlerter
+		d   *diodes.Unbounded
+	)
+
+	byteSize := func(data diodes.GenericDataType) int {
+		return len(*(*[]byte)(data))
+	}
+
+	set := func(s string) {
+		b := []byte(s)
+		d.Set(diodes.GenericDataType(&b))
+	}
+
+	next := func() (string, bool) {
+		data, ok := d.TryNext()
+		if !ok {
+			return "", false
+		}
+		return string(*(*[]byte)(data)), true
+	}
+
+	BeforeEach(func() {
+		spy = newSpyAlerter()
+		d = diodes.NewUnbounded(2, 10, byteSize, spy)
+	})
+
+	It("returns false when there is no data", func() {
+		_, ok := next()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("grows past the segment size without dropping", func() {
+		for _, s := range []string{"a", "b", "c", "d", "e"} {
+			set(s)
+		}
+		Expect(d.Bytes()).To(Equal(5))
+
+		var got []string
+		for {
+			s, ok := next()
+			if !ok {
+				break
+			}
+			got = append(got, s)
+		}
+		Expect(got).To(Equal([]string{"a", "b", "c", "d", "e"}))
+		Expect(d.Bytes()).To(BeZero())
+		Expect(d.Dropped()).To(BeZero())
+		Expect(spy.AlertInput.Missed).To(BeEmpty())
+	})
+
+	It("drops the oldest values once the byte cap is reached", func() {
+		set("aaaa")
+		set("bbbb")
+		set("cccc")
+
+		s, ok := next()
+		Expect(ok).To(BeTrue())
+		Expect(s).To(Equal("bbbb"))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(1)))
+		Expect(d.Dropped()).To(Equal(uint64(1)))
+
+		s, _ = next()
+		Expect(s).To(Equal("cccc"))
+	})
+
+	It("drops values that are larger than the byte cap", func() {
+		set("a")
+		set("this is too large")
+
+		s, _ := next()
+		Expect(s).To(Equal("a"))
+		Expect(spy.AlertInput.Missed).To(Receive(Equal(1)))
+	})
+
+	It("keeps working after being drained", func() {
+		for i := 0; i < 3; i++ {
+			set("a")
+			set("b")
+			set("c")
+			for {
+				if _, ok := next(); !ok {
+					break
+				}
+			}
+		}
+
+		st := d.Stats()
+		Expect(st.Writes).To(Equal(uint64(9)))
+		Expect(st.Reads).To(Equal(uint64(9)))
+		Expect(st.Dropped).To(BeZero())
+		Expect(st.Lag).To(BeZero())
+	})
+
+	It("reports the number of unread values as the lag", func() {
+		set("a")
+		set("b")
+		next()
+
+		Expect(d.Stats().Lag).To(Equal(uint64(1)))
+	})
+
+	It("accounts for every write under concurrent writers", func() {
+		d = diodes.NewUnbounded(16, 100, byteSize, nil)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					set("x")
+				}
+			}()
+		}
+		wg.Wait()
+
+		var reads uint64
+		for {
+			if _, ok := next(); !ok {
+				break
+			}
+			reads++
+		}
+		Expect(reads).To(Equal(uint64(100)))
+		Expect(reads + d.Dropped()).To(Equal(uint64(4000)))
+	})
+
+	Context("with a spill threshold", func() {
+		It("counts values set past the threshold as spilled", func() {
+			d = diodes.NewUnbounded(2, 10, byteSize, spy, diodes.WithSpillThreshold(2, 0))
+			for _, s := range []string{"a", "b", "c", "d"} {
+				set(s)
+			}
+			Expect(d.Spilled()).To(Equal(uint64(2)))
+
+			next()
+			next()
+			set("e")
+			Expect(d.Spilled()).To(Equal(uint64(3)))
+
+			next()
+			next()
+			next()
+			set("f")
+			Expect(d.Spilled()).To(Equal(uint64(3)))
+		})
+
+		It("drops spilled values that waited longer than the max age", func() {
+			d = diodes.NewUnbounded(2, 10, byteSize, spy, diodes.WithSpillThreshold(1, 10*time.Millisecond))
+			set("a")
+			set("b")
+			set("c")
+			time.Sleep(20 * time.Millisecond)
+			set("d")
+
+			s, _ := next()
+			Expect(s).To(Equal("a"))
+
+			s, _ = next()
+			Expect(s).To(Equal("d"))
+			Expect(spy.AlertInput.Missed).To(Receive(Equal(2)))
+			Expect(d.Expired()).To(Equal(uint64(2)))
+			Expect(d.Dropped()).To(Equal(uint64(2)))
+		})
+
+		It("returns spilled values within the max age", func() {
+			d = diodes.NewUnbounded(2, 10, byteSize, spy, diodes.WithSpillThreshold(1, time.Minute))
+			set("a")
+			set("b")
+
+			next()
+			s, ok := next()
+			Expect(ok).To(BeTrue())
+			Expect(s).To(Equal("b"))
+			Expect(d.Expired()).To(BeZero())
+		})
+	})
+
+	It("reports the backlog crossing the watermarks", func() {
+		var states []diodes.WatermarkState
+		d = diodes.NewUnbounded(2, 10, byteSize, spy, diodes.WithUnboundedWatermarks(3, 1, func(s diodes.WatermarkState) {
+			states = append(states, s)
+			d.Len()
+		}))
+
+		for _, s := range []string{"a", "b", "c", "d"} {
+			set(s)
+		}
+		Expect(states).To(Equal([]diodes.WatermarkState{diodes.WatermarkHigh}))
+
+		next()
+		next()
+		Expect(states).To(HaveLen(1))
+		next()
+		Expect(states).To(Equal([]diodes.WatermarkState{diodes.WatermarkHigh, diodes.WatermarkLow}))
+	})
+})
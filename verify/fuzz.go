@@ -0,0 +1,54 @@
+package verify
+
+import (
+	"testing"
+)
+
+// maxFuzzSize bounds the size of the diodes the fuzz targets create.
+const maxFuzzSize = 64
+
+// Fuzz runs CheckSequential against diodes of random sizes with random
+// operations, where every byte of the input is an operation. It is meant to
+// be called from a fuzz test:
+//
+//	func FuzzOneToOne(f *testing.F) {
+//		verify.Fuzz(f, func(size int, a diodes.Alerter) diodes.Diode {
+//			return diodes.NewOneToOne(size, a)
+//		})
+//	}
+func Fuzz(f *testing.F, newDiode NewFunc) {
+	f.Add(uint8(4), []byte{1, 1, 0, 1, 0, 0, 0})
+	f.Add(uint8(2), []byte{1, 1, 1, 1, 1, 0, 0, 0})
+	f.Add(uint8(1), []byte{1, 0, 1, 1, 0, 0})
+	f.Add(uint8(3), []byte{1, 1, 1, 1, 1, 1, 1, 1, 0, 1, 0, 0, 0})
+
+	f.Fuzz(func(t *testing.T, size uint8, input []byte) {
+		ops := make([]bool, len(input))
+		for i, b := range input {
+			// Reads only find data after writes, so bias towards writes to
+			// get lapped readers more often.
+			ops[i] = b%3 != 0
+		}
+
+		if err := CheckSequential(newDiode, 1+int(size)%maxFuzzSize, ops); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// FuzzConcurrent runs CheckConcurrent with random sizes and numbers of
+// writers, up to maxWriters, and values. The interleaving of the writers and
+// the reader is up to the scheduler, so failures are not always reproducible
+// from the input.
+func FuzzConcurrent(f *testing.F, newDiode NewFunc, maxWriters int) {
+	f.Add(uint8(4), uint8(1), uint16(100))
+	f.Add(uint8(16), uint8(8), uint16(500))
+	f.Add(uint8(1), uint8(2), uint16(50))
+
+	f.Fuzz(func(t *testing.T, size, writers uint8, perWriter uint16) {
+		err := CheckConcurrent(newDiode, 1+int(size)%maxFuzzSize, 1+int(writers)%max(maxWriters, 1), int(perWriter)%2000)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+package verify_test
+
+import (
+	"testing"
+
+	"code.cloudfoundry.org/go-diodes/verify"
+)
+
+func FuzzOneToOne(f *testing.F) {
+	verify.Fuzz(f, newOneToOne)
+}
+
+func FuzzManyToOne(f *testing.F) {
+	verify.Fuzz(f, newManyToOne)
+}
+
+func FuzzManyToOneConcurrent(f *testing.F) {
+	verify.FuzzConcurrent(f, newManyToOne, 8)
+}
@@ -0,0 +1,54 @@
+package verify
+
+// Model is the reference behavior of a ring buffer diode with a single
+// reader, which values it hands out and how many it drops. It tracks the
+// sequence numbers of values, i.e. the order in which they were set, instead
+// of the values themselves.
+//
+// A reader that was lapped skips ahead to the value in the slot it reads,
+// which is the newest value of that slot, so it drops everything before.
+type Model struct {
+	size     uint64
+	writeSeq uint64
+	readSeq  uint64
+	dropped  uint64
+}
+
+// NewModel returns the model of a diode with the given number of slots.
+func NewModel(size int) *Model {
+	return &Model{size: uint64(size)}
+}
+
+// Set records a write and returns its sequence number.
+func (m *Model) Set() uint64 {
+	seq := m.writeSeq
+	m.writeSeq++
+	return seq
+}
+
+// TryNext returns the sequence number of the value the next read returns and
+// how many values the read drops right before it. It returns false if the
+// read finds no data.
+func (m *Model) TryNext() (seq, dropped uint64, ok bool) {
+	if m.readSeq >= m.writeSeq {
+		return 0, 0, false
+	}
+
+	if m.writeSeq-m.readSeq > m.size {
+		// The slot of the read index holds the newest value that was set in
+		// it since.
+		newest := m.readSeq + m.size*((m.writeSeq-1-m.readSeq)/m.size)
+		dropped = newest - m.readSeq
+		m.dropped += dropped
+		m.readSeq = newest
+	}
+
+	seq = m.readSeq
+	m.readSeq++
+	return seq, dropped, true
+}
+
+// Dropped returns the total number of values the model dropped.
+func (m *Model) Dropped() uint64 {
+	return m.dropped
+}
@@ -0,0 +1,150 @@
+// Package verify checks that diodes behave like a diode: every value is read
+// at most once, values of a writer are read in the order they were set, a
+// lapped reader drops the oldest values and reports exactly what it
+// dropped. It compares sequential runs against a Model of the drop-oldest
+// behavior, checks concurrent runs for the properties that hold under any
+// interleaving and provides fuzz targets for both, so that downstream diode
+// configurations can be verified in CI.
+package verify
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"code.cloudfoundry.org/go-diodes"
+)
+
+// NewFunc creates the diode under test with the given number of slots. The
+// alerter must be installed as the alerter of the diode.
+type NewFunc func(size int, alerter diodes.Alerter) diodes.Diode
+
+// alertCounter sums the alerts of a diode.
+type alertCounter struct {
+	missed atomic.Uint64
+}
+
+func (a *alertCounter) Alert(missed int) {
+	a.missed.Add(uint64(missed))
+}
+
+// value is set on the diode under test. It identifies the writer that set
+// it and its sequence number within that writer.
+type value struct {
+	writer int
+	seq    uint64
+}
+
+// CheckSequential runs the operations on a new diode and on the Model, one
+// at a time, and returns an error describing the first difference. Every
+// operation that is true sets a value and every operation that is false
+// tries to read one.
+func CheckSequential(newDiode NewFunc, size int, ops []bool) error {
+	if size < 1 {
+		return fmt.Errorf("verify: size must be positive, got %d", size)
+	}
+
+	alerts := new(alertCounter)
+	d := newDiode(size, alerts)
+	m := NewModel(size)
+
+	for i, set := range ops {
+		if set {
+			d.Set(diodes.GenericDataType(&value{seq: m.Set()}))
+			continue
+		}
+
+		before := alerts.missed.Load()
+		data, ok := d.TryNext()
+		seq, dropped, want := m.TryNext()
+		switch {
+		case ok != want:
+			return fmt.Errorf("verify: op %d: read returned %t, want %t", i, ok, want)
+		case !ok:
+			continue
+		case (*value)(data).seq != seq:
+			return fmt.Errorf("verify: op %d: read seq %d, want %d", i, (*value)(data).seq, seq)
+		case alerts.missed.Load()-before != dropped:
+			return fmt.Errorf("verify: op %d: alerted %d drops, want %d", i, alerts.missed.Load()-before, dropped)
+		}
+	}
+
+	return nil
+}
+
+// CheckConcurrent sets perWriter values from each of the given number of
+// writers while a single reader reads concurrently, then drains the diode.
+// It returns an error if a value was read twice or out of the order its
+// writer set it in, or if the reads and the alerted drops do not add up to
+// the writes.
+func CheckConcurrent(newDiode NewFunc, size, writers, perWriter int) error {
+	if size < 1 || writers < 1 || perWriter < 0 {
+		return fmt.Errorf("verify: invalid run of %d writers of %d values on %d slots", writers, perWriter, size)
+	}
+
+	alerts := new(alertCounter)
+	d := newDiode(size, alerts)
+
+	var (
+		wg   sync.WaitGroup
+		done atomic.Bool
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for seq := 0; seq < perWriter; seq++ {
+				d.Set(diodes.GenericDataType(&value{writer: w, seq: uint64(seq)}))
+				if seq%64 == 0 {
+					runtime.Gosched()
+				}
+			}
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		done.Store(true)
+	}()
+
+	next := make([]uint64, writers)
+	var reads uint64
+	read := func() (bool, error) {
+		data, ok := d.TryNext()
+		if !ok {
+			return false, nil
+		}
+
+		v := (*value)(data)
+		if v.writer < 0 || v.writer >= writers {
+			return false, fmt.Errorf("verify: read a value of unknown writer %d", v.writer)
+		}
+		if v.seq < next[v.writer] {
+			return false, fmt.Errorf("verify: writer %d: read seq %d after seq %d", v.writer, v.seq, next[v.writer]-1)
+		}
+		next[v.writer] = v.seq + 1
+		reads++
+		return true, nil
+	}
+
+	for !done.Load() {
+		if _, err := read(); err != nil {
+			return err
+		}
+	}
+	for {
+		ok, err := read()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+	}
+
+	writes := uint64(writers * perWriter)
+	if dropped := alerts.missed.Load(); reads+dropped != writes {
+		return fmt.Errorf("verify: %d reads and %d drops do not add up to %d writes", reads, dropped, writes)
+	}
+	return nil
+}
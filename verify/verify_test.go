@@ -0,0 +1,144 @@
+package verify_test
+
+import (
+	"io"
+	"log"
+	"math/rand"
+
+	"code.cloudfoundry.org/go-diodes"
+	"code.cloudfoundry.org/go-diodes/diodestest"
+	"code.cloudfoundry.org/go-diodes/verify"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func newOneToOne(size int, a diodes.Alerter) diodes.Diode {
+	return diodes.NewOneToOne(size, a)
+}
+
+func newManyToOne(size int, a diodes.Alerter) diodes.Diode {
+	return diodes.NewManyToOne(size, a)
+}
+
+func newManyToMany(size int, a diodes.Alerter) diodes.Diode {
+	return diodes.NewManyToMany(size, a)
+}
+
+// newUnbounded never drops, unlike the model.
+func newUnbounded(_ int, a diodes.Alerter) diodes.Diode {
+	return diodestest.NewDiode(a)
+}
+
+// duplicating hands out every value twice.
+type duplicating struct {
+	diodes.Diode
+	last diodes.GenericDataType
+}
+
+func (d *duplicating) TryNext() (diodes.GenericDataType, bool) {
+	if d.last != nil {
+		data := d.last
+		d.last = nil
+		return data, true
+	}
+
+	data, ok := d.Diode.TryNext()
+	d.last = data
+	return data, ok
+}
+
+var _ = Describe("Model", func() {
+	It("reads values in order", func() {
+		m := verify.NewModel(4)
+		m.Set()
+		m.Set()
+
+		seq, dropped, ok := m.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(seq).To(BeZero())
+		Expect(dropped).To(BeZero())
+
+		_, _, ok = m.TryNext()
+		Expect(ok).To(BeTrue())
+		_, _, ok = m.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("skips a lapped reader to the newest value of its slot", func() {
+		m := verify.NewModel(4)
+		for i := 0; i < 10; i++ {
+			m.Set()
+		}
+
+		seq, dropped, ok := m.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(seq).To(Equal(uint64(8)))
+		Expect(dropped).To(Equal(uint64(8)))
+
+		seq, _, _ = m.TryNext()
+		Expect(seq).To(Equal(uint64(9)))
+		_, _, ok = m.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(m.Dropped()).To(Equal(uint64(8)))
+	})
+})
+
+var _ = Describe("CheckSequential", func() {
+	randomOps := func(rng *rand.Rand, n int) []bool {
+		ops := make([]bool, n)
+		for i := range ops {
+			ops[i] = rng.Intn(3) != 0
+		}
+		return ops
+	}
+
+	DescribeTable("finds no difference to the model",
+		func(newDiode verify.NewFunc) {
+			rng := rand.New(rand.NewSource(GinkgoRandomSeed()))
+			for _, size := range []int{1, 2, 3, 8} {
+				for i := 0; i < 50; i++ {
+					Expect(verify.CheckSequential(newDiode, size, randomOps(rng, 100))).To(Succeed())
+				}
+			}
+		},
+		Entry("OneToOne", verify.NewFunc(newOneToOne)),
+		Entry("ManyToOne", verify.NewFunc(newManyToOne)),
+		Entry("ManyToMany", verify.NewFunc(newManyToMany)),
+	)
+
+	It("finds a diode that does not drop the oldest values", func() {
+		err := verify.CheckSequential(newUnbounded, 2, []bool{true, true, true, false})
+		Expect(err).To(MatchError(ContainSubstring("read seq 0, want 2")))
+	})
+
+	It("rejects an invalid size", func() {
+		Expect(verify.CheckSequential(newOneToOne, 0, nil)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("CheckConcurrent", func() {
+	BeforeEach(func() {
+		log.SetOutput(io.Discard)
+		DeferCleanup(log.SetOutput, GinkgoWriter)
+	})
+
+	It("finds no violations", func() {
+		Expect(verify.CheckConcurrent(newOneToOne, 16, 1, 5000)).To(Succeed())
+		Expect(verify.CheckConcurrent(newManyToOne, 16, 8, 1000)).To(Succeed())
+		Expect(verify.CheckConcurrent(newManyToMany, 16, 8, 1000)).To(Succeed())
+	})
+
+	It("finds a diode that reads values twice", func() {
+		newDuplicating := func(size int, a diodes.Alerter) diodes.Diode {
+			return &duplicating{Diode: diodes.NewOneToOne(size, a)}
+		}
+
+		err := verify.CheckConcurrent(newDuplicating, 16, 1, 100)
+		Expect(err).To(MatchError(ContainSubstring("read seq")))
+	})
+
+	It("rejects an invalid run", func() {
+		Expect(verify.CheckConcurrent(newOneToOne, 16, 0, 10)).NotTo(Succeed())
+	})
+})
@@ -2,16 +2,110 @@ package diodes
 
 import (
 	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"time"
 )
 
 // Waiter will use a channel signal to alert the reader to when data is
 // available.
 type Waiter struct {
 	Diode
-	c   chan struct{}
-	ctx context.Context
+	c      chan struct{}
+	ctx    context.Context
+	closed atomic.Bool
+	end    drainEnd
+
+	wakeLatency *Histogram
+	signaledAt  atomic.Int64
+	budget      readBudget
+	filter      readFilter
+	mode        SignalMode
+	spins       int
+	onCancel    CancelPolicy
+	clock       Clock
+	timer       Timer
+
+	// keepingUp and spinCost are only used by the reader with
+	// SignalAdaptive. spinCost is how long the last spins took.
+	keepingUp bool
+	spinCost  int64
+	selector  atomic.Pointer[chan struct{}]
+	stop      *stopper
+
+	// coalesce, signalEvery and signalDelay configure coalesced signals.
+	// waiting is set while the reader is about to block and pending counts
+	// the values set since.
+	coalesce    bool
+	signalEvery int64
+	signalDelay time.Duration
+	delayTimer  Timer
+	waiting     atomic.Bool
+	pending     atomic.Int64
+
+	// empty is set once the reader found no data, so that the next Set
+	// invokes onFirstPending.
+	onFirstPending func()
+	empty          atomic.Bool
 }
 
+var _ Diode = (*Waiter)(nil)
+
+// SignalMode selects how the reader of a Waiter waits for data.
+type SignalMode int
+
+const (
+	// SignalCond blocks the reader until Set signals that data is available.
+	// It is the default.
+	SignalCond SignalMode = iota
+
+	// SignalSpin never blocks the reader. It retries in a loop, yielding the
+	// processor in between, which gives the lowest latency at the cost of a
+	// busy core. Set does not need to signal the reader.
+	SignalSpin
+
+	// SignalHybrid spins like SignalSpin for a number of retries (see
+	// WithSignalSpins) and then blocks like SignalCond.
+	SignalHybrid
+
+	// SignalAdaptive spins like SignalHybrid while the reader keeps up,
+	// i.e. while data is set within about as long as the spins take. Once
+	// the reader blocked for longer, it blocks right away until data is
+	// set within that time again, so an idle stream does not keep a core
+	// busy.
+	SignalAdaptive
+)
+
+// CancelPolicy selects what the reader of a Waiter returns once its context
+// is done while data is left in the diode.
+type CancelPolicy int
+
+const (
+	// ReturnOnCancel returns the data the reader finds in the diode, but
+	// returns nil, or the context's error, as soon as the reader notices
+	// that the context is done while it waits. Whether data that was set
+	// right before the context was cancelled is returned is therefore up to
+	// timing. It is the default.
+	ReturnOnCancel CancelPolicy = iota
+
+	// DrainOnCancel keeps returning the data left in the diode once the
+	// context is done and only returns nil, or the context's error, once
+	// the diode is empty. The diode is read once more after the context is
+	// done, so everything that was set before the context was cancelled is
+	// returned.
+	DrainOnCancel
+
+	// ImmediateOnCancel returns nil, or the context's error, right away
+	// once the context is done, no matter whether data is left in the
+	// diode. TryNext keeps reading the diode.
+	ImmediateOnCancel
+)
+
+// defaultSignalSpins is the number of retries of SignalHybrid before the
+// reader blocks.
+const defaultSignalSpins = 100
+
 // WaiterConfigOption can be used to setup the waiter.
 type WaiterConfigOption func(*Waiter)
 
@@ -24,12 +118,120 @@ func WithWaiterContext(ctx context.Context) WaiterConfigOption {
 	})
 }
 
+// WithCancelPolicy sets what Next, NextCtx and the other blocking reads
+// return once the context of WithWaiterContext or the one given to the read
+// is done while data is left in the diode. The default is ReturnOnCancel.
+// Stop interrupts the reads right away either way.
+func WithCancelPolicy(policy CancelPolicy) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.onCancel = policy
+	})
+}
+
+// WithWakeLatencyHistogram records how long it takes a blocked Next to
+// return after Set signaled that data is available in the given histogram.
+// Enabling it makes every Set read the clock.
+func WithWakeLatencyHistogram(h *Histogram) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.wakeLatency = h
+	})
+}
+
+// WithWaiterReadBudget limits how many values Next returns in a row while
+// data is backlogged before it yields the processor to other go-routines,
+// so that catching up on a backlog does not monopolize it. The default is
+// no limit.
+func WithWaiterReadBudget(n int) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.budget.limit = n
+	})
+}
+
+// WithSignalMode sets how the reader waits for data. The default is
+// SignalCond.
+func WithSignalMode(mode SignalMode) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.mode = mode
+	})
+}
+
+// WithSignalSpins sets how many times the reader retries before it blocks
+// with SignalHybrid and SignalAdaptive. The default is 100.
+func WithSignalSpins(n int) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.spins = n
+	})
+}
+
+// WithSignalCoalescing makes Set only signal the reader when it is blocked
+// waiting for data, instead of on every Set, so that a reader that keeps up
+// with a high rate stream is not woken up over and over. With n greater than
+// 1 a blocked reader is only signaled once n values were set since it
+// started waiting, and again after at most maxDelay when fewer values are
+// set, which trades latency for fewer wake ups. Without a positive maxDelay
+// n is ignored. The default is to signal on every Set.
+func WithSignalCoalescing(n int, maxDelay time.Duration) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.coalesce = true
+		c.signalEvery = 1
+		if maxDelay > 0 {
+			c.signalEvery = int64(n)
+			c.signalDelay = maxDelay
+		}
+	})
+}
+
+// WithWaiterFilter drops every value for which keep returns false before
+// it is returned by the read methods, so that consumers do not need a layer
+// of their own to discard most of the data. keep is invoked on the reader's
+// go-routine. Filtered values are counted by Filtered, apart from the values
+// the diode dropped.
+func WithWaiterFilter(keep func(GenericDataType) bool) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.filter.keep = keep
+	})
+}
+
+// WithWaiterTransform replaces every value with the result of transform
+// before it is returned by the read methods. transform is invoked on the
+// reader's go-routine, after the filter of WithWaiterFilter.
+func WithWaiterTransform(transform func(GenericDataType) GenericDataType) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.filter.transform = transform
+	})
+}
+
+// WithOnFirstPending invokes f on the writer's go-routine when Set adds a
+// value to a diode the reader found empty, i.e. once per batch of values
+// the reader did not read yet. It lets a consumer that reads in batches,
+// e.g. with TryNext, start a flush timer only when there is something to
+// flush instead of ticking all the time. f should return quickly.
+func WithOnFirstPending(f func()) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.onFirstPending = f
+	})
+}
+
+// WithWaiterClock sets the clock that the timeouts of NextWithTimeout and
+// the maximum delay of coalesced signals are measured with, e.g. a fake
+// clock in tests. The default is the system clock.
+func WithWaiterClock(clock Clock) WaiterConfigOption {
+	return WaiterConfigOption(func(c *Waiter) {
+		c.clock = clock
+	})
+}
+
 // NewWaiter returns a new Waiter that wraps the given diode.
 func NewWaiter(d Diode, opts ...WaiterConfigOption) *Waiter {
 	w := new(Waiter)
 	w.Diode = d
 	w.c = make(chan struct{}, 1)
 	w.ctx = context.Background()
+	w.spins = defaultSignalSpins
+	w.clock = systemClock{}
+	w.keepingUp = true
+	w.stop = newStopper()
+	w.empty.Store(true)
 
 	for _, opt := range opts {
 		opt(w)
@@ -42,30 +244,482 @@ func NewWaiter(d Diode, opts ...WaiterConfigOption) *Waiter {
 // to wake up any readers.
 func (w *Waiter) Set(data GenericDataType) {
 	w.Diode.Set(data)
-	w.broadcast()
+	w.announce()
+}
+
+// announce tells the reader that data was set.
+func (w *Waiter) announce() {
+	if w.onFirstPending != nil && w.empty.CompareAndSwap(true, false) {
+		w.onFirstPending()
+	}
+	if w.wakeLatency != nil {
+		w.signaledAt.CompareAndSwap(0, nanotime())
+	}
+	w.broadcast(false)
+}
+
+// SetCtx invokes the wrapped diode's SetCtx with the given data, so that it
+// waits for room until the context is done, and wakes up the reader like
+// Set. See OneToOne.SetCtx. Diodes without SetCtx, such as Latest, never
+// wait, so the data is set with Set.
+func (w *Waiter) SetCtx(ctx context.Context, data GenericDataType) error {
+	d, ok := w.Diode.(interface {
+		SetCtx(ctx context.Context, data GenericDataType) error
+	})
+	if !ok {
+		w.Set(data)
+		return nil
+	}
+
+	err := d.SetCtx(ctx, data)
+	w.announce()
+	return err
 }
 
-// broadcast sends to the channel if it can.
-func (w *Waiter) broadcast() {
+// broadcast sends to the channel if it can, and to the channel of the
+// Selector the waiter belongs to. A spinning reader does not need to be
+// woken up, and with coalesced signals neither does a reader that is not
+// waiting, unless force is set.
+func (w *Waiter) broadcast(force bool) {
+	if c := w.selector.Load(); c != nil {
+		select {
+		case *c <- struct{}{}:
+		default:
+		}
+	}
+
+	if w.mode == SignalSpin {
+		return
+	}
+
+	if w.coalesce && !force {
+		if !w.waiting.Load() {
+			return
+		}
+		if w.signalEvery > 1 && w.pending.Add(1) < w.signalEvery {
+			return
+		}
+		if !w.waiting.CompareAndSwap(true, false) {
+			return
+		}
+	}
+
 	select {
 	case w.c <- struct{}{}:
 	default:
 	}
 }
 
+// Close marks the end of the stream by setting a sentinel value on the diode
+// and waking up the reader. Once the reader has read everything that was set
+// before Close, Next returns nil and TryNext returns (nil, false). No data
+// should be set after Close.
+func (w *Waiter) Close() {
+	w.Diode.Set(endOfStream)
+	w.broadcast(true)
+}
+
+// Drain reads everything that was set before it was called, waiting for
+// values that writers are still storing, and then ends the stream like
+// Close without setting anything on the diode, so it is safe for diodes
+// with a single writer. Diodes that can not tell how far to read, e.g.
+// wrappers, are read until they are empty. It returns once the end of the
+// stream was reached or, with the values read so far and the context's error
+// (see NextCtx), once the context is done. It must be called by the reader.
+func (w *Waiter) Drain(ctx context.Context) ([]GenericDataType, error) {
+	w.end.start(w.Diode)
+
+	var data []GenericDataType
+	for {
+		v, err := w.next(ctx, nil)
+		if errors.Is(err, ErrClosed) {
+			return data, nil
+		}
+		if err != nil {
+			return data, err
+		}
+		data = append(data, v)
+	}
+}
+
+// Closed reports whether the reader has reached the end of the stream.
+func (w *Waiter) Closed() bool {
+	return w.closed.Load()
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return
+// (nil, false). Values dropped by the filter are skipped.
+func (w *Waiter) TryNext() (GenericDataType, bool) {
+	if w.closed.Load() {
+		return nil, false
+	}
+
+	for {
+		if w.end.reached() {
+			w.closed.Store(true)
+			return nil, false
+		}
+
+		data, ok := w.Diode.TryNext()
+		if !ok {
+			if w.end.emptied() {
+				w.closed.Store(true)
+				return nil, false
+			}
+			if w.onFirstPending == nil || w.empty.Load() {
+				return nil, false
+			}
+
+			// A value set before the diode was marked empty did not invoke
+			// onFirstPending, so look once more. If there is one, the
+			// reader takes it and the mark back.
+			w.empty.Store(true)
+			if data, ok = w.Diode.TryNext(); !ok {
+				return nil, false
+			}
+			w.empty.CompareAndSwap(true, false)
+		}
+		if data == endOfStream {
+			w.closed.Store(true)
+			return nil, false
+		}
+
+		if data, ok = w.filter.apply(data); ok {
+			return data, true
+		}
+	}
+}
+
+// Filtered returns the number of values the filter of WithWaiterFilter
+// dropped. It is safe to call from any go-routine.
+func (w *Waiter) Filtered() uint64 {
+	return w.filter.filtered.Load()
+}
+
 // Next returns the next data point on the wrapped diode. If there is no new
 // data, it will wait for Set to be called or the context to be done. If the
-// context is done, then nil will be returned.
+// context is done or the end of the stream was reached, then nil will be
+// returned. Whether data left in the diode is returned once the context is
+// done depends on the cancel policy (see WithCancelPolicy).
 func (w *Waiter) Next() GenericDataType {
+	data, _ := w.next(w.ctx, nil)
+	return data
+}
+
+// NextCtx is like Next but also returns once the given context is done. It
+// returns ErrClosed once the end of the stream was reached, ErrStopped once
+// Stop was called and the error of whichever context is done otherwise,
+// matching ErrTimeout if its deadline passed. Unlike Next, a nil value with
+// a nil error is legitimate data.
+func (w *Waiter) NextCtx(ctx context.Context) (GenericDataType, error) {
+	return w.next(ctx, nil)
+}
+
+// NextWithTimeout is like Next but also returns (nil, false) once the
+// timeout passed, e.g. to periodically flush a partial batch. It reuses a
+// single timer instead of allocating a context for every call.
+func (w *Waiter) NextWithTimeout(timeout time.Duration) (GenericDataType, bool) {
+	if w.timer == nil {
+		w.timer = w.clock.NewTimer(timeout)
+	} else {
+		w.timer.Reset(timeout)
+	}
+
+	data, err := w.next(w.ctx, w.timer.C())
+
+	// Drain a tick that fired but was not received, so it does not end the
+	// next call right away.
+	if !w.timer.Stop() && err != ErrTimeout {
+		select {
+		case <-w.timer.C():
+		default:
+		}
+	}
+	return data, err == nil
+}
+
+// next returns the next data point, waiting until the context is done or
+// the timeout channel, which may be nil, fires.
+func (w *Waiter) next(ctx context.Context, timeout <-chan time.Time) (GenericDataType, error) {
+	w.stop.enter()
+	defer w.stop.exit()
+
+	var (
+		waited bool
+		spins  int
+		spunAt int64
+	)
 	for {
-		data, ok := w.Diode.TryNext()
+		if w.stop.stopped() {
+			return nil, ErrStopped
+		}
+		if w.onCancel == ImmediateOnCancel {
+			if err := w.contextDone(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		data, ok := w.TryNext()
 		if ok {
-			return data
+			w.woken(waited)
+			return data, nil
 		}
-		select {
-		case <-w.ctx.Done():
-			return nil
-		case <-w.c:
+		if w.closed.Load() {
+			return nil, ErrClosed
+		}
+
+		if w.spin(spins) {
+			if spins == 0 && w.mode == SignalAdaptive {
+				spunAt = nanotime()
+			}
+			if data, done, err := w.spinOnce(ctx, timeout); done {
+				return data, err
+			}
+			spins++
+			waited = true
+			continue
+		}
+
+		blockedAt := w.startBlock(spins, spunAt)
+		if w.coalesce && !w.waiting.Load() {
+			// Values set before the reader announced that it is waiting did
+			// not signal it, so it has to look once more before it blocks.
+			w.pending.Store(0)
+			w.waiting.Store(true)
+			continue
+		}
+
+		if data, done, err := w.block(ctx, timeout, blockedAt); done {
+			return data, err
 		}
+		waited = true
+	}
+}
+
+// woken accounts for a value the reader read, after it waited for it if
+// waited is set.
+func (w *Waiter) woken(waited bool) {
+	if w.coalesce && w.waiting.Load() {
+		w.waiting.Store(false)
+	}
+	w.observeWake(waited)
+	w.budget.spend()
+}
+
+// spinOnce yields the processor once instead of blocking. Unless a context
+// is done, the timeout fired or the waiter was stopped, it returns false.
+// Otherwise it returns true along with what the read returns.
+func (w *Waiter) spinOnce(ctx context.Context, timeout <-chan time.Time) (GenericDataType, bool, error) {
+	select {
+	case <-ctx.Done():
+		data, err := w.cancelled(ctx)
+		return data, true, err
+	case <-w.ctx.Done():
+		data, err := w.cancelled(w.ctx)
+		return data, true, err
+	case <-timeout:
+		return nil, true, ErrTimeout
+	case <-w.stop.c:
+		return nil, true, ErrStopped
+	default:
+	}
+
+	w.budget.reset()
+	runtime.Gosched()
+	return nil, false, nil
+}
+
+// startBlock returns when the reader started to block and, with
+// SignalAdaptive, records how long it spun before.
+func (w *Waiter) startBlock(spins int, spunAt int64) int64 {
+	if w.mode != SignalAdaptive {
+		return 0
+	}
+
+	blockedAt := nanotime()
+	if spins > 0 {
+		w.spinCost = blockedAt - spunAt
+	}
+	return blockedAt
+}
+
+// block waits until the reader is signaled or the delay of coalesced
+// signals passed, in which case it returns false. If a context is done, the
+// timeout fired or the waiter was stopped, it returns true along with what
+// the read returns.
+func (w *Waiter) block(ctx context.Context, timeout <-chan time.Time, blockedAt int64) (GenericDataType, bool, error) {
+	delay := w.startDelay()
+	select {
+	case <-ctx.Done():
+		w.stopDelay()
+		data, err := w.cancelled(ctx)
+		return data, true, err
+	case <-w.ctx.Done():
+		w.stopDelay()
+		data, err := w.cancelled(w.ctx)
+		return data, true, err
+	case <-timeout:
+		w.stopDelay()
+		return nil, true, ErrTimeout
+	case <-w.stop.c:
+		w.stopDelay()
+		return nil, true, ErrStopped
+	case <-w.c:
+		w.stopDelay()
+	case <-delay:
+		w.waiting.Store(false)
+	}
+
+	w.budget.reset()
+	w.adapt(blockedAt)
+	return nil, false, nil
+}
+
+// contextDone returns the error of whichever context is done, if any.
+func (w *Waiter) contextDone(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return contextErr(ctx)
+	}
+	if w.ctx.Err() != nil {
+		return contextErr(w.ctx)
+	}
+	return nil
+}
+
+// cancelled returns what a read returns once ctx is done. With
+// DrainOnCancel, the diode is read once more, since data may have been set
+// after the reader last looked, but before the context was cancelled.
+func (w *Waiter) cancelled(ctx context.Context) (GenericDataType, error) {
+	if w.onCancel == DrainOnCancel {
+		if data, ok := w.TryNext(); ok {
+			w.budget.spend()
+			return data, nil
+		}
+		if w.closed.Load() {
+			return nil, ErrClosed
+		}
+	}
+	return nil, contextErr(ctx)
+}
+
+// Stop makes the blocking reads return right away, interrupting any wait
+// for data in progress. Next then returns nil and NextCtx ErrStopped, no
+// matter whether data is left in the diode. TryNext keeps reading the diode.
+// It is safe to call from any go-routine, more than once.
+func (w *Waiter) Stop() {
+	w.stop.stop()
+}
+
+// Shutdown stops the waiter like Stop and waits until the reads in flight
+// returned, or until the context is done, in which case it returns the
+// context's error.
+func (w *Waiter) Shutdown(ctx context.Context) error {
+	return w.stop.shutdown(ctx)
+}
+
+// startDelay returns a channel that fires once the reader waited for the
+// maximum delay of coalesced signals, or nil if signals are not delayed.
+func (w *Waiter) startDelay() <-chan time.Time {
+	if w.signalEvery <= 1 {
+		return nil
+	}
+
+	if w.delayTimer == nil {
+		w.delayTimer = w.clock.NewTimer(w.signalDelay)
+	} else {
+		w.delayTimer.Reset(w.signalDelay)
+	}
+	return w.delayTimer.C()
+}
+
+// stopDelay stops the delay timer and drains a tick that fired but was not
+// received, so it does not end the next wait right away.
+func (w *Waiter) stopDelay() {
+	if w.delayTimer != nil {
+		stopTimer(w.delayTimer)
+	}
+}
+
+// NextN waits like Next until data is available and then reads up to
+// len(dst) values into dst without waiting for more. It returns the number
+// of values written to dst, which is only 0 if the context is done or the
+// end of the stream was reached, so nil data is counted as well. It does
+// not allocate, so dst can be reused across calls.
+func (w *Waiter) NextN(dst []GenericDataType) int {
+	if len(dst) == 0 {
+		return 0
+	}
+
+	data, err := w.next(w.ctx, nil)
+	if err != nil {
+		return 0
+	}
+	dst[0] = data
+
+	n := 1
+	for n < len(dst) {
+		data, ok := w.TryNext()
+		if !ok {
+			break
+		}
+		w.budget.spend()
+		dst[n] = data
+		n++
+	}
+	return n
+}
+
+// NextAvailable is like NextN but returns the values in a new slice of up to
+// limit values, which is nil if the context is done or the end of the stream
+// was reached. Use NextN to reuse a slice across calls instead.
+func (w *Waiter) NextAvailable(limit int) []GenericDataType {
+	if limit <= 0 {
+		return nil
+	}
+
+	dst := make([]GenericDataType, limit)
+	n := w.NextN(dst)
+	if n == 0 {
+		return nil
+	}
+	return dst[:n]
+}
+
+// spin reports whether the reader should retry instead of blocking after the
+// given number of retries.
+func (w *Waiter) spin(spins int) bool {
+	switch w.mode {
+	case SignalSpin:
+		return true
+	case SignalHybrid:
+		return spins < w.spins
+	case SignalAdaptive:
+		return w.keepingUp && spins < w.spins
+	default:
+		return false
+	}
+}
+
+// adapt decides whether the reader spins the next time it waits with
+// SignalAdaptive, given when it blocked: it keeps up if it was woken up
+// within about as long as its spins take.
+func (w *Waiter) adapt(blockedAt int64) {
+	if w.mode == SignalAdaptive {
+		w.keepingUp = nanotime()-blockedAt <= w.spinCost
+	}
+}
+
+// observeWake records the wake latency if Next had to wait for the data it
+// returns. The signal time is reset either way, so that the next wait is
+// measured from the Set that ends it.
+func (w *Waiter) observeWake(waited bool) {
+	if w.wakeLatency == nil {
+		return
+	}
+
+	at := w.signaledAt.Swap(0)
+	if waited && at != 0 {
+		w.wakeLatency.Observe(time.Duration(nanotime() - at))
 	}
 }
@@ -0,0 +1,124 @@
+package diodes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// WaiterPool is like a Waiter that lets several go-routines block in Next
+// at the same time, e.g. a pool of workers sharing one diode. Every value is
+// handed to exactly one of them. Reads of the wrapped diode are serialized,
+// so it only needs to be safe for the writers, not for several readers.
+type WaiterPool struct {
+	d Diode
+
+	mu sync.Mutex
+
+	// c carries one signal per value set or read, up to the number of
+	// readers. A reader that reads a value passes a signal on to the next
+	// blocked reader, as there may be more to read.
+	c chan struct{}
+
+	// done is closed once a reader reached the end of the stream, to wake
+	// up every other reader.
+	done      chan struct{}
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+var _ Diode = (*WaiterPool)(nil)
+
+// NewWaiterPool returns a new WaiterPool that wraps the given diode and
+// wakes up to n blocked readers at once. Any number of go-routines may call
+// Next, but only n of them are woken up by a burst of values.
+func NewWaiterPool(d Diode, n int) *WaiterPool {
+	return &WaiterPool{
+		d:    d,
+		c:    make(chan struct{}, max(n, 1)),
+		done: make(chan struct{}),
+	}
+}
+
+// Set invokes the wrapped diode's Set with the given data and wakes up one
+// of the blocked readers.
+func (p *WaiterPool) Set(data GenericDataType) {
+	p.d.Set(data)
+	p.signal()
+}
+
+// Close marks the end of the stream by setting a sentinel value on the diode.
+// Once everything that was set before Close was read, Next returns nil on
+// every reader. No data should be set after Close.
+func (p *WaiterPool) Close() {
+	p.Set(endOfStream)
+}
+
+// Closed reports whether a reader reached the end of the stream.
+func (p *WaiterPool) Closed() bool {
+	return p.closed.Load()
+}
+
+func (p *WaiterPool) signal() {
+	select {
+	case p.c <- struct{}{}:
+	default:
+	}
+}
+
+// TryNext will attempt to read from the wrapped diode. If there is no data
+// available or the end of the stream was reached, it will return
+// (nil, false). It is safe to call from several go-routines.
+func (p *WaiterPool) TryNext() (GenericDataType, bool) {
+	if p.closed.Load() {
+		return nil, false
+	}
+
+	p.mu.Lock()
+	data, ok := p.d.TryNext()
+	p.mu.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+	if data == endOfStream {
+		p.closed.Store(true)
+		p.closeOnce.Do(func() { close(p.done) })
+		return nil, false
+	}
+
+	p.signal()
+	return data, true
+}
+
+// Next returns the next data point on the wrapped diode. If there is no new
+// data, it will wait for Set to be called. Once the end of the stream was
+// reached, nil will be returned. It is safe to call from several
+// go-routines.
+func (p *WaiterPool) Next() GenericDataType {
+	data, _ := p.NextCtx(context.Background())
+	return data
+}
+
+// NextCtx is like Next but also returns once the given context is done. It
+// returns ErrClosed once the end of the stream was reached and the error of
+// the context otherwise, matching ErrTimeout if its deadline passed.
+func (p *WaiterPool) NextCtx(ctx context.Context) (GenericDataType, error) {
+	for {
+		data, ok := p.TryNext()
+		if ok {
+			return data, nil
+		}
+		if p.closed.Load() {
+			return nil, ErrClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, contextErr(ctx)
+		case <-p.done:
+			return nil, ErrClosed
+		case <-p.c:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package diodes_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WaiterPool", func() {
+	var p *diodes.WaiterPool
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			p.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	BeforeEach(func() {
+		p = diodes.NewWaiterPool(diodes.NewManyToOne(1024, nil), 4)
+	})
+
+	It("returns the values in order to a single reader", func() {
+		set(0, 3)
+		Expect(*(*int)(p.Next())).To(Equal(0))
+		Expect(*(*int)(p.Next())).To(Equal(1))
+
+		data, ok := p.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(2))
+
+		_, ok = p.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("wakes up a blocked reader", func() {
+		got := make(chan int)
+		go func() {
+			got <- *(*int)(p.Next())
+		}()
+		Consistently(got, 20*time.Millisecond).ShouldNot(Receive())
+
+		set(0, 1)
+		Eventually(got).Should(Receive(Equal(0)))
+	})
+
+	It("hands every value to exactly one of several blocked readers", func() {
+		const (
+			readers = 4
+			writes  = 1000
+		)
+
+		var (
+			mu   sync.Mutex
+			seen = make(map[int]int)
+			wg   sync.WaitGroup
+		)
+		for i := 0; i < readers; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				for {
+					data := p.Next()
+					if data == nil {
+						return
+					}
+					mu.Lock()
+					seen[*(*int)(data)]++
+					mu.Unlock()
+				}
+			}()
+		}
+
+		set(0, writes)
+		p.Close()
+		wg.Wait()
+
+		Expect(seen).To(HaveLen(writes))
+		for v, n := range seen {
+			Expect(n).To(Equal(1), "value %d", v)
+		}
+		Expect(p.Closed()).To(BeTrue())
+	})
+
+	It("wakes up every reader once the stream is closed", func() {
+		done := make(chan error, 3)
+		for i := 0; i < 3; i++ {
+			go func() {
+				_, err := p.NextCtx(context.Background())
+				done <- err
+			}()
+		}
+		Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+		p.Close()
+		for i := 0; i < 3; i++ {
+			Eventually(done).Should(Receive(MatchError(diodes.ErrClosed)))
+		}
+	})
+
+	It("returns the error of the context", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := p.NextCtx(ctx)
+		Expect(err).To(MatchError(diodes.ErrTimeout))
+	})
+})
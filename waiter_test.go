@@ -2,6 +2,8 @@ package diodes_test
 
 import (
 	"context"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	"code.cloudfoundry.org/go-diodes"
@@ -83,3 +85,618 @@ var _ = Describe("Waiter", func() {
 		})
 	})
 })
+
+var _ = Describe("Waiter Close()", func() {
+	var w *diodes.Waiter
+
+	BeforeEach(func() {
+		w = diodes.NewWaiter(diodes.NewManyToOne(5, nil))
+	})
+
+	It("returns the data set before Close and then nil", func() {
+		data := []byte("some-data")
+		w.Set(diodes.GenericDataType(&data))
+		w.Close()
+
+		Expect(*(*[]byte)(w.Next())).To(Equal(data))
+		Expect(w.Closed()).To(BeFalse())
+		Expect(w.Next() == nil).To(BeTrue())
+		Expect(w.Closed()).To(BeTrue())
+
+		_, ok := w.TryNext()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("wakes up a blocked reader", func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.Next()
+		}()
+
+		w.Close()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("Waiter with a wake latency histogram", func() {
+	var (
+		h *diodes.Histogram
+		w *diodes.Waiter
+	)
+
+	BeforeEach(func() {
+		h = diodes.NewHistogram()
+		w = diodes.NewWaiter(diodes.NewManyToOne(5, nil), diodes.WithWakeLatencyHistogram(h))
+	})
+
+	It("records how long a blocked reader took to wake up", func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.Next()
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		data := []byte("some-data")
+		w.Set(diodes.GenericDataType(&data))
+
+		Eventually(done).Should(BeClosed())
+		Expect(h.Count()).To(Equal(uint64(1)))
+		Expect(h.Max()).To(BeNumerically("<", 10*time.Millisecond))
+	})
+
+	It("does not record reads that did not wait", func() {
+		data := []byte("some-data")
+		w.Set(diodes.GenericDataType(&data))
+		w.Next()
+
+		Expect(h.Count()).To(BeZero())
+	})
+})
+
+var _ = Describe("Waiter with a read budget", func() {
+	It("returns every value of a backlog", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(100, nil), diodes.WithWaiterReadBudget(10))
+		for i := 0; i < 100; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+
+		for i := 0; i < 100; i++ {
+			Expect(*(*int)(w.Next())).To(Equal(i))
+		}
+	})
+})
+
+var _ = Describe("Waiter NextN()", func() {
+	var w *diodes.Waiter
+
+	BeforeEach(func() {
+		w = diodes.NewWaiter(diodes.NewManyToOne(10, nil))
+	})
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	It("reads up to len(dst) available values", func() {
+		set(0, 5)
+
+		dst := make([]diodes.GenericDataType, 3)
+		Expect(w.NextN(dst)).To(Equal(3))
+		Expect(*(*int)(dst[2])).To(Equal(2))
+		Expect(w.NextN(dst)).To(Equal(2))
+		Expect(*(*int)(dst[1])).To(Equal(4))
+	})
+
+	It("waits for the first value only", func() {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			set(0, 1)
+		}()
+
+		Expect(w.NextN(make([]diodes.GenericDataType, 3))).To(Equal(1))
+	})
+
+	It("returns 0 at the end of the stream", func() {
+		set(0, 2)
+		w.Close()
+
+		dst := make([]diodes.GenericDataType, 3)
+		Expect(w.NextN(dst)).To(Equal(2))
+		Expect(w.NextN(dst)).To(Equal(0))
+		Expect(w.Closed()).To(BeTrue())
+	})
+})
+
+var _ = Describe("Waiter NextAvailable()", func() {
+	It("returns the available values in one slice", func() {
+		w := diodes.NewWaiter(diodes.NewManyToOne(10, nil))
+		for i := 0; i < 5; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+
+		got := w.NextAvailable(3)
+		Expect(got).To(HaveLen(3))
+		Expect(*(*int)(got[2])).To(Equal(2))
+		Expect(w.NextAvailable(10)).To(HaveLen(2))
+
+		w.Close()
+		Expect(w.NextAvailable(10)).To(BeNil())
+	})
+})
+
+var _ = Describe("Waiter NextCtx()", func() {
+	var w *diodes.Waiter
+
+	BeforeEach(func() {
+		w = diodes.NewWaiter(diodes.NewManyToOne(10, nil))
+	})
+
+	It("returns nil data as data", func() {
+		w.Set(nil)
+
+		data, err := w.NextCtx(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data == nil).To(BeTrue())
+	})
+
+	It("returns the error of the given context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := w.NextCtx(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("returns ErrTimeout once the deadline passes", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		_, err := w.NextCtx(ctx)
+		Expect(err).To(MatchError(diodes.ErrTimeout))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("returns ErrClosed at the end of the stream", func() {
+		w.Close()
+
+		_, err := w.NextCtx(context.Background())
+		Expect(err).To(MatchError(diodes.ErrClosed))
+	})
+})
+
+var _ = Describe("Waiter with a signal mode", func() {
+	DescribeTable("waits for data to be set",
+		func(mode diodes.SignalMode) {
+			w := diodes.NewWaiter(diodes.NewManyToOne(5, nil), diodes.WithSignalMode(mode), diodes.WithSignalSpins(10))
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				data := []byte("some-data")
+				w.Set(diodes.GenericDataType(&data))
+			}()
+
+			Expect(*(*[]byte)(w.Next())).To(Equal([]byte("some-data")))
+		},
+		Entry("cond", diodes.SignalCond),
+		Entry("spin", diodes.SignalSpin),
+		Entry("hybrid", diodes.SignalHybrid),
+		Entry("adaptive", diodes.SignalAdaptive),
+	)
+
+	DescribeTable("stops waiting once the context is done",
+		func(mode diodes.SignalMode) {
+			w := diodes.NewWaiter(diodes.NewManyToOne(5, nil), diodes.WithSignalMode(mode), diodes.WithSignalSpins(10))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := w.NextCtx(ctx)
+			Expect(err).To(MatchError(diodes.ErrTimeout))
+		},
+		Entry("cond", diodes.SignalCond),
+		Entry("spin", diodes.SignalSpin),
+		Entry("hybrid", diodes.SignalHybrid),
+		Entry("adaptive", diodes.SignalAdaptive),
+	)
+
+	It("stops spinning with SignalAdaptive once the reader waited longer than its spins", func() {
+		d := &countingDiode{Diode: diodes.NewManyToOne(5, nil)}
+		w := diodes.NewWaiter(d, diodes.WithSignalMode(diodes.SignalAdaptive), diodes.WithSignalSpins(1000))
+
+		setLater := func() {
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				w.Set(diodes.GenericDataType(new(int)))
+			}()
+		}
+
+		setLater()
+		w.Next()
+		Expect(d.reads.Load()).To(BeNumerically(">", 1000))
+
+		d.reads.Store(0)
+		setLater()
+		w.Next()
+		Expect(d.reads.Load()).To(BeNumerically("<", 10))
+	})
+
+	It("stops a spinning reader on Close", func() {
+		w := diodes.NewWaiter(diodes.NewManyToOne(5, nil), diodes.WithSignalMode(diodes.SignalSpin))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.Next()
+		}()
+
+		w.Close()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("Waiter Drain()", func() {
+	var w *diodes.Waiter
+
+	BeforeEach(func() {
+		w = diodes.NewWaiter(diodes.NewManyToOne(10, nil))
+	})
+
+	It("returns everything that was set before", func() {
+		for i := 0; i < 3; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+		w.Next()
+
+		data, err := w.Drain(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(2))
+		Expect(*(*int)(data[0])).To(Equal(1))
+		Expect(*(*int)(data[1])).To(Equal(2))
+		Expect(w.Closed()).To(BeTrue())
+	})
+
+	It("returns the error of the context if the end is not reached", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// A writer claimed the first slot but never stores its value.
+		d := diodes.NewManyToOne(10, nil)
+		diodes.SetIndexes(d, 1, 0)
+		w = diodes.NewWaiter(d)
+
+		_, err := w.Drain(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("does not set anything on the diode", func() {
+		d := diodes.NewSPSC(10, nil)
+		w = diodes.NewWaiter(d)
+		v := 1
+		w.Set(diodes.GenericDataType(&v))
+
+		data, err := w.Drain(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(HaveLen(1))
+		Expect(d.Len()).To(BeZero())
+
+		w.Set(diodes.GenericDataType(&v))
+		_, ok := w.TryNext()
+		Expect(ok).To(BeFalse())
+		Expect(d.Len()).To(Equal(1))
+	})
+})
+
+var _ = Describe("Waiter NextWithTimeout()", func() {
+	var w *diodes.Waiter
+
+	BeforeEach(func() {
+		w = diodes.NewWaiter(diodes.NewManyToOne(5, nil))
+	})
+
+	It("returns available data", func() {
+		data := []byte("some-data")
+		w.Set(diodes.GenericDataType(&data))
+
+		result, ok := w.NextWithTimeout(time.Minute)
+		Expect(ok).To(BeTrue())
+		Expect(*(*[]byte)(result)).To(Equal(data))
+	})
+
+	It("returns false once the timeout passed", func() {
+		start := time.Now()
+		_, ok := w.NextWithTimeout(10 * time.Millisecond)
+		Expect(ok).To(BeFalse())
+		Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+	})
+
+	It("is not ended by the timeout of a previous call", func() {
+		data := []byte("some-data")
+		w.Set(diodes.GenericDataType(&data))
+		w.NextWithTimeout(time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			w.Set(diodes.GenericDataType(&data))
+		}()
+
+		_, ok := w.NextWithTimeout(time.Minute)
+		Expect(ok).To(BeTrue())
+	})
+
+	It("does not allocate", func() {
+		w.NextWithTimeout(time.Millisecond)
+
+		Expect(testing.AllocsPerRun(10, func() {
+			w.NextWithTimeout(time.Microsecond)
+		})).To(BeZero())
+	})
+})
+
+var _ = Describe("Waiter with coalesced signals", func() {
+	set := func(w *diodes.Waiter, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	It("reads every value while only signaling a waiting reader", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(1024, nil), diodes.WithSignalCoalescing(1, 0))
+
+		go func() {
+			for i := 0; i < 1000; i++ {
+				set(w, i, i+1)
+				if i%100 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}()
+
+		for i := 0; i < 1000; i++ {
+			Expect(*(*int)(w.Next())).To(Equal(i))
+		}
+	})
+
+	It("wakes a waiting reader once enough values were set", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(8, nil), diodes.WithSignalCoalescing(3, time.Minute))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.Next()
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		set(w, 0, 2)
+		Consistently(done, 50*time.Millisecond).ShouldNot(BeClosed())
+
+		set(w, 2, 3)
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("wakes a waiting reader after the maximum delay", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(8, nil), diodes.WithSignalCoalescing(3, 50*time.Millisecond))
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			set(w, 0, 1)
+		}()
+
+		start := time.Now()
+		Expect(*(*int)(w.Next())).To(Equal(0))
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("wakes a waiting reader on Close", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(8, nil), diodes.WithSignalCoalescing(3, time.Minute))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			w.Next()
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		w.Close()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+var _ = Describe("Waiter with a filter", func() {
+	It("skips and counts the values the filter drops", func() {
+		w := diodes.NewWaiter(diodes.NewManyToOne(16, nil),
+			diodes.WithWaiterFilter(func(data diodes.GenericDataType) bool {
+				return *(*int)(data) >= 3
+			}),
+			diodes.WithWaiterTransform(func(data diodes.GenericDataType) diodes.GenericDataType {
+				j := 10 * *(*int)(data)
+				return diodes.GenericDataType(&j)
+			}),
+		)
+
+		go func() {
+			for i := 0; i < 5; i++ {
+				j := i
+				w.Set(diodes.GenericDataType(&j))
+			}
+		}()
+
+		Expect(*(*int)(w.Next())).To(Equal(30))
+		Expect(*(*int)(w.Next())).To(Equal(40))
+		Expect(w.Filtered()).To(Equal(uint64(3)))
+	})
+})
+
+var _ = Describe("Waiter with a first pending callback", func() {
+	var (
+		w     *diodes.Waiter
+		calls int
+	)
+
+	set := func(from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	BeforeEach(func() {
+		calls = 0
+		w = diodes.NewWaiter(diodes.NewManyToOne(16, nil), diodes.WithOnFirstPending(func() {
+			calls++
+		}))
+	})
+
+	It("is invoked once per batch of unread values", func() {
+		set(0, 3)
+		Expect(calls).To(Equal(1))
+
+		_, ok := w.TryNext()
+		Expect(ok).To(BeTrue())
+		set(3, 4)
+		Expect(calls).To(Equal(1))
+
+		for {
+			if _, ok := w.TryNext(); !ok {
+				break
+			}
+		}
+		set(4, 6)
+		Expect(calls).To(Equal(2))
+	})
+
+	It("is invoked again once the reader found the diode empty", func() {
+		set(0, 1)
+		w.TryNext()
+		w.TryNext()
+		Expect(calls).To(Equal(1))
+
+		set(1, 2)
+		Expect(calls).To(Equal(2))
+		Expect(*(*int)(w.Next())).To(Equal(1))
+	})
+})
+
+var _ = Describe("Waiter with a cancel policy", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	set := func(w *diodes.Waiter, from, to int) {
+		for i := from; i < to; i++ {
+			j := i
+			w.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	It("returns the data left in the diode with DrainOnCancel", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(4, nil), diodes.WithWaiterContext(ctx), diodes.WithCancelPolicy(diodes.DrainOnCancel))
+		set(w, 0, 2)
+		cancel()
+
+		Expect(*(*int)(w.Next())).To(Equal(0))
+		Expect(*(*int)(w.Next())).To(Equal(1))
+		Expect(w.Next() == nil).To(BeTrue())
+
+		_, err := w.NextCtx(context.Background())
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("returns data set right before the context was cancelled with DrainOnCancel", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(4, nil), diodes.WithCancelPolicy(diodes.DrainOnCancel))
+		for i := 0; i < 100; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				j := i
+				w.Set(diodes.GenericDataType(&j))
+				cancel()
+			}()
+
+			data, err := w.NextCtx(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*(*int)(data)).To(Equal(i))
+		}
+	})
+
+	It("returns nil right away with ImmediateOnCancel", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(4, nil), diodes.WithWaiterContext(ctx), diodes.WithCancelPolicy(diodes.ImmediateOnCancel))
+		set(w, 0, 2)
+		cancel()
+
+		Expect(w.Next() == nil).To(BeTrue())
+		_, err := w.NextCtx(context.Background())
+		Expect(err).To(MatchError(context.Canceled))
+
+		data, ok := w.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*int)(data)).To(Equal(0))
+	})
+
+	It("applies to the context given to the read", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(4, nil), diodes.WithCancelPolicy(diodes.ImmediateOnCancel))
+		set(w, 0, 1)
+		cancel()
+
+		_, err := w.NextCtx(ctx)
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
+
+var _ = Describe("Waiter Stop()", func() {
+	It("interrupts a blocked reader right away", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(4, nil))
+
+		done := make(chan error)
+		go func() {
+			_, err := w.NextCtx(context.Background())
+			done <- err
+		}()
+		Consistently(done, 20*time.Millisecond).ShouldNot(Receive())
+
+		Expect(w.Shutdown(context.Background())).To(Succeed())
+		Eventually(done).Should(Receive(MatchError(diodes.ErrStopped)))
+		Expect(w.Next() == nil).To(BeTrue())
+	})
+
+	It("interrupts a spinning reader", func() {
+		w := diodes.NewWaiter(diodes.NewOneToOne(4, nil), diodes.WithSignalMode(diodes.SignalSpin))
+
+		done := make(chan error)
+		go func() {
+			_, err := w.NextCtx(context.Background())
+			done <- err
+		}()
+
+		w.Stop()
+		Eventually(done).Should(Receive(MatchError(diodes.ErrStopped)))
+	})
+})
+
+// countingDiode counts the reads of the wrapped diode.
+type countingDiode struct {
+	diodes.Diode
+	reads atomic.Int64
+}
+
+func (d *countingDiode) TryNext() (diodes.GenericDataType, bool) {
+	d.reads.Add(1)
+	return d.Diode.TryNext()
+}
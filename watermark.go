@@ -0,0 +1,75 @@
+package diodes
+
+import "sync/atomic"
+
+// WatermarkState is the state of the backlog of a diode with watermarks
+// (see WithWatermarks).
+type WatermarkState int
+
+const (
+	// WatermarkLow means the backlog fell to or below the low watermark. It
+	// is the state of a new diode.
+	WatermarkLow WatermarkState = iota
+
+	// WatermarkHigh means the backlog reached the high watermark.
+	WatermarkHigh
+)
+
+// String returns the name of the state.
+func (s WatermarkState) String() string {
+	if s == WatermarkHigh {
+		return "high"
+	}
+	return "low"
+}
+
+// WithWatermarks invokes cb with WatermarkHigh once the number of unread
+// values reaches high, and with WatermarkLow once it falls back to low or
+// below, e.g. to turn down the verbosity of producers while the reader is
+// behind. cb is invoked once per crossing, on the go-routine of the writer
+// or the reader that crossed the watermark, so it should return quickly.
+// The backlog is checked on every Set and every read, so the diode does not
+// need to be polled. The OneToMany diode does not support it.
+func WithWatermarks(high, low int, cb func(WatermarkState)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.watermarks = &watermarks{
+			high: uint64(max(high, 1)),
+			low:  uint64(max(min(low, high-1), 0)),
+			cb:   cb,
+		}
+	})
+}
+
+// watermarks tracks whether the backlog of a diode is above its high
+// watermark.
+type watermarks struct {
+	high, low uint64
+	cb        func(WatermarkState)
+	above     atomic.Bool
+}
+
+// observe fires the callback if the given backlog crossed a watermark. Only
+// the go-routine that flips the state fires it, so concurrent writers and
+// readers fire every crossing once.
+func (w *watermarks) observe(backlog uint64) {
+	above := w.above.Load()
+	switch {
+	case !above && backlog >= w.high:
+		if w.above.CompareAndSwap(false, true) {
+			w.cb(WatermarkHigh)
+		}
+	case above && backlog <= w.low:
+		if w.above.CompareAndSwap(true, false) {
+			w.cb(WatermarkLow)
+		}
+	}
+}
+
+// observeBacklog checks the number of unread values against the watermarks,
+// if there are any.
+func (c *diodeConfig) observeBacklog(nextWrite, nextRead, size uint64) {
+	if c.watermarks == nil {
+		return
+	}
+	c.watermarks.observe(unread(nextWrite, nextRead, size))
+}
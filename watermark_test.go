@@ -0,0 +1,104 @@
+package diodes_test
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithWatermarks", func() {
+	set := func(d diodes.Diode, n int) {
+		for i := 0; i < n; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+	}
+
+	read := func(d diodes.Diode, n int) {
+		for i := 0; i < n; i++ {
+			d.TryNext()
+		}
+	}
+
+	DescribeTable("fires once per crossing",
+		func(newDiode func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode) {
+			var states []diodes.WatermarkState
+			d := newDiode(16, diodes.WithWatermarks(8, 2, func(s diodes.WatermarkState) {
+				states = append(states, s)
+			}))
+
+			set(d, 7)
+			Expect(states).To(BeEmpty())
+
+			set(d, 1)
+			Expect(states).To(Equal([]diodes.WatermarkState{diodes.WatermarkHigh}))
+
+			set(d, 4)
+			read(d, 9)
+			Expect(states).To(HaveLen(1))
+
+			read(d, 1)
+			Expect(states).To(Equal([]diodes.WatermarkState{diodes.WatermarkHigh, diodes.WatermarkLow}))
+
+			read(d, 2)
+			set(d, 1)
+			Expect(states).To(HaveLen(2))
+
+			set(d, 8)
+			Expect(states).To(Equal([]diodes.WatermarkState{diodes.WatermarkHigh, diodes.WatermarkLow, diodes.WatermarkHigh}))
+		},
+		Entry("OneToOne", func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewOneToOne(size, nil, opts...)
+		}),
+		Entry("ManyToOne", func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToOne(size, nil, opts...)
+		}),
+		Entry("ManyToMany", func(size int, opts ...diodes.DiodeConfigOption) diodes.Diode {
+			return diodes.NewManyToMany(size, nil, opts...)
+		}),
+	)
+
+	It("alternates between the states with concurrent writers", func() {
+		var (
+			mu     sync.Mutex
+			states []diodes.WatermarkState
+		)
+		d := diodes.NewManyToOne(64, nil, diodes.WithWatermarks(32, 4, func(s diodes.WatermarkState) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, s)
+		}))
+
+		for round := 0; round < 10; round++ {
+			var wg sync.WaitGroup
+			for w := 0; w < 4; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					set(d, 10)
+				}()
+			}
+			wg.Wait()
+			read(d, 64)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		Expect(states).To(HaveLen(20))
+		for i, s := range states {
+			if i%2 == 0 {
+				Expect(s).To(Equal(diodes.WatermarkHigh))
+			} else {
+				Expect(s).To(Equal(diodes.WatermarkLow))
+			}
+		}
+	})
+
+	It("names the states", func() {
+		Expect(diodes.WatermarkHigh.String()).To(Equal("high"))
+		Expect(diodes.WatermarkLow.String()).To(Equal("low"))
+	})
+})
@@ -0,0 +1,128 @@
+package diodes
+
+import (
+	"sync"
+	"time"
+)
+
+// WindowedAlerter is an Alerter that coalesces the alerts of a window into
+// a single callback, so that an overloaded diode does not cause a storm of
+// alerts. The window starts with the first alert after the previous window
+// was reported and the callback is invoked on its own go-routine once it
+// ends, with the total number of dropped values and the largest single
+// drop. It is safe to share between diodes.
+type WindowedAlerter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	handle   func(total, maxBurst int)
+	total    int
+	maxBurst int
+	timer    *time.Timer
+	stopped  bool
+}
+
+// NewWindowedAlerter returns a new WindowedAlerter that invokes handle at
+// most once per interval.
+func NewWindowedAlerter(interval time.Duration, handle func(total, maxBurst int)) *WindowedAlerter {
+	return &WindowedAlerter{
+		interval: interval,
+		handle:   handle,
+	}
+}
+
+// Alert adds the drops to the current window.
+func (a *WindowedAlerter) Alert(missed int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.stopped {
+		return
+	}
+
+	a.total += missed
+	a.maxBurst = max(a.maxBurst, missed)
+	if a.timer == nil {
+		a.timer = time.AfterFunc(a.interval, a.flush)
+	}
+}
+
+// Stop reports the drops of the current window right away, if there were
+// any, and ignores every alert afterwards.
+func (a *WindowedAlerter) Stop() {
+	a.mu.Lock()
+	a.stopped = true
+	if a.timer == nil || !a.timer.Stop() {
+		// The window was never started or is being reported already.
+		a.mu.Unlock()
+		return
+	}
+	a.mu.Unlock()
+
+	a.flush()
+}
+
+func (a *WindowedAlerter) flush() {
+	a.mu.Lock()
+	total, maxBurst := a.total, a.maxBurst
+	a.total, a.maxBurst = 0, 0
+	a.timer = nil
+	a.mu.Unlock()
+
+	a.handle(total, maxBurst)
+}
+
+// EdgeTriggeredAlerter is an Alerter that reports the first drop after a
+// quiet period right away, e.g. to page fast, and coalesces the drops that
+// follow it like a WindowedAlerter. The first drop is reported on the
+// go-routine of the reader and the windows on their own go-routine, so the
+// callback must be safe for concurrent use. It is safe to share between
+// diodes.
+type EdgeTriggeredAlerter struct {
+	mu      sync.Mutex
+	quiet   time.Duration
+	handle  func(total, maxBurst int)
+	last    time.Time
+	stopped bool
+	window  *WindowedAlerter
+}
+
+// NewEdgeTriggeredAlerter returns a new EdgeTriggeredAlerter that invokes
+// handle right away for the first drop after quiet passed without drops,
+// and at most once per window for the drops after it.
+func NewEdgeTriggeredAlerter(quiet, window time.Duration, handle func(total, maxBurst int)) *EdgeTriggeredAlerter {
+	return &EdgeTriggeredAlerter{
+		quiet:  quiet,
+		handle: handle,
+		window: NewWindowedAlerter(window, handle),
+	}
+}
+
+// Alert reports the drops right away if they end a quiet period and adds
+// them to the current window otherwise.
+func (a *EdgeTriggeredAlerter) Alert(missed int) {
+	a.mu.Lock()
+	now := time.Now()
+	edge := a.last.IsZero() || now.Sub(a.last) >= a.quiet
+	a.last = now
+	stopped := a.stopped
+	a.mu.Unlock()
+
+	if stopped {
+		return
+	}
+	if edge {
+		a.handle(missed, missed)
+		return
+	}
+	a.window.Alert(missed)
+}
+
+// Stop reports the drops of the current window right away, if there were
+// any, and ignores every alert afterwards.
+func (a *EdgeTriggeredAlerter) Stop() {
+	a.mu.Lock()
+	a.stopped = true
+	a.mu.Unlock()
+
+	a.window.Stop()
+}
@@ -0,0 +1,117 @@
+package diodes_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WindowedAlerter", func() {
+	type window struct {
+		total, maxBurst int
+	}
+
+	var (
+		windows chan window
+		a       *diodes.WindowedAlerter
+	)
+
+	BeforeEach(func() {
+		windows = make(chan window, 10)
+		a = diodes.NewWindowedAlerter(50*time.Millisecond, func(total, maxBurst int) {
+			windows <- window{total, maxBurst}
+		})
+		DeferCleanup(a.Stop)
+	})
+
+	It("coalesces the alerts of a window", func() {
+		a.Alert(3)
+		a.Alert(10)
+		a.Alert(1)
+		Expect(windows).ToNot(Receive())
+
+		Eventually(windows).Should(Receive(Equal(window{total: 14, maxBurst: 10})))
+		Consistently(windows, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("starts a new window with the next alert", func() {
+		a.Alert(3)
+		Eventually(windows).Should(Receive(Equal(window{total: 3, maxBurst: 3})))
+
+		a.Alert(5)
+		Eventually(windows).Should(Receive(Equal(window{total: 5, maxBurst: 5})))
+	})
+
+	It("reports the current window when it is stopped", func() {
+		a.Alert(7)
+		a.Stop()
+		Expect(windows).To(Receive(Equal(window{total: 7, maxBurst: 7})))
+
+		a.Alert(1)
+		Consistently(windows, 100*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("can be used as the alerter of a diode", func() {
+		d := diodes.NewOneToOne(2, a)
+		for i := 0; i < 10; i++ {
+			j := i
+			d.Set(diodes.GenericDataType(&j))
+		}
+		d.TryNext()
+
+		Eventually(windows).Should(Receive(Equal(window{total: 8, maxBurst: 8})))
+	})
+})
+
+var _ = Describe("EdgeTriggeredAlerter", func() {
+	type window struct {
+		total, maxBurst int
+	}
+
+	var (
+		windows chan window
+		a       *diodes.EdgeTriggeredAlerter
+	)
+
+	BeforeEach(func() {
+		windows = make(chan window, 10)
+		a = diodes.NewEdgeTriggeredAlerter(200*time.Millisecond, 50*time.Millisecond, func(total, maxBurst int) {
+			windows <- window{total, maxBurst}
+		})
+		DeferCleanup(a.Stop)
+	})
+
+	It("reports the first drop right away and coalesces the ones after it", func() {
+		a.Alert(3)
+		Expect(windows).To(Receive(Equal(window{total: 3, maxBurst: 3})))
+
+		a.Alert(10)
+		a.Alert(1)
+		Expect(windows).ToNot(Receive())
+		Eventually(windows).Should(Receive(Equal(window{total: 11, maxBurst: 10})))
+	})
+
+	It("reports the first drop after a quiet period right away", func() {
+		a.Alert(3)
+		Expect(windows).To(Receive())
+
+		time.Sleep(250 * time.Millisecond)
+		a.Alert(5)
+		Expect(windows).To(Receive(Equal(window{total: 5, maxBurst: 5})))
+	})
+
+	It("reports the current window when it is stopped", func() {
+		a.Alert(3)
+		a.Alert(7)
+		Expect(windows).To(Receive(Equal(window{total: 3, maxBurst: 3})))
+
+		a.Stop()
+		Expect(windows).To(Receive(Equal(window{total: 7, maxBurst: 7})))
+
+		a.Alert(1)
+		Consistently(windows, 100*time.Millisecond).ShouldNot(Receive())
+	})
+})
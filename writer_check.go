@@ -0,0 +1,48 @@
+package diodes
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// writerCheck detects overlapping Set calls on a diode that only supports a
+// single writer.
+type writerCheck struct {
+	busy     atomic.Bool
+	onMisuse func(stack string)
+}
+
+// WithSingleWriterCheck enables a debug mode for the OneToOne and OneToMany
+// diodes, which only support a single writer, that detects Set calls of
+// different go-routines overlapping each other. onMisuse is invoked with the
+// stack of the offending writer, or a nil panics with it, so that a second
+// writer surfaces in tests instead of silently corrupting the diode. Calls
+// that do not happen to overlap go unnoticed, so it is no replacement for
+// the race detector. The other diodes ignore it.
+func WithSingleWriterCheck(onMisuse func(stack string)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.writerCheck = &writerCheck{onMisuse: onMisuse}
+	})
+}
+
+// enter marks the start of a Set call. It reports the misuse if another Set
+// call is in progress and returns whether the caller owns the writer side
+// and needs to call exit.
+func (w *writerCheck) enter() bool {
+	if w.busy.CompareAndSwap(false, true) {
+		return true
+	}
+
+	stack := string(debug.Stack())
+	if w.onMisuse == nil {
+		panic(fmt.Sprintf("diodes: concurrent Set on a diode with a single writer\n%s", stack))
+	}
+	w.onMisuse(stack)
+	return false
+}
+
+// exit marks the end of the Set call that owns the writer side.
+func (w *writerCheck) exit() {
+	w.busy.Store(false)
+}
@@ -0,0 +1,101 @@
+package diodes_test
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WithSingleWriterCheck", func() {
+	var data []byte
+
+	BeforeEach(func() {
+		data = []byte("some-data")
+	})
+
+	// skipRace skips tests with overlapping writers, which are the very
+	// data race the check detects.
+	skipRace := func() {
+		if raceEnabled {
+			Skip("overlapping writers are a data race")
+		}
+	}
+
+	// blockedWriter starts a writer whose Set waits for the reader of the full
+	// diode, so that the next Set overlaps with it.
+	blockedWriter := func(d diodes.Diode) *sync.WaitGroup {
+		d.Set(diodes.GenericDataType(&data))
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Set(diodes.GenericDataType(&data))
+		}()
+		time.Sleep(20 * time.Millisecond)
+		return &wg
+	}
+
+	It("reports overlapping writers", func() {
+		skipRace()
+		stacks := make(chan string, 10)
+		d := diodes.NewOneToOne(1, nil,
+			diodes.WithBackpressure(time.Minute),
+			diodes.WithSingleWriterCheck(func(stack string) { stacks <- stack }),
+		)
+		wg := blockedWriter(d)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Set(diodes.GenericDataType(&data))
+		}()
+		Eventually(stacks).Should(Receive(ContainSubstring("writer_check_test.go")))
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		Eventually(func() chan struct{} {
+			d.TryNext()
+			return done
+		}).Should(BeClosed())
+	})
+
+	It("panics without a handler", func() {
+		skipRace()
+		d := diodes.NewOneToOne(1, nil,
+			diodes.WithBackpressure(time.Minute),
+			diodes.WithSingleWriterCheck(nil),
+		)
+		wg := blockedWriter(d)
+
+		Expect(func() {
+			d.Set(diodes.GenericDataType(&data))
+		}).To(PanicWith(ContainSubstring("concurrent Set")))
+
+		d.TryNext()
+		wg.Wait()
+	})
+
+	It("does not report writers taking turns", func() {
+		d := diodes.NewOneToOne(5, nil, diodes.WithSingleWriterCheck(nil))
+
+		for i := 0; i < 3; i++ {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.Set(diodes.GenericDataType(&data))
+			}()
+			wg.Wait()
+		}
+
+		Expect(d.Len()).To(Equal(3))
+	})
+})
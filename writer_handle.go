@@ -0,0 +1,72 @@
+package diodes
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// WriterHandle is a writer that registered itself with a diode via
+// RegisterWriter. It must be closed once the writer is done so that the
+// diode can keep track of its active writers.
+type WriterHandle struct {
+	set    func(GenericDataType)
+	stats  *diodeStats
+	closed atomic.Bool
+}
+
+var _ Writer = (*WriterHandle)(nil)
+
+// Set sets the data on the diode the writer is registered with.
+func (w *WriterHandle) Set(data GenericDataType) {
+	w.set(data)
+}
+
+// Close unregisters the writer. It is safe to call Close more than once.
+func (w *WriterHandle) Close() {
+	if w.closed.CompareAndSwap(false, true) {
+		w.stats.writers.Add(-1)
+	}
+}
+
+// WithWriterLeakDetection enables a debug mode where onLeak is invoked with
+// the stack of the registration when a WriterHandle is garbage collected
+// without having been closed. It relies on finalizers and captures a stack
+// for every registration, so it should not be used in production.
+func WithWriterLeakDetection(onLeak func(stack string)) DiodeConfigOption {
+	return DiodeConfigOption(func(c *diodeConfig) {
+		c.onWriterLeak = onLeak
+	})
+}
+
+func newWriterHandle(set func(GenericDataType), stats *diodeStats, onLeak func(string)) *WriterHandle {
+	w := &WriterHandle{
+		set:   set,
+		stats: stats,
+	}
+	stats.writers.Add(1)
+
+	if onLeak != nil {
+		stack := string(debug.Stack())
+		runtime.SetFinalizer(w, func(w *WriterHandle) {
+			if !w.closed.Load() {
+				w.Close()
+				onLeak(stack)
+			}
+		})
+	}
+
+	return w
+}
+
+// RegisterWriter returns a handle for a writer of the diode. The number of
+// writers that are registered and not yet closed is reported by Stats.
+func (d *OneToOne) RegisterWriter() *WriterHandle {
+	return newWriterHandle(d.Set, &d.diodeStats, d.onWriterLeak)
+}
+
+// RegisterWriter returns a handle for a writer of the diode. The number of
+// writers that are registered and not yet closed is reported by Stats.
+func (d *ManyToOne) RegisterWriter() *WriterHandle {
+	return newWriterHandle(d.Set, &d.diodeStats, d.onWriterLeak)
+}
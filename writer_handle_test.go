@@ -0,0 +1,92 @@
+package diodes_test
+
+import (
+	"runtime"
+	"sync"
+
+	"code.cloudfoundry.org/go-diodes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriterHandle", func() {
+	It("sets data on the diode", func() {
+		d := diodes.NewManyToOne(5, nil)
+		w := d.RegisterWriter()
+		defer w.Close()
+
+		data := []byte("some-data")
+		w.Set(diodes.GenericDataType(&data))
+
+		result, ok := d.TryNext()
+		Expect(ok).To(BeTrue())
+		Expect(*(*[]byte)(result)).To(Equal(data))
+	})
+
+	It("counts active writers", func() {
+		d := diodes.NewManyToOne(5, nil)
+		w1 := d.RegisterWriter()
+		w2 := d.RegisterWriter()
+		Expect(d.Stats().ActiveWriters).To(Equal(int64(2)))
+
+		w1.Close()
+		w1.Close()
+		Expect(d.Stats().ActiveWriters).To(Equal(int64(1)))
+
+		w2.Close()
+		Expect(d.Stats().ActiveWriters).To(BeZero())
+	})
+
+	It("counts active writers of a OneToOne diode", func() {
+		d := diodes.NewOneToOne(5, nil)
+		w := d.RegisterWriter()
+		Expect(d.Stats().ActiveWriters).To(Equal(int64(1)))
+
+		w.Close()
+		Expect(d.Stats().ActiveWriters).To(BeZero())
+	})
+
+	Context("with leak detection", func() {
+		var (
+			mu     sync.Mutex
+			stacks []string
+		)
+
+		BeforeEach(func() {
+			mu.Lock()
+			defer mu.Unlock()
+			stacks = nil
+		})
+
+		leaks := func() []string {
+			runtime.GC()
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), stacks...)
+		}
+
+		newDiode := func() *diodes.ManyToOne {
+			return diodes.NewManyToOne(5, nil, diodes.WithWriterLeakDetection(func(stack string) {
+				mu.Lock()
+				defer mu.Unlock()
+				stacks = append(stacks, stack)
+			}))
+		}
+
+		It("reports writers that were never closed with their registration stack", func() {
+			d := newDiode()
+			d.RegisterWriter()
+
+			Eventually(leaks).Should(ConsistOf(ContainSubstring("writer_handle_test.go")))
+			Expect(d.Stats().ActiveWriters).To(BeZero())
+		})
+
+		It("does not report closed writers", func() {
+			d := newDiode()
+			d.RegisterWriter().Close()
+
+			Consistently(leaks, "200ms").Should(BeEmpty())
+		})
+	})
+})